@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/bcicen/ctop/config"
+	ui "github.com/gizak/termui"
+)
+
+// Container fields available to custom action templates
+type ActionContext struct {
+	ID     string
+	Name   string
+	Image  string
+	Pid    int
+	Labels map[string]string
+	Env    map[string]string // whitelisted environment variables only
+}
+
+func newActionContext(c *Container) ActionContext {
+	pid, _ := strconv.Atoi(c.GetMeta("pid"))
+	labels := c.Labels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	return ActionContext{
+		ID:     c.Id,
+		Name:   rawName(c),
+		Image:  c.GetMeta("image"),
+		Pid:    pid,
+		Labels: labels,
+		Env:    c.Env().Values(),
+	}
+}
+
+// Bind keys for all configured custom actions. Keys already bound
+// to a builtin action are skipped
+func handleActions(run func(*config.Action)) {
+	for _, a := range config.GlobalActions {
+		path := "/sys/kbd/" + a.Key
+		if _, ok := ui.DefaultEvtStream.Handlers[path]; ok {
+			log.Warningf("action %s: key %s already in use", a.Name, a.Key)
+			continue
+		}
+		action := a
+		ui.Handle(path, func(ui.Event) { run(action) })
+	}
+}
+
+// Run a custom action against a container
+func RunAction(a *config.Action, c *Container) {
+	if c == nil || !checkWritable("action "+a.Name) {
+		return
+	}
+	cmdStr, err := a.Render(newActionContext(c))
+	if err != nil {
+		log.NotifyError("action %s: %s", a.Name, err)
+		return
+	}
+	log.Infof("running action %s: %s", a.Name, cmdStr)
+
+	cmd := exec.Command("/bin/sh", "-c", cmdStr)
+	if a.Detach {
+		goTask(func() {
+			out, err := cmd.CombinedOutput()
+			log.Debugf("action %s output: %s", a.Name, out)
+			notifyActionExit(a, err)
+		})
+		return
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	suspendUI(func() {
+		fmt.Printf("running %s: %s\n", a.Name, cmdStr)
+		err = cmd.Run()
+	})
+	notifyActionExit(a, err)
+}
+
+func notifyActionExit(a *config.Action, err error) {
+	switch err := err.(type) {
+	case nil:
+		log.Notify("action %s exited with status 0", a.Name)
+	case *exec.ExitError:
+		log.NotifyError("action %s %s", a.Name, err)
+	default:
+		log.NotifyError("action %s failed: %s", a.Name, err)
+	}
+}
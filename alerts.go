@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/logging"
+	"github.com/bcicen/ctop/metrics"
+)
+
+const (
+	webhookTimeout   = 5 * time.Second
+	webhookQueueSize = 100
+)
+
+var (
+	alerts        = make(map[alertKey]*alertState)
+	netReadings   = make(map[string]netReading)            // last network counters, by container key
+	ruleScopes    = make(map[*config.Rule]containerFilter) // parsed rule scopes, by rule
+	alertsLock    sync.Mutex
+	webhookClient = &http.Client{Timeout: webhookTimeout}
+	webhookQueue  = make(chan webhookMsg, webhookQueueSize)
+	webhookOnce   sync.Once
+)
+
+type webhookMsg struct {
+	rule    *config.Rule
+	payload alertPayload
+}
+
+type alertKey struct {
+	rule *config.Rule
+	key  string // container key
+}
+
+// Key of the notification of an alert, replaced each time the rule
+// fires again for the container
+func (k alertKey) notifyKey() string { return fmt.Sprintf("alert %s %s", k.rule, k.key) }
+
+// Network counters of a container as of its last check, from which
+// net_rx and net_tx rules are evaluated as rates
+type netReading struct {
+	rx, tx int64
+	at     time.Time
+}
+
+// Return the rates in bytes/s received and sent since the previous
+// reading, or -1 for a rate not known, as on a first reading or a
+// counter reset. Must be called with alertsLock held
+func netRatesSince(key string, m metrics.Metrics) (rx, tx int64) {
+	now := time.Now()
+	prev, ok := netReadings[key]
+	netReadings[key] = netReading{m.NetRx, m.NetTx, now}
+	secs := now.Sub(prev.at).Seconds()
+	rate := func(cur, last int64) int64 {
+		if !ok || secs <= 0 || last < 0 || cur < last {
+			return -1
+		}
+		return int64(float64(cur-last) / secs)
+	}
+	return rate(m.NetRx, prev.rx), rate(m.NetTx, prev.tx)
+}
+
+type alertState struct {
+	firing   bool
+	notified bool      // whether the current firing was notified, or suppressed by cooldown
+	lastSent time.Time // time the last firing was notified
+	value    int64     // value last read while firing
+}
+
+// Webhook request body, sent when a rule fires or clears
+type alertPayload struct {
+	Event     string         `json:"event"` // "firing" or "resolved"
+	Rule      string         `json:"rule"`
+	Scope     string         `json:"scope,omitempty"`
+	Metric    string         `json:"metric"`
+	Threshold int64          `json:"threshold"`
+	Value     int64          `json:"value"`
+	Container alertContainer `json:"container"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type alertContainer struct {
+	ID     string `json:"id"`
+	Source string `json:"source"`
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+}
+
+// Return the value of a rule metric, or false if not yet read.
+// Network metrics are rates, given in bytes/s
+func ruleValue(metric string, m metrics.Metrics, rxRate, txRate int64) (int64, bool) {
+	var v int64
+	switch metric {
+	case "cpu":
+		v = int64(m.CPUUtil)
+	case "mem":
+		v = int64(m.MemPercent)
+	case "mem_bytes":
+		v = m.MemUsage
+	case "net_rx":
+		v = rxRate
+	case "net_tx":
+		v = txRate
+	case "pids":
+		v = int64(m.Pids)
+	}
+	return v, v >= 0
+}
+
+// Return the filter selecting the containers a rule applies to,
+// matching all containers for an unscoped rule. Must be called with
+// alertsLock held
+func ruleScope(r *config.Rule) containerFilter {
+	f, ok := ruleScopes[r]
+	if !ok {
+		f, _ = parseFilter(r.Scope)
+		ruleScopes[r] = f
+	}
+	return f
+}
+
+// Check the scope of a rule, as a container filter
+func validRuleScope(r *config.Rule) error {
+	if _, err := parseFilter(r.Scope); err != nil {
+		return fmt.Errorf("rule %s: %s", r, err)
+	}
+	return nil
+}
+
+// Exit on a rule whose scope is not a valid container filter
+func validRules() {
+	for _, r := range config.GlobalRules {
+		if err := validRuleScope(r); err != nil {
+			fmt.Printf("invalid alert: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// Evaluate threshold rules against newly read container metrics,
+// alerting on rules that fire or clear. Rules apply to containers
+// matching their scope; a container leaving the scope of a firing rule
+// clears it. Alerts for a rule are not repeated within its cooldown; a
+// clear is only sent for a notified firing
+func checkAlerts(c *Container, m metrics.Metrics) {
+	if len(config.GlobalRules) == 0 {
+		return
+	}
+	alertsLock.Lock()
+	defer alertsLock.Unlock()
+
+	paused := c.State() == "paused"
+	rxRate, txRate := netRatesSince(c.Key(), m)
+	for _, r := range config.GlobalRules {
+		// the cpu of a paused container is frozen at zero
+		if paused && r.Metric == "cpu" {
+			continue
+		}
+		v, ok := ruleValue(r.Metric, m, rxRate, txRate)
+		if !ok {
+			continue
+		}
+		key := alertKey{r, c.Key()}
+		inScope := ruleScope(r).match(c)
+		st, ok := alerts[key]
+		if !ok {
+			if !inScope {
+				continue
+			}
+			st = &alertState{}
+			alerts[key] = st
+		}
+
+		breached := inScope && r.Breached(v)
+		if breached {
+			st.value = v
+		}
+		if breached == st.firing {
+			continue
+		}
+		st.firing = breached
+
+		if breached {
+			st.notified = time.Since(st.lastSent) >= r.Cooldown
+			if !st.notified {
+				log.Debugf("alert %s for %s suppressed by cooldown", r, c.GetMeta("name"))
+				continue
+			}
+			st.lastSent = time.Now()
+			log.NotifyErrorKey(key.notifyKey(), "alert: %s %s (%d)", c.GetMeta("name"), r, v)
+			if isWatched(c.Key()) {
+				alertUser(fmt.Sprintf("%s: %s (%d)", c.GetMeta("name"), r, v))
+			}
+			sendAlert(r, newAlertPayload("firing", r, c, v))
+			continue
+		}
+
+		if st.notified {
+			st.notified = false
+			logging.DismissKey(key.notifyKey())
+			log.Notify("resolved: %s %s (%d)", c.GetMeta("name"), r, v)
+			sendAlert(r, newAlertPayload("resolved", r, c, v))
+		}
+	}
+}
+
+// Describe the rules firing for a container, by key, with the values
+// last read, for the expanded view
+func firingRules(key string) string {
+	alertsLock.Lock()
+	defer alertsLock.Unlock()
+	var firing []string
+	for _, r := range config.GlobalRules {
+		if st, ok := alerts[alertKey{r, key}]; ok && st.firing {
+			firing = append(firing, fmt.Sprintf("%s (%d)", r, st.value))
+		}
+	}
+	return strings.Join(firing, "\n")
+}
+
+// Return the number of rules firing, over all containers
+func firingCount() (n int) {
+	alertsLock.Lock()
+	defer alertsLock.Unlock()
+	for _, st := range alerts {
+		if st.firing {
+			n++
+		}
+	}
+	return n
+}
+
+// Discard alert state for a container, by key
+func clearAlerts(key string) {
+	alertsLock.Lock()
+	defer alertsLock.Unlock()
+	delete(netReadings, key)
+	for k := range alerts {
+		if k.key == key {
+			delete(alerts, k)
+		}
+	}
+}
+
+func newAlertPayload(event string, r *config.Rule, c *Container, v int64) alertPayload {
+	return alertPayload{
+		Event:     event,
+		Rule:      r.String(),
+		Scope:     r.Scope,
+		Metric:    r.Metric,
+		Threshold: r.Value,
+		Value:     v,
+		Container: alertContainer{
+			ID:     c.Id,
+			Source: c.Source,
+			Name:   c.GetMeta("name"),
+			Image:  c.GetMeta("image"),
+		},
+		Timestamp: time.Now(),
+	}
+}
+
+// Queue an alert for delivery to the rule webhook, if any. Alerts are
+// delivered in order by a background goroutine, and dropped if the
+// queue is full
+func sendAlert(r *config.Rule, p alertPayload) {
+	if r.Webhook == "" {
+		return
+	}
+	webhookOnce.Do(func() { go deliverWebhooks() })
+	select {
+	case webhookQueue <- webhookMsg{r, p}:
+	default:
+		log.Errorf("webhook queue full, dropped alert for rule %s", r)
+	}
+}
+
+func deliverWebhooks() {
+	for msg := range webhookQueue {
+		if err := postWebhook(msg.rule.Webhook, msg.payload); err != nil {
+			log.Errorf("webhook for rule %s failed: %s", msg.rule, err)
+		}
+	}
+}
+
+func postWebhook(url string, p alertPayload) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(p); err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(url, "application/json", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Send a sample alert payload to a webhook, exiting with an error
+// if delivery fails
+func TestWebhook(url string) {
+	p := alertPayload{
+		Event:     "firing",
+		Rule:      "cpu>80",
+		Metric:    "cpu",
+		Threshold: 80,
+		Value:     93,
+		Container: alertContainer{
+			ID:     "0123456789ab",
+			Source: sourceLabel("unix:///var/run/docker.sock"),
+			Name:   "ctop-test",
+			Image:  "ctop-test:latest",
+		},
+		Timestamp: time.Now(),
+	}
+	if err := postWebhook(url, p); err != nil {
+		fmt.Printf("webhook test failed: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("sent test payload to %s\n", url)
+}
+
+// Validate the alert rules of a config file and those given as
+// options, their thresholds and scopes, listing each rule. Returns
+// false if any is invalid
+func CheckAlertConfig(path string, specs []string) bool {
+	if path == "" {
+		path = config.FindFile()
+	}
+	var rules []*config.Rule
+	var errs []error
+	if path != "" {
+		var err error
+		rules, errs, err = config.CheckRules(path, validRuleScope)
+		if err != nil {
+			fmt.Printf("failed to read config: %s\n", err)
+			return false
+		}
+	}
+	for _, s := range specs {
+		r, err := config.NewRule(s)
+		if err == nil {
+			err = validRuleScope(r)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("-alert %s: %s", s, err))
+			continue
+		}
+		r.Source = config.SourceFlag
+		rules = append(rules, r)
+	}
+
+	for _, r := range rules {
+		scope := "all containers"
+		if r.Scope != "" {
+			scope = r.Scope
+		}
+		fmt.Printf("ok: %s%s%d, cooldown %s, applies to %s (%s)\n", r.Metric, r.Op, r.Value, r.Cooldown, scope, r.Source)
+	}
+	for _, err := range errs {
+		fmt.Printf("invalid: %s\n", err)
+	}
+	fmt.Printf("%d valid, %d invalid alert rules\n", len(rules), len(errs))
+	return len(errs) == 0
+}
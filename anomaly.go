@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+)
+
+const (
+	// rates needed before a baseline is trusted
+	minBaselineRates = 10
+	// rate in bytes/s below which no anomaly is flagged, so that
+	// mostly idle containers are not flagged for small bursts
+	minAnomalyRate = 1024
+	// recent samples the baseline is taken from
+	anomalyBaseline = 60
+)
+
+var (
+	anomalies     = make(map[string]*anomalyState)
+	anomaliesLock sync.Mutex
+)
+
+type anomalyState struct {
+	over    int // consecutive samples above the threshold
+	flagged bool
+}
+
+// Return configured anomaly factor and consecutive samples, with
+// a zero factor if detection is disabled
+func anomalyConfig() (float64, int) {
+	factor, err := strconv.ParseFloat(config.GetVal("netAnomalyFactor"), 64)
+	if err != nil || factor < 0 {
+		factor = 0
+	}
+	samples, err := strconv.Atoi(config.GetVal("netAnomalySamples"))
+	if err != nil || samples < 1 {
+		samples = 5
+	}
+	return factor, samples
+}
+
+// Return combined network rx+tx rates in bytes/s between consecutive
+// history samples, skipping unread samples and counter resets
+func netRates(history []Sample) (rates []float64) {
+	for n := 1; n < len(history); n++ {
+		prev, cur := history[n-1], history[n]
+		if prev.NetRx < 0 || prev.NetTx < 0 || cur.NetRx < 0 || cur.NetTx < 0 {
+			continue
+		}
+		delta := (cur.NetRx + cur.NetTx) - (prev.NetRx + prev.NetTx)
+		secs := cur.Time.Sub(prev.Time).Seconds()
+		if delta < 0 || secs <= 0 {
+			continue
+		}
+		rates = append(rates, float64(delta)/secs)
+	}
+	return rates
+}
+
+func median(a []float64) float64 {
+	s := append([]float64{}, a...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// Check the latest network rate of a container against its baseline,
+// the median of prior rates in its history. A container is flagged
+// once its rate exceeds the baseline by the configured factor for the
+// configured number of consecutive samples, and cleared on the first
+// sample back under it
+func checkNetAnomaly(c *Container) {
+	factor, samples := anomalyConfig()
+
+	anomaliesLock.Lock()
+	defer anomaliesLock.Unlock()
+
+	st, ok := anomalies[c.Key()]
+	if !ok {
+		st = &anomalyState{}
+		anomalies[c.Key()] = st
+	}
+
+	rates := netRates(c.Recent(anomalyBaseline))
+	if factor == 0 || len(rates) < minBaselineRates+1 {
+		st.over = 0
+		setNetAnomaly(c, st, false, 0, 0)
+		return
+	}
+
+	rate := rates[len(rates)-1]
+	baseline := median(rates[:len(rates)-1])
+	if rate >= minAnomalyRate && rate > baseline*factor {
+		st.over++
+	} else {
+		st.over = 0
+	}
+	setNetAnomaly(c, st, st.over >= samples, rate, baseline)
+}
+
+func setNetAnomaly(c *Container, st *anomalyState, flagged bool, rate, baseline float64) {
+	// marked on every sample, as widgets may be rebuilt
+	c.Widgets().SetNetAnomaly(flagged)
+	if flagged == st.flagged {
+		return
+	}
+	st.flagged = flagged
+
+	name := c.GetMeta("name")
+	if flagged {
+		ratio := "from an idle baseline"
+		if baseline >= 1 {
+			ratio = fmt.Sprintf("%.0fx its baseline of %s/s", rate/baseline, cwidgets.ByteFormat(int64(baseline)))
+		}
+		log.NotifyError("net anomaly: %s at %s/s, %s", name, cwidgets.ByteFormat(int64(rate)), ratio)
+		return
+	}
+	log.Notify("net anomaly cleared: %s", name)
+}
+
+// Discard network anomaly state for a container
+func clearNetAnomaly(c *Container) {
+	anomaliesLock.Lock()
+	defer anomaliesLock.Unlock()
+	delete(anomalies, c.Key())
+	c.Widgets().SetNetAnomaly(false)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var apiServer *http.Server
+
+// Single container, with recent metric history
+type apiContainer struct {
+	*jsonContainer
+	History []apiSample `json:"history"`
+}
+
+type apiSample struct {
+	Time time.Time `json:"time"`
+	*jsonMetrics
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// Start the read-only JSON API on the given address until stopAPI
+// is called. Returns an error if the address cannot be bound
+func startAPI(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", apiHealth)
+	mux.HandleFunc("/containers", apiContainers)
+	mux.HandleFunc("/containers/", apiContainerByID)
+	apiServer = &http.Server{Handler: apiMethods(mux)}
+	safeGo(func() {
+		if err := apiServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("api listener: %s", err)
+		}
+	})
+	log.Noticef("serving api on %s", l.Addr())
+	return nil
+}
+
+// Gracefully stop an HTTP server, waiting briefly for open requests
+func shutdownServer(s *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.Shutdown(ctx)
+}
+
+func stopAPI() {
+	if apiServer == nil {
+		return
+	}
+	shutdownServer(apiServer)
+	apiServer = nil
+}
+
+// Reject all requests other than GET
+func apiMethods(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			apiWrite(w, http.StatusMethodNotAllowed, apiError{"method not allowed"})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func apiWrite(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiHealth(w http.ResponseWriter, r *http.Request) {
+	if lost := cursor.Source().LostSince(); !lost.IsZero() {
+		msg := fmt.Sprintf("connection lost since %s", lost.Format(time.RFC3339))
+		apiWrite(w, http.StatusServiceUnavailable, apiError{msg})
+		return
+	}
+	apiWrite(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func apiContainers(w http.ResponseWriter, r *http.Request) {
+	list := []*jsonContainer{}
+	for _, c := range cursor.Source().All() {
+		list = append(list, newJSONContainer(c))
+	}
+	apiWrite(w, http.StatusOK, list)
+}
+
+func apiContainerByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/containers/")
+	c, err := findContainer(id)
+	if err != nil {
+		apiWrite(w, http.StatusNotFound, apiError{err.Error()})
+		return
+	}
+
+	ac := apiContainer{
+		jsonContainer: newJSONContainer(c),
+		History:       []apiSample{},
+	}
+	for _, s := range c.History() {
+		ac.History = append(ac.History, apiSample{s.Time, newJSONMetrics(s.Metrics)})
+	}
+	apiWrite(w, http.StatusOK, ac)
+}
+
+// Find a container by full ID or unique ID prefix
+func findContainer(id string) (*Container, error) {
+	if id == "" {
+		return nil, fmt.Errorf("no container id given")
+	}
+	if c, ok := cursor.Source().Get(id); ok {
+		return c, nil
+	}
+	var match *Container
+	for _, c := range cursor.Source().All() {
+		if strings.HasPrefix(c.Id, id) {
+			if match != nil {
+				return nil, fmt.Errorf("ambiguous container id: %s", id)
+			}
+			match = c
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no such container: %s", id)
+	}
+	return match, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/docker/docker/pkg/term"
+	ui "github.com/gizak/termui"
+	"github.com/nsf/termbox-go"
+)
+
+// Release the terminal while running f, restoring the
+// UI afterwards even if f exits abnormally
+func suspendUI(f func()) {
+	termLock.Lock()
+	termbox.Close()
+	termReleased = true
+	termLock.Unlock()
+	defer func() {
+		termLock.Lock()
+		defer termLock.Unlock()
+		termReleased = false
+		if err := termbox.Init(); err != nil {
+			panic(err)
+		}
+		ui.Clear()
+	}()
+	f()
+}
+
+// Suspend the UI and attach the terminal to a container's
+// standard streams, restoring the UI once detached
+func AttachView(c *Container) {
+	if c == nil || !checkWritable("attach") {
+		return
+	}
+	if c.State() != "running" {
+		log.Notify("cannot attach to %s: container not running", c.GetMeta("name"))
+		return
+	}
+
+	opts := AttachOpts{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		TTY:    c.GetMeta("tty") == "true",
+		Width:  ui.TermWidth(),
+		Height: ui.TermHeight(),
+	}
+
+	suspendUI(func() { attach(c, opts) })
+}
+
+// Closed to detach from a container attached without a TTY, by
+// ctrl-d or SIGINT; nil while not attached so
+var (
+	attachDetach chan struct{}
+	attachLock   sync.Mutex
+)
+
+// Detach from a container attached without a TTY, returning false
+// if none is, e.g. for SIGINT to quit instead
+func detachAttached() bool {
+	attachLock.Lock()
+	defer attachLock.Unlock()
+	if attachDetach == nil {
+		return false
+	}
+	close(attachDetach)
+	attachDetach = nil
+	return true
+}
+
+func attach(c *Container, opts AttachOpts) {
+	in, err := newAttachInput(os.Stdin)
+	if err != nil {
+		log.NotifyError("attach to %s failed: %s", c.GetMeta("name"), err)
+		return
+	}
+	defer in.Close()
+
+	if opts.TTY {
+		// pass keys, including the detach sequence, through to the container
+		fmt.Printf("attached to %s, detach with ctrl-p ctrl-q\n", c.GetMeta("name"))
+		opts.Stdin = in
+		if fd, ok := term.GetFdInfo(os.Stdin); ok {
+			state, err := term.SetRawTerminal(fd)
+			if err != nil {
+				log.Errorf("failed to set raw terminal: %s", err)
+			} else {
+				defer term.RestoreTerminal(fd, state)
+			}
+		}
+	} else {
+		// the daemon reads no detach sequence without a TTY, so input
+		// is kept, detaching on ctrl-d or ctrl-c
+		fmt.Printf("attached to %s without a TTY, input is not passed; detach with ctrl-d or ctrl-c\n", c.GetMeta("name"))
+		opts.Stdin = nil
+		detach := make(chan struct{})
+		opts.Detach = detach
+		attachLock.Lock()
+		attachDetach = detach
+		attachLock.Unlock()
+		defer detachAttached()
+		safeGo(func() { waitEOF(in) })
+	}
+
+	if err := cursor.Source().Attach(c.Id, opts); err != nil {
+		log.NotifyError("attach to %s failed: %s", c.GetMeta("name"), err)
+		return
+	}
+	log.Notify("detached from %s", c.GetMeta("name"))
+}
+
+// Discard terminal input until ctrl-d, then detach
+func waitEOF(in *attachInput) {
+	buf := make([]byte, 256)
+	for {
+		if _, err := in.Read(buf); err != nil {
+			if !in.Closed() {
+				detachAttached()
+			}
+			return
+		}
+	}
+}
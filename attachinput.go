@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+// Terminal input passed to an attached container. Reads wait for
+// input with select before reading, so that once closed no read is
+// left blocked on the terminal, where it would take the first key
+// pressed back in the UI
+type attachInput struct {
+	f         *os.File
+	cancel    *os.File // read end of a pipe, readable once done is closed
+	done      *os.File
+	closed    int32
+	closeOnce sync.Once
+}
+
+func newAttachInput(f *os.File) (*attachInput, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	return &attachInput{f: f, cancel: r, done: w}, nil
+}
+
+// Read once input is available, returning io.EOF once closed
+func (in *attachInput) Read(p []byte) (int, error) {
+	if in.Closed() {
+		return 0, io.EOF
+	}
+	fd, cancel := int(in.f.Fd()), int(in.cancel.Fd())
+	for {
+		var set syscall.FdSet
+		if !fdSet(&set, fd) || !fdSet(&set, cancel) {
+			// beyond the descriptors select takes, reads may block
+			return in.f.Read(p)
+		}
+		n := fd
+		if cancel > n {
+			n = cancel
+		}
+		err := selectRead(n+1, &set)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if fdIsSet(&set, cancel) {
+			in.cancel.Close()
+			return 0, io.EOF
+		}
+		return in.f.Read(p)
+	}
+}
+
+// Stop reading, ending any read waiting for input. Called by the
+// attach once the container's output ends, and on returning
+func (in *attachInput) Close() error {
+	in.closeOnce.Do(func() {
+		atomic.StoreInt32(&in.closed, 1)
+		in.done.Close()
+	})
+	return nil
+}
+
+func (in *attachInput) Closed() bool { return atomic.LoadInt32(&in.closed) == 1 }
+
+// Add a descriptor to a select set, returning false if out of range
+func fdSet(set *syscall.FdSet, fd int) bool {
+	bits := int(8 * unsafe.Sizeof(set.Bits[0]))
+	if fd < 0 || fd >= len(set.Bits)*bits {
+		return false
+	}
+	set.Bits[fd/bits] |= 1 << uint(fd%bits)
+	return true
+}
+
+func fdIsSet(set *syscall.FdSet, fd int) bool {
+	bits := int(8 * unsafe.Sizeof(set.Bits[0]))
+	return set.Bits[fd/bits]&(1<<uint(fd%bits)) != 0
+}
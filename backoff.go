@@ -0,0 +1,115 @@
+package main
+
+import (
+	"time"
+)
+
+const (
+	inspectBackoffMin  = 2 * time.Second  // delay before retrying a failed inspect
+	inspectBackoffMax  = 4 * time.Minute  // longest delay between retries
+	inspectRepeatedLog = 10 * time.Minute // interval of summaries of a repeated error
+)
+
+// Consecutive inspect failures of a container, and when to retry
+type inspectFailure struct {
+	count   int       // consecutive failures
+	retry   time.Time // refreshes are skipped until then
+	err     string    // last error
+	repeats int       // times err repeated since last logged
+	logged  time.Time // time err or a summary of it was last logged
+}
+
+// Delay before retrying after a number of consecutive failures,
+// doubling with each failure up to inspectBackoffMax
+func inspectBackoff(count int) time.Duration {
+	d := inspectBackoffMin
+	for n := 1; n < count && d < inspectBackoffMax; n++ {
+		d *= 2
+	}
+	if d > inspectBackoffMax {
+		d = inspectBackoffMax
+	}
+	return d
+}
+
+// Return whether a container may be inspected, rather than being held
+// back after failing to inspect
+func (cm *DockerContainerSource) inspectDue(id string) bool {
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	f, ok := cm.failing[id]
+	return !ok || !time.Now().Before(f.retry)
+}
+
+// Record a failed inspect of a container, backing off further retries
+// and marking its row. An error identical to the last is counted
+// rather than logged, with a summary logged once it changes, the
+// inspect succeeds, or every inspectRepeatedLog
+func (cm *DockerContainerSource) inspectFailed(id string, err error) {
+	// failures on a lost connection are not particular to the container
+	if cm.disconnected() {
+		return
+	}
+	msg := err.Error()
+	now := time.Now()
+
+	cm.lock.Lock()
+	f, ok := cm.failing[id]
+	if !ok {
+		f = &inspectFailure{}
+		cm.failing[id] = f
+	}
+	f.count++
+	f.retry = now.Add(inspectBackoff(f.count))
+	switch {
+	case f.err != msg:
+		logRepeats(id, f)
+		log.Errorf("inspect of container %s failed: %s", id, msg)
+		f.err, f.repeats, f.logged = msg, 0, now
+	case now.Sub(f.logged) >= inspectRepeatedLog:
+		f.repeats++
+		logRepeats(id, f)
+		f.repeats, f.logged = 0, now
+	default:
+		f.repeats++
+	}
+	retry := f.retry
+	cm.lock.Unlock()
+
+	if c, ok := cm.Get(id); ok {
+		c.SetMeta("inspect error", msg+"\nretrying at "+retry.Local().Format("15:04:05"))
+	}
+}
+
+// Clear the failures of a container once inspected
+func (cm *DockerContainerSource) inspectSucceeded(c *Container) {
+	cm.lock.Lock()
+	f, ok := cm.failing[c.Id]
+	if ok {
+		logRepeats(c.Id, f)
+		delete(cm.failing, c.Id)
+	}
+	cm.lock.Unlock()
+	if ok {
+		log.Infof("inspect of container %s succeeded after %d failures", c.Id, f.count)
+		c.SetMeta("inspect error", "")
+	}
+}
+
+// Log a summary of the repeats of the last error of a failure, if any
+func logRepeats(id string, f *inspectFailure) {
+	if f.repeats > 0 {
+		log.Errorf("inspect of container %s failed: %s (repeated %d×)", id, f.err, f.repeats)
+	}
+}
+
+// Refresh a container at once, retrying an inspect held back after
+// failures
+func (cm *DockerContainerSource) RefreshNow(id string) {
+	cm.lock.Lock()
+	if f, ok := cm.failing[id]; ok {
+		f.retry = time.Time{}
+	}
+	cm.lock.Unlock()
+	safeGo(func() { cm.refreshID(id) })
+}
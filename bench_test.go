@@ -0,0 +1,181 @@
+// +build !release
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/bcicen/ctop/logging"
+	"github.com/bcicen/ctop/metrics"
+	"github.com/bcicen/ctop/widgets"
+)
+
+// Container counts each benchmark is run with
+var benchSizes = []int{100, 1000, 2000, 5000}
+
+// Containers updated between iterations, as a fraction of all
+const benchChurn = 10
+
+var benchOnce sync.Once
+
+func benchInit() {
+	benchOnce.Do(func() {
+		log = logging.Init()
+		config.Init()
+		config.SetSwitchVal("allContainers", true)
+		config.Update("sortField", "cpu")
+	})
+}
+
+// Return a mock source of n containers with randomized metadata and
+// metrics. Collectors are not started, so metrics only change when
+// randomized again
+func benchSource(n int) *MockContainerSource {
+	rand.Seed(1)
+	cs := &MockContainerSource{
+		procHost: &metrics.ProcHost{},
+		limits:   make(map[string]Limits),
+	}
+	for i := 0; i < n; i++ {
+		c := NewContainer(fmt.Sprintf("%012x", i), "bench", metrics.NewMock(1))
+		c.SetMeta("name", fmt.Sprintf("bench-%d", i))
+		c.SetMeta("image", fmt.Sprintf("image-%d:latest", i%20))
+		c.SetMeta("state", makeState())
+		randomizeMetrics(c)
+		cs.containers = append(cs.containers, c)
+	}
+	return cs
+}
+
+func randomizeMetrics(c *Container) {
+	m := metrics.Metrics{
+		CPUUtil:      rand.Intn(100),
+		NetRx:        rand.Int63n(1 << 30),
+		NetTx:        rand.Int63n(1 << 30),
+		MemLimit:     2 << 30,
+		MemUsage:     rand.Int63n(2 << 30),
+		IOBytesRead:  rand.Int63n(1 << 30),
+		IOBytesWrite: rand.Int63n(1 << 30),
+		Pids:         rand.Intn(200),
+	}
+	m.MemPercent = int(m.MemUsage * 100 / m.MemLimit)
+	c.setMetrics(m)
+	c.Widgets().SetMetrics(m)
+}
+
+// Randomize metrics of a share of containers, as between refreshes
+func churn(cs *MockContainerSource) {
+	for i := 0; i < len(cs.containers)/benchChurn; i++ {
+		randomizeMetrics(cs.containers[rand.Intn(len(cs.containers))])
+	}
+}
+
+func benchSizesRun(b *testing.B, f func(*testing.B, *MockContainerSource)) {
+	benchInit()
+	for _, n := range benchSizes {
+		cs := benchSource(n)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) { f(b, cs) })
+	}
+}
+
+// Sort and filter through All, as on each refresh, with a share of
+// container metrics changed between iterations
+func BenchmarkAll(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, cs *MockContainerSource) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			churn(cs)
+			b.StartTimer()
+			cs.All()
+		}
+	})
+}
+
+// Sort from scratch, as on changing the sort field
+func BenchmarkSortFull(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, cs *MockContainerSource) {
+		for i := 0; i < b.N; i++ {
+			lastOrder.Lock()
+			lastOrder.key = ""
+			lastOrder.Unlock()
+			cs.containers.Sort()
+		}
+	})
+}
+
+// Evaluate the filter for all containers, as on changing the filter
+func BenchmarkFilterChange(b *testing.B) {
+	filters := []string{"bench-1", "image:image-1 state:running"}
+	benchSizesRun(b, func(b *testing.B, cs *MockContainerSource) {
+		for i := 0; i < b.N; i++ {
+			config.Update("filterStr", filters[i%2])
+			cs.containers.Filter()
+		}
+		config.Update("filterStr", "")
+	})
+}
+
+// Refresh cursor rows from the source, as on each refresh
+func BenchmarkRefreshContainers(b *testing.B) {
+	benchSizesRun(b, func(b *testing.B, cs *MockContainerSource) {
+		cursor = &GridCursor{cSource: cs, expanded: make(map[string]bool)}
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			churn(cs)
+			b.StartTimer()
+			cursor.RefreshContainers()
+		}
+	})
+}
+
+// Build grid rows and draw a page of them, as on each redraw
+func BenchmarkBuildRows(b *testing.B) {
+	const width, page = 200, 50
+	benchSizesRun(b, func(b *testing.B, cs *MockContainerSource) {
+		cursor = &GridCursor{cSource: cs, expanded: make(map[string]bool)}
+		cursor.RefreshContainers()
+		cGrid = compact.NewCompactGrid()
+		banner = widgets.NewCTopBanner()
+		for i := 0; i < b.N; i++ {
+			buildRows()
+			for n, r := range cGrid.Rows {
+				if n == page {
+					break
+				}
+				r.SetY(n + 2)
+				r.SetWidth(width)
+				r.Buffer()
+			}
+		}
+	})
+}
+
+// Refresh, build and draw a page of rows, as on each tick
+func BenchmarkFrame(b *testing.B) {
+	const width, page = 200, 50
+	benchSizesRun(b, func(b *testing.B, cs *MockContainerSource) {
+		cursor = &GridCursor{cSource: cs, expanded: make(map[string]bool)}
+		cGrid = compact.NewCompactGrid()
+		banner = widgets.NewCTopBanner()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			churn(cs)
+			b.StartTimer()
+			cursor.RefreshContainers()
+			buildRows()
+			for n, r := range cGrid.Rows {
+				if n == page {
+					break
+				}
+				r.SetY(n + 2)
+				r.SetWidth(width)
+				r.Buffer()
+			}
+		}
+	})
+}
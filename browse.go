@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/widgets/menu"
+	ui "github.com/gizak/termui"
+)
+
+// Published container port, bound to a host address
+type portBinding struct {
+	Port     string // container port and protocol, e.g. "80/tcp"
+	HostIP   string
+	HostPort string
+}
+
+// Parse published port bindings from container metadata
+func publishedPorts(c *Container) (ports []portBinding) {
+	for _, line := range strings.Split(c.GetMeta("ports"), "\n") {
+		parts := strings.SplitN(line, " -> ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		idx := strings.LastIndex(parts[1], ":")
+		if idx < 0 {
+			continue
+		}
+		ports = append(ports, portBinding{
+			Port:     parts[0],
+			HostIP:   parts[1][:idx],
+			HostPort: parts[1][idx+1:],
+		})
+	}
+	return ports
+}
+
+// Return the hostname of the docker daemon, or an empty
+// string if the daemon is local
+func daemonHost() string {
+	return endpointHost(cursor.Source().Endpoint())
+}
+
+// Return the host of a remote endpoint, or "" for a local endpoint
+func endpointHost(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "unix" || u.Scheme == "npipe" {
+		return ""
+	}
+	host := u.Hostname()
+	if host == "localhost" || host == "127.0.0.1" || host == "::1" {
+		return ""
+	}
+	return host
+}
+
+// Build a browsable URL for a published port
+func (b portBinding) URL() string {
+	host := b.HostIP
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+		if h := daemonHost(); h != "" {
+			host = h
+		}
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(host, b.HostPort))
+}
+
+// Open a URL with the configured command, or the platform default
+func openURL(u string) {
+	cmd := strings.Fields(config.GetVal("openCmd"))
+	if len(cmd) == 0 {
+		cmd = []string{"xdg-open"}
+		if runtime.GOOS == "darwin" {
+			cmd = []string{"open"}
+		}
+	}
+	c := exec.Command(cmd[0], append(cmd[1:], u)...)
+	if err := c.Start(); err != nil {
+		log.NotifyError("failed to open %s: %s", u, err)
+		return
+	}
+	log.Notify("opened %s", u)
+	// reap the command once done, reporting failure
+	safeGo(func() {
+		if err := c.Wait(); err != nil {
+			log.Errorf("%s %s: %s", cmd[0], u, err)
+		}
+	})
+}
+
+// Open a published port of the selected container in the browser,
+// prompting for a port if several are published
+func BrowseMenu() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+
+	ports := publishedPorts(c)
+	switch len(ports) {
+	case 0:
+		log.Notify("%s has no published ports", c.GetMeta("name"))
+		return
+	case 1:
+		openURL(ports[0].URL())
+		return
+	}
+
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	m := menu.NewMenu()
+	m.Selectable = true
+	m.BorderLabel = "Open Port"
+	for _, p := range ports {
+		u := p.URL()
+		m.AddItems(menu.Item{Val: u, Label: fmt.Sprintf("%s -> %s", p.Port, u)})
+	}
+
+	HandleKeys("up", m.Up)
+	HandleKeys("down", m.Down)
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		openURL(m.SelectedItem().Val)
+		ui.StopLoop()
+	})
+
+	ui.Render(m)
+	ui.Loop()
+}
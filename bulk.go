@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/widgets/menu"
+	ui "github.com/gizak/termui"
+)
+
+const bulkWorkers = 4 // concurrent container operations
+
+// Display a list of containers, returning true if confirmed
+func confirmContainers(label string, targets Containers) (confirmed bool) {
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	m := menu.NewMenu()
+	m.Selectable = true
+	m.BorderLabel = fmt.Sprintf("%s [y/n]", label)
+	for _, c := range targets {
+		m.AddItems(menu.Item{
+			Val:   c.Id,
+			Label: fmt.Sprintf("%s (%s)", c.GetMeta("name"), c.State()),
+		})
+	}
+
+	HandleKeys("up", m.Up)
+	HandleKeys("down", m.Down)
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/sys/kbd/n", func(ui.Event) { ui.StopLoop() })
+	ui.Handle("/sys/kbd/y", func(ui.Event) {
+		confirmed = true
+		ui.StopLoop()
+	})
+
+	ui.Render(m)
+	ui.Loop()
+	return confirmed
+}
+
+// Apply op to all target containers with bounded concurrency, showing
+// progress in the footer and notifying a summary once complete
+func runBulk(done string, targets Containers, op func(string) error) {
+	ui.Clear()
+	RedrawRows(true)
+
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		count   int
+		failed  []string
+		workers = make(chan struct{}, bulkWorkers)
+	)
+
+	progress := func() {
+		footer.Flash(fmt.Sprintf("%d/%d %s%c", count, len(targets), done, cwidgets.Glyphs.Ellipsis), time.Minute)
+		ui.Render(footer)
+	}
+	progress()
+
+	for _, c := range targets {
+		wg.Add(1)
+		workers <- struct{}{}
+		c := c
+		safeGo(func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			err := op(c.Id)
+
+			lock.Lock()
+			defer lock.Unlock()
+			count++
+			if err != nil {
+				log.Errorf("%s: %s", c.GetMeta("name"), err)
+				failed = append(failed, c.GetMeta("name"))
+			}
+			progress()
+		})
+	}
+	wg.Wait()
+	footer.Hide()
+
+	succeeded := len(targets) - len(failed)
+	if len(failed) > 0 {
+		log.NotifyError("%s %d containers, %d failed: %v", done, succeeded, len(failed), failed)
+		return
+	}
+	log.Notify("%s %d containers", done, succeeded)
+}
+
+// Return all displayed, running containers
+func runningShown() (list Containers) {
+	for _, c := range cursor.filtered {
+		if c.State() == "running" {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Confirm and apply an operation to all displayed, running containers.
+// Without an active filter, a second confirmation is required
+func bulkRunningMenu(verb, done string, op func(string) error) {
+	targets := runningShown()
+	if len(targets) == 0 {
+		log.Notify("no running containers to %s", verb)
+		return
+	}
+	if !confirmContainers(fmt.Sprintf("%s %d containers?", verb, len(targets)), targets) {
+		return
+	}
+	if config.GetVal("filterStr") == "" {
+		label := fmt.Sprintf("No filter active: really %s all %d containers?", verb, len(targets))
+		if !confirmContainers(label, targets) {
+			return
+		}
+	}
+	runBulk(done, targets, op)
+}
+
+func StopAllMenu() {
+	bulkRunningMenu("stop", "stopped", cursor.Source().Stop)
+}
+
+func RestartAllMenu() {
+	bulkRunningMenu("restart", "restarted", cursor.Source().Restart)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/logging"
+)
+
+// Archive of displayed containers' inspect documents, the metrics
+// snapshot, the event timeline and recent log entries, for attaching
+// to support tickets. Entries are written under a directory named
+// after the bundle
+type bundleWriter struct {
+	dir string
+	tw  *tar.Writer
+	now time.Time
+}
+
+func (b *bundleWriter) add(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    b.dir + "/" + name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := b.tw.Write(data)
+	return err
+}
+
+func defaultBundlePath() string {
+	return fmt.Sprintf("./ctop-bundle-%s.tar.gz", time.Now().Format("20060102-150405"))
+}
+
+// Write a support bundle of the given containers to path as a gzipped
+// tar, calling progress after each container is inspected. Environment
+// values not whitelisted by envShow are masked in inspect documents.
+// Containers failing to inspect are listed in errors.txt. Returns the
+// number of containers bundled
+func writeBundle(path string, containers Containers, progress func(done, total int)) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	b := &bundleWriter{
+		dir: strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar"),
+		tw:  tar.NewWriter(gw),
+		now: time.Now(),
+	}
+
+	var errs []string
+	for n, c := range containers {
+		doc, err := cursor.Source().Inspect(c.Id)
+		if err == nil {
+			doc, err = redactInspect(doc)
+		}
+		var data []byte
+		if err == nil {
+			data, err = json.MarshalIndent(doc, "", "  ")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %s", c.Id, c.GetMeta("name"), err))
+		} else if err := b.add(bundleEntryName(c), data); err != nil {
+			return 0, err
+		}
+		if progress != nil {
+			progress(n+1, len(containers))
+		}
+	}
+
+	snap := jsonSnapshot{Timestamp: b.now, Containers: []*jsonContainer{}}
+	for _, c := range containers {
+		snap.Containers = append(snap.Containers, newJSONContainer(c))
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := b.add("metrics.json", data); err != nil {
+		return 0, err
+	}
+	if err := b.add("events.txt", bundleEvents()); err != nil {
+		return 0, err
+	}
+	if err := b.add("ctop.log", bundleLog()); err != nil {
+		return 0, err
+	}
+	if len(errs) > 0 {
+		if err := b.add("errors.txt", []byte(strings.Join(errs, "\n")+"\n")); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := b.tw.Close(); err != nil {
+		return 0, err
+	}
+	if err := gw.Close(); err != nil {
+		return 0, err
+	}
+	return len(containers) - len(errs), f.Close()
+}
+
+// Return the bundle entry name of a container's inspect document
+func bundleEntryName(c *Container) string {
+	id := c.Id
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	name := strings.Replace(c.GetMeta("name"), "/", "_", -1)
+	return fmt.Sprintf("containers/%s-%s.json", name, id)
+}
+
+// Return retained events as lines, oldest first, with RFC 3339 times
+func bundleEvents() []byte {
+	events, _ := timelineEvents()
+	var s string
+	for _, e := range events {
+		s += fmt.Sprintf("%s [%s %s]\n", e.format(time.RFC3339), e.Source, e.ID)
+	}
+	return []byte(s)
+}
+
+// Return retained log entries as lines, oldest first
+func bundleLog() []byte {
+	entries, _ := logging.Entries()
+	var s string
+	for _, e := range entries {
+		s += e.String() + "\n"
+	}
+	return []byte(s)
+}
+
+// Write a support bundle, reporting progress in the footer
+func exportBundle(path string, containers Containers) {
+	n, err := writeBundle(path, containers, func(done, total int) {
+		footer.Flash(fmt.Sprintf("bundling: %d of %d containers inspected", done, total), 2*time.Second)
+	})
+	if err != nil {
+		os.Remove(path)
+		log.NotifyError("failed to write support bundle: %s", err)
+		return
+	}
+	log.Notify("wrote support bundle of %d containers to %s", n, path)
+}
+
+// Prompt for a path and write a support bundle of displayed containers
+func BundleMenu() {
+	containers := append(Containers{}, cursor.filtered...)
+	path := defaultBundlePath()
+	if promptInput("Write support bundle to", path, nil, func(s string) error {
+		path = s
+		return checkExportPath(s)
+	}) {
+		goTask(func() { exportBundle(path, containers) })
+	}
+}
+
+// Write a support bundle without the UI, after one refresh interval
+func ExportBundle(path string) {
+	if err := checkExportPath(path); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write support bundle: %s\n", err)
+		os.Exit(1)
+	}
+	time.Sleep(refreshInterval())
+	cursor.RefreshContainers()
+	n, err := writeBundle(path, cursor.filtered, nil)
+	if err != nil {
+		os.Remove(path)
+		fmt.Fprintf(os.Stderr, "failed to write support bundle: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote support bundle of %d containers to %s\n", n, path)
+}
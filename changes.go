@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/widgets/changes"
+	ui "github.com/gizak/termui"
+)
+
+// Filesystem change of a container, of kind 'A' (added),
+// 'C' (changed) or 'D' (deleted)
+type FileChange struct {
+	Path string
+	Kind rune
+}
+
+// Filesystem changes read in the background
+type changesResult struct {
+	list []FileChange
+	err  error
+}
+
+// Filesystem changes of a container, read in the background and
+// applied to the view from the UI loop
+type changesLoader struct {
+	c       *Container
+	results chan changesResult
+	loading bool
+}
+
+func newChangesLoader(c *Container) *changesLoader {
+	return &changesLoader{c: c, results: make(chan changesResult, 1)}
+}
+
+// Start reading changes, unless already reading
+func (l *changesLoader) load(v *changes.View) {
+	if l.loading {
+		return
+	}
+	l.loading = true
+	v.Status = "loading changes..."
+	ui.Render(v)
+	safeGo(func() {
+		list, err := cursor.Source().Changes(l.c.Id)
+		l.results <- changesResult{list, err}
+	})
+}
+
+// Apply changes once read, returning true if the view was updated
+func (l *changesLoader) apply(v *changes.View) bool {
+	select {
+	case r := <-l.results:
+		l.loading = false
+		if r.err != nil {
+			v.Status = fmt.Sprintf("failed to read changes: %s", r.err)
+			log.Errorf("failed to read changes of %s: %s", l.c.GetMeta("name"), r.err)
+			return true
+		}
+		entries := make([]changes.Entry, len(r.list))
+		for n, fc := range r.list {
+			entries[n] = changes.Entry{Path: fc.Path, Kind: fc.Kind}
+		}
+		v.SetEntries(entries)
+		return true
+	default:
+		return false
+	}
+}
+
+func alignChanges(v *changes.View) {
+	v.X, v.Y = 0, 0
+	v.Width = ui.TermWidth()
+	v.Height = ui.TermHeight() - 1
+	footer.Align()
+}
+
+// Browse filesystem changes of a container
+func ChangesView(c *Container) {
+	v := changes.NewView()
+	v.Title = fmt.Sprintf("changes: %s", c.GetMeta("name"))
+	alignChanges(v)
+	l := newChangesLoader(c)
+	l.load(v)
+
+	for {
+		var filter bool
+
+		ui.Clear()
+		ui.DefaultEvtStream.ResetHandlers()
+		ui.Render(v)
+
+		HandleKeys("up", v.Up)
+		HandleKeys("down", v.Down)
+		HandleKeys("pgup", v.PgUp)
+		HandleKeys("pgdown", v.PgDown)
+		HandleKeys("exit", ui.StopLoop)
+
+		ui.Handle("/sys/kbd//", func(ui.Event) {
+			filter = true
+			ui.StopLoop()
+		})
+		ui.Handle("/sys/kbd/r", func(ui.Event) { l.load(v) })
+		ui.Handle("/timer/refresh", func(ui.Event) {
+			if l.apply(v) {
+				ui.Render(v)
+			}
+		})
+		ui.Handle("/sys/wnd/resize", func(ui.Event) {
+			ui.Clear()
+			alignChanges(v)
+			ui.Render(v)
+		})
+
+		ui.Loop()
+		if !filter {
+			break
+		}
+		changesFilter(v)
+	}
+	ui.DefaultEvtStream.ResetHandlers()
+}
+
+// Read a path filter in the footer, applying it as it is typed
+func changesFilter(v *changes.View) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+	defer footer.Hide()
+
+	prev := v.Filter()
+	query := prev
+	update := func() {
+		v.SetFilter(query)
+		footer.Flash(fmt.Sprintf("filter paths: %s", query), time.Minute)
+		ui.Render(v, footer)
+	}
+	update()
+
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		key := strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		switch {
+		case key == "C-8" && len(query) > 0:
+			query = query[:len(query)-1]
+		case len(key) == 1:
+			query += key
+		}
+		update()
+	})
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		v.SetFilter(prev)
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	ui "github.com/gizak/termui"
+)
+
+// Clipboard commands, in order of preference
+var clipboardCmds = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// Copy text to the system clipboard with the first available
+// clipboard command, or failing that via an OSC 52 terminal sequence,
+// which also works over SSH. Returns whether the copy is confirmed,
+// as terminals not supporting OSC 52 silently ignore it
+func copyToClipboard(s string) (bool, error) {
+	cmdErr := copyCmd(s)
+	if cmdErr == nil {
+		return true, nil
+	}
+	log.Debugf("clipboard command failed, using OSC 52: %s", cmdErr)
+	if err := copyOSC52(s); err != nil {
+		return false, cmdErr
+	}
+	return false, nil
+}
+
+func copyOSC52(s string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(s)))
+	return err
+}
+
+func copyCmd(s string) error {
+	for _, args := range clipboardCmds {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(s)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %s", args[0], err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no clipboard command available")
+}
+
+// Copy a value to the clipboard, displaying it in the footer
+// for manual selection if copying fails or is unconfirmed
+func copyValue(desc, val string) {
+	confirmed, err := copyToClipboard(val)
+	switch {
+	case err != nil:
+		log.Errorf("failed to copy %s: %s", desc, err)
+		footer.Flash(fmt.Sprintf("%s: %s", desc, val), 30*time.Second)
+	case !confirmed:
+		// shown as well, in case the terminal ignored OSC 52
+		footer.Flash(fmt.Sprintf("sent %s to terminal clipboard: %s", desc, val), 30*time.Second)
+	default:
+		log.Notify("copied %s: %s", desc, val)
+	}
+}
+
+// Copy a value from the selected container, chosen by the next key pressed
+func CopyMenu() {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+
+	prompt := "copy: [i]d, [n]ame, [e]xec command"
+	if c.GetMeta("raw name") != "" {
+		prompt = "copy: [i]d, [n]ame, [r]aw name, [e]xec command"
+	}
+	footer.Flash(prompt, time.Minute)
+	ui.Render(footer)
+
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		footer.Hide()
+		switch strings.Replace(e.Path, "/sys/kbd/", "", -1) {
+		case "i":
+			copyValue("container id", c.Id)
+		case "n":
+			copyValue("container name", c.GetMeta("name"))
+		case "r":
+			copyValue("raw container name", rawName(c))
+		case "e":
+			copyValue("exec command", fmt.Sprintf("docker exec -it %s sh", c.Id))
+		}
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
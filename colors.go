@@ -3,6 +3,9 @@ package main
 import (
 	"regexp"
 
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/bcicen/ctop/widgets"
 	ui "github.com/gizak/termui"
 )
 
@@ -31,8 +34,17 @@ var ColorMap = map[string]ui.Attribute{
 	"menu.text.bg":       ui.ColorDefault,
 	"menu.border.fg":     ui.ColorCyan,
 	"menu.label.fg":      ui.ColorGreen,
+	"changes.added.fg":   ui.ColorGreen,
+	"changes.changed.fg": ui.ColorYellow,
+	"changes.deleted.fg": ui.ColorRed,
 	"header.fg":          ui.ColorBlack,
 	"header.bg":          ui.ColorWhite,
+	"inspect.cursor.bg":  ui.ColorBlue,
+	"inspect.key.fg":     ui.ColorCyan,
+	"inspect.string.fg":  ui.ColorGreen,
+	"inspect.number.fg":  ui.ColorMagenta,
+	"inspect.bool.fg":    ui.ColorYellow,
+	"inspect.null.fg":    ui.ColorRed,
 	"gauge.bar.bg":       ui.ColorGreen,
 	"gauge.percent.fg":   ui.ColorWhite,
 	"linechart.axes.fg":  ui.ColorDefault,
@@ -40,11 +52,22 @@ var ColorMap = map[string]ui.Attribute{
 	"mbarchart.bar.bg":   ui.ColorGreen,
 	"mbarchart.num.fg":   ui.ColorWhite,
 	"mbarchart.text.fg":  ui.ColorWhite,
+	"output.match.fg":    ui.ColorBlack,
+	"output.match.bg":    ui.ColorYellow,
 	"par.text.fg":        ui.ColorWhite,
 	"par.text.bg":        ui.ColorDefault,
 	"par.text.hi":        ui.ColorBlack,
+	"par.text.dim":       ui.ColorBlack | ui.AttrBold,
 	"sparkline.line.fg":  ui.ColorGreen,
 	"sparkline.title.fg": ui.ColorWhite,
+	"net.anomaly":        ui.ColorRed,    // kept when colors are inverted
+	"user.root":          ui.ColorRed,    // kept when colors are inverted
+	"image.stale":        ui.ColorYellow, // kept when colors are inverted
+	"name.error":         ui.ColorRed,    // kept when colors are inverted
+	"sec.elevated":       ui.ColorYellow, // kept when colors are inverted
+	"sec.privileged":     ui.ColorRed,    // kept when colors are inverted
+	"change.up":          ui.ColorRed,    // kept when colors are inverted
+	"change.down":        ui.ColorGreen,  // kept when colors are inverted
 }
 
 func InvertColorMap() {
@@ -56,3 +79,33 @@ func InvertColorMap() {
 	}
 	ColorMap["par.text.hi"] = ui.ColorWhite
 }
+
+var defaultColorMap = copyColorMap(ColorMap)
+
+func copyColorMap(m map[string]ui.Attribute) map[string]ui.Attribute {
+	c := make(map[string]ui.Attribute, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Apply the default or inverted color theme, as set by invertColors
+func applyTheme() {
+	ColorMap = copyColorMap(defaultColorMap)
+	if config.GetSwitchVal("invertColors") {
+		InvertColorMap()
+	}
+	ui.ColorMap = ColorMap
+}
+
+// Rebuild all widgets, picking up a changed color theme
+func rebuildWidgets() {
+	for _, c := range cursor.Source().All() {
+		c.ResetWidgets()
+	}
+	cGrid = compact.NewCompactGrid()
+	header = widgets.NewCTopHeader()
+	footer = widgets.NewCTopFooter()
+	banner = widgets.NewCTopBanner()
+}
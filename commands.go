@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Command of the : prompt, applied to a container given by ID, name
+// or a unique prefix of either
+type promptCommand struct {
+	mutating bool // disabled in read-only mode
+	run      func(c *Container)
+}
+
+var promptCommands = map[string]promptCommand{
+	"select":  {run: selectContainer},
+	"expand":  {run: ExpandView},
+	"inspect": {run: InspectView},
+	"stop": {mutating: true, run: containerOp("stop", "stopped", func(id string) error {
+		return cursor.Source().Stop(id)
+	})},
+	"restart": {mutating: true, run: containerOp("restart", "restarted", func(id string) error {
+		return cursor.Source().Restart(id)
+	})},
+	"pause": {mutating: true, run: containerOp("pause", "paused", func(id string) error {
+		return cursor.Source().Pause(id)
+	})},
+	"unpause": {mutating: true, run: containerOp("unpause", "unpaused", func(id string) error {
+		return cursor.Source().Unpause(id)
+	})},
+	"rm": {mutating: true, run: containerOp("remove", "removed", func(id string) error {
+		return cursor.Source().Remove(id)
+	})},
+}
+
+// Return a command running op on a container in the background,
+// notifying the outcome
+func containerOp(verb, done string, op func(string) error) func(*Container) {
+	return func(c *Container) {
+		name := c.GetMeta("name")
+		goTask(func() {
+			if err := op(c.Id); err != nil {
+				log.NotifyError("failed to %s %s: %s", verb, name, err)
+				return
+			}
+			log.Notify("%s %s", done, name)
+		})
+	}
+}
+
+func promptCommandNames() (names []string) {
+	for name := range promptCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse a : prompt line of a command and container, resolving the
+// container among all those tracked
+func parseCommand(s string) (promptCommand, *Container, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return promptCommand{}, nil, fmt.Errorf("expected a command and container, e.g. stop 3f2a")
+	}
+	cmd, ok := promptCommands[fields[0]]
+	if !ok {
+		return promptCommand{}, nil, fmt.Errorf("unknown command %s, expected one of: %s", fields[0], strings.Join(promptCommandNames(), ", "))
+	}
+	if cmd.mutating && readOnly() {
+		return promptCommand{}, nil, fmt.Errorf("read-only mode: %s is disabled", fields[0])
+	}
+	c, err := resolveContainer(fields[1])
+	if err != nil {
+		return promptCommand{}, nil, err
+	}
+	return cmd, c, nil
+}
+
+// Prompt for a command and run it on the container given
+func CommandMenu() {
+	var (
+		cmd promptCommand
+		c   *Container
+	)
+	if promptInput(":", "", nil, func(s string) (err error) {
+		cmd, c, err = parseCommand(s)
+		return err
+	}) {
+		cmd.run(c)
+	}
+}
+
+// Move the cursor to a container, flashing why if it is not displayed
+func selectContainer(c *Container) {
+	cursor.RefreshContainers()
+	if !cursor.Select(c) {
+		log.Notify("%s is not displayed, check the filter or press a to show all", c.GetMeta("name"))
+	}
+}
+
+// Start with the cursor on the container given with -select, exiting
+// if it does not resolve to exactly one container
+func selectStartup(s string) {
+	c, err := resolveContainer(s)
+	if err != nil {
+		Shutdown()
+		fmt.Printf("invalid -select: %s\n", err)
+		os.Exit(1)
+	}
+	selectContainer(c)
+}
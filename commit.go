@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/widgets"
+	ui "github.com/gizak/termui"
+)
+
+var (
+	// image repository, with optional registry host and port
+	imageRepoRe = regexp.MustCompile(`^([a-zA-Z0-9.-]+(:[0-9]+)?/)?[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+	imageTagRe  = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+)
+
+// Split and validate an image reference of the form repository[:tag]
+func parseImageRef(s string) (repo, tag string, err error) {
+	repo, tag = s, "latest"
+	if idx := strings.LastIndex(s, ":"); idx > strings.LastIndex(s, "/") {
+		repo, tag = s[:idx], s[idx+1:]
+	}
+	if !imageRepoRe.MatchString(repo) {
+		return "", "", fmt.Errorf("invalid repository name: %s", repo)
+	}
+	if !imageTagRe.MatchString(tag) {
+		return "", "", fmt.Errorf("invalid tag: %s", tag)
+	}
+	return repo, tag, nil
+}
+
+// Read a line of input in a prompt at the bottom of the screen, with
+// an initial error message if err is non-nil. The submit func is called
+// on enter; any error returned is displayed and the prompt kept open
+// for editing. Returns false if cancelled
+func promptInput(label, data string, err error, submit func(string) error) (ok bool) {
+	i := widgets.NewInput()
+	i.BorderLabel = label
+	i.SetMaxLen(64)
+	i.Data = data
+	if err != nil {
+		i.SetError(err.Error())
+	}
+	return runPrompt(i, submit)
+}
+
+// Read input from a prompt at the bottom of the screen, as in promptInput
+func runPrompt(i *widgets.Input, submit func(string) error) (ok bool) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	align := func() {
+		ui.Clear()
+		RedrawRows(false)
+		i.SetY(ui.TermHeight() - i.Height)
+		ui.Render(i)
+	}
+	align()
+
+	i.InputHandlers()
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		if err := submit(i.Data); err != nil {
+			i.SetError(err.Error())
+			align()
+			return
+		}
+		ok = true
+		ui.StopLoop()
+	})
+	ui.Loop()
+	return ok
+}
+
+// Commit the selected container to an image
+func CommitMenu() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	name := c.GetMeta("name")
+
+	var ref, repo, tag, comment string
+	var commitErr error
+	ref = fmt.Sprintf("%s:snapshot-%s", strings.ToLower(name), time.Now().Format("20060102"))
+
+	for {
+		ok := promptInput("Commit: repository[:tag]", ref, commitErr, func(s string) (err error) {
+			ref = s
+			repo, tag, err = parseImageRef(s)
+			return err
+		})
+		if !ok {
+			return
+		}
+		if !promptInput("Commit: comment (optional)", comment, nil, func(s string) error {
+			comment = s
+			return nil
+		}) {
+			return
+		}
+
+		footer.Flash(fmt.Sprintf("committing %s to %s:%s...", name, repo, tag), time.Minute)
+		ui.Render(footer)
+		id, err := cursor.Source().Commit(c.Id, repo, tag, comment)
+		footer.Hide()
+		if err == nil {
+			log.Notify("committed %s as %s:%s (%s)", name, repo, tag, shortImageID(id))
+			break
+		}
+		// retry from the tag prompt, displaying the API error verbatim
+		log.Errorf("commit of %s failed: %s", name, err)
+		commitErr = err
+	}
+
+	commitFollowUp(c)
+}
+
+// Optionally stop or remove a container after committing it
+func commitFollowUp(c *Container) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+	defer footer.Hide()
+
+	name := c.GetMeta("name")
+	footer.Flash(fmt.Sprintf("%s committed: [s]top, stop and [r]emove, or any other key to keep running", name), time.Minute)
+	ui.Clear()
+	RedrawRows(false)
+
+	var action string
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		action = strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		ui.StopLoop()
+	})
+	ui.Loop()
+
+	if action != "s" && action != "r" {
+		return
+	}
+	if c.State() == "running" {
+		if err := cursor.Source().Stop(c.Id); err != nil {
+			log.NotifyError("failed to stop %s: %s", name, err)
+			return
+		}
+	}
+	if action == "r" {
+		if err := cursor.Source().Remove(c.Id); err != nil {
+			log.NotifyError("failed to remove %s: %s", name, err)
+			return
+		}
+		log.Notify("removed %s", name)
+		return
+	}
+	log.Notify("stopped %s", name)
+}
+
+// Trim digest algorithm and shorten an image ID for display
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// User-defined command, run against the selected container
+type Action struct {
+	Name    string
+	Key     string // keybinding
+	Command string // command template, run via the shell
+	Detach  bool   // run in the background rather than suspending the UI
+	Source  string // where the action was defined
+	tmpl    *template.Template
+}
+
+var GlobalActions []*Action
+
+// Render action command with the given container context
+func (a *Action) Render(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := a.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Build a custom action, validating its key and command template
+func newAction(name, key, command string, detach bool) (*Action, error) {
+	if name == "" {
+		return nil, fmt.Errorf("action name must not be empty")
+	}
+	if len(key) != 1 {
+		return nil, fmt.Errorf("action %s: key must be a single character", name)
+	}
+	tmpl, err := ShellTemplate(name, command)
+	if err != nil {
+		return nil, fmt.Errorf("action %s: %s", name, err)
+	}
+	return &Action{
+		Name:    name,
+		Key:     key,
+		Command: command,
+		Detach:  detach,
+		tmpl:    tmpl,
+	}, nil
+}
+
+// Parse an action given as "name,key[,detach]=command"
+func parseAction(s string) (*Action, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid action %s: expected name,key[,detach]=command", quote(s))
+	}
+	opts := strings.Split(parts[0], ",")
+	if len(opts) < 2 || len(opts) > 3 {
+		return nil, fmt.Errorf("invalid action %s: expected name,key[,detach]=command", quote(s))
+	}
+	var detach bool
+	if len(opts) == 3 {
+		if opts[2] != "detach" {
+			return nil, fmt.Errorf("invalid action option: %s", opts[2])
+		}
+		detach = true
+	}
+	return newAction(strings.TrimSpace(opts[0]), strings.TrimSpace(opts[1]), parts[1], detach)
+}
+
+// Parse and register an action given as "name,key[,detach]=command",
+// recording the source it was given in
+func ParseAction(s, source string) error {
+	a, err := parseAction(s)
+	if err != nil {
+		return err
+	}
+	a.Source = source
+	GlobalActions = append(GlobalActions, a)
+	log.Infof("loaded config action: %s: %s", quote(a.Name), quote(a.Command))
+	return nil
+}
+
+// Return the action as given to ParseAction
+func (a *Action) Spec() string {
+	s := a.Name + "," + a.Key
+	if a.Detach {
+		s += ",detach"
+	}
+	return s + "=" + a.Command
+}
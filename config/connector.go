@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+const connectorPrefix = "connector."
+
+// Options specific to a single connector, defined in a
+// [connector.NAME] config file section and validated by the
+// connector when constructed
+type ConnectorSection struct {
+	Name   string
+	path   string
+	values map[string]string
+	lines  map[string]int // line number of each key
+}
+
+var connectorSections = make(map[string]*ConnectorSection)
+
+func newConnectorSection(name, path string) *ConnectorSection {
+	if s, ok := connectorSections[name]; ok {
+		return s
+	}
+	s := &ConnectorSection{
+		Name:   name,
+		path:   path,
+		values: make(map[string]string),
+		lines:  make(map[string]int),
+	}
+	connectorSections[name] = s
+	return s
+}
+
+// Return the config section for the named connector, which is
+// empty if not defined in the config file
+func GetConnectorSection(name string) *ConnectorSection {
+	if s, ok := connectorSections[name]; ok {
+		return s
+	}
+	return &ConnectorSection{Name: name}
+}
+
+// Return names of all connector sections defined, sorted
+func ConnectorSectionNames() (names []string) {
+	for name := range connectorSections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *ConnectorSection) set(key, val string, line int) {
+	s.values[key] = val
+	s.lines[key] = line
+}
+
+// Return the keys set in the section, sorted
+func (s *ConnectorSection) Keys() (keys []string) {
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Return the value of a key, or an empty string if not set
+func (s *ConnectorSection) Get(key string) string {
+	return s.values[key]
+}
+
+// Return an error about the given key, naming the
+// config file location, section and key
+func (s *ConnectorSection) Errorf(key, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if line, ok := s.lines[key]; ok {
+		return fmt.Errorf("%s:%d: [%s%s] %s: %s", s.path, line, connectorPrefix, s.Name, key, msg)
+	}
+	return fmt.Errorf("[%s%s] %s: %s", connectorPrefix, s.Name, key, msg)
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"unicode"
+)
+
+// Sources a param or switch value may be set from, in increasing
+// order of precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceUI      = "ui" // changed at runtime, overriding all others
+)
+
+// Shorter environment variable names accepted for some config keys,
+// after the name derived from the key
+var envAliases = map[string][]string{
+	"filterStr":       {"CTOP_FILTER"},
+	"refreshInterval": {"CTOP_REFRESH_RATE"},
+}
+
+// Config key overridable from the environment
+type EnvVar struct {
+	Name    string
+	Aliases []string // other names accepted for the key
+	Key     string
+	Val     string
+	Source  string
+}
+
+// Return the environment variable name for a config key,
+// e.g. CTOP_SORT_FIELD for sortField
+func EnvName(key string) string {
+	var b bytes.Buffer
+	b.WriteString("CTOP_")
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// Look up the environment variable setting a config key, by its
+// derived name or else an alias, returning the name found
+func lookupEnv(key string) (name, val string, ok bool) {
+	for _, n := range append([]string{EnvName(key)}, envAliases[key]...) {
+		if v, ok := os.LookupEnv(n); ok {
+			return n, v, true
+		}
+	}
+	return EnvName(key), "", false
+}
+
+// Return all config keys overridable from the environment, with their
+// current values and sources. Keys other than params and switches,
+// which may not have a single value, are listed with any value set in
+// the environment
+func EnvVars() (vars []EnvVar) {
+	for _, p := range GlobalParams {
+		vars = append(vars, EnvVar{EnvName(p.Key), envAliases[p.Key], p.Key, p.Val, p.Source})
+	}
+	for _, s := range GlobalSwitches {
+		vars = append(vars, EnvVar{EnvName(s.Key), envAliases[s.Key], s.Key, fmt.Sprintf("%t", s.Val), s.Source})
+	}
+	for _, k := range fileKeyNames() {
+		v := EnvVar{Name: EnvName(k), Aliases: envAliases[k], Key: k}
+		if _, val, ok := lookupEnv(k); ok {
+			v.Val, v.Source = val, SourceEnv
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// Load params, switches and other config keys from CTOP_* environment
+// variables, overriding defaults and config file values. As with
+// config files, invalid values are returned as warnings
+func LoadEnv() (warnings []string) {
+	keys := fileKeyNames()
+	for _, p := range GlobalParams {
+		keys = append(keys, p.Key)
+	}
+	for _, s := range GlobalSwitches {
+		keys = append(keys, s.Key)
+	}
+
+	for _, k := range keys {
+		name, val, ok := lookupEnv(k)
+		if !ok {
+			continue
+		}
+		if err := setKey(k, val, SourceEnv); err != nil {
+			msg := fmt.Sprintf("%s: %s", name, err)
+			log.Warning(msg)
+			warnings = append(warnings, msg)
+		}
+	}
+	return warnings
+}
+
+func fileKeyNames() (keys []string) {
+	for k := range fileKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Write the complete effective configuration as a config file: every
+// param and switch including defaults, by settings group, followed by
+// actions, alerts, profiles and connector sections. Values set from
+// the environment or command line are written as in effect, noted in
+// a comment; values set by the active profile are written as they
+// were before it was applied, the profile being selected instead
+func Export(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	written := make(map[string]bool)
+	group := func(name string, keys []string, labels map[string]string) {
+		if len(keys) == 0 {
+			return
+		}
+		fmt.Fprintf(bw, "\n# %s\n", name)
+		for _, k := range keys {
+			fmt.Fprintln(bw)
+			if l := labels[k]; l != "" {
+				fmt.Fprintf(bw, "# %s\n", l)
+			}
+			if _, ok := baseValue(k); !ok {
+				_, source := current(k)
+				writeSource(bw, k, source)
+			}
+			fmt.Fprintf(bw, "%s = %s\n", k, fileValue(k))
+			written[k] = true
+		}
+	}
+
+	labels := make(map[string]string)
+	for _, p := range GlobalParams {
+		labels[p.Key] = p.Label
+	}
+	for _, s := range GlobalSwitches {
+		labels[s.Key] = s.Label
+	}
+	for _, g := range SettingGroups {
+		var keys []string
+		for _, s := range Settings() {
+			if s.Group == g {
+				keys = append(keys, s.Key)
+			}
+		}
+		group(g, keys, labels)
+	}
+	var other []string
+	for _, p := range GlobalParams {
+		if !written[p.Key] {
+			other = append(other, p.Key)
+		}
+	}
+	for _, s := range GlobalSwitches {
+		if !written[s.Key] {
+			other = append(other, s.Key)
+		}
+	}
+	group("Other", other, labels)
+
+	if len(GlobalActions) > 0 {
+		fmt.Fprintf(bw, "\n# Actions\n\n")
+		for _, a := range GlobalActions {
+			writeSource(bw, "action", a.Source)
+			fmt.Fprintf(bw, "action = %s\n", quoteValue(a.Spec()))
+		}
+	}
+	if len(GlobalHooks) > 0 {
+		fmt.Fprintf(bw, "\n# Hooks\n\n")
+		for _, h := range GlobalHooks {
+			writeSource(bw, h.Key(), h.Source)
+			fmt.Fprintf(bw, "%s = %s\n", h.Key(), quoteValue(h.Command))
+		}
+	}
+	if len(GlobalRules) > 0 {
+		fmt.Fprintf(bw, "\n# Alerts\n\n")
+		for _, r := range GlobalRules {
+			writeSource(bw, "alert", r.Source)
+			fmt.Fprintf(bw, "alert = %s\n", quoteValue(r.Spec()))
+		}
+	}
+
+	for _, name := range ProfileNames() {
+		p := Profiles[name]
+		fmt.Fprintf(bw, "\n[%s%s]\n", profilePrefix, name)
+		for _, k := range p.Keys {
+			fmt.Fprintf(bw, "%s = %s\n", k, quoteValue(p.Values[k]))
+		}
+	}
+	for _, name := range ConnectorSectionNames() {
+		s := connectorSections[name]
+		fmt.Fprintf(bw, "\n[%s%s]\n", connectorPrefix, name)
+		for _, k := range s.Keys() {
+			fmt.Fprintf(bw, "%s = %s\n", k, quoteValue(s.values[k]))
+		}
+	}
+	return bw.Flush()
+}
+
+// Note a value not set from a config file or by default
+func writeSource(w io.Writer, key, source string) {
+	switch source {
+	case SourceEnv:
+		name, _, _ := lookupEnv(key)
+		fmt.Fprintf(w, "# from environment: %s\n", name)
+	case SourceFlag:
+		fmt.Fprintf(w, "# from command line flag\n")
+	}
+}
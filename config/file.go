@@ -0,0 +1,410 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Keys accepted in config files other than params and switches,
+// which may be repeated, registered with the source they were set from
+var fileKeys = map[string]func(val, source string) error{
+	"action":       ParseAction,
+	"alert":        ParseRule,
+	"on_die":       parseHook("die"),
+	"on_oom":       parseHook("oom"),
+	"on_unhealthy": parseHook("unhealthy"),
+}
+
+// Validation of fileKeys values, without registering them
+var fileKeyChecks = map[string]func(string) error{
+	"action":       func(s string) error { _, err := parseAction(s); return err },
+	"alert":        func(s string) error { _, err := NewRule(s); return err },
+	"on_die":       checkHook("die"),
+	"on_oom":       checkHook("oom"),
+	"on_unhealthy": checkHook("unhealthy"),
+}
+
+const profilePrefix = "profile."
+
+// Return config file search paths, in order of preference
+func FilePaths() []string {
+	var paths []string
+	home := os.Getenv("HOME")
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && home != "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	if xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "ctop", "config"))
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".ctop"))
+	}
+	return paths
+}
+
+// Return the first config file found in the search paths, if any
+func FindFile() string {
+	for _, p := range FilePaths() {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Line of a config file: a [section] header, a key = value line
+// or a malformed line
+type fileLine struct {
+	n        int
+	header   bool
+	section  string // section name within brackets, as given
+	key, val string
+	err      error
+}
+
+// Read the header and key = value lines of a config file, skipping
+// blank lines and comments
+func readFile(path string) ([]fileLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []fileLine
+	var section string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "["), "]"))
+			l := fileLine{n: n, header: true, section: section}
+			if !strings.HasSuffix(line, "]") {
+				l.err = fmt.Errorf("expected [section]")
+			}
+			lines = append(lines, l)
+			continue
+		}
+		l := fileLine{n: n, section: section}
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			l.key, l.val = strings.TrimSpace(kv[0]), unquote(strings.TrimSpace(kv[1]))
+		} else {
+			l.err = fmt.Errorf("expected key = value")
+		}
+		lines = append(lines, l)
+	}
+	return lines, scanner.Err()
+}
+
+// Load params and switches from a config file of key = value lines.
+// Keys following a [profile.NAME] header define a named profile, and
+// those following a [connector.NAME] header are options for that
+// connector; other TOML-style [section] headers, blank lines and
+// comments starting with '#' are ignored. Values may be quoted. Unknown keys and invalid
+// values are returned as warnings rather than failing the load, so
+// config files remain usable across versions
+func LoadFile(path string) (warnings []string, err error) {
+	lines, err := readFile(path)
+	if err != nil && lines == nil {
+		return nil, err
+	}
+
+	warn := func(n int, format string, args ...interface{}) {
+		msg := fmt.Sprintf("%s:%d: %s", path, n, fmt.Sprintf(format, args...))
+		log.Warning(msg)
+		warnings = append(warnings, msg)
+	}
+
+	var profile *Profile
+	var connector *ConnectorSection
+	for _, l := range lines {
+		if l.header {
+			profile, connector = nil, nil
+			if l.err != nil {
+				continue
+			}
+			if name := sectionName(l.section, profilePrefix); name != "" {
+				profile = newProfile(name)
+			}
+			if name := sectionName(l.section, connectorPrefix); name != "" {
+				connector = newConnectorSection(name, path)
+			}
+			continue
+		}
+		if l.err != nil {
+			warn(l.n, "%s", l.err)
+			continue
+		}
+
+		if connector != nil {
+			connector.set(l.key, l.val, l.n)
+			continue
+		}
+		if profile != nil {
+			if err := profile.set(l.key, l.val); err != nil {
+				warn(l.n, "[%s%s] %s", profilePrefix, profile.Name, err)
+			}
+			continue
+		}
+		if err := setKey(l.key, l.val, SourceFile); err != nil {
+			warn(l.n, "%s", err)
+		}
+	}
+	if err != nil {
+		return warnings, err
+	}
+	log.Infof("loaded config file: %s", path)
+	return warnings, nil
+}
+
+// Validate a config file without loading it, strictly: unknown
+// sections and keys, malformed lines and invalid values are all
+// errors, each naming the line, section and key. check validates the
+// values of params and connector options further, given the section
+// as named in the file, or an empty string for top-level keys
+func CheckFile(path string, check func(section, key, val string) error) (errs []error, err error) {
+	lines, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var section string
+	var profile *Profile
+	fail := func(l fileLine, err error) {
+		loc := fmt.Sprintf("%s:%d: ", path, l.n)
+		if section != "" {
+			loc += fmt.Sprintf("[%s] ", section)
+		}
+		if l.key != "" {
+			loc += l.key + ": "
+		}
+		errs = append(errs, fmt.Errorf("%s%s", loc, err))
+	}
+
+	for _, l := range lines {
+		if l.header {
+			section, profile = l.section, nil
+			switch {
+			case l.err != nil:
+				fail(l, l.err)
+				section = ""
+			case sectionName(l.section, profilePrefix) != "":
+				profile = &Profile{Name: sectionName(l.section, profilePrefix), Values: make(map[string]string)}
+			case sectionName(l.section, connectorPrefix) != "":
+			default:
+				fail(l, fmt.Errorf("unknown section, expected [%sNAME] or [%sNAME]", profilePrefix, connectorPrefix))
+			}
+			continue
+		}
+		if l.err != nil {
+			fail(l, l.err)
+			continue
+		}
+
+		var err error
+		switch {
+		case profile != nil:
+			err = profile.set(l.key, l.val)
+		case section == "":
+			err = checkKey(l.key, l.val)
+		}
+		if err == nil {
+			err = check(section, l.key, l.val)
+		}
+		if err != nil {
+			fail(l, err)
+		}
+	}
+	return errs, nil
+}
+
+// Validate a top-level key and value without setting it
+func checkKey(key, val string) error {
+	if f, ok := fileKeyChecks[key]; ok {
+		return f(val)
+	}
+	if isParam(key) {
+		return nil
+	}
+	if isSwitch(key) {
+		if _, err := strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("invalid value: %s", quote(val))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown key")
+}
+
+// Set a param, switch or other config key from a string value
+func setKey(key, val, source string) error {
+	if f, ok := fileKeys[key]; ok {
+		return f(val, source)
+	}
+	for _, p := range GlobalParams {
+		if p.Key == key {
+			UpdateFrom(key, val, source)
+			return nil
+		}
+	}
+	for _, s := range GlobalSwitches {
+		if s.Key == key {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %s", key, quote(val))
+			}
+			SetSwitchFrom(key, b, source)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key: %s", key)
+}
+
+// Return the name given in a PREFIXNAME section,
+// or an empty string for any other section
+func sectionName(section, prefix string) string {
+	if !strings.HasPrefix(section, prefix) {
+		return ""
+	}
+	return unquote(strings.TrimPrefix(section, prefix))
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if s[0] == '"' {
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Returned by SaveFile when there is no config file to update and
+// no setting was changed at runtime
+var ErrNoChanges = errors.New("no settings changed")
+
+// Write the current values of the given params and switches to a
+// config file, replacing existing top-level lines for those keys and
+// adding any not yet present ahead of profile and connector sections.
+// Comments, sections and other keys are preserved. Values set by the
+// active profile are saved as they were before it was applied, and
+// values given in the environment or on the command line are not
+// saved, leaving those in the file as they are. A missing file is only
+// created when a setting was changed at runtime
+func SaveFile(path string, keys []string) error {
+	var (
+		saved   []string
+		changed bool
+		values  = make(map[string]string)
+	)
+	for _, k := range keys {
+		switch _, source := current(k); source {
+		case SourceEnv, SourceFlag:
+			continue
+		case SourceUI:
+			changed = true
+		}
+		saved = append(saved, k)
+		values[k] = fileValue(k)
+	}
+
+	var lines []string
+	if b, err := ioutil.ReadFile(path); err == nil {
+		lines = strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	} else if !os.IsNotExist(err) {
+		return err
+	} else if !changed {
+		return ErrNoChanges
+	}
+
+	written := make(map[string]bool)
+	end := len(lines) // end of top-level keys
+	inSection := false
+	for n, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			section := strings.TrimSpace(strings.Trim(line, "[]"))
+			inSection = strings.HasSuffix(line, "]") &&
+				(sectionName(section, profilePrefix) != "" || sectionName(section, connectorPrefix) != "")
+			if inSection && end == len(lines) {
+				end = n
+			}
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if inSection || len(kv) != 2 || strings.HasPrefix(kv[0], "#") {
+			continue
+		}
+		k := strings.TrimSpace(kv[0])
+		if v, ok := values[k]; ok && !written[k] {
+			lines[n] = fmt.Sprintf("%s = %s", k, v)
+			written[k] = true
+		}
+	}
+	var missing []string
+	for _, k := range saved {
+		if !written[k] {
+			missing = append(missing, fmt.Sprintf("%s = %s", k, values[k]))
+		}
+	}
+	if len(missing) > 0 && end < len(lines) {
+		missing = append(missing, "")
+	}
+	lines = append(lines[:end], append(missing, lines[end:]...)...)
+
+	if err := WriteFile(path, []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return err
+	}
+	log.Infof("saved config file: %s", path)
+	return nil
+}
+
+// Replace a config file with the given contents, creating its
+// directory if needed. The file is written in full before replacing
+// any existing one, so that it is never left partly written
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Format a param or switch value for a config file, quoting
+// values that would otherwise not be read back as given
+func fileValue(k string) string {
+	v, ok := baseValue(k)
+	if !ok {
+		v, _ = current(k)
+	}
+	if isSwitch(k) {
+		return v
+	}
+	return quoteValue(v)
+}
+
+// Quote a value that would otherwise not be read back as given
+func quoteValue(v string) string {
+	if v == "" || v != strings.TrimSpace(v) || strings.ContainsAny(v, "\"'#") {
+		return strconv.Quote(v)
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Prefix of hook keys, followed by the event, e.g. on_die
+const hookKeyPrefix = "on_"
+
+// Command run on a container lifecycle event
+type Hook struct {
+	Event   string
+	Command string // command template, run via the shell
+	Source  string // where the hook was defined
+	tmpl    *template.Template
+}
+
+var GlobalHooks []*Hook
+
+// Render hook command with the given container context
+func (h *Hook) Render(data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := h.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Return the config key the hook is defined with, e.g. on_die
+func (h *Hook) Key() string { return hookKeyPrefix + h.Event }
+
+// Build a hook, validating its command template
+func newHook(event, command string) (*Hook, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("%s%s: command must not be empty", hookKeyPrefix, event)
+	}
+	tmpl, err := ShellTemplate(hookKeyPrefix+event, command)
+	if err != nil {
+		return nil, fmt.Errorf("%s%s: %s", hookKeyPrefix, event, err)
+	}
+	return &Hook{Event: event, Command: command, tmpl: tmpl}, nil
+}
+
+// Return a function registering hooks for the given event, recording
+// the source each was given in
+func parseHook(event string) func(s, source string) error {
+	return func(s, source string) error {
+		h, err := newHook(event, s)
+		if err != nil {
+			return err
+		}
+		h.Source = source
+		GlobalHooks = append(GlobalHooks, h)
+		log.Infof("loaded config hook: %s: %s", h.Key(), quote(h.Command))
+		return nil
+	}
+}
+
+// Return a function validating hooks for the given event
+func checkHook(event string) func(s string) error {
+	return func(s string) error {
+		_, err := newHook(event, s)
+		return err
+	}
+}
@@ -15,10 +15,13 @@ var (
 
 func Init() {
 	for _, p := range params {
+		p.Source = SourceDefault
+		p.def = p.Val
 		GlobalParams = append(GlobalParams, p)
 		log.Infof("loaded config param: %s: %s", quote(p.Key), quote(p.Val))
 	}
 	for _, s := range switches {
+		s.Source = SourceDefault
 		GlobalSwitches = append(GlobalSwitches, s)
 		log.Infof("loaded config switch: %s: %t", quote(s.Key), s.Val)
 	}
@@ -36,3 +39,31 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// Settings menu categories, in display order
+var SettingGroups = []string{"Display", "Filtering", "Notifications", "Commands", "General"}
+
+// Param or switch adjustable at runtime from the settings menu
+type Setting struct {
+	Key    string
+	Label  string
+	Group  string
+	Switch bool
+}
+
+// Return all runtime-adjustable params and switches, ordered by group
+func Settings() (settings []Setting) {
+	for _, g := range SettingGroups {
+		for _, p := range GlobalParams {
+			if p.Group == g {
+				settings = append(settings, Setting{p.Key, p.Label, g, false})
+			}
+		}
+		for _, s := range GlobalSwitches {
+			if s.Group == g {
+				settings = append(settings, Setting{s.Key, s.Label, g, true})
+			}
+		}
+	}
+	return settings
+}
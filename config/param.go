@@ -1,23 +1,250 @@
 package config
 
+import "fmt"
+
 // defaults
 var params = []*Param{
 	&Param{
 		Key:   "filterStr",
 		Val:   "",
 		Label: "Container Name or ID Filter",
+		Group: "Filtering",
 	},
 	&Param{
 		Key:   "sortField",
 		Val:   "state",
 		Label: "Container Sort Field",
+		Group: "Filtering",
+	},
+	&Param{
+		Key:   "endpoint",
+		Val:   "",
+		Label: "Docker Daemon Address",
+	},
+	&Param{
+		Key:   "tlsCertPath",
+		Val:   "",
+		Label: "Docker TLS Certificate Directory",
+	},
+	&Param{
+		Key:   "profile",
+		Val:   "",
+		Label: "Host Profile",
+	},
+	&Param{
+		Key:   "connector",
+		Val:   "docker",
+		Label: "Container Source",
+	},
+	&Param{
+		Key:   "refreshInterval",
+		Val:   "1s",
+		Label: "UI Refresh Interval",
+		Group: "Display",
+	},
+	&Param{
+		Key:   "openCmd",
+		Val:   "",
+		Label: "Command Used to Open URLs",
+		Group: "Commands",
+	},
+	// comma-separated IDs of containers pinned to the top, in pin order
+	&Param{
+		Key:   "pins",
+		Val:   "",
+		Label: "Pinned Containers",
+	},
+	&Param{
+		Key:   "pipeCmd",
+		Val:   "",
+		Label: "Default Pipe Command",
+		Group: "Commands",
+	},
+	&Param{
+		Key:   "notifyEvents",
+		Val:   "die,oom,unhealthy",
+		Label: "Watched Container Events",
+		Group: "Notifications",
+	},
+	// metric columns highlighted on change, with highlightChanges
+	&Param{
+		Key:   "highlightColumns",
+		Val:   "cpu,mem,net,io,pids",
+		Label: "Highlighted Metric Columns",
+		Group: "Display",
+	},
+	// percent change of a metric since the last tick highlighted
+	&Param{
+		Key:   "highlightDelta",
+		Val:   "10",
+		Label: "Highlighted Change (%)",
+		Group: "Display",
+	},
+	// runs of each event hook allowed per minute, beyond which events are skipped
+	&Param{
+		Key:   "hookRate",
+		Val:   "10",
+		Label: "Event Hook Runs per Minute",
+		Group: "Commands",
+	},
+	// container events retained for the event timeline
+	&Param{
+		Key:   "eventHistory",
+		Val:   "500",
+		Label: "Event Timeline Length",
+		Group: "Notifications",
+	},
+	// flag network rates above a multiple of each container's
+	// baseline (median) rate for a number of consecutive samples
+	&Param{
+		Key:   "netAnomalyFactor",
+		Val:   "0",
+		Label: "Net Anomaly Factor (0 = off)",
+		Group: "Notifications",
+	},
+	&Param{
+		Key:   "netAnomalySamples",
+		Val:   "5",
+		Label: "Net Anomaly Consecutive Samples",
+		Group: "Notifications",
+	},
+	// metric samples retained per container, bounding the time
+	// window of graphs in the expanded view
+	&Param{
+		Key:   "historyLen",
+		Val:   "300",
+		Label: "Metric History Length (samples)",
+		Group: "Display",
+	},
+	&Param{
+		Key:   "columns",
+		Val:   "status,name,id,cpu,mem,net,io,pids",
+		Label: "Enabled Columns",
+		Group: "Display",
+	},
+	// timestamp layout, in Go reference time syntax
+	&Param{
+		Key:   "timeFormat",
+		Val:   "Mon Jan 2 15:04:05 2006",
+		Label: "Timestamp Format",
+		Group: "Display",
+	},
+	// "compact" (3d4h) or "long" (3 days 4 hours)
+	&Param{
+		Key:   "durationStyle",
+		Val:   "compact",
+		Label: "Duration Style",
+		Group: "Display",
+	},
+	// terminal title template, with fields Running, Containers, Alerts
+	// and Endpoint
+	&Param{
+		Key:   "titleFormat",
+		Val:   "ctop: {{.Running}} ▶ {{.Alerts}} ⚠",
+		Label: "Terminal Title Format",
+		Group: "Display",
+	},
+	// metric shown by the gauge column: cpu, mem, net or pids
+	&Param{
+		Key:   "gaugeMetric",
+		Val:   "cpu",
+		Label: "Gauge Column Metric",
+		Group: "Display",
+	},
+	// gauge color thresholds, in percent
+	&Param{
+		Key:   "gaugeWarn",
+		Val:   "30",
+		Label: "Gauge Warning Level (%)",
+		Group: "Display",
+	},
+	&Param{
+		Key:   "gaugeCrit",
+		Val:   "70",
+		Label: "Gauge Critical Level (%)",
+		Group: "Display",
+	},
+	// column width hints, given as "N" or "MIN..MAX"
+	&Param{
+		Key:   "nameWidth",
+		Val:   "",
+		Label: "Name Column Width",
+	},
+	&Param{
+		Key:   "idWidth",
+		Val:   "",
+		Label: "CID Column Width",
+	},
+	&Param{
+		Key:   "imageWidth",
+		Val:   "",
+		Label: "Image Column Width",
+	},
+	&Param{
+		Key:   "userWidth",
+		Val:   "",
+		Label: "User Column Width",
+	},
+	&Param{
+		Key:   "cpuWidth",
+		Val:   "",
+		Label: "CPU Column Width",
+	},
+	&Param{
+		Key:   "memWidth",
+		Val:   "",
+		Label: "MEM Column Width",
+	},
+	&Param{
+		Key:   "netWidth",
+		Val:   "",
+		Label: "NET Column Width",
+	},
+	&Param{
+		Key:   "ioWidth",
+		Val:   "",
+		Label: "IO Column Width",
+	},
+	&Param{
+		Key:   "envShow",
+		Val:   "",
+		Label: "Environment Variables Shown by Value",
+	},
+	&Param{
+		Key:   "summaryLabel",
+		Val:   "",
+		Label: "Summary Label Key",
+		Group: "Display",
+	},
+	&Param{
+		Key:   "sinceWidth",
+		Val:   "",
+		Label: "State Since Column Width",
+	},
+	&Param{
+		Key:   "cputimeWidth",
+		Val:   "",
+		Label: "CPU Time Column Width",
+	},
+	&Param{
+		Key:   "securityWidth",
+		Val:   "",
+		Label: "Security Column Width",
+	},
+	&Param{
+		Key:   "imagesizeWidth",
+		Val:   "",
+		Label: "Image Size Column Width",
 	},
 }
 
 type Param struct {
-	Key   string
-	Val   string
-	Label string
+	Key    string
+	Val    string
+	Label  string
+	Group  string // settings menu category; empty if not adjustable at runtime
+	Source string // where Val was set from
+	def    string // default value, as of Init
 }
 
 // Get Param by key
@@ -39,6 +266,35 @@ func GetVal(k string) string {
 func Update(k, v string) {
 	p := Get(k)
 	log.Noticef("config change: %s: %s -> %s", k, quote(p.Val), quote(v))
+	if p.Val != v {
+		p.Source = SourceUI
+	}
 	p.Val = v
 	// log.Errorf("ignoring update for non-existant parameter: %s", k)
 }
+
+// Set param value, recording the source it was set from
+func UpdateFrom(k, v, source string) {
+	Update(k, v)
+	Get(k).Source = source
+}
+
+// Check params set from config files, profiles and the environment,
+// resetting those check rejects to their default. Returns a warning
+// for each, as for invalid values when loading
+func CheckParams(check func(key, val string) error) (warnings []string) {
+	for _, p := range GlobalParams {
+		switch p.Source {
+		case SourceFile, SourceProfile, SourceEnv:
+		default:
+			continue
+		}
+		if err := check(p.Key, p.Val); err != nil {
+			msg := fmt.Sprintf("%s = %s: %s, using default %s", p.Key, quote(p.Val), err, quote(p.def))
+			log.Warning(msg)
+			warnings = append(warnings, msg)
+			p.Val, p.Source = p.def, SourceDefault
+		}
+	}
+	return warnings
+}
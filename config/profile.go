@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Named set of params and switches, defined in a [profile.NAME]
+// config file section and applied over top-level values
+type Profile struct {
+	Name   string
+	Keys   []string // in order defined
+	Values map[string]string
+}
+
+// previously set value of a key overridden by the active profile
+type setting struct {
+	val    string
+	source string
+}
+
+var (
+	Profiles = make(map[string]*Profile)
+	replaced = make(map[string]setting)
+)
+
+func newProfile(name string) *Profile {
+	if p, ok := Profiles[name]; ok {
+		return p
+	}
+	p := &Profile{Name: name, Values: make(map[string]string)}
+	Profiles[name] = p
+	return p
+}
+
+// Add a key to a profile, validating it as a param or switch
+func (p *Profile) set(key, val string) error {
+	if !isParam(key) && !isSwitch(key) || key == "profile" {
+		return fmt.Errorf("key not allowed in profile: %s", key)
+	}
+	if isSwitch(key) {
+		if _, err := strconv.ParseBool(val); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", key, quote(val))
+		}
+	}
+	if _, ok := p.Values[key]; !ok {
+		p.Keys = append(p.Keys, key)
+	}
+	p.Values[key] = val
+	return nil
+}
+
+// Return names of all defined profiles, sorted
+func ProfileNames() (names []string) {
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply the named profile, first restoring any values overridden by
+// the active profile. Profiles inherit all top-level values they do
+// not set, and do not override values given in the environment or on
+// the command line. An empty name applies no profile
+func ApplyProfile(name string) error {
+	p, ok := Profiles[name]
+	if !ok && name != "" {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+
+	for k, s := range replaced {
+		setKey(k, s.val, s.source)
+	}
+	replaced = make(map[string]setting)
+
+	if p != nil {
+		for _, k := range p.Keys {
+			val, source := current(k)
+			if source == SourceEnv || source == SourceFlag {
+				continue
+			}
+			replaced[k] = setting{val, source}
+			setKey(k, p.Values[k], SourceProfile)
+		}
+	}
+	Update("profile", name)
+	return nil
+}
+
+// Return the value of a key as it would be without the active profile
+func baseValue(k string) (string, bool) {
+	if s, ok := replaced[k]; ok {
+		if _, source := current(k); source == SourceProfile {
+			return s.val, true
+		}
+	}
+	return "", false
+}
+
+// Return the current value and source of a param or switch
+func current(k string) (val, source string) {
+	if isSwitch(k) {
+		sw := GetSwitch(k)
+		return strconv.FormatBool(sw.Val), sw.Source
+	}
+	p := Get(k)
+	return p.Val, p.Source
+}
+
+func isParam(k string) bool {
+	for _, p := range GlobalParams {
+		if p.Key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func isSwitch(k string) bool {
+	for _, s := range GlobalSwitches {
+		if s.Key == k {
+			return true
+		}
+	}
+	return false
+}
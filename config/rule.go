@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics a threshold rule may be defined on
+var RuleMetrics = []string{"cpu", "mem", "mem_bytes", "net_rx", "net_tx", "pids"}
+
+const defaultCooldown = time.Minute
+
+// Threshold on a container metric, alerting when breached
+type Rule struct {
+	Metric   string
+	Op       string // ">" or "<"
+	Value    int64
+	Webhook  string        // optional URL notified when the rule fires and clears
+	Cooldown time.Duration // minimum time between repeated alerts
+	Scope    string        // filter selecting the containers the rule applies to, or empty for all
+	Source   string        // where the rule was defined
+}
+
+var GlobalRules []*Rule
+
+func (r *Rule) String() string {
+	s := fmt.Sprintf("%s%s%d", r.Metric, r.Op, r.Value)
+	if r.Scope != "" {
+		s += " for " + r.Scope
+	}
+	return s
+}
+
+// Return whether the given metric value breaches this rule
+func (r *Rule) Breached(v int64) bool {
+	if r.Op == "<" {
+		return v < r.Value
+	}
+	return v > r.Value
+}
+
+// Return the rule as given to ParseRule, omitting the default cooldown
+func (r *Rule) Spec() string {
+	s := fmt.Sprintf("%s%s%d", r.Metric, r.Op, r.Value)
+	if r.Cooldown != defaultCooldown {
+		s += ",cooldown=" + r.Cooldown.String()
+	}
+	if r.Scope != "" {
+		s += ",scope=" + r.Scope
+	}
+	if r.Webhook != "" {
+		s += ",webhook=" + r.Webhook
+	}
+	return s
+}
+
+// Register a threshold rule, validating its metric and webhook
+func AddRule(r *Rule) error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	GlobalRules = append(GlobalRules, r)
+	log.Infof("loaded config rule: %s", quote(r.String()))
+	return nil
+}
+
+// Validate a rule's metric and webhook, defaulting its cooldown
+func (r *Rule) check() error {
+	var known bool
+	for _, m := range RuleMetrics {
+		if r.Metric == m {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown rule metric %s, expected one of: %s", quote(r.Metric), strings.Join(RuleMetrics, ", "))
+	}
+	if r.Op != ">" && r.Op != "<" {
+		return fmt.Errorf("rule %s: operator must be > or <", r)
+	}
+	if r.Webhook != "" {
+		u, err := url.Parse(r.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("rule %s: invalid webhook url %s", r, quote(r.Webhook))
+		}
+	}
+	if r.Cooldown == 0 {
+		r.Cooldown = defaultCooldown
+	}
+	return nil
+}
+
+// Parse and register a rule given as
+// "metric>value[,cooldown=duration][,scope=filter][,webhook=url]",
+// recording the source it was given in. The scope is a container
+// filter, checked by the caller, and may contain commas
+func ParseRule(s, source string) error {
+	r, err := NewRule(s)
+	if err != nil {
+		return err
+	}
+	r.Source = source
+	return AddRule(r)
+}
+
+// Parse and validate a rule without registering it
+func NewRule(s string) (*Rule, error) {
+	r := &Rule{}
+	if i := strings.Index(s, ",webhook="); i >= 0 {
+		r.Webhook = s[i+len(",webhook="):]
+		s = s[:i]
+	}
+	if i := strings.Index(s, ",scope="); i >= 0 {
+		r.Scope = strings.TrimSpace(s[i+len(",scope="):])
+		s = s[:i]
+		if r.Scope == "" {
+			return nil, fmt.Errorf("invalid rule %s: empty scope", quote(s))
+		}
+	}
+
+	opts := strings.Split(s, ",")
+	expr := opts[0]
+	i := strings.IndexAny(expr, "<>")
+	if i <= 0 {
+		return nil, fmt.Errorf("invalid rule %s: expected metric>value or metric<value", quote(expr))
+	}
+	r.Metric, r.Op = strings.TrimSpace(expr[:i]), expr[i:i+1]
+	val, err := strconv.ParseInt(strings.TrimSpace(expr[i+1:]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rule %s: value must be an integer", quote(expr))
+	}
+	r.Value = val
+
+	for _, opt := range opts[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || kv[0] != "cooldown" {
+			return nil, fmt.Errorf("invalid rule option: %s", quote(opt))
+		}
+		d, err := time.ParseDuration(kv[1])
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid rule cooldown: %s", quote(kv[1]))
+		}
+		r.Cooldown = d
+	}
+	return r, r.check()
+}
+
+// Parse the alert rules of a config file without registering them,
+// returning those valid, and an error naming the line of each invalid
+// rule. check validates each parsed rule further
+func CheckRules(path string, check func(*Rule) error) (rules []*Rule, errs []error, err error) {
+	lines, err := readFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var section string
+	for _, l := range lines {
+		if l.header {
+			section = l.section
+			continue
+		}
+		if section != "" || l.key != "alert" {
+			continue
+		}
+		r, err := NewRule(l.val)
+		if err == nil {
+			err = check(r)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %s", path, l.n, err))
+			continue
+		}
+		r.Source = SourceFile
+		rules = append(rules, r)
+	}
+	return rules, errs, nil
+}
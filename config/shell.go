@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Functions of shell command templates. Every value a command template
+// outputs is passed through shquote, unless its pipeline already ends
+// in shquote or raw
+var shellFuncs = template.FuncMap{
+	"shquote": ShellQuote,
+	"raw":     func(v interface{}) string { return fmt.Sprint(v) },
+}
+
+// Parse a template rendering a command run via the shell. Container
+// fields such as names, labels and metadata may be set by anyone able
+// to start a container, so all output values are quoted as single
+// shell words, never interpreted by the shell
+func ShellTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(shellFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			quoteActions(t.Tree.Root)
+		}
+	}
+	return tmpl, nil
+}
+
+// Append shquote to the pipeline of every action outputting a value
+func quoteActions(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			quoteActions(child)
+		}
+	case *parse.IfNode:
+		quoteActions(n.List)
+		quoteActions(n.ElseList)
+	case *parse.RangeNode:
+		quoteActions(n.List)
+		quoteActions(n.ElseList)
+	case *parse.WithNode:
+		quoteActions(n.List)
+		quoteActions(n.ElseList)
+	case *parse.ActionNode:
+		// declarations output nothing
+		if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 {
+			return
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if id, ok := last.Args[0].(*parse.IdentifierNode); ok && shellFuncs[id.Ident] != nil {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Args:     []parse.Node{parse.NewIdentifier("shquote").SetTree(nil).SetPos(n.Pos)},
+		})
+	}
+}
+
+// Quote a value as a single shell word. Values of only characters
+// without special meaning to the shell are left as they are
+func ShellQuote(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("@%+=:,./_-", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -6,23 +6,120 @@ var switches = []*Switch{
 		Key:   "sortReversed",
 		Val:   false,
 		Label: "Reverse Sort Order",
+		Group: "Filtering",
 	},
 	&Switch{
 		Key:   "allContainers",
 		Val:   true,
 		Label: "Show All Containers",
+		Group: "Filtering",
+	},
+	&Switch{
+		Key:   "showSandboxes",
+		Val:   false,
+		Label: "Show Pod Sandbox Containers",
+		Group: "Filtering",
+	},
+	&Switch{
+		Key:   "filterPreviousNames",
+		Val:   false,
+		Label: "Match Previous Names in Filters",
+		Group: "Filtering",
 	},
 	&Switch{
 		Key:   "enableHeader",
 		Val:   true,
 		Label: "Enable Status Header",
+		Group: "Display",
+	},
+	&Switch{
+		Key:   "enableSummary",
+		Val:   true,
+		Label: "Enable Host Summary",
+		Group: "Display",
+	},
+	&Switch{
+		Key:   "wideMode",
+		Val:   false,
+		Label: "Show All Columns",
+		Group: "Display",
+	},
+	&Switch{
+		Key:   "groupByImage",
+		Val:   false,
+		Label: "Group Containers by Image",
+		Group: "Display",
+	},
+	&Switch{
+		Key:   "asciiMode",
+		Val:   false,
+		Label: "ASCII-only Rendering",
+		Group: "Display",
+	},
+	&Switch{
+		Key:   "invertColors",
+		Val:   false,
+		Label: "Invert Default Colors",
+		Group: "Display",
+	},
+	// briefly color metric cells changed by more than highlightDelta
+	&Switch{
+		Key:   "highlightChanges",
+		Val:   false,
+		Label: "Highlight Changed Metrics",
+		Group: "Display",
+	},
+	// set the terminal title to titleFormat on each refresh
+	&Switch{
+		Key:   "setTitle",
+		Val:   false,
+		Label: "Set Terminal Title",
+		Group: "Display",
+	},
+	// disables all actions changing containers; not adjustable at runtime
+	&Switch{
+		Key:   "readOnly",
+		Val:   false,
+		Label: "Read-only Mode",
+	},
+	&Switch{
+		Key:   "saveState",
+		Val:   true,
+		Label: "Save Settings on Exit",
+		Group: "General",
+	},
+	&Switch{
+		Key:   "savePins",
+		Val:   false,
+		Label: "Save Pinned Containers",
+		Group: "General",
+	},
+	&Switch{
+		Key:   "collectWhileSuspended",
+		Val:   true,
+		Label: "Collect Metrics While Suspended",
+		Group: "General",
+	},
+	&Switch{
+		Key:   "notifyBell",
+		Val:   false,
+		Label: "Bell on Watched Container Events",
+		Group: "Notifications",
+	},
+	&Switch{
+		Key:   "notifyDesktop",
+		Val:   false,
+		Label: "Desktop Notifications",
+		Group: "Notifications",
 	},
 }
 
 type Switch struct {
-	Key   string
-	Val   bool
-	Label string
+	Key    string
+	Val    bool
+	Label  string
+	Group  string // settings menu category; empty if not adjustable at runtime
+	Source string // where Val was set from
 }
 
 // Return Switch by key
@@ -40,11 +137,28 @@ func GetSwitchVal(k string) bool {
 	return GetSwitch(k).Val
 }
 
+// Set a boolean switch
+func SetSwitchVal(k string, val bool) {
+	sw := GetSwitch(k)
+	log.Noticef("config change: %s: %t -> %t", k, sw.Val, val)
+	if sw.Val != val {
+		sw.Source = SourceUI
+	}
+	sw.Val = val
+}
+
+// Set a boolean switch, recording the source it was set from
+func SetSwitchFrom(k string, val bool, source string) {
+	SetSwitchVal(k, val)
+	GetSwitch(k).Source = source
+}
+
 // Toggle a boolean switch
 func Toggle(k string) {
 	sw := GetSwitch(k)
 	newVal := sw.Val != true
 	log.Noticef("config change: %s: %t -> %t", k, sw.Val, newVal)
+	sw.Source = SourceUI
 	sw.Val = newVal
 	//log.Errorf("ignoring toggle for non-existant switch: %s", k)
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+)
+
+const diffContext = 2 // unchanged lines shown around each change
+
+// Write the effective configuration to a file, or stdout given "-"
+func ExportConfig(path string) error {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	fmt.Fprintf(w, "# ctop configuration, exported by %s\n", versionStr)
+	return config.Export(w)
+}
+
+// Validate a config file and install it to the config path, after
+// showing the changes to the existing config and asking for
+// confirmation unless forced
+func ImportConfig(path string, force bool) error {
+	errs, err := config.CheckFile(path, checkConfigValue)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		return fmt.Errorf("%s: %d invalid lines, not imported", path, len(errs))
+	}
+	if configPath == "" {
+		return fmt.Errorf("no config file path")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	old, err := ioutil.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	diff := lineDiff(splitLines(string(old)), splitLines(string(data)))
+	if len(diff) == 0 {
+		fmt.Printf("%s is unchanged\n", configPath)
+		return nil
+	}
+
+	if !force {
+		fmt.Printf("--- %s\n+++ %s\n", configPath, path)
+		for _, l := range diff {
+			fmt.Println(l)
+		}
+		fmt.Printf("install to %s? [y/N] ", configPath)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return fmt.Errorf("not imported")
+		}
+	}
+	if err := config.WriteFile(configPath, data); err != nil {
+		return err
+	}
+	log.Noticef("imported config file %s to %s", path, configPath)
+	fmt.Printf("installed %s\n", configPath)
+	return nil
+}
+
+// Validate a param value or connector option beyond what the config
+// package checks, given the config file section it is set in
+// Check params loaded from the config file, profiles and environment
+// as the settings menu would, falling back to defaults for any invalid
+func checkLoadedParams() []string {
+	return config.CheckParams(func(key, val string) error {
+		return checkConfigValue("", key, val)
+	})
+}
+
+func checkConfigValue(section, key, val string) error {
+	if name := strings.TrimPrefix(section, "connector."); name != section {
+		c, ok := connectors[name]
+		if !ok {
+			return fmt.Errorf("unknown connector, expected one of: %s", strings.Join(connectorNames(), ", "))
+		}
+		if !known(c.keys, key) {
+			return fmt.Errorf("unknown key, expected one of: %s", strings.Join(c.keys, ", "))
+		}
+		return nil
+	}
+	switch key {
+	case "connector":
+		if _, ok := connectors[val]; !ok {
+			return fmt.Errorf("invalid connector %s, expected one of: %s", val, strings.Join(connectorNames(), ", "))
+		}
+	case "timeFormat":
+		return validTimeFormat(val)
+	case "durationStyle":
+		return validDurationStyle(val)
+	}
+	if hook := settingHooks[key]; hook.validate != nil {
+		return hook.validate(val)
+	}
+	return nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+// Compare two versions of a file line by line, returning lines
+// removed prefixed with "-" and added with "+", along with a few
+// unchanged lines of context around each change prefixed with " ".
+// Unchanged lines elided between changes are marked "..."
+func lineDiff(a, b []string) []string {
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	var changed []bool
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines, changed = append(lines, " "+a[i]), append(changed, false)
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines, changed = append(lines, "-"+a[i]), append(changed, true)
+			i++
+		default:
+			lines, changed = append(lines, "+"+b[j]), append(changed, true)
+			j++
+		}
+	}
+
+	// keep changes and the context around them
+	keep := make([]bool, len(lines))
+	var found bool
+	for n, c := range changed {
+		if !c {
+			continue
+		}
+		found = true
+		for k := n - diffContext; k <= n+diffContext; k++ {
+			if k >= 0 && k < len(lines) {
+				keep[k] = true
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	var diff []string
+	for n, l := range lines {
+		if keep[n] {
+			diff = append(diff, l)
+		} else if n == 0 || keep[n-1] {
+			diff = append(diff, "...")
+		}
+	}
+	return diff
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+)
+
+// Container source selectable with -connector, constructed
+// with the options in its [connector.NAME] config section
+type connector struct {
+	keys []string // keys accepted in its config section
+	new  func(*config.ConnectorSection) (ContainerSource, error)
+}
+
+var connectors = map[string]connector{
+	"docker": {
+		keys: dockerConnectorKeys,
+		new: func(s *config.ConnectorSection) (ContainerSource, error) {
+			return newDockerContainerSource(s)
+		},
+	},
+	"kubelet": {
+		keys: kubeletConnectorKeys,
+		new: func(s *config.ConnectorSection) (ContainerSource, error) {
+			return newKubeletSource(s)
+		},
+	},
+}
+
+// Return names of all connectors, sorted
+func connectorNames() (names []string) {
+	for name := range connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return a source playing back a recording, if one is given, or
+// one built by the configured connector
+func newContainerSource() (ContainerSource, error) {
+	if replayPath != "" {
+		return NewReplaySource(replayPath), nil
+	}
+	name := config.GetVal("connector")
+	c, ok := connectors[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid connector: %s, expected one of: %s", name, strings.Join(connectorNames(), ", "))
+	}
+	section := config.GetConnectorSection(name)
+	if err := checkSection(section, c.keys); err != nil {
+		return nil, err
+	}
+	return guardSource(c.new(section))
+}
+
+// Ensure the configured connector is known, and the keys of its
+// section and of all other known connector sections are valid.
+// Sections of unknown connectors are ignored with a warning
+func validConnector() {
+	name := config.GetVal("connector")
+	if _, ok := connectors[name]; !ok {
+		fmt.Printf("invalid connector: %s, expected one of: %s\n", name, strings.Join(connectorNames(), ", "))
+		os.Exit(1)
+	}
+	for _, name := range config.ConnectorSectionNames() {
+		c, ok := connectors[name]
+		if !ok {
+			log.Notify("config: ignoring [connector.%s], unknown connector, expected one of: %s", name, strings.Join(connectorNames(), ", "))
+			continue
+		}
+		if err := checkSection(config.GetConnectorSection(name), c.keys); err != nil {
+			fmt.Printf("invalid config: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// Ensure all keys set in a connector section are among those given
+func checkSection(s *config.ConnectorSection, keys []string) error {
+	for _, k := range s.Keys() {
+		if !known(keys, k) {
+			if len(keys) == 0 {
+				return s.Errorf(k, "unknown key, connector accepts no options")
+			}
+			return s.Errorf(k, "unknown key, expected one of: %s", strings.Join(keys, ", "))
+		}
+	}
+	return nil
+}
+
+// Return the label of containers from the source at an endpoint: the
+// host of a remote endpoint, the local hostname for a local one, or
+// the endpoint itself for sources without a host, such as replays
+func sourceLabel(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	switch {
+	case err != nil || u.Scheme == "":
+		return endpoint // e.g. mock
+	case u.Scheme == "replay":
+		return u.Scheme
+	}
+	if host := endpointHost(endpoint); host != "" {
+		return host
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "localhost"
+}
@@ -1,76 +1,452 @@
 package main
 
 import (
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/config"
 	"github.com/bcicen/ctop/cwidgets"
 	"github.com/bcicen/ctop/cwidgets/compact"
 	"github.com/bcicen/ctop/metrics"
 )
 
-// Metrics and metadata representing a container
-type Container struct {
+// Default number of recent metric samples retained per container
+const defaultHistoryLen = 300
+
+// Metrics read at a point in time
+type Sample struct {
+	Time time.Time
 	metrics.Metrics
+}
+
+// Times and exit details of a container's lifecycle. Zero times are
+// unknown or not applicable
+type Lifecycle struct {
+	Created      time.Time `json:"created"`
+	Started      time.Time `json:"started"`  // last start
+	Finished     time.Time `json:"finished"` // last exit
+	Since        time.Time `json:"since"`    // current state entered
+	Exited       bool      `json:"exited"`   // exited or dead, with ExitCode
+	ExitCode     int       `json:"exit_code"`
+	RestartCount int       `json:"restart_count"`
+	ImageID      string    `json:"image_id"`
+}
+
+// Metrics and metadata representing a container. Metrics, metadata
+// and labels are updated by the container source and collector while
+// read for display, and are only accessed through their methods
+type Container struct {
 	Id        string
-	Meta      map[string]string
-	Widgets   *compact.Compact
-	updater   cwidgets.WidgetUpdater
+	Source    string // label of the source or host
+	key       string
+	widgets   *compact.Compact
+	updater   cwidgets.WidgetUpdater // widgets metrics and metadata are sent to
 	collector metrics.Collector
-	display   bool // display this container in compact view
+	display   bool          // display this container in compact view
+	version   uint64        // incremented on each metadata or label change
+	filtered  uint64        // version display was last evaluated at
+	fields    *filterFields // metadata matched by filters, as of its version
+	latest    metrics.Metrics
+	life      Lifecycle
+	meta      map[string]string
+	labels    map[string]string
+	env       containerEnv
+	names     []previousName // names before renames, oldest first
+	history   []Sample       // ring of recent samples, oldest at histNext once full
+	histNext  int
+	failed    bool         // collector stopped after a panic, not restarted
+	removed   bool         // removed from its source, not to collect again
+	lock      sync.RWMutex // guards all of the above
+	collect   sync.Mutex   // serializes starting and stopping the collector
 }
 
-func NewContainer(id string, collector metrics.Collector) *Container {
+func NewContainer(id, source string, collector metrics.Collector) *Container {
 	widgets := compact.NewCompact(id)
+	key := id
+	if source != "" {
+		key = source + "/" + id
+	}
 	return &Container{
-		Metrics:   metrics.NewMetrics(),
 		Id:        id,
-		Meta:      make(map[string]string),
-		Widgets:   widgets,
+		Source:    source,
+		key:       key,
+		version:   1,
+		latest:    metrics.NewMetrics(),
+		meta:      make(map[string]string),
+		widgets:   widgets,
 		updater:   widgets,
 		collector: collector,
 	}
 }
 
+// Return the identity of a container across sources and hosts, on
+// which IDs and names need not be unique. Rows not belonging to a
+// source, such as group headers, are identified by ID alone
+func (c *Container) Key() string { return c.key }
+
+// Return the compact row widgets of the container
+func (c *Container) Widgets() *compact.Compact {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.widgets
+}
+
+// Replace the compact row widgets, as when the color theme changes,
+// sending current metadata and metrics to the new widgets
+func (c *Container) ResetWidgets() {
+	w := compact.NewCompact(c.Id)
+	c.lock.Lock()
+	c.widgets = w
+	c.lock.Unlock()
+	c.SetUpdater(w)
+	if c.State() == "running" {
+		w.SetMetrics(c.Metrics())
+	}
+}
+
 func (c *Container) SetUpdater(u cwidgets.WidgetUpdater) {
+	c.lock.Lock()
 	c.updater = u
-	for k, v := range c.Meta {
-		c.updater.SetMeta(k, v)
+	c.lock.Unlock()
+	for k, v := range c.MetaCopy() {
+		u.SetMeta(k, v)
 	}
 }
 
+// Return the widgets metrics and metadata are currently sent to
+func (c *Container) getUpdater() cwidgets.WidgetUpdater {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.updater
+}
+
 func (c *Container) SetMeta(k, v string) {
-	c.Meta[k] = v
-	c.updater.SetMeta(k, v)
+	c.lock.Lock()
+	if c.meta[k] != v {
+		c.meta[k] = v
+		c.version++
+	}
+	u := c.updater
+	c.lock.Unlock()
+	u.SetMeta(k, v)
 }
 
 func (c *Container) GetMeta(k string) string {
-	if v, ok := c.Meta[k]; ok {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if v, ok := c.meta[k]; ok {
 		return v
 	}
 	return ""
 }
 
+// Return a copy of all metadata, safe for use outside the UI
+func (c *Container) MetaCopy() map[string]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	meta := make(map[string]string, len(c.meta))
+	for k, v := range c.meta {
+		meta[k] = v
+	}
+	return meta
+}
+
+// Return the lifecycle times and exit details of the container
+func (c *Container) Lifecycle() Lifecycle {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.life
+}
+
+// Replace lifecycle times and exit details, and the creation time
+// displayed
+func (c *Container) SetLifecycle(l Lifecycle) {
+	c.lock.Lock()
+	if c.life != l {
+		c.life = l
+		c.version++
+	}
+	c.lock.Unlock()
+	if !l.Created.IsZero() {
+		c.SetMeta("created", formatTime(l.Created))
+	}
+}
+
+// Return whether the container is displayed in the compact view
+func (c *Container) Displayed() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.display
+}
+
+// Return whether display should be re-evaluated, if metadata
+// changed since last evaluated, and the current metadata version
+func (c *Container) displayStale() (bool, uint64) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.filtered != c.version, c.version
+}
+
+func (c *Container) setDisplay(b bool, version uint64) {
+	c.lock.Lock()
+	c.display, c.filtered = b, version
+	c.lock.Unlock()
+}
+
+// Return metadata matched by filters, gathered and lowercased once
+// per metadata version rather than on each evaluation
+func (c *Container) filterFields() *filterFields {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.fields == nil || c.fields.version != c.version {
+		c.fields = newFilterFields(c.version, c.meta, c.labels, c.Source)
+	}
+	return c.fields
+}
+
+// Return the container state, e.g. running or exited
+func (c *Container) State() string {
+	return c.GetMeta("state")
+}
+
+// Return container labels. The map returned must not be modified
+func (c *Container) Labels() map[string]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.labels
+}
+
+// Replace container labels
+func (c *Container) SetLabels(labels map[string]string) {
+	c.lock.Lock()
+	c.labels = labels
+	c.version++
+	c.lock.Unlock()
+}
+
+// Return the names the container had before renames, oldest first
+func (c *Container) PreviousNames() []previousName {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]previousName{}, c.names...)
+}
+
+// Record a name the container had until renamed at the given time,
+// keeping the last maxPreviousNames
+func (c *Container) AddPreviousName(name string, until time.Time) {
+	if name == "" {
+		return
+	}
+	c.lock.Lock()
+	c.names = append(c.names, previousName{name, until})
+	if len(c.names) > maxPreviousNames {
+		c.names = c.names[len(c.names)-maxPreviousNames:]
+	}
+	detail := previousNamesDetail(c.names)
+	c.lock.Unlock()
+	c.SetMeta("previously", detail)
+}
+
+// Return the retained environment of the container
+func (c *Container) Env() containerEnv {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.env
+}
+
+// Replace the container environment from "KEY=value" entries, keeping
+// values of whitelisted variables only
+func (c *Container) SetEnv(env []string) {
+	e := newContainerEnv(env)
+	c.lock.Lock()
+	c.env = e
+	c.lock.Unlock()
+}
+
+// Return the most recently read metrics
+func (c *Container) Metrics() metrics.Metrics {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.latest
+}
+
+func (c *Container) setMetrics(m metrics.Metrics) {
+	c.lock.Lock()
+	c.latest = m
+	c.lock.Unlock()
+}
+
+// Return the configured number of metric samples retained
+func historyLen() int {
+	n, err := strconv.Atoi(config.GetVal("historyLen"))
+	if err != nil || n < 2 {
+		return defaultHistoryLen
+	}
+	return n
+}
+
+// Return recent metric samples, oldest first
+func (c *Container) History() []Sample {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.recent(len(c.history))
+}
+
+// Return up to the n most recent metric samples, oldest first
+func (c *Container) Recent(n int) []Sample {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.recent(n)
+}
+
+func (c *Container) recent(n int) []Sample {
+	if n > len(c.history) {
+		n = len(c.history)
+	}
+	h := make([]Sample, 0, n)
+	for i := len(c.history) - n; i < len(c.history); i++ {
+		h = append(h, c.history[(c.histNext+i)%len(c.history)])
+	}
+	return h
+}
+
+func (c *Container) addHistory(m metrics.Metrics) {
+	n := historyLen()
+	s := Sample{time.Now(), m}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.history) == n {
+		c.history[c.histNext] = s
+		c.histNext = (c.histNext + 1) % n
+		return
+	}
+	// filling, or resized: keep oldest first
+	if c.histNext > 0 {
+		c.history = c.recent(len(c.history))
+		c.histNext = 0
+	}
+	c.history = append(c.history, s)
+	if over := len(c.history) - n; over > 0 {
+		c.history = append(c.history[:0], c.history[over:]...)
+	}
+}
+
 func (c *Container) SetState(s string) {
 	c.SetMeta("state", s)
-	// start collector, if needed
-	if s == "running" && !c.collector.Running() {
+	c.collect.Lock()
+	defer c.collect.Unlock()
+	// start collector, if needed, unless removed by a concurrent
+	// destroy while the state was being read
+	if s == "running" && !c.collector.Running() && !c.Failed() && !c.Removed() && !collectionHeld() {
 		c.collector.Start()
 		c.Read(c.collector.Stream())
 	}
-	// stop collector, if needed
+	// stop collector, if needed; the metrics of a paused container are
+	// frozen, and collection resumes once it is unpaused
 	if s != "running" && c.collector.Running() {
 		c.collector.Stop()
 	}
 }
 
+// Stop the metrics collector, if running
+func (c *Container) StopCollector() {
+	c.collect.Lock()
+	defer c.collect.Unlock()
+	if c.collector.Running() {
+		c.collector.Stop()
+	}
+}
+
+// Mark a container removed from its source, stopping its collector
+// for good. A state set by a refresh still in flight for it will not
+// start collecting again
+func (c *Container) Remove() {
+	c.collect.Lock()
+	defer c.collect.Unlock()
+	c.lock.Lock()
+	c.removed = true
+	c.lock.Unlock()
+	if c.collector.Running() {
+		c.collector.Stop()
+	}
+}
+
+// Return whether the container was removed from its source
+func (c *Container) Removed() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.removed
+}
+
+// Return whether the collector was stopped after a panic
+func (c *Container) Failed() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.failed || c.collector.Failed()
+}
+
+// Return whether the metrics collector is running, and its error count
+func (c *Container) CollectorState() (bool, int) {
+	return c.collector.Running(), c.collector.Errors()
+}
+
+// Apply a new memory limit to current metrics, ahead of the next
+// metrics read from the collector
+func (c *Container) SetMemLimit(limit int64) {
+	if limit <= 0 {
+		return
+	}
+	c.lock.Lock()
+	c.latest.MemLimit = limit
+	c.latest.MemPercent = int(float64(c.latest.MemUsage) / float64(limit) * 100)
+	m := c.latest
+	c.lock.Unlock()
+	c.getUpdater().SetMetrics(m)
+}
+
 // Read metric stream, updating widgets
 func (c *Container) Read(stream chan metrics.Metrics) {
-	go func() {
+	safeGo(func() {
+		defer c.recoverReader(stream)
 		for metrics := range stream {
-			c.Metrics = metrics
-			c.updater.SetMetrics(metrics)
+			c.setMetrics(metrics)
+			c.addHistory(metrics)
+			checkAlerts(c, metrics)
+			checkNetAnomaly(c)
+			c.getUpdater().SetMetrics(metrics)
 		}
 		log.Infof("reader stopped for container: %s", c.Id)
-		c.Metrics = metrics.NewMetrics()
-		c.Widgets.Reset()
-	}()
+		clearAlerts(c.Key())
+		clearNetAnomaly(c)
+		c.setMetrics(metrics.NewMetrics())
+		c.Widgets().Reset()
+	})
 	log.Infof("reader started for container: %s", c.Id)
 }
+
+// Recover a panic while reading metrics, stopping the collector and
+// marking it failed while leaving the UI running. Must be deferred
+// directly by the reader
+func (c *Container) recoverReader(stream chan metrics.Metrics) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	log.Criticalf("panic reading metrics for container %s: %v\n%s", c.Id, r, debug.Stack())
+	log.NotifyError("collector for %s stopped after an internal error: %v", c.GetMeta("name"), r)
+
+	c.lock.Lock()
+	c.failed = true
+	c.lock.Unlock()
+
+	// drain the stream until the collector has stopped
+	safeGo(c.collector.Stop)
+	for range stream {
+	}
+	clearAlerts(c.Key())
+	clearNetAnomaly(c)
+	c.setMetrics(metrics.NewMetrics())
+	c.Widgets().Reset()
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/widgets/output"
+	ui "github.com/gizak/termui"
+)
+
+const (
+	lastOutputLines = 15   // lines shown in the expanded view of a stopped container
+	logViewLines    = 1000 // lines read into the log view
+)
+
+// Container source able to read the logged output of a container
+type logSource interface {
+	Logs(id string, tail int) ([]string, error)
+}
+
+// Terminal escape sequences, as used for colored output
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+// Split logged output into lines for display, removing escape
+// sequences and carriage returns
+func outputLines(s string) []string {
+	s = ansiEscape.ReplaceAllString(strings.TrimRight(s, "\n"), "")
+	s = strings.Replace(strings.Replace(s, "\r", "", -1), "\t", "    ", -1)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// Last output of a run of a stopped container
+type outputSnippet struct {
+	started time.Time // start time of the run
+	lines   []string
+}
+
+// Last output of stopped containers by key, read once per run
+var lastOutput = struct {
+	sync.Mutex
+	snippets map[string]outputSnippet
+}{snippets: make(map[string]outputSnippet)}
+
+// Return the cached last output of a container's latest run, if read
+func cachedOutput(c *Container) ([]string, bool) {
+	lastOutput.Lock()
+	defer lastOutput.Unlock()
+	s, ok := lastOutput.snippets[c.Key()]
+	if !ok || !s.started.Equal(c.Lifecycle().Started) {
+		return nil, false
+	}
+	return s.lines, true
+}
+
+// Read the last lines output by a stopped container, or a message
+// saying why they cannot be shown, and cache them until it starts
+// again. Returns false if its source cannot read logs
+func readLastOutput(c *Container) ([]string, bool) {
+	ls, ok := unwrapSource(cursor.Source()).(logSource)
+	if !ok {
+		return nil, false
+	}
+	started := c.Lifecycle().Started
+	lines, err := ls.Logs(c.Id, lastOutputLines)
+	switch {
+	case err != nil:
+		lines = []string{err.Error()}
+	case len(lines) == 0:
+		lines = []string{"no output"}
+	}
+
+	lastOutput.Lock()
+	lastOutput.snippets[c.Key()] = outputSnippet{started, lines}
+	lastOutput.Unlock()
+	return lines, true
+}
+
+// Show the last logged output of a container, scrollable and
+// searchable. r reads it again
+func ContainerLogView(c *Container) {
+	ls, ok := unwrapSource(cursor.Source()).(logSource)
+	if !ok {
+		log.NotifyError("logs are not available from %s", cursor.Source().Endpoint())
+		return
+	}
+
+	v := output.NewView()
+	v.Title = "logs: " + c.GetMeta("name")
+	load := func() {
+		lines, err := ls.Logs(c.Id, logViewLines)
+		if err != nil {
+			v.SetText(err.Error())
+			return
+		}
+		if len(lines) == 0 {
+			lines = []string{"no output"}
+		}
+		v.SetLines(lines, nil)
+		v.Bottom()
+	}
+	alignPipe(v)
+	load()
+
+	runOutputView(v, func() {
+		ui.Handle("/sys/kbd/r", func(ui.Event) {
+			load()
+			ui.Render(v)
+		})
+	})
+}
+
+// Return a message for a failure to read the logs of a container
+// with the given log driver
+func logsError(err error, driver string) error {
+	switch driver {
+	case "", "json-file", "local", "journald":
+		return fmt.Errorf("failed to read logs: %s", err)
+	}
+	return fmt.Errorf("logs unavailable (driver: %s)", driver)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets/compact"
+)
+
+// Write displayed containers as CSV, with a header row of enabled
+// columns followed by the full container ID, source, creation time,
+// uptime and lifecycle details. Returns rows written
+func writeCSV(path string) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	cols := compact.EnabledColumns()
+	w := csv.NewWriter(f)
+
+	var header []string
+	for _, col := range cols {
+		header = append(header, columnLabel(col))
+	}
+	w.Write(append(header, "FULL ID", "SOURCE", "CREATED", "UPTIME",
+		"CREATED AT", "STARTED AT", "FINISHED AT", "EXIT CODE", "RESTARTS", "HEALTH", "IMAGE ID"))
+
+	for _, c := range cursor.filtered {
+		var row []string
+		for _, col := range cols {
+			row = append(row, columnText(c, col))
+		}
+		life := c.Lifecycle()
+		exitCode := ""
+		if life.Exited {
+			exitCode = strconv.Itoa(life.ExitCode)
+		}
+		w.Write(append(row, c.Id, c.Source, c.GetMeta("created"), containerUptime(c),
+			exportTime(life.Created), exportTime(life.Started), exportTime(life.Finished), exitCode,
+			strconv.Itoa(life.RestartCount), c.GetMeta("health"), life.ImageID))
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+	return len(cursor.filtered), f.Close()
+}
+
+func defaultCSVPath() string {
+	return fmt.Sprintf("./ctop-%s.csv", time.Now().Format("20060102-150405"))
+}
+
+// Prompt for a path and write the displayed table to it as CSV
+func CSVMenu() {
+	path := defaultCSVPath()
+	var rows int
+	ok := promptInput("Export table to CSV", path, nil, func(s string) (err error) {
+		if err = checkExportPath(s); err != nil {
+			return err
+		}
+		path = s
+		rows, err = writeCSV(s)
+		return err
+	})
+	if ok {
+		log.Notify("wrote %d rows to %s", rows, path)
+	}
+}
+
+// Write a single CSV snapshot without the UI, after one refresh interval
+func ExportCSV(path string) {
+	time.Sleep(refreshInterval())
+	cursor.RefreshContainers()
+	rows, err := writeCSV(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to export csv: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %d rows to %s\n", rows, path)
+}
@@ -2,28 +2,71 @@ package main
 
 import (
 	"math"
+	"strings"
+	"sync"
 
 	ui "github.com/gizak/termui"
 )
 
 type GridCursor struct {
-	selectedID string // id of currently selected container
-	filtered   Containers
-	cSource    ContainerSource
+	selectedKey string     // key of currently selected row
+	filtered    Containers // displayed containers
+	rows        Containers // displayed rows, including any group headers
+	pinned      int        // pinned containers, leading rows
+	groups      map[string]*containerGroup
+	expanded    map[string]bool // expanded groups and parent rows, by row key
+	grouping    string          // group header prefix at last refresh, empty if ungrouped
+	total       int             // all containers tracked by the source, displayed or not
+	cSource     ContainerSource
+	sourceLock  sync.RWMutex // guards cSource, read by API, metrics and forwarding goroutines
 }
 
 func NewGridCursor() *GridCursor {
+	cs, err := newContainerSource()
+	if perr, ok := err.(*permissionError); ok {
+		exitPermissionDenied(perr)
+	}
+	if err != nil {
+		panic(err)
+	}
 	return &GridCursor{
-		cSource: NewDockerContainerSource(),
+		cSource:  cs,
+		expanded: make(map[string]bool),
 	}
 }
 
-func (gc *GridCursor) Len() int { return len(gc.filtered) }
+// Return the current container source
+func (gc *GridCursor) Source() ContainerSource {
+	gc.sourceLock.RLock()
+	defer gc.sourceLock.RUnlock()
+	return gc.cSource
+}
+
+// Replace the container source, closing the previous one
+func (gc *GridCursor) SetSource(cs ContainerSource) {
+	gc.sourceLock.Lock()
+	old := gc.cSource
+	gc.cSource = cs
+	gc.sourceLock.Unlock()
+	gc.selectedKey = ""
+	gc.filtered = Containers{}
+	gc.rows = Containers{}
+	gc.groups = nil
+	old.Close()
+}
+
+// Return the number of rows displayed
+func (gc *GridCursor) Len() int { return len(gc.rows) }
 
+// Return the number of containers tracked, including those not displayed
+func (gc *GridCursor) Total() int { return gc.total }
+
+// Return the selected container, or nil if none or
+// a group header row is selected
 func (gc *GridCursor) Selected() *Container {
 	idx := gc.Idx()
-	if idx < gc.Len() {
-		return gc.filtered[idx]
+	if idx < gc.Len() && gc.SelectedGroup() == nil {
+		return gc.rows[idx]
 	}
 	return nil
 }
@@ -34,16 +77,40 @@ func (gc *GridCursor) RefreshContainers() (lenChanged bool) {
 
 	// Containers filtered by display bool
 	gc.filtered = Containers{}
-	var cursorVisible bool
-	for _, c := range gc.cSource.All() {
-		if c.display {
-			if c.Id == gc.selectedID {
-				cursorVisible = true
-			}
+	all := gc.Source().All()
+	gc.total = len(all)
+	for _, c := range all {
+		if c.Displayed() {
 			gc.filtered = append(gc.filtered, c)
 		}
 	}
 
+	gc.filtered.disambiguate()
+
+	prefix := groupPrefix()
+	if prefix != gc.grouping {
+		gc.switchGrouping(prefix)
+		gc.grouping = prefix
+	}
+	gc.pinned = gc.filtered.pinFirst()
+	gc.rows = gc.filtered
+	if prefix != "" {
+		// pinned containers lead, outside of any group
+		rows := append(Containers{}, gc.filtered[:gc.pinned]...)
+		gc.rows = append(rows, gc.groupRows(prefix, gc.filtered[gc.pinned:])...)
+	}
+	if ps, ok := unwrapSource(gc.Source()).(parentSource); ok {
+		gc.rows = gc.nestChildren(ps, gc.rows)
+	}
+
+	var cursorVisible bool
+	for _, c := range gc.rows {
+		if c.Key() == gc.selectedKey {
+			cursorVisible = true
+			break
+		}
+	}
+
 	if oldLen != gc.Len() {
 		lenChanged = true
 	}
@@ -51,27 +118,39 @@ func (gc *GridCursor) RefreshContainers() (lenChanged bool) {
 	if !cursorVisible {
 		gc.Reset()
 	}
-	if gc.selectedID == "" {
+	if gc.selectedKey == "" {
 		gc.Reset()
 	}
 	return lenChanged
 }
 
+// Return the container with the given key, displayed or not
+func (gc *GridCursor) lookup(key string) (*Container, bool) {
+	for _, c := range gc.Source().All() {
+		if c.Key() == key {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
 // Set an initial cursor position, if possible
 func (gc *GridCursor) Reset() {
-	for _, c := range gc.cSource.All() {
-		c.Widgets.Name.UnHighlight()
+	if g := gc.SelectedGroup(); g != nil {
+		g.header.Widgets().Name.UnHighlight()
+	} else if c, ok := gc.lookup(gc.selectedKey); ok {
+		c.Widgets().Name.UnHighlight()
 	}
 	if gc.Len() > 0 {
-		gc.selectedID = gc.filtered[0].Id
-		gc.filtered[0].Widgets.Name.Highlight()
+		gc.selectedKey = gc.rows[0].Key()
+		gc.rows[0].Widgets().Name.Highlight()
 	}
 }
 
 // Return current cursor index
 func (gc *GridCursor) Idx() int {
-	for n, c := range gc.filtered {
-		if c.Id == gc.selectedID {
+	for n, c := range gc.rows {
+		if c.Key() == gc.selectedKey {
 			return n
 		}
 	}
@@ -106,12 +185,12 @@ func (gc *GridCursor) Up() {
 	if idx <= 0 { // already at top
 		return
 	}
-	active := gc.filtered[idx]
-	next := gc.filtered[idx-1]
+	active := gc.rows[idx]
+	next := gc.rows[idx-1]
 
-	active.Widgets.Name.UnHighlight()
-	gc.selectedID = next.Id
-	next.Widgets.Name.Highlight()
+	active.Widgets().Name.UnHighlight()
+	gc.selectedKey = next.Key()
+	next.Widgets().Name.Highlight()
 
 	gc.ScrollPage()
 	ui.Render(cGrid)
@@ -122,12 +201,12 @@ func (gc *GridCursor) Down() {
 	if idx >= gc.Len()-1 { // already at bottom
 		return
 	}
-	active := gc.filtered[idx]
-	next := gc.filtered[idx+1]
+	active := gc.rows[idx]
+	next := gc.rows[idx+1]
 
-	active.Widgets.Name.UnHighlight()
-	gc.selectedID = next.Id
-	next.Widgets.Name.Highlight()
+	active.Widgets().Name.UnHighlight()
+	gc.selectedKey = next.Key()
+	next.Widgets().Name.Highlight()
 
 	gc.ScrollPage()
 	ui.Render(cGrid)
@@ -144,12 +223,12 @@ func (gc *GridCursor) PgUp() {
 	cGrid.Offset = int(math.Max(float64(cGrid.Offset-cGrid.MaxRows()),
 		float64(0)))
 
-	active := gc.filtered[idx]
-	next := gc.filtered[nextidx]
+	active := gc.rows[idx]
+	next := gc.rows[nextidx]
 
-	active.Widgets.Name.UnHighlight()
-	gc.selectedID = next.Id
-	next.Widgets.Name.Highlight()
+	active.Widgets().Name.UnHighlight()
+	gc.selectedKey = next.Key()
+	next.Widgets().Name.Highlight()
 
 	cGrid.Align()
 	ui.Render(cGrid)
@@ -167,13 +246,100 @@ func (gc *GridCursor) PgDown() {
 	cGrid.Offset = int(math.Min(float64(cGrid.Offset+cGrid.MaxRows()),
 		float64(gc.Len()-cGrid.MaxRows())))
 
-	active := gc.filtered[idx]
-	next := gc.filtered[nextidx]
+	active := gc.rows[idx]
+	next := gc.rows[nextidx]
+
+	active.Widgets().Name.UnHighlight()
+	gc.selectedKey = next.Key()
+	next.Widgets().Name.Highlight()
+
+	cGrid.Align()
+	ui.Render(cGrid)
+}
+
+// Move cursor to the given row index
+func (gc *GridCursor) Jump(idx int) {
+	if idx < 0 || idx >= gc.Len() {
+		return
+	}
+
+	active := gc.rows[gc.Idx()]
+	next := gc.rows[idx]
+
+	active.Widgets().Name.UnHighlight()
+	gc.selectedKey = next.Key()
+	next.Widgets().Name.Highlight()
 
-	active.Widgets.Name.UnHighlight()
-	gc.selectedID = next.Id
-	next.Widgets.Name.Highlight()
+	// scroll page to make row visible
+	if idx < cGrid.Offset {
+		cGrid.Offset = idx
+	}
+	if idx >= cGrid.Offset+cGrid.MaxRows() {
+		cGrid.Offset = idx - cGrid.MaxRows() + 1
+	}
 
 	cGrid.Align()
 	ui.Render(cGrid)
 }
+
+// Move cursor to the given container, returning false if it is not
+// displayed
+func (gc *GridCursor) Select(c *Container) bool {
+	for n, row := range gc.rows {
+		if row.Key() == c.Key() {
+			gc.Jump(n)
+			return true
+		}
+	}
+	return false
+}
+
+// Move cursor to the next container whose name begins with
+// the given string, wrapping around to the first row
+func (gc *GridCursor) JumpPrefix(s string) {
+	s = strings.ToLower(s)
+	idx := gc.Idx()
+	for i := 1; i <= gc.Len(); i++ {
+		n := (idx + i) % gc.Len()
+		name := strings.ToLower(gc.rows[n].GetMeta("name"))
+		if strings.HasPrefix(name, s) {
+			gc.Jump(n)
+			return
+		}
+	}
+}
+
+// Toggle display of row index numbers in place of status
+func (gc *GridCursor) ShowIndex(show bool) {
+	for n, c := range gc.rows {
+		if show {
+			c.Widgets().Status.ShowIndex(n + 1)
+		} else {
+			c.Widgets().Status.HideIndex()
+		}
+	}
+	ui.Render(cGrid)
+}
+
+// Suffix the names of containers sharing a name with a container of
+// another source with their source, e.g. "web_1 @hostA"
+func (a Containers) disambiguate() {
+	names := make([]string, len(a))
+	sources := make(map[string]string, len(a)) // first source by name
+	shared := make(map[string]bool)
+	for n, c := range a {
+		names[n] = c.GetMeta("name")
+		if s, ok := sources[names[n]]; !ok {
+			sources[names[n]] = c.Source
+		} else if s != c.Source {
+			shared[names[n]] = true
+		}
+	}
+	for n, c := range a {
+		var suffix string
+		if shared[names[n]] {
+			suffix = " @" + c.Source
+		}
+		c.Widgets().SetNameSuffix(suffix)
+	}
+}
@@ -0,0 +1,153 @@
+package compact
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/metrics"
+	ui "github.com/gizak/termui"
+)
+
+// Metric columns which may be highlighted on change, set by
+// highlightColumns, in the order of their cells in a row
+var ChangeColumns = []string{"cpu", "mem", "net", "io", "pids"}
+
+// Ticks a changed cell stays highlighted, fading on the last
+const changeTicks = 2
+
+// Smallest change highlighted in each column, whatever highlightDelta,
+// so idle containers do not flicker. The gauge column is keyed by the
+// metric it shows
+var minChange = map[string]float64{
+	"cpu":       2,       // percentage points
+	"gauge mem": 2,       // percentage points
+	"gauge net": 1 << 10, // bytes/s
+	"pids":      1,
+	"mem":       1 << 20, // bytes
+	"net":       1 << 10, // bytes/s
+	"io":        1 << 10, // bytes/s
+}
+
+// Value of a metric column at the last tick, and any highlight of a
+// change since. Rows keep one for each of ChangeColumns, so tracking
+// is bounded and ends with the row
+type cellChange struct {
+	metric string // metric the value was read from, for the gauge column
+	prev   float64
+	seen   bool
+	up     bool // direction of the last change
+	ticks  int  // ticks left highlighted
+}
+
+// Ensure highlighted columns are all among ChangeColumns
+func ValidChangeColumns(s string) error {
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" && indexOf(ChangeColumns, name) < 0 {
+			return fmt.Errorf("unknown column %q, expected any of: %s", name, strings.Join(ChangeColumns, ", "))
+		}
+	}
+	return nil
+}
+
+// Return the index of s in list, or -1 if not found
+func indexOf(list []string, s string) int {
+	for n, v := range list {
+		if v == s {
+			return n
+		}
+	}
+	return -1
+}
+
+// Return the highlighted columns, or none if highlighting is disabled
+func changeColumns() map[string]bool {
+	cols := make(map[string]bool)
+	if !config.GetSwitchVal("highlightChanges") {
+		return cols
+	}
+	for _, name := range strings.Split(config.GetVal("highlightColumns"), ",") {
+		cols[strings.TrimSpace(name)] = true
+	}
+	return cols
+}
+
+// Return the relative change highlighted, as a fraction
+func changeDelta() float64 {
+	n, err := strconv.ParseFloat(config.GetVal("highlightDelta"), 64)
+	if err != nil || n < 0 {
+		return 0.1
+	}
+	return n / 100
+}
+
+// Compare the metrics of a tick to those of the previous one, fading
+// earlier highlights and highlighting columns changed by more than
+// highlightDelta of their previous value
+func (row *Compact) trackChanges(m metrics.Metrics) {
+	cols := changeColumns()
+	gauge := gaugeMetric()
+	values := []struct {
+		metric string
+		v      float64
+	}{
+		{"cpu", float64(m.CPUUtil)},
+		{"mem", float64(m.MemUsage)},
+		// counters are compared by rate, so a slowdown shows as a decrease
+		{"net", float64(row.netRate.rate)},
+		{"io", float64(row.ioRate.rate)},
+		{"pids", float64(m.Pids)},
+	}
+	switch gauge {
+	case "mem":
+		values[0].metric, values[0].v = "gauge mem", float64(m.MemPercent)
+	case "net":
+		values[0].metric, values[0].v = "gauge net", float64(row.netRate.rate)
+	case "pids":
+		values[0].metric, values[0].v = "pids", float64(m.Pids)
+	}
+
+	for n, val := range values {
+		ch := &row.changes[n]
+		if ch.ticks > 0 {
+			ch.ticks--
+		}
+		// unread metrics and unknown rates are negative
+		if !cols[ChangeColumns[n]] || val.v < 0 || !ch.seen || ch.metric != val.metric {
+			*ch = cellChange{metric: val.metric, prev: val.v, seen: val.v >= 0}
+			continue
+		}
+		diff := val.v - ch.prev
+		if math.Abs(diff) >= math.Max(ch.prev*changeDelta(), minChange[val.metric]) {
+			ch.up, ch.ticks = diff > 0, changeTicks
+		}
+		ch.prev = val.v
+	}
+}
+
+// Recolor the text of changed metric cells, red for an increase and
+// green for a decrease, bold until the last tick of the highlight
+func (row *Compact) highlightChanges(buf ui.Buffer) {
+	for _, c := range enabledColumns() {
+		n := indexOf(ChangeColumns, c.Name)
+		if n < 0 || row.changes[n].ticks == 0 {
+			continue
+		}
+		ch := row.changes[n]
+		fg := ui.ThemeAttr("change.down")
+		if ch.up {
+			fg = ui.ThemeAttr("change.up")
+		}
+		if ch.ticks > 1 {
+			fg |= ui.AttrBold
+		}
+		for p := range row.column(c.Name).Buffer().CellMap {
+			if c, ok := buf.CellMap[p]; ok {
+				c.Fg = fg
+				buf.CellMap[p] = c
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package compact
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+)
+
+// Compact view column
+type Column struct {
+	Name     string // unique column name
+	Label    string // header text
+	Sort     string // sort field for column, if any
+	Width    int    // static width, 0 == auto width
+	Natural  int    // auto width column size in wide mode
+	MinWidth int    // auto width hints, 0 == no limit
+	MaxWidth int
+}
+
+// Return the static width of the column, or else its width in
+// wide mode, e.g. for output without the UI
+func (c *Column) NaturalWidth() int {
+	if c.Width != 0 {
+		return c.Width
+	}
+	return c.natural()
+}
+
+// Return column width in wide mode
+func (c *Column) natural() int {
+	if c.MaxWidth > 0 {
+		return c.MaxWidth
+	}
+	if c.Natural < c.MinWidth {
+		return c.MinWidth
+	}
+	return c.Natural
+}
+
+// All known columns, in display order
+var Columns = []*Column{
+	&Column{Name: "status", Sort: "state", Width: 3},
+	&Column{Name: "name", Label: "NAME", Sort: "name", Natural: 24},
+	&Column{Name: "id", Label: "CID", Sort: "id", Natural: 12},
+	&Column{Name: "image", Label: "IMAGE", Sort: "image", Natural: 36},
+	&Column{Name: "user", Label: "USER", Sort: "user", Natural: 12},
+	&Column{Name: "cpu", Label: "CPU", Sort: "cpu", Natural: 16},
+	&Column{Name: "mem", Label: "MEM", Sort: "mem", Natural: 20},
+	&Column{Name: "net", Label: "NET RX/TX", Sort: "net", Natural: 20},
+	&Column{Name: "io", Label: "IO R/W", Sort: "io", Natural: 20},
+	&Column{Name: "pids", Label: "PIDS", Sort: "pids", Width: 4},
+	&Column{Name: "since", Label: "STATE SINCE", Sort: "since", Natural: 12},
+	&Column{Name: "cputime", Label: "CPU TIME", Sort: "cputime", Natural: 10},
+	&Column{Name: "security", Label: "SECURITY", Sort: "security", Natural: 10},
+	&Column{Name: "imagesize", Label: "IMAGE SIZE", Sort: "imagesize", Natural: 10},
+}
+
+var (
+	colOffset int // number of columns scrolled past in wide mode
+	layoutGen int // incremented on any change to column layout
+)
+
+func wideMode() bool { return config.GetSwitchVal("wideMode") }
+
+// Reset column scroll position and force layout of all rows
+func ResetLayout() {
+	colOffset = 0
+	layoutGen++
+}
+
+// Scroll columns left(negative) or right(positive) in wide mode
+func ScrollColumns(n int) {
+	if !wideMode() {
+		return
+	}
+	colOffset += n
+	// leading status column is never scrolled
+	if max := len(Columns) - 2; colOffset > max {
+		colOffset = max
+	}
+	if colOffset < 0 {
+		colOffset = 0
+	}
+	layoutGen++
+}
+
+// Return a description of the visible columns in wide mode,
+// given the total available width
+func ColumnWindow(width int) string {
+	if !wideMode() {
+		return ""
+	}
+	cols := enabledColumns()
+	widths := calcWidths(width, cols)
+
+	// count scrolled columns fully visible within width, following
+	// the status column, which is neither scrolled nor counted
+	width -= widths[0] + colSpacing
+	visible := 0
+	for n := range cols[1:] {
+		width -= widths[n+1] + colSpacing
+		if width < 0 {
+			break
+		}
+		visible++
+	}
+
+	first := colOffset + 1 // one-indexed
+	last := colOffset + visible
+	return fmt.Sprintf("columns %d-%d of %d", first, last, len(Columns)-1)
+}
+
+// Return enabled columns for use outside the package, e.g. by
+// -stdout output
+func EnabledColumns() []*Column { return enabledColumns() }
+
+// Return enabled columns, in display order
+func enabledColumns() (cols []*Column) {
+	// all columns are displayed in wide mode, starting from scroll offset
+	if wideMode() {
+		cols = append(cols, Columns[0])
+		return append(cols, Columns[colOffset+1:]...)
+	}
+
+	enabled := make(map[string]bool)
+	for _, s := range strings.Split(config.GetVal("columns"), ",") {
+		enabled[strings.TrimSpace(s)] = true
+	}
+	for _, c := range Columns {
+		if enabled[c.Name] {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// Load column width hints from config
+func loadWidthHints() {
+	for _, c := range Columns {
+		s := config.GetVal(c.Name + "Width")
+		if s == "" {
+			continue
+		}
+		min, max, err := parseWidthHint(s)
+		if err != nil {
+			log.Errorf("invalid width for column %s: %s", c.Name, err)
+			continue
+		}
+		c.MinWidth, c.MaxWidth = min, max
+	}
+}
+
+// Parse a width hint of the form "N" or "MIN..MAX"
+func parseWidthHint(s string) (min, max int, err error) {
+	parts := strings.SplitN(s, "..", 2)
+	if min, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return 0, 0, err
+	}
+	max = min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, err
+		}
+	}
+	if min < 0 || max < min {
+		return 0, 0, fmt.Errorf("bad width range: %s", s)
+	}
+	return min, max, nil
+}
@@ -1,9 +1,64 @@
 package compact
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
+// Metrics the main gauge column may show, set by gaugeMetric. The
+// column keeps the name "cpu" whichever is shown
+var GaugeMetrics = []string{"cpu", "mem", "net", "pids"}
+
+// Header label and sort field of the gauge column for each metric
+var gaugeColumns = map[string]struct{ label, sort string }{
+	"cpu":  {"CPU", "cpu"},
+	"mem":  {"MEM %", "mem %"},
+	"net":  {"NET RATE", "net rate"},
+	"pids": {"PIDS", "pids"},
+}
+
+// Network rates from 1KiB/s to 1GiB/s fill the net rate gauge on a
+// log scale, as they lack a limit to scale against
+const (
+	netGaugeMin = 1 << 10
+	netGaugeMax = 1 << 30
+)
+
+// Ensure a gauge metric is one of GaugeMetrics
+func ValidGaugeMetric(s string) error {
+	if _, ok := gaugeColumns[s]; !ok {
+		return fmt.Errorf("gauge metric %q, expected one of: %s", s, strings.Join(GaugeMetrics, ", "))
+	}
+	return nil
+}
+
+// Return the configured gauge column metric, or cpu if invalid
+func gaugeMetric() string {
+	if s := config.GetVal("gaugeMetric"); ValidGaugeMetric(s) == nil {
+		return s
+	}
+	return "cpu"
+}
+
+// Set the gauge column header and sort field from the configured
+// metric, returning the sort field replaced and the one set
+func ApplyGaugeMetric() (prev, cur string) {
+	gc := gaugeColumns[gaugeMetric()]
+	for _, c := range Columns {
+		if c.Name == "cpu" {
+			prev = c.Sort
+			c.Label, c.Sort = gc.label, gc.sort
+		}
+	}
+	return prev, gc.sort
+}
+
 type GaugeCol struct {
 	*ui.Gauge
 }
@@ -23,12 +78,68 @@ func (w *GaugeCol) Reset() {
 	w.Percent = 0
 }
 
+func (w *GaugeCol) Buffer() ui.Buffer {
+	buf := w.Gauge.Buffer()
+	if !cwidgets.ASCIIMode() {
+		return buf
+	}
+	// draw filled portion of bar with fill character
+	for p, c := range buf.CellMap {
+		if c.Ch == ' ' && c.Bg != w.Bg {
+			c.Ch = cwidgets.Glyphs.GaugeFill
+			buf.CellMap[p] = c
+		}
+	}
+	return buf
+}
+
+// Set the gauge label and fill, colored by the configured thresholds
+// if colored. The fill is at least 5% so that the bar stays visible
+func (w *GaugeCol) set(label string, percent int, colored bool) {
+	w.Label = label
+	w.BarColor = ui.ThemeAttr("gauge.bar.bg")
+	if colored {
+		w.BarColor = colorScale(percent)
+	}
+	if percent < 5 {
+		percent = 5
+		w.BarColor = ui.ThemeAttr("gauge.bar.bg")
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	w.Percent = percent
+}
+
+// Return gauge color for a percentage, by configured thresholds
 func colorScale(n int) ui.Attribute {
-	if n > 70 {
+	if n > gaugeLevel("gaugeCrit", 70) {
 		return ui.ColorRed
 	}
-	if n > 30 {
+	if n > gaugeLevel("gaugeWarn", 30) {
 		return ui.ColorYellow
 	}
 	return ui.ColorGreen
 }
+
+// Return a configured gauge color threshold, or def if invalid
+func gaugeLevel(k string, def int) int {
+	n, err := strconv.Atoi(config.GetVal(k))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// Return the percentage of a log scale between netGaugeMin and
+// netGaugeMax a network rate in bytes/s falls at
+func netScale(rate float64) int {
+	if rate <= netGaugeMin {
+		return 0
+	}
+	n := math.Log(rate/netGaugeMin) / math.Log(netGaugeMax/netGaugeMin) * 100
+	if n > 100 {
+		return 100
+	}
+	return int(n)
+}
@@ -1,6 +1,9 @@
 package compact
 
 import (
+	"image"
+
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
@@ -13,18 +16,35 @@ type CompactGrid struct {
 	Width  int
 	Height int
 	Offset int // starting row offset
+	empty  *ui.Par
+	page   int // rows visible, as of last Align
+	bottom int // lines kept free below the grid
 }
 
 func NewCompactGrid() *CompactGrid {
+	loadWidthHints()
+	ApplyGaugeMetric()
 	header = NewCompactHeader() // init column header
-	return &CompactGrid{}
+	return &CompactGrid{empty: newEmptyPar()}
+}
+
+func newEmptyPar() *ui.Par {
+	p := ui.NewPar("")
+	p.Border = false
+	p.Height = 1
+	p.TextFgColor = ui.ThemeAttr("par.text.fg")
+	return p
 }
 
+// Set message displayed in place of rows when grid is empty
+func (cg *CompactGrid) SetEmpty(s string) { cg.empty.Text = s }
+
 func (cg *CompactGrid) Align() {
 	y := cg.Y
 	if cg.Offset >= len(cg.Rows) {
 		cg.Offset = 0
 	}
+	cg.page = cg.MaxRows()
 	// update row ypos, width recursively
 	for _, r := range cg.pageRows() {
 		r.SetY(y)
@@ -33,24 +53,52 @@ func (cg *CompactGrid) Align() {
 	}
 }
 
-func (cg *CompactGrid) Clear()         { cg.Rows = []ui.GridBufferer{} }
+func (cg *CompactGrid) Clear()         { cg.Rows = cg.Rows[:0] }
 func (cg *CompactGrid) GetHeight() int { return len(cg.Rows) + header.Height }
 func (cg *CompactGrid) SetX(x int)     { cg.X = x }
 func (cg *CompactGrid) SetY(y int)     { cg.Y = y }
 func (cg *CompactGrid) SetWidth(w int) { cg.Width = w }
-func (cg *CompactGrid) MaxRows() int   { return ui.TermHeight() - header.Height - cg.Y }
+func (cg *CompactGrid) MaxRows() int   { return ui.TermHeight() - header.Height - cg.Y - cg.bottom }
 
+// Keep the given number of lines free below the grid, e.g. for
+// a persistent footer
+func (cg *CompactGrid) SetBottom(n int) { cg.bottom = n }
+
+// Return the header and rows visible from the current offset. Rows
+// outside of the page are neither aligned nor drawn
 func (cg *CompactGrid) pageRows() (rows []ui.GridBufferer) {
+	end := cg.Offset + cg.page
+	if end > len(cg.Rows) {
+		end = len(cg.Rows)
+	}
 	rows = append(rows, header)
-	rows = append(rows, cg.Rows[cg.Offset:]...)
+	if cg.Offset < end {
+		rows = append(rows, cg.Rows[cg.Offset:end]...)
+	}
 	return rows
 }
 
 func (cg *CompactGrid) Buffer() ui.Buffer {
-	buf := ui.NewBuffer()
+	buf := sizedBuffer(cg.Width * (cg.page + header.Height))
 	for _, r := range cg.pageRows() {
 		buf.Merge(r.Buffer())
 	}
+	if len(cg.Rows) == 0 && cg.empty.Text != "" {
+		cg.empty.X = 1
+		cg.empty.Y = cg.Y + header.Height + 1
+		cg.empty.Width = cg.Width - 1
+		buf.Merge(cg.empty.Buffer())
+	}
+	return cwidgets.ASCIIBuffer(buf)
+}
+
+// Return an empty buffer with capacity for n cells, avoiding
+// repeated growth when merging many widgets
+func sizedBuffer(n int) ui.Buffer {
+	buf := ui.NewBuffer()
+	if n > 0 {
+		buf.CellMap = make(map[image.Point]ui.Cell, n)
+	}
 	return buf
 }
 
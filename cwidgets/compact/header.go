@@ -1,22 +1,32 @@
 package compact
 
 import (
+	"fmt"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
+var headerFocus string // name of the column focused for sorting, if any
+
+// Highlight the header of the named column, or none if empty
+func SetHeaderFocus(name string) { headerFocus = name }
+
 type CompactHeader struct {
 	X, Y   int
 	Width  int
 	Height int
-	pars   []*ui.Par
+	pars   map[string]*ui.Par
 }
 
 func NewCompactHeader() *CompactHeader {
-	fields := []string{"", "NAME", "CID", "CPU", "MEM", "NET RX/TX", "IO R/W", "PIDS"}
-	ch := &CompactHeader{}
-	ch.Height = 2
-	for _, f := range fields {
-		ch.addFieldPar(f)
+	ch := &CompactHeader{
+		Height: 2,
+		pars:   make(map[string]*ui.Par),
+	}
+	for _, c := range Columns {
+		ch.addFieldPar(c.Name, c.Label)
 	}
 	return ch
 }
@@ -27,18 +37,18 @@ func (ch *CompactHeader) GetHeight() int {
 
 func (ch *CompactHeader) SetWidth(w int) {
 	x := ch.X
-	autoWidth := calcWidth(w)
-	for n, col := range ch.pars {
-		// set column to static width
-		if colWidths[n] != 0 {
-			col.SetX(x)
-			col.SetWidth(colWidths[n])
-			x += colWidths[n]
+	cols := enabledColumns()
+	widths := calcWidths(w, cols)
+	for n, c := range cols {
+		col := ch.pars[c.Name]
+		col.SetX(x)
+		col.SetWidth(widths[n])
+		// static width columns are not padded
+		if c.Width != 0 {
+			x += widths[n]
 			continue
 		}
-		col.SetX(x)
-		col.SetWidth(autoWidth)
-		x += autoWidth + colSpacing
+		x += widths[n] + colSpacing
 	}
 	ch.Width = w
 }
@@ -54,17 +64,38 @@ func (ch *CompactHeader) SetY(y int) {
 	ch.Y = y
 }
 
+// Render enabled column headers, marking the sort column with the
+// sort direction and highlighting any focused column
 func (ch *CompactHeader) Buffer() ui.Buffer {
 	buf := ui.NewBuffer()
-	for _, p := range ch.pars {
+	sortField := config.GetVal("sortField")
+	for _, c := range enabledColumns() {
+		p := ch.pars[c.Name]
+		p.Text = c.Label
+		p.TextFgColor = ui.ThemeAttr("par.text.fg")
+		if c.Sort != "" && c.Sort == sortField {
+			arrow := cwidgets.Glyphs.DownArrow
+			if config.GetSwitchVal("sortReversed") {
+				arrow = cwidgets.Glyphs.UpArrow
+			}
+			if p.Text != "" {
+				p.Text += " "
+			}
+			p.Text += string(arrow)
+			p.TextFgColor |= ui.AttrBold
+		}
+		if c.Name == headerFocus {
+			p.Text = fmt.Sprintf("%-*s", p.Width, p.Text)
+			p.TextFgColor |= ui.AttrReverse
+		}
 		buf.Merge(p.Buffer())
 	}
 	return buf
 }
 
-func (ch *CompactHeader) addFieldPar(s string) {
+func (ch *CompactHeader) addFieldPar(name, s string) {
 	p := ui.NewPar(s)
 	p.Height = ch.Height
 	p.Border = false
-	ch.pars = append(ch.pars, p)
+	ch.pars[name] = p
 }
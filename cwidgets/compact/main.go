@@ -1,6 +1,10 @@
 package compact
 
 import (
+	"image"
+	"strconv"
+	"sync"
+
 	"github.com/bcicen/ctop/logging"
 	"github.com/bcicen/ctop/metrics"
 	ui "github.com/gizak/termui"
@@ -9,17 +13,45 @@ import (
 var log = logging.Init()
 
 type Compact struct {
-	Status *Status
-	Name   *TextCol
-	Cid    *TextCol
-	Cpu    *GaugeCol
-	Memory *GaugeCol
-	Net    *TextCol
-	IO     *TextCol
-	Pids   *TextCol
-	X, Y   int
-	Width  int
-	Height int
+	Status  *Status
+	Name    *TextCol
+	Cid     *TextCol
+	Image   *TextCol
+	User    *TextCol
+	Cpu     *GaugeCol
+	Memory  *GaugeCol
+	Net     *TextCol
+	IO      *TextCol
+	Pids    *TextCol
+	Since   *TextCol
+	CPUTime *TextCol
+	Sec     *TextCol
+	ImgSize *TextCol
+	X, Y    int
+	Width   int
+	Height  int
+	stale   bool // metrics are out of date
+	paused  bool // container paused, its collector suspended
+	divide  bool // underline row, separating it from rows below
+	netHot  bool // network rate flagged as anomalous
+	name    string
+	image   string
+	old     bool   // a newer image of the image reference was pulled
+	broken  bool   // the container failed to inspect
+	watched bool   // container opted into notifications
+	suffix  string // appended to the name, e.g. to disambiguate it
+	layout  int    // column layout generation at last resize
+
+	pidsLimit int64    // pids limit, 0 if unlimited
+	netRate   byteRate // of bytes received and sent
+	ioRate    byteRate // of bytes read and written
+
+	changes [5]cellChange // metric column values and highlights, by ChangeColumns
+
+	// guards the row against metrics and metadata updates from
+	// collector and refresh goroutines while it is drawn. Held by
+	// exported methods, column setters they call excepted
+	lock sync.Mutex
 }
 
 func NewCompact(id string) *Compact {
@@ -28,16 +60,22 @@ func NewCompact(id string) *Compact {
 		id = id[:12]
 	}
 	row := &Compact{
-		Status: NewStatus(),
-		Name:   NewTextCol("-"),
-		Cid:    NewTextCol(id),
-		Cpu:    NewGaugeCol(),
-		Memory: NewGaugeCol(),
-		Net:    NewTextCol("-"),
-		IO:     NewTextCol("-"),
-		Pids:   NewTextCol("-"),
-		X:      1,
-		Height: 1,
+		Status:  NewStatus(),
+		Name:    NewTextCol("-"),
+		Cid:     NewTextCol(id),
+		Image:   NewTextCol("-"),
+		User:    NewTextCol("-"),
+		Cpu:     NewGaugeCol(),
+		Memory:  NewGaugeCol(),
+		Net:     NewTextCol("-"),
+		IO:      NewTextCol("-"),
+		Pids:    NewTextCol("-"),
+		Since:   NewTextCol("-"),
+		CPUTime: NewTextCol("-"),
+		Sec:     NewTextCol("-"),
+		ImgSize: NewTextCol("-"),
+		X:       1,
+		Height:  1,
 	}
 	return row
 }
@@ -51,24 +89,65 @@ func NewCompact(id string) *Compact {
 //}
 
 func (row *Compact) SetMeta(k, v string) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
 	switch k {
 	case "name":
-		row.Name.Set(v)
+		row.name = v
+		row.setName()
+	case "image":
+		row.image = v
+		row.setImage()
+	case "stale image":
+		row.old = v != ""
+		row.setImage()
+	case "inspect error":
+		row.broken = v != ""
+		row.setName()
+	case "user":
+		row.SetUser(v)
+	case "posture":
+		row.SetPosture(v)
+	case "image bytes":
+		row.SetImageSize(v)
+	case "pids limit":
+		row.pidsLimit, _ = strconv.ParseInt(v, 10, 64)
 	case "state":
 		row.Status.Set(v)
+		row.paused = v == "paused"
 	}
 }
 
+// Set text shown after the name, such as the source of a container
+// sharing its name with one of another source
+func (row *Compact) SetNameSuffix(s string) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	if s == row.suffix {
+		return
+	}
+	row.suffix = s
+	row.setName()
+}
+
 func (row *Compact) SetMetrics(m metrics.Metrics) {
-	row.SetCPU(m.CPUUtil)
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	// rates are tracked whatever is shown, to be current once shown
+	row.netRate.update(m.NetRx + m.NetTx)
+	row.ioRate.update(m.IOBytesRead + m.IOBytesWrite)
+	row.setGauge(m)
 	row.SetNet(m.NetRx, m.NetTx)
 	row.SetMem(m.MemUsage, m.MemLimit, m.MemPercent)
 	row.SetIO(m.IOBytesRead, m.IOBytesWrite)
 	row.SetPids(m.Pids)
+	row.trackChanges(m)
 }
 
 // Set gauges, counters to default unread values
 func (row *Compact) Reset() {
+	row.lock.Lock()
+	defer row.lock.Unlock()
 	row.Cpu.Reset()
 	row.Memory.Reset()
 	row.Net.Reset()
@@ -81,62 +160,185 @@ func (row *Compact) GetHeight() int {
 }
 
 func (row *Compact) SetX(x int) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
 	row.X = x
 }
 
 func (row *Compact) SetY(y int) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
 	if y == row.Y {
 		return
 	}
-	for _, col := range row.all() {
-		col.SetY(y)
+	for _, c := range Columns {
+		row.column(c.Name).SetY(y)
 	}
 	row.Y = y
 }
 
 func (row *Compact) SetWidth(width int) {
-	if width == row.Width {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	if width == row.Width && row.layout == layoutGen {
 		return
 	}
 	x := row.X
-	autoWidth := calcWidth(width)
-	for n, col := range row.all() {
-		if colWidths[n] != 0 {
-			col.SetX(x)
-			col.SetWidth(colWidths[n])
-			x += colWidths[n]
+	cols := enabledColumns()
+	widths := calcWidths(width, cols)
+	for n, c := range cols {
+		col := row.column(c.Name)
+		col.SetX(x)
+		col.SetWidth(widths[n])
+		// static width columns are not padded
+		if c.Width != 0 {
+			x += widths[n]
 			continue
 		}
-		col.SetX(x)
-		col.SetWidth(autoWidth)
-		x += autoWidth + colSpacing
+		x += widths[n] + colSpacing
 	}
 	row.Width = width
+	row.layout = layoutGen
 }
 
 func (row *Compact) Buffer() ui.Buffer {
-	buf := ui.NewBuffer()
-
-	buf.Merge(row.Status.Buffer())
-	buf.Merge(row.Name.Buffer())
-	buf.Merge(row.Cid.Buffer())
-	buf.Merge(row.Cpu.Buffer())
-	buf.Merge(row.Memory.Buffer())
-	buf.Merge(row.Net.Buffer())
-	buf.Merge(row.IO.Buffer())
-	buf.Merge(row.Pids.Buffer())
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	buf := sizedBuffer(row.Width * row.Height)
+	for _, col := range row.all() {
+		buf.Merge(col.Buffer())
+	}
+	row.highlightChanges(buf)
+	if row.stale || row.paused {
+		row.dim(buf)
+	}
+	if row.paused {
+		row.dimText(buf)
+	}
+	if row.broken {
+		row.colorName(buf, ui.ThemeAttr("name.error"))
+	}
+	if row.divide {
+		row.underline(buf)
+	}
 	return buf
 }
 
-func (row *Compact) all() []ui.GridBufferer {
-	return []ui.GridBufferer{
-		row.Status,
-		row.Name,
-		row.Cid,
-		row.Cpu,
-		row.Memory,
-		row.Net,
-		row.IO,
-		row.Pids,
+// Underline the row to separate it from rows below
+func (row *Compact) SetDivider(divide bool) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	row.divide = divide
+}
+
+func (row *Compact) underline(buf ui.Buffer) {
+	for x := row.X; x < row.X+row.Width; x++ {
+		p := image.Pt(x, row.Y)
+		c, ok := buf.CellMap[p]
+		if !ok {
+			c = ui.Cell{Ch: ' ', Bg: ui.ColorDefault}
+		}
+		c.Fg |= ui.AttrUnderline
+		buf.CellMap[p] = c
+	}
+}
+
+// Mark row metrics as out of date
+func (row *Compact) SetStale(stale bool) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	row.stale = stale
+}
+
+// Render metric columns in dimmed colors
+func (row *Compact) dim(buf ui.Buffer) {
+	fg := ui.ThemeAttr("par.text.dim")
+	for _, col := range []ui.GridBufferer{row.Cpu, row.Memory, row.Net, row.IO, row.Pids} {
+		for p := range col.Buffer().CellMap {
+			if c, ok := buf.CellMap[p]; ok {
+				c.Fg = fg
+				c.Bg = ui.ColorDefault
+				buf.CellMap[p] = c
+			}
+		}
+	}
+}
+
+// Render text columns in dimmed colors, other than where highlighted
+func (row *Compact) dimText(buf ui.Buffer) {
+	fg := ui.ThemeAttr("par.text.dim")
+	for _, col := range []ui.GridBufferer{row.Name, row.Cid, row.Image, row.User, row.Since, row.CPUTime, row.Sec, row.ImgSize} {
+		for p := range col.Buffer().CellMap {
+			if c, ok := buf.CellMap[p]; ok && c.Bg == ui.ColorDefault {
+				c.Fg = fg
+				buf.CellMap[p] = c
+			}
+		}
+	}
+}
+
+// Render the name in a color, other than where highlighted
+func (row *Compact) colorName(buf ui.Buffer, fg ui.Attribute) {
+	for p := range row.Name.Buffer().CellMap {
+		if c, ok := buf.CellMap[p]; ok && c.Bg == ui.ColorDefault {
+			c.Fg = fg
+			buf.CellMap[p] = c
+		}
+	}
+}
+
+// Return widgets for all enabled columns
+func (row *Compact) all() (cols []ui.GridBufferer) {
+	for _, c := range enabledColumns() {
+		cols = append(cols, row.column(c.Name))
+	}
+	return cols
+}
+
+// Return the untruncated text displayed in a given column
+func (row *Compact) ColumnText(name string) string {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	switch col := row.column(name).(type) {
+	case *TextCol:
+		return col.text
+	case *GaugeCol:
+		return col.Label
+	}
+	return ""
+}
+
+// Return widget for a given column name
+func (row *Compact) column(name string) ui.GridBufferer {
+	switch name {
+	case "status":
+		return row.Status
+	case "name":
+		return row.Name
+	case "id":
+		return row.Cid
+	case "image":
+		return row.Image
+	case "user":
+		return row.User
+	case "cpu":
+		return row.Cpu
+	case "mem":
+		return row.Memory
+	case "net":
+		return row.Net
+	case "io":
+		return row.IO
+	case "pids":
+		return row.Pids
+	case "since":
+		return row.Since
+	case "cputime":
+		return row.CPUTime
+	case "security":
+		return row.Sec
+	case "imagesize":
+		return row.ImgSize
 	}
+	return nil
 }
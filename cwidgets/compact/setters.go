@@ -3,16 +3,35 @@ package compact
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/metrics"
 	ui "github.com/gizak/termui"
 )
 
 func (row *Compact) SetNet(rx int64, tx int64) {
 	label := fmt.Sprintf("%s / %s", cwidgets.ByteFormat(rx), cwidgets.ByteFormat(tx))
+	if row.netHot {
+		label = fmt.Sprintf("%c %s", cwidgets.Glyphs.UpArrow, label)
+	}
 	row.Net.Set(label)
 }
 
+// Mark the network column of a row with an anomalous rate, taking
+// effect from the next metrics update
+func (row *Compact) SetNetAnomaly(b bool) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	row.netHot = b
+	if b {
+		row.Net.TextFgColor = ui.ThemeAttr("net.anomaly")
+	} else {
+		row.Net.TextFgColor = ui.ThemeAttr("par.text.fg")
+	}
+}
+
 func (row *Compact) SetIO(read int64, write int64) {
 	label := fmt.Sprintf("%s / %s", cwidgets.ByteFormat(read), cwidgets.ByteFormat(write))
 	row.IO.Set(label)
@@ -24,16 +43,53 @@ func (row *Compact) SetPids(val int) {
 }
 
 func (row *Compact) SetCPU(val int) {
-	row.Cpu.BarColor = colorScale(val)
-	row.Cpu.Label = fmt.Sprintf("%s%%", strconv.Itoa(val))
-	if val < 5 {
-		val = 5
-		row.Cpu.BarColor = ui.ThemeAttr("gauge.bar.bg")
+	row.Cpu.set(fmt.Sprintf("%s%%", strconv.Itoa(val)), val, true)
+}
+
+// Set the gauge column from metrics, by the configured gauge metric.
+// Memory and pids fill it against their limits
+func (row *Compact) setGauge(m metrics.Metrics) {
+	switch gaugeMetric() {
+	case "mem":
+		row.Cpu.set(fmt.Sprintf("%d%%", m.MemPercent), m.MemPercent, true)
+	case "net":
+		rate := row.netRate.rate
+		if rate < 0 {
+			rate = 0
+		}
+		row.Cpu.set(fmt.Sprintf("%s/s", cwidgets.ByteFormat(rate)), netScale(float64(rate)), false)
+	case "pids":
+		if row.pidsLimit <= 0 {
+			row.Cpu.set(strconv.Itoa(m.Pids), 0, false)
+			return
+		}
+		label := fmt.Sprintf("%d / %d", m.Pids, row.pidsLimit)
+		row.Cpu.set(label, int(int64(m.Pids)*100/row.pidsLimit), true)
+	default:
+		row.SetCPU(m.CPUUtil)
 	}
-	if val > 100 {
-		val = 100
+}
+
+// Rate of a byte counter, e.g. of bytes received and sent, from
+// its successive readings
+type byteRate struct {
+	total int64     // as of at
+	at    time.Time // time of the last reading
+	rate  int64     // bytes/s since the previous reading, -1 if unknown
+}
+
+// Read the counter, updating the rate. The rate is unknown until
+// read twice, and while the counter is unread or after it is reset
+func (r *byteRate) update(total int64) {
+	now := time.Now()
+	prev, at := r.total, r.at
+	r.total, r.at = total, now
+	secs := now.Sub(at).Seconds()
+	if at.IsZero() || prev < 0 || total < prev || secs <= 0 {
+		r.rate = -1
+		return
 	}
-	row.Cpu.Percent = val
+	r.rate = int64(float64(total-prev) / secs)
 }
 
 func (row *Compact) SetMem(val int64, limit int64, percent int) {
@@ -46,3 +102,101 @@ func (row *Compact) SetMem(val int64, limit int64, percent int) {
 	}
 	row.Memory.Percent = percent
 }
+
+// Set the image column, marked in a warning color if a newer image of
+// the reference has been pulled since the container was created
+func (row *Compact) setImage() {
+	if row.old {
+		row.Image.Set(fmt.Sprintf("%c %s", cwidgets.Glyphs.UpArrow, row.image))
+		row.Image.TextFgColor = ui.ThemeAttr("image.stale")
+	} else {
+		row.Image.Set(row.image)
+		row.Image.TextFgColor = ui.ThemeAttr("par.text.fg")
+	}
+}
+
+// Set the name, marked if the container failed to inspect
+func (row *Compact) setName() {
+	name := row.name + row.suffix
+	if row.watched {
+		name = string(cwidgets.Glyphs.Watch) + " " + name
+	}
+	if row.broken {
+		name = "! " + name
+	}
+	row.Name.Set(name)
+}
+
+// Mark the row of a container opted into notifications
+func (row *Compact) SetWatched(watched bool) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	if watched == row.watched {
+		return
+	}
+	row.watched = watched
+	row.setName()
+}
+
+// Set the user the container runs as, in a warning color if root
+func (row *Compact) SetUser(user string) {
+	row.User.Set(user)
+	if isRootUser(user) {
+		row.User.TextFgColor = ui.ThemeAttr("user.root")
+	} else {
+		row.User.TextFgColor = ui.ThemeAttr("par.text.fg")
+	}
+}
+
+// Set the size of the container's image from its size in bytes
+func (row *Compact) SetImageSize(bytes string) {
+	n, err := strconv.ParseInt(bytes, 10, 64)
+	if err != nil {
+		row.ImgSize.Set("-")
+		return
+	}
+	row.ImgSize.Set(cwidgets.ByteFormat(n))
+}
+
+// Set the security posture, in a warning color if elevated and an
+// error color if privileged
+func (row *Compact) SetPosture(level string) {
+	if level == "" {
+		level = "-"
+	}
+	row.Sec.Set(level)
+	switch level {
+	case "elevated":
+		row.Sec.TextFgColor = ui.ThemeAttr("sec.elevated")
+	case "privileged":
+		row.Sec.TextFgColor = ui.ThemeAttr("sec.privileged")
+	default:
+		row.Sec.TextFgColor = ui.ThemeAttr("par.text.fg")
+	}
+}
+
+// Return whether a user, as a name or numeric UID optionally followed
+// by a group, is root. Other UIDs cannot be resolved outside of the
+// container, and are not
+func isRootUser(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name == "" || name == "root" || name == "0"
+}
+
+func (row *Compact) SetSince(s string) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	if s == "" {
+		s = "-"
+	}
+	row.Since.Set(s)
+}
+
+func (row *Compact) SetCPUTime(s string) {
+	row.lock.Lock()
+	defer row.lock.Unlock()
+	if s == "" {
+		s = "-"
+	}
+	row.CPUTime.Set(s)
+}
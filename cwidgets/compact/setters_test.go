@@ -0,0 +1,30 @@
+// +build !release
+
+package compact
+
+import "testing"
+
+func TestIsRootUser(t *testing.T) {
+	cases := []struct {
+		user string
+		want bool
+	}{
+		{"", true}, // image default
+		{"root", true},
+		{"0", true},
+		{"root:root", true},
+		{"0:0", true},
+		{"0:1000", true},
+		{"root:staff", true},
+		{"1000", false},
+		{"1000:0", false},
+		{"nobody", false},
+		{"rootless", false},
+		{"Root", false},
+	}
+	for _, tc := range cases {
+		if got := isRootUser(tc.user); got != tc.want {
+			t.Errorf("isRootUser(%q) = %v, want %v", tc.user, got, tc.want)
+		}
+	}
+}
@@ -2,32 +2,32 @@ package compact
 
 import (
 	"fmt"
+	"strconv"
 
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
-const (
-	mark        = string('\u25C9')
-	vBar        = string('\u25AE')
-	statusWidth = 3
-)
+const statusWidth = 3
 
 // Status indicator
 type Status struct {
 	*ui.Par
+	text    string // status indicator text
+	indexed bool   // display row index in place of status
 }
 
 func NewStatus() *Status {
-	p := ui.NewPar(mark)
+	p := ui.NewPar(string(cwidgets.Glyphs.Mark))
 	p.Border = false
 	p.Height = 1
 	p.Width = statusWidth
-	return &Status{p}
+	return &Status{Par: p, text: p.Text}
 }
 
 func (s *Status) Set(val string) {
 	// defaults
-	text := mark
+	text := string(cwidgets.Glyphs.Mark)
 	color := ui.ColorDefault
 
 	switch val {
@@ -36,9 +36,25 @@ func (s *Status) Set(val string) {
 	case "exited":
 		color = ui.ColorRed
 	case "paused":
+		vBar := string(cwidgets.Glyphs.VBar)
 		text = fmt.Sprintf("%s%s", vBar, vBar)
+		color = ui.ColorYellow
 	}
 
-	s.Text = text
+	s.text = text
 	s.TextFgColor = color
+	if !s.indexed {
+		s.Text = text
+	}
+}
+
+// Display a row index number in place of the status indicator
+func (s *Status) ShowIndex(n int) {
+	s.indexed = true
+	s.Text = strconv.Itoa(n)
+}
+
+func (s *Status) HideIndex() {
+	s.indexed = false
+	s.Text = s.text
 }
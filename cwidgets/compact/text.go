@@ -1,11 +1,13 @@
 package compact
 
 import (
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
 type TextCol struct {
 	*ui.Par
+	text string // untruncated text
 }
 
 func NewTextCol(s string) *TextCol {
@@ -13,7 +15,7 @@ func NewTextCol(s string) *TextCol {
 	p.Border = false
 	p.Height = 1
 	p.Width = 20
-	return &TextCol{p}
+	return &TextCol{p, s}
 }
 
 func (w *TextCol) Highlight() {
@@ -27,9 +29,15 @@ func (w *TextCol) UnHighlight() {
 }
 
 func (w *TextCol) Reset() {
-	w.Text = "-"
+	w.Set("-")
 }
 
 func (w *TextCol) Set(s string) {
-	w.Text = s
+	w.text = s
+	w.Text = cwidgets.MidTruncate(s, w.Width)
+}
+
+func (w *TextCol) SetWidth(width int) {
+	w.Par.SetWidth(width)
+	w.Text = cwidgets.MidTruncate(w.text, width)
 }
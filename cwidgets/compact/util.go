@@ -9,29 +9,55 @@ import (
 
 const colSpacing = 1
 
-// per-column width. 0 == auto width
-var colWidths = []int{
-	3, // status
-	0, // name
-	0, // cid
-	0, // cpu
-	0, // memory
-	0, // net
-	0, // io
-	4, // pids
-}
+// Calculate per-column widths, given total width. Auto width columns
+// share any remaining space, within the bounds of their width hints
+func calcWidths(width int, cols []*Column) []int {
+	widths := make([]int, len(cols))
+	width -= colSpacing * len(cols)
+
+	var auto []int
+	for n, c := range cols {
+		if c.Width != 0 {
+			widths[n] = c.Width
+			width -= c.Width
+			continue
+		}
+		// use natural column width in wide mode
+		if wideMode() {
+			widths[n] = c.natural()
+			continue
+		}
+		auto = append(auto, n)
+	}
 
-// Calculate per-column width, given total width
-func calcWidth(width int) int {
-	spacing := colSpacing * len(colWidths)
-	var staticCols int
-	for _, w := range colWidths {
-		width -= w
-		if w == 0 {
-			staticCols += 1
+	// fix width of any columns whose hints are exceeded by an even
+	// share of the remaining space, repeating until all columns fit
+	for len(auto) > 0 {
+		share := width / len(auto)
+		var next []int
+		for _, n := range auto {
+			c := cols[n]
+			switch {
+			case c.MaxWidth > 0 && share > c.MaxWidth:
+				widths[n] = c.MaxWidth
+			case share < c.MinWidth:
+				widths[n] = c.MinWidth
+			default:
+				next = append(next, n)
+				continue
+			}
+			width -= widths[n]
+		}
+		if len(next) == len(auto) {
+			for _, n := range next {
+				widths[n] = share
+			}
+			break
 		}
+		auto = next
 	}
-	return (width - spacing) / staticCols
+
+	return widths
 }
 
 func centerParText(p *ui.Par) {
@@ -0,0 +1,97 @@
+package expanded
+
+import (
+	"fmt"
+
+	"github.com/bcicen/ctop/cwidgets"
+	ui "github.com/gizak/termui"
+)
+
+const minCompareWidth = 40 // minimum per-column width
+
+// Side-by-side expanded view of two containers
+type Compare struct {
+	Left  *Expanded
+	Right *Expanded
+	Y     int
+	Width int
+}
+
+func NewCompare(leftID, rightID string) *Compare {
+	return &Compare{
+		Left:  NewExpanded(leftID),
+		Right: NewExpanded(rightID),
+		Width: ui.TermWidth(),
+	}
+}
+
+func (c *Compare) Up() {
+	if c.Y < 0 {
+		c.Y++
+		c.Align()
+		ui.Render(c)
+	}
+}
+
+func (c *Compare) Down() {
+	if c.Y > (ui.TermHeight() - c.GetHeight()) {
+		c.Y--
+		c.Align()
+		ui.Render(c)
+	}
+}
+
+func (c *Compare) SetWidth(w int) { c.Width = w }
+
+// Return width of a single column
+func (c *Compare) colWidth() int { return (c.Width - 1) / 2 }
+
+// Return height of the tallest column
+func (c *Compare) GetHeight() int {
+	h := c.Left.GetHeight()
+	if rh := c.Right.GetHeight(); rh > h {
+		h = rh
+	}
+	return h
+}
+
+func (c *Compare) Align() {
+	// reset offset if needed
+	if c.GetHeight() <= ui.TermHeight() {
+		c.Y = 0
+	}
+
+	w := c.colWidth()
+	c.Left.X = 0
+	c.Right.X = w + 1
+	for _, e := range []*Expanded{c.Left, c.Right} {
+		e.setColWidth(w)
+		e.Y = c.Y
+		y := e.Y
+		for _, i := range e.all() {
+			i.SetX(e.X)
+			i.SetY(y)
+			y += i.GetHeight()
+		}
+		e.Mem.Align()
+	}
+	log.Debugf("compare align: width=%v col-width=%v", c.Width, w)
+}
+
+func (c *Compare) Buffer() ui.Buffer {
+	buf := ui.NewBuffer()
+	if c.colWidth() < minCompareWidth {
+		ui.Clear()
+		p := termSizeError()
+		p.Text = fmt.Sprintf("screen too narrow to compare! (min width %d)", minCompareWidth*2+1)
+		p.Width = len(p.Text)
+		buf.Merge(p.Buffer())
+		return buf
+	}
+	for _, e := range []*Expanded{c.Left, c.Right} {
+		for _, i := range e.all() {
+			buf.Merge(i.Buffer())
+		}
+	}
+	return cwidgets.ASCIIBuffer(buf)
+}
@@ -1,32 +1,28 @@
 package expanded
 
 import (
+	"fmt"
+
 	ui "github.com/gizak/termui"
 )
 
 type Cpu struct {
-	*ui.LineChart
-	hist FloatHist
+	*Graph
 }
 
 func NewCpu() *Cpu {
-	cpu := &Cpu{ui.NewLineChart(), NewFloatHist(55)}
-	cpu.Mode = "dot"
+	cpu := &Cpu{NewGraph()}
 	cpu.BorderLabel = "CPU"
 	cpu.Height = 12
 	cpu.Width = colWidth[0]
 	cpu.X = 0
-	cpu.DataLabels = cpu.hist.Labels
-
-	// hack to force the default minY scale to 0
-	tmpData := []float64{20}
-	cpu.Data = tmpData
-	_ = cpu.Buffer()
-
-	cpu.Data = cpu.hist.Data
+	cpu.Top = 100
+	cpu.Value = func(s Sample) float64 { return float64(s.CPUUtil) }
+	cpu.Format = func(v float64) string { return fmt.Sprintf("%.0f%%", v) }
 	return cpu
 }
 
-func (w *Cpu) Update(val int) {
-	w.hist.Append(float64(val))
+func (w *Cpu) Buffer() ui.Buffer {
+	w.BorderLabel = fmt.Sprintf("CPU (%s)", w.Status())
+	return w.Graph.Buffer()
 }
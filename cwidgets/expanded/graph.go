@@ -0,0 +1,244 @@
+package expanded
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/metrics"
+	ui "github.com/gizak/termui"
+)
+
+const graphLabelWidth = 6 // width of y-axis labels
+
+// Time windows selectable for the CPU and MEM graphs
+var Windows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+// Metrics read at a point in time
+type Sample struct {
+	Time time.Time
+	metrics.Metrics
+}
+
+// Sample with the highest value among those of a graph column
+type peak struct {
+	val  float64
+	time time.Time
+	ok   bool // column has samples
+}
+
+// Dot graph of a metric over a time window, with one column per period
+// of the window. A column plots the peak of the samples in its period,
+// so that spikes show however long the window
+type Graph struct {
+	*ui.Block
+	Value     func(Sample) float64
+	Format    func(float64) string // value format for axis labels and the crosshair
+	Top       float64              // y-axis maximum when fixed, or 0 to scale to the data
+	Fixed     bool
+	Cursor    int // crosshair column back from the latest, or -1 if hidden
+	LineColor ui.Attribute
+	AxesColor ui.Attribute
+	samples   []Sample
+	window    time.Duration
+	interval  time.Duration
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		Block:     ui.NewBlock(),
+		Format:    func(v float64) string { return fmt.Sprintf("%.0f", v) },
+		Cursor:    -1,
+		LineColor: ui.ThemeAttr("linechart.line.fg"),
+		AxesColor: ui.ThemeAttr("linechart.axes.fg"),
+		window:    Windows[0],
+		interval:  time.Second,
+	}
+}
+
+// Set samples to plot, oldest first, the window of time shown up to
+// the latest and the interval samples are taken at
+func (g *Graph) Set(samples []Sample, window, interval time.Duration) {
+	g.samples = samples
+	g.window = window
+	g.interval = interval
+}
+
+// Return the number of columns plotted across the window
+func (g *Graph) columns() int {
+	w := g.InnerBounds().Dx() - graphLabelWidth - 1
+	if w < 1 {
+		return 0
+	}
+	period := g.period(w)
+	if n := int(g.window / period); n < w {
+		return n
+	}
+	return w
+}
+
+// Return the period of time of each column, no shorter than the
+// sample interval so that no column is left empty
+func (g *Graph) period(width int) time.Duration {
+	p := g.window / time.Duration(width)
+	if p < g.interval {
+		p = g.interval
+	}
+	return p
+}
+
+// Return the peak sample of each column, latest last. Column periods
+// are aligned to the clock, so that peaks keep their column as the
+// graph moves along
+func (g *Graph) peaks() []peak {
+	n := g.columns()
+	cols := make([]peak, n)
+	if n == 0 || len(g.samples) == 0 {
+		return cols
+	}
+	period := int64(g.period(g.InnerBounds().Dx() - graphLabelWidth - 1))
+	last := g.samples[len(g.samples)-1].Time.UnixNano() / period
+	for i := len(g.samples) - 1; i >= 0; i-- {
+		s := g.samples[i]
+		k := int(last - s.Time.UnixNano()/period)
+		if k >= n {
+			break
+		}
+		v := g.Value(s)
+		if v < 0 {
+			continue // not read
+		}
+		if col := &cols[n-1-k]; !col.ok || v > col.val {
+			*col = peak{v, s.Time, true}
+		}
+	}
+	return cols
+}
+
+// Move the crosshair n columns back, within the columns plotted
+func (g *Graph) MoveCursor(n int) {
+	if g.Cursor < 0 {
+		return
+	}
+	g.Cursor += n
+	if max := g.columns() - 1; g.Cursor > max {
+		g.Cursor = max
+	}
+	if g.Cursor < 0 {
+		g.Cursor = 0
+	}
+}
+
+// Describe the window and scale shown, and the sample under the
+// crosshair if shown
+func (g *Graph) Status() string {
+	scale := "auto"
+	if g.Fixed && g.Top > 0 {
+		scale = "fixed"
+	}
+	s := fmt.Sprintf("%s, %s", formatWindow(g.window), scale)
+	if g.Cursor < 0 {
+		return s
+	}
+	peaks := g.peaks()
+	if g.Cursor >= len(peaks) {
+		return s
+	}
+	p := peaks[len(peaks)-1-g.Cursor]
+	if !p.ok {
+		return s + " | no sample"
+	}
+	return fmt.Sprintf("%s | %s at %s", s, g.Format(p.val), p.time.Format("15:04:05"))
+}
+
+// Return the y-axis maximum: the fixed maximum, or one over the
+// highest value plotted
+func (g *Graph) top(peaks []peak) float64 {
+	if g.Fixed && g.Top > 0 {
+		return g.Top
+	}
+	var max float64
+	for _, p := range peaks {
+		if p.ok && p.val > max {
+			max = p.val
+		}
+	}
+	if max == 0 {
+		return 1
+	}
+	return max * 1.2
+}
+
+func (g *Graph) Buffer() ui.Buffer {
+	buf := g.Block.Buffer()
+	area := g.InnerBounds()
+	width, height := area.Dx()-graphLabelWidth-1, area.Dy()-2
+	if width < 1 || height < 2 {
+		return buf
+	}
+	peaks := g.peaks()
+	top := g.top(peaks)
+
+	origX, origY := area.Min.X+graphLabelWidth, area.Min.Y+height
+	buf.Set(origX, origY, ui.Cell{Ch: ui.ORIGIN, Fg: g.AxesColor, Bg: g.Bg})
+	for x := origX + 1; x <= origX+width; x++ {
+		buf.Set(x, origY, ui.Cell{Ch: ui.HDASH, Fg: g.AxesColor, Bg: g.Bg})
+	}
+	for y := origY - height; y < origY; y++ {
+		buf.Set(origX, y, ui.Cell{Ch: ui.VDASH, Fg: g.AxesColor, Bg: g.Bg})
+	}
+
+	// y-axis labels at the top, middle and bottom, right aligned
+	for _, l := range []struct {
+		y   int
+		val float64
+	}{{origY - height, top}, {origY - height/2, top / 2}, {origY - 1, 0}} {
+		s := []rune(g.Format(l.val))
+		if len(s) > graphLabelWidth-1 {
+			s = s[:graphLabelWidth-1]
+		}
+		for i, r := range s {
+			buf.Set(origX-1-len(s)+i, l.y, ui.Cell{Ch: r, Fg: g.AxesColor, Bg: g.Bg})
+		}
+	}
+
+	// x-axis labels, the start of the window and now
+	start := []rune("-" + formatWindow(g.window))
+	for i, r := range start {
+		buf.Set(origX+1+i, origY+1, ui.Cell{Ch: r, Fg: g.AxesColor, Bg: g.Bg})
+	}
+	for i, r := range "now" {
+		buf.Set(origX+width-2+i, origY+1, ui.Cell{Ch: r, Fg: g.AxesColor, Bg: g.Bg})
+	}
+
+	cursorX := -1
+	if g.Cursor >= 0 && g.Cursor < len(peaks) {
+		cursorX = origX + width - g.Cursor
+		for y := origY - height; y < origY; y++ {
+			buf.Set(cursorX, y, ui.Cell{Ch: ui.VDASH, Fg: ui.ColorYellow, Bg: g.Bg})
+		}
+	}
+
+	for i, p := range peaks {
+		if !p.ok {
+			continue
+		}
+		level := int(math.Floor(p.val/top*float64(height-1) + 0.5))
+		if level > height-1 {
+			level = height - 1
+		}
+		x := origX + width - (len(peaks) - 1 - i)
+		c := ui.Cell{Ch: cwidgets.Glyphs.Dot, Fg: g.LineColor, Bg: g.Bg}
+		if x == cursorX {
+			c.Fg = ui.ColorYellow | ui.AttrBold
+		}
+		buf.Set(x, origY-1-level, c)
+	}
+	return buf
+}
+
+// Format a window as a number of minutes
+func formatWindow(d time.Duration) string {
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
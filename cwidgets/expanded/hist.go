@@ -37,24 +37,3 @@ func (h *DiffHist) Append(val int) {
 	}
 	h.lastVal = val
 }
-
-type FloatHist struct {
-	Val    float64   // most current data point
-	Data   []float64 // historical data points
-	Labels []string
-}
-
-func NewFloatHist(max int) FloatHist {
-	return FloatHist{
-		Data:   make([]float64, max),
-		Labels: make([]string, max),
-	}
-}
-
-func (h FloatHist) Append(val float64) {
-	if len(h.Data) == cap(h.Data) {
-		h.Data = append(h.Data[:0], h.Data[1:]...)
-	}
-	h.Val = val
-	h.Data = append(h.Data, val)
-}
@@ -6,7 +6,7 @@ import (
 	ui "github.com/gizak/termui"
 )
 
-var displayInfo = []string{"id", "name", "image", "ports", "state"}
+var displayInfo = []string{"id", "name", "previously", "raw name", "image", "stale image", "image size", "image shared", "user", "security", "env", "ports", "networks", "limits", "tmpfs", "gpu", "restart", "state", "inspect error", "alerts", "created", "uptime", "cpu time", "healthcheck", "pid", "namespaces"}
 
 type Info struct {
 	*ui.Table
@@ -30,7 +30,7 @@ func (w *Info) Set(k, v string) {
 	// rebuild rows
 	w.Rows = [][]string{}
 	for _, k := range displayInfo {
-		if v, ok := w.data[k]; ok {
+		if v, ok := w.data[k]; ok && v != "" {
 			w.Rows = append(w.Rows, mkInfoRows(k, v)...)
 		}
 	}
@@ -1,6 +1,10 @@
 package expanded
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
 	"github.com/bcicen/ctop/logging"
 	"github.com/bcicen/ctop/metrics"
 	ui "github.com/gizak/termui"
@@ -13,13 +17,18 @@ var (
 )
 
 type Expanded struct {
-	Info  *Info
-	Net   *Net
-	Cpu   *Cpu
-	Mem   *Mem
-	IO    *IO
-	X, Y  int
-	Width int
+	Info     *Info
+	Output   *Output
+	Net      *Net
+	Cpu      *Cpu
+	Mem      *Mem
+	IO       *IO
+	X, Y     int
+	Width    int
+	hist     []Sample // recent samples, oldest first, for graphs
+	histLen  int      // samples retained
+	interval time.Duration
+	window   int // index of the graph window in Windows
 }
 
 func NewExpanded(id string) *Expanded {
@@ -27,12 +36,15 @@ func NewExpanded(id string) *Expanded {
 		id = id[:12]
 	}
 	return &Expanded{
-		Info:  NewInfo(id),
-		Net:   NewNet(),
-		Cpu:   NewCpu(),
-		Mem:   NewMem(),
-		IO:    NewIO(),
-		Width: ui.TermWidth(),
+		Info:     NewInfo(id),
+		Output:   NewOutput(),
+		Net:      NewNet(),
+		Cpu:      NewCpu(),
+		Mem:      NewMem(),
+		IO:       NewIO(),
+		Width:    ui.TermWidth(),
+		histLen:  60,
+		interval: time.Second,
 	}
 }
 
@@ -55,16 +67,81 @@ func (e *Expanded) Down() {
 func (e *Expanded) SetWidth(w int)      { e.Width = w }
 func (e *Expanded) SetMeta(k, v string) { e.Info.Set(k, v) }
 
+// Show the last lines output by a stopped container, or none
+func (e *Expanded) SetOutput(lines []string) { e.Output.Set(lines) }
+
 func (e *Expanded) SetMetrics(m metrics.Metrics) {
-	e.Cpu.Update(m.CPUUtil)
+	e.hist = append(e.hist, Sample{time.Now(), m})
+	if over := len(e.hist) - e.histLen; over > 0 {
+		e.hist = append(e.hist[:0], e.hist[over:]...)
+	}
+	e.setGraphs()
 	e.Net.Update(m.NetRx, m.NetTx)
-	e.Mem.Update(int(m.MemUsage), int(m.MemLimit))
+	e.Mem.Update(m.MemUsage, m.MemLimit)
 	e.IO.Update(m.IOBytesRead, m.IOBytesWrite)
 }
 
+// Set the samples graphed when opened, oldest first, along with the
+// number retained and the interval they are taken at
+func (e *Expanded) SetHistory(samples []Sample, n int, interval time.Duration) {
+	e.hist = samples
+	e.histLen = n
+	e.interval = interval
+	if len(samples) > 0 {
+		last := samples[len(samples)-1]
+		e.Mem.Update(last.MemUsage, last.MemLimit)
+	}
+	e.setGraphs()
+}
+
+func (e *Expanded) setGraphs() {
+	samples := append([]Sample{}, e.hist...)
+	window := Windows[e.window]
+	e.Cpu.Set(samples, window, e.interval)
+	e.Mem.Chart.Set(samples, window, e.interval)
+}
+
+// Select the graph window at index n of Windows, if no longer than
+// the time covered by the samples retained
+func (e *Expanded) SetWindow(n int) error {
+	if n < 0 || n >= len(Windows) {
+		return fmt.Errorf("no such window")
+	}
+	if n > 0 && Windows[n] > time.Duration(e.histLen)*e.interval {
+		return fmt.Errorf("%s window is longer than the history retained, %d samples at %s", formatWindow(Windows[n]), e.histLen, e.interval)
+	}
+	e.window = n
+	e.setGraphs()
+	return nil
+}
+
+// Toggle graphs between a fixed y-axis, at 100% CPU and the memory
+// limit, and one scaled to the values shown
+func (e *Expanded) ToggleScale() {
+	e.Cpu.Fixed = !e.Cpu.Fixed
+	e.Mem.Chart.Fixed = e.Cpu.Fixed
+}
+
+// Show or hide the graph crosshair, at the latest sample when shown
+func (e *Expanded) ToggleCrosshair() {
+	c := 0
+	if e.Cpu.Cursor >= 0 {
+		c = -1
+	}
+	e.Cpu.Cursor = c
+	e.Mem.Chart.Cursor = c
+}
+
+// Move the graph crosshair n columns back in time, or forward if negative
+func (e *Expanded) MoveCrosshair(n int) {
+	e.Cpu.MoveCursor(n)
+	e.Mem.Chart.MoveCursor(n)
+}
+
 // Return total column height
 func (e *Expanded) GetHeight() (h int) {
 	h += e.Info.Height
+	h += e.Output.Height
 	h += e.Net.Height
 	h += e.Cpu.Height
 	h += e.Mem.Height
@@ -80,6 +157,7 @@ func (e *Expanded) Align() {
 
 	y := e.Y
 	for _, i := range e.all() {
+		i.SetX(e.X)
 		i.SetY(y)
 		y += i.GetHeight()
 	}
@@ -94,6 +172,13 @@ func (e *Expanded) Align() {
 func calcWidth(w int) {
 }
 
+// Set width of all widgets in column
+func (e *Expanded) setColWidth(w int) {
+	for _, i := range e.all() {
+		i.SetWidth(w)
+	}
+}
+
 func (e *Expanded) Buffer() ui.Buffer {
 	buf := ui.NewBuffer()
 	if e.Width < (colWidth[0] + colWidth[1]) {
@@ -102,16 +187,20 @@ func (e *Expanded) Buffer() ui.Buffer {
 		return buf
 	}
 	buf.Merge(e.Info.Buffer())
+	if e.Output.Height > 0 {
+		buf.Merge(e.Output.Buffer())
+	}
 	buf.Merge(e.Cpu.Buffer())
 	buf.Merge(e.Mem.Buffer())
 	buf.Merge(e.Net.Buffer())
 	buf.Merge(e.IO.Buffer())
-	return buf
+	return cwidgets.ASCIIBuffer(buf)
 }
 
 func (e *Expanded) all() []ui.GridBufferer {
 	return []ui.GridBufferer{
 		e.Info,
+		e.Output,
 		e.Cpu,
 		e.Mem,
 		e.Net,
@@ -9,10 +9,8 @@ import (
 
 type Mem struct {
 	*ui.Block
-	Chart      *ui.MBarChart
+	Chart      *Graph
 	InnerLabel *ui.Par
-	valHist    *IntHist
-	limitHist  *IntHist
 }
 
 func NewMem() *Mem {
@@ -20,22 +18,17 @@ func NewMem() *Mem {
 		Block:      ui.NewBlock(),
 		Chart:      newMemChart(),
 		InnerLabel: newMemLabel(),
-		valHist:    NewIntHist(9),
-		limitHist:  NewIntHist(9),
 	}
 	mem.Height = 13
 	mem.Width = colWidth[0]
 	mem.BorderLabel = "MEM"
-
-	mem.Chart.Data[0] = mem.valHist.Data
-	mem.Chart.Data[1] = mem.limitHist.Data
-	mem.Chart.DataLabels = mem.valHist.Labels
-
 	return mem
 }
 
 func (w *Mem) Align() {
 	y := w.Y + 1
+	w.InnerLabel.SetX(w.X + 1)
+	w.Chart.SetX(w.X + 1)
 	w.InnerLabel.SetY(y)
 	w.Chart.SetY(y + w.InnerLabel.Height)
 
@@ -44,6 +37,7 @@ func (w *Mem) Align() {
 }
 
 func (w *Mem) Buffer() ui.Buffer {
+	w.BorderLabel = fmt.Sprintf("MEM (%s)", w.Chart.Status())
 	buf := ui.NewBuffer()
 	buf.Merge(w.Block.Buffer())
 	buf.Merge(w.InnerLabel.Buffer())
@@ -60,24 +54,16 @@ func newMemLabel() *ui.Par {
 	return p
 }
 
-func newMemChart() *ui.MBarChart {
-	mbar := ui.NewMBarChart()
-	mbar.X = 1
-	mbar.Border = false
-	mbar.BarGap = 1
-	mbar.BarWidth = 6
-
-	mbar.BarColor[1] = ui.ColorBlack
-	mbar.NumColor[1] = ui.ColorBlack
-
-	mbar.NumFmt = cwidgets.ByteFormatInt
-	//mbar.ShowScale = true
-	return mbar
+func newMemChart() *Graph {
+	g := NewGraph()
+	g.X = 1
+	g.Border = false
+	g.Value = func(s Sample) float64 { return float64(s.MemUsage) }
+	g.Format = func(v float64) string { return cwidgets.ByteFormat(int64(v)) }
+	return g
 }
 
-func (w *Mem) Update(val int, limit int) {
-	w.valHist.Append(val)
-	w.limitHist.Append(limit - val)
-	w.InnerLabel.Text = fmt.Sprintf("%v / %v", cwidgets.ByteFormatInt(val), cwidgets.ByteFormatInt(limit))
-	//w.Data[0] = w.hist.data
+func (w *Mem) Update(val int64, limit int64) {
+	w.Chart.Top = float64(limit)
+	w.InnerLabel.Text = fmt.Sprintf("%v / %v", cwidgets.ByteFormat(val), cwidgets.ByteFormat(limit))
 }
@@ -0,0 +1,27 @@
+package expanded
+
+import (
+	ui "github.com/gizak/termui"
+)
+
+// Last lines of output of a stopped container
+type Output struct {
+	*ui.List
+}
+
+func NewOutput() *Output {
+	l := ui.NewList()
+	l.BorderLabel = "LAST OUTPUT"
+	l.Width = colWidth[0]
+	l.Height = 0
+	return &Output{l}
+}
+
+// Set lines to show, hiding the section if there are none
+func (w *Output) Set(lines []string) {
+	w.Items = lines
+	w.Height = 0
+	if len(lines) > 0 {
+		w.Height = len(lines) + 2
+	}
+}
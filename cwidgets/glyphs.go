@@ -0,0 +1,104 @@
+package cwidgets
+
+import (
+	ui "github.com/gizak/termui"
+)
+
+// Drawing characters used by widgets
+type GlyphSet struct {
+	Mark      rune // status indicator
+	VBar      rune // paused status indicator
+	Dot       rune // linechart data point
+	GaugeFill rune // filled portion of a gauge bar
+	Ellipsis  rune
+	UpArrow   rune
+	DownArrow rune
+	Watch     rune // watched container, before its name
+}
+
+var (
+	Glyphs    = unicodeGlyphs
+	asciiMode bool
+
+	unicodeGlyphs = GlyphSet{
+		Mark:      '◉',
+		VBar:      '▮',
+		Dot:       '•',
+		GaugeFill: ' ',
+		Ellipsis:  '…',
+		UpArrow:   '▲',
+		DownArrow: '▼',
+		Watch:     '◆',
+	}
+	asciiGlyphs = GlyphSet{
+		Mark:      'o',
+		VBar:      '|',
+		Dot:       '*',
+		GaugeFill: '#',
+		Ellipsis:  '~',
+		UpArrow:   '^',
+		DownArrow: 'v',
+		Watch:     '+',
+	}
+)
+
+// ASCII equivalents for characters drawn internally by termui
+var asciiMap = map[rune]rune{
+	// borders
+	'─': '-',
+	'│': '|',
+	'┌': '+',
+	'┐': '+',
+	'└': '+',
+	'┘': '+',
+	// sparkline blocks
+	'▁': '_',
+	'▂': '_',
+	'▃': '-',
+	'▄': '-',
+	'▅': '=',
+	'▆': '=',
+	'▇': '#',
+	'█': '#',
+	// linechart
+	'•': '*',
+	'┊': '|',
+	'┈': '-',
+	// punctuation
+	'—': '-',
+	'…': '~',
+}
+
+// Swap all drawing characters for plain ASCII equivalents, or back
+func SetASCII(on bool) {
+	Glyphs = unicodeGlyphs
+	if on {
+		Glyphs = asciiGlyphs
+	}
+	asciiMode = on
+}
+
+func ASCIIMode() bool { return asciiMode }
+
+// Replace any non-ASCII characters in the given buffer,
+// if ASCII-only mode is enabled
+func ASCIIBuffer(buf ui.Buffer) ui.Buffer {
+	if !asciiMode {
+		return buf
+	}
+	for p, c := range buf.CellMap {
+		if c.Ch < 128 {
+			continue
+		}
+		switch {
+		case asciiMap[c.Ch] != 0:
+			c.Ch = asciiMap[c.Ch]
+		case c.Ch >= '⠀' && c.Ch <= '⣿': // braille patterns
+			c.Ch = '.'
+		default:
+			c.Ch = '?'
+		}
+		buf.CellMap[p] = c
+	}
+	return buf
+}
@@ -46,3 +46,18 @@ func getPrecision(f float64) int {
 	}
 	return 2 // default precision
 }
+
+// Truncate a string to the given length, replacing the
+// middle portion with an ellipsis
+func MidTruncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	if n < 3 {
+		return string(r[:n])
+	}
+	head := (n - 1) / 2
+	tail := n - 1 - head
+	return string(r[:head]) + string(Glyphs.Ellipsis) + string(r[len(r)-tail:])
+}
@@ -21,10 +21,11 @@ func logEvent(e ui.Event) {
 // log container, metrics, and widget state
 func dumpContainer(c *Container) {
 	msg := fmt.Sprintf("logging state for container: %s\n", c.Id)
-	for k, v := range c.Meta {
+	for k, v := range c.MetaCopy() {
 		msg += fmt.Sprintf("Meta.%s = %s\n", k, v)
 	}
-	msg += inspect(&c.Metrics)
+	m := c.Metrics()
+	msg += inspect(&m)
 	log.Infof(msg)
 }
 
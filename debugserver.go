@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+)
+
+var debugServer *http.Server
+
+// Internal state reported by a container source, for diagnosis
+type sourceState struct {
+	RefreshQueue int        `json:"refresh_queue"` // containers queued for refresh
+	LastEvent    *time.Time `json:"last_event"`
+	// seconds since each watched goroutine last beat
+	Heartbeats map[string]float64 `json:"heartbeats,omitempty"`
+}
+
+// Container source reporting its internal state
+type stateReporter interface {
+	DebugState() sourceState
+}
+
+type debugState struct {
+	Time                time.Time        `json:"time"`
+	Goroutines          int              `json:"goroutines"`
+	Containers          int              `json:"containers"`
+	CollectorsRunning   int              `json:"collectors_running"`
+	CollectorGoroutines int64            `json:"collector_goroutines"`
+	HostRoot            string           `json:"host_root"`
+	CgroupVersion       int              `json:"cgroup_version"`
+	Source              *sourceState     `json:"source"`
+	Collectors          []debugCollector `json:"collectors"`
+}
+
+type debugCollector struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Errors  int    `json:"errors"`
+}
+
+// Start serving pprof profiles and internal state on the given
+// address, which must be a loopback address, until stopDebugServer
+// is called
+func startDebugServer(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("%s is not a loopback address", addr)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/ctop", debugHandler)
+	debugServer = &http.Server{Handler: mux}
+	safeGo(func() {
+		if err := debugServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("debug listener: %s", err)
+		}
+	})
+	log.Noticef("serving debug endpoints on %s", l.Addr())
+	return nil
+}
+
+func stopDebugServer() {
+	if debugServer == nil {
+		return
+	}
+	shutdownServer(debugServer)
+	debugServer = nil
+}
+
+func debugHandler(w http.ResponseWriter, r *http.Request) {
+	s := debugState{
+		Time:                time.Now(),
+		Goroutines:          runtime.NumGoroutine(),
+		CollectorGoroutines: metrics.Goroutines(),
+		HostRoot:            metrics.HostRoot(),
+		CgroupVersion:       metrics.CgroupVersion(),
+		Collectors:          []debugCollector{},
+	}
+	if sr, ok := unwrapSource(cursor.Source()).(stateReporter); ok {
+		state := sr.DebugState()
+		s.Source = &state
+	}
+	for _, c := range cursor.Source().All() {
+		running, errors := c.CollectorState()
+		if running {
+			s.CollectorsRunning++
+		}
+		s.Collectors = append(s.Collectors, debugCollector{
+			ID:      c.Id,
+			Name:    c.GetMeta("name"),
+			Running: running,
+			Errors:  errors,
+		})
+		s.Containers++
+	}
+	apiWrite(w, http.StatusOK, s)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Container source reporting progress of listing and inspecting its
+// containers, as at startup and on reconnect
+type discoverySource interface {
+	Discovery() (done, total int, started time.Time)
+}
+
+// Container source populating containers before reading their full
+// details, reading them on demand
+type detailSource interface {
+	WantDetails(id string)
+}
+
+// Request full details of a container, if not yet read
+func wantDetails(c *Container) {
+	if ds, ok := unwrapSource(cursor.Source()).(detailSource); ok && c != nil {
+		ds.WantDetails(c.Id)
+	}
+}
+
+// Container source able to refresh a container on request
+type refresher interface {
+	RefreshNow(id string)
+}
+
+// Refresh the selected container at once, retrying an inspect held
+// back after failures
+func refreshSelected() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	if r, ok := unwrapSource(cursor.Source()).(refresher); ok {
+		r.RefreshNow(c.Id)
+		footer.Flash(fmt.Sprintf("refreshing %s", c.GetMeta("name")), 2*time.Second)
+	}
+}
+
+// Time after which discovery progress includes the time elapsed
+const discoverySlow = 2 * time.Second
+
+// Return discovery progress as a count of containers inspected, or an
+// empty string if not in progress
+func discoveryProgress() string {
+	ds, ok := unwrapSource(cursor.Source()).(discoverySource)
+	if !ok {
+		return ""
+	}
+	done, total, started := ds.Discovery()
+	if done >= total {
+		return ""
+	}
+	s := fmt.Sprintf("%d/%d inspected", done, total)
+	if d := time.Since(started); d >= discoverySlow {
+		s += fmt.Sprintf(" (%s)", d-d%time.Second)
+	}
+	return s
+}
@@ -1,18 +1,74 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"sort"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/bcicen/ctop/config"
 	"github.com/bcicen/ctop/metrics"
 	"github.com/fsouza/go-dockerclient"
 )
 
+const (
+	stopTimeout  = 10 // seconds before a stopping container is killed
+	infoInterval = 30 * time.Second
+	pingInterval = 2 * time.Second
+	maxFailures  = 3 // consecutive API failures before connection is considered lost
+
+	defaultRefreshWorkers = 8                     // concurrent container inspects
+	backfillInterval      = 50 * time.Millisecond // between inspects of listed containers
+	removedTTL            = 10 * time.Minute
+)
+
 type ContainerSource interface {
 	All() Containers
+	Snapshot() Containers
 	Get(string) (*Container, bool)
+	LostSince() time.Time
+	Host() metrics.HostMetrics
+	Endpoint() string
+	Inspect(string) (interface{}, error)
+	Attach(string, AttachOpts) error
+	Rename(string, string) error
+	Limits(string) (Limits, error)
+	UpdateLimits(string, Limits) error
+	SetRestartPolicy(id, name string, maxRetry int) error
+	Remove(string) error
+	ForceRemove(string) error
+	Stop(string) error
+	Restart(string) error
+	Pause(string) error
+	Unpause(string) error
+	Commit(id, repo, tag, comment string) (string, error)
+	PullImage(image string, progress func(PullProgress)) error
+	Recreate(id string) (string, error)
+	Changes(string) ([]FileChange, error)
+	Export(context.Context, string, io.Writer) error
+	Close()
+}
+
+// Streams and terminal settings used when attaching to a container
+type AttachOpts struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	TTY    bool // container has a TTY; streams are raw rather than multiplexed
+	Width  int  // terminal size, applied to the container TTY
+	Height int
+	Detach <-chan struct{} // closed to detach, if not by the container TTY
 }
 
 type DockerContainerSource struct {
@@ -20,47 +76,461 @@ type DockerContainerSource struct {
 	containers   map[string]*Container
 	needsRefresh chan string // container IDs requiring refresh
 	lock         sync.RWMutex
+	failures     int       // consecutive API failures
+	lostAt       time.Time // time connection was lost, if disconnected
+	procHost     *metrics.ProcHost
+	hostInfo     metrics.HostMetrics // cached daemon info, for remote daemons
+	infoTime     time.Time
+	infoReading  bool      // daemon info being read
+	done         chan bool // closed when the source is closed
+	lastEvent    time.Time // time the last docker event was received
+	eventStarts  int       // times the event listener was started
+	workers      int
+	inflight     map[string]bool            // IDs being refreshed, true if requeued meanwhile
+	removed      map[string]time.Time       // recently removed IDs, not to be re-added
+	eventTimes   map[string]time.Time       // times of state change events, until refreshed
+	discovering  map[string]bool            // listed IDs not yet inspected since listing
+	failing      map[string]*inspectFailure // IDs failing to inspect
+	listed       int                        // containers in the last full listing
+	listedAt     time.Time
+	networks     map[string]*docker.Network // inspected networks by ID
+	images       map[string]imageRef        // image IDs by reference, as last resolved
+	peers        map[string]peerRef         // untracked namespace peers by reference, as last inspected
+	imageDocs    map[string]*imageInfo      // image sizes by ID
+	watchdog     *Watchdog
 }
 
-func NewDockerContainerSource() *DockerContainerSource {
+// Keys accepted in the [connector.docker] config section
+var dockerConnectorKeys = []string{"endpoint", "tlsCertPath", "apiVersion", "workers", "maxStreams"}
+
+func newDockerContainerSource(section *config.ConnectorSection) (*DockerContainerSource, error) {
+	workers := defaultRefreshWorkers
+	if s := section.Get("workers"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, section.Errorf("workers", "expected a positive number, got %s", s)
+		}
+		workers = n
+	}
+	var maxStreams int
+	if s := section.Get("maxStreams"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, section.Errorf("maxStreams", "expected a number, 0 for no limit, got %s", s)
+		}
+		maxStreams = n
+	}
+	metrics.SetMaxStreams(maxStreams)
+
 	// init docker client
-	client, err := docker.NewClientFromEnv()
+	client, err := newDockerClient(section)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	cm := &DockerContainerSource{
 		client:       client,
 		containers:   make(map[string]*Container),
 		needsRefresh: make(chan string, 60),
 		lock:         sync.RWMutex{},
+		procHost:     &metrics.ProcHost{},
+		done:         make(chan bool),
+		workers:      workers,
+		inflight:     make(map[string]bool),
+		removed:      make(map[string]time.Time),
+		eventTimes:   make(map[string]time.Time),
+		networks:     make(map[string]*docker.Network),
+		images:       make(map[string]imageRef),
+		peers:        make(map[string]peerRef),
+		imageDocs:    make(map[string]*imageInfo),
+		failing:      make(map[string]*inspectFailure),
+	}
+	cm.watchdog = NewWatchdog(cm.done)
+	cm.Loop()
+	if err := cm.refreshAll(); err != nil {
+		cm.Close()
+		if permissionDenied(err) {
+			return nil, &permissionError{client.Endpoint(), err}
+		}
+		return nil, err
+	}
+	// the event watcher stops when the connection is lost, and is
+	// restarted once reconnected
+	cm.watchdog.Go("events", cm.disconnected, cm.watchEvents)
+	cm.watchdog.Go("connection", nil, cm.watchConnection)
+	cm.watchdog.Go("backfill", nil, cm.backfill)
+	return cm, nil
+}
+
+// Return a client for the configured docker endpoint, or one
+// configured by DOCKER_HOST and related variables if none is given.
+// A top-level endpoint, as set by a profile or option, takes precedence
+// over one in the [connector.docker] section. DOCKER_HOST takes
+// precedence over either when only given in the config file
+func newDockerClient(section *config.ConnectorSection) (*docker.Client, error) {
+	apiVersion := section.Get("apiVersion")
+	if apiVersion != "" {
+		if _, err := docker.NewAPIVersion(apiVersion); err != nil {
+			return nil, section.Errorf("apiVersion", "%s", err)
+		}
+	}
+
+	endpoint := config.Get("endpoint")
+	if endpoint.Val == "" {
+		endpoint = &config.Param{Val: section.Get("endpoint"), Source: config.SourceFile}
+	}
+	if endpoint.Val == "" || (os.Getenv("DOCKER_HOST") != "" && endpoint.Source == config.SourceFile) {
+		return docker.NewVersionedClientFromEnv(apiVersion)
+	}
+
+	certPath := config.GetVal("tlsCertPath")
+	if certPath == "" {
+		certPath = section.Get("tlsCertPath")
+	}
+	if certPath == "" {
+		return docker.NewVersionedClient(endpoint.Val, apiVersion)
+	}
+	var files []string
+	for _, name := range []string{"cert.pem", "key.pem", "ca.pem"} {
+		f := filepath.Join(certPath, name)
+		if _, err := os.Stat(f); err != nil {
+			return nil, section.Errorf("tlsCertPath", "%s", err)
+		}
+		files = append(files, f)
+	}
+	return docker.NewVersionedTLSClient(endpoint.Val, files[0], files[1], files[2], apiVersion)
+}
+
+// Stop watching the docker daemon and all container collectors
+func (cm *DockerContainerSource) Close() {
+	cm.lock.Lock()
+	if cm.closed() {
+		cm.lock.Unlock()
+		return
+	}
+	close(cm.done)
+	var containers []*Container
+	for _, c := range cm.containers {
+		containers = append(containers, c)
+	}
+	cm.lock.Unlock()
+
+	for _, c := range containers {
+		c.Remove()
+	}
+	log.Infof("closed docker connection: %s", cm.client.Endpoint())
+}
+
+func (cm *DockerContainerSource) closed() bool {
+	select {
+	case <-cm.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Queue a container for refresh, unless the source is closed
+func (cm *DockerContainerSource) queueRefresh(id string) {
+	select {
+	case cm.needsRefresh <- id:
+	case <-cm.done:
+	}
+}
+
+// Return the time connection to the docker daemon was lost,
+// or a zero time if currently connected
+func (cm *DockerContainerSource) LostSince() time.Time {
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	return cm.lostAt
+}
+
+// Return internal state, for the debug endpoint
+func (cm *DockerContainerSource) DebugState() sourceState {
+	// read before taking the source lock, which watchdog holds take
+	// while the watchdog lock is held
+	ages := cm.watchdog.Ages()
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	s := sourceState{
+		RefreshQueue: len(cm.needsRefresh),
+		Heartbeats:   ages,
+	}
+	if !cm.lastEvent.IsZero() {
+		t := cm.lastEvent
+		s.LastEvent = &t
 	}
-	go cm.Loop()
-	cm.refreshAll()
-	go cm.watchEvents()
-	return cm
+	return s
+}
+
+func (cm *DockerContainerSource) Endpoint() string {
+	return cm.client.Endpoint()
+}
+
+// Return host metrics, read from /proc when connected to a local
+// daemon, or from daemon info otherwise. Daemon info is read in the
+// background, returning the last read meanwhile
+func (cm *DockerContainerSource) Host() metrics.HostMetrics {
+	if strings.HasPrefix(cm.client.Endpoint(), "unix://") {
+		return cm.procHost.Read()
+	}
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	if !cm.infoReading && time.Since(cm.infoTime) > infoInterval {
+		cm.infoReading = true
+		safeGo(cm.readInfo)
+	}
+	return cm.hostInfo
+}
+
+// Read daemon info, caching the host metrics returned by Host
+func (cm *DockerContainerSource) readInfo() {
+	info, err := cm.client.Info()
+	if err != nil {
+		cm.apiFailed(err)
+		if !cm.disconnected() {
+			log.Errorf("failed to read docker info: %s", err)
+		}
+	}
+
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	cm.infoReading = false
+	if err != nil {
+		return
+	}
+	cm.hostInfo = metrics.NewHostMetrics()
+	cm.hostInfo.NCPU = info.NCPU
+	cm.hostInfo.MemTotal = info.MemTotal
+	cm.infoTime = time.Now()
+}
+
+// Record a failed API call, marking the connection as lost after
+// too many consecutive failures, or at once if permission to the
+// daemon socket is lost, as when it is recreated with other owners
+func (cm *DockerContainerSource) apiFailed(err error) {
+	selfAPIErrors.inc(apiErrorType(err))
+	cm.lock.Lock()
+	cm.failures++
+	failures := cm.failures
+	cm.lock.Unlock()
+	if failures >= maxFailures || permissionDenied(err) {
+		cm.connLost(err)
+	}
+}
+
+// Return whether the connection to the docker daemon is lost
+func (cm *DockerContainerSource) disconnected() bool {
+	return !cm.LostSince().IsZero()
+}
+
+func (cm *DockerContainerSource) connLost(err error) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	if cm.lostAt.IsZero() {
+		cm.lostAt = time.Now()
+		if permissionDenied(err) {
+			err = &permissionError{cm.client.Endpoint(), err}
+		}
+		log.NotifyError("docker connection lost: %s", err)
+	}
+}
+
+// Periodically check daemon connectivity, resyncing all
+// containers once a lost connection is restored
+func (cm *DockerContainerSource) watchConnection(beat func() bool) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cm.done:
+			return
+		case <-ticker.C:
+		}
+		if !beat() {
+			return
+		}
+		if err := cm.client.Ping(); err != nil {
+			cm.apiFailed(err)
+			continue
+		}
+
+		cm.lock.Lock()
+		cm.failures = 0
+		lost := !cm.lostAt.IsZero()
+		cm.lock.Unlock()
+
+		if lost {
+			cm.reconnect()
+		}
+	}
+}
+
+func (cm *DockerContainerSource) reconnect() {
+	log.Info("docker connection available, resyncing containers")
+	// connects and disconnects may have been missed
+	cm.lock.Lock()
+	cm.networks = make(map[string]*docker.Network)
+	// failures while disconnected are retried at once
+	for _, f := range cm.failing {
+		f.retry = time.Time{}
+	}
+	cm.lock.Unlock()
+	cm.watchdog.Restart("events")
+	if err := cm.refreshAll(); err != nil {
+		cm.apiFailed(err)
+		return
+	}
+	cm.lock.Lock()
+	cm.lostAt = time.Time{}
+	cm.lock.Unlock()
+	log.Notify("docker connection restored")
 }
 
 // Docker events watcher
-func (cm *DockerContainerSource) watchEvents() {
+func (cm *DockerContainerSource) watchEvents(beat func() bool) {
 	log.Info("docker event listener starting")
+	cm.lock.Lock()
+	if cm.eventStarts++; cm.eventStarts > 1 {
+		selfEventRestarts.inc()
+	}
+	cm.lock.Unlock()
 	events := make(chan *docker.APIEvents)
-	cm.client.AddEventListener(events)
+	if err := cm.client.AddEventListener(events); err != nil {
+		log.NotifyError("failed to start docker event listener: %s", err)
+		return
+	}
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
 
-	for e := range events {
-		if e.Type != "container" {
-			continue
+	for {
+		select {
+		case <-cm.done:
+			cm.client.RemoveEventListener(events)
+			log.Info("docker event listener stopped")
+			return
+		case <-ticker.C:
+			if !beat() {
+				cm.client.RemoveEventListener(events)
+				log.Info("docker event listener replaced")
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				// event stream is closed by the client on connection failure
+				cm.connLost(fmt.Errorf("event stream closed"))
+				log.Info("docker event listener stopped")
+				return
+			}
+			cm.handleEvent(e)
+		}
+	}
+}
+
+func (cm *DockerContainerSource) handleEvent(e *docker.APIEvents) {
+	cm.lock.Lock()
+	cm.lastEvent = time.Now()
+	cm.lock.Unlock()
+
+	if e.Type == "network" {
+		cm.networkEvent(e)
+		return
+	}
+	if e.Type == "image" {
+		cm.imageEvent(e)
+		return
+	}
+	if e.Type != "container" {
+		return
+	}
+	cm.recordEvent(e)
+	switch e.Action {
+	case "start", "die", "pause", "unpause", "rename":
+		log.Debugf("handling docker event: action=%s id=%s", e.Action, e.ID)
+		if e.Action != "rename" {
+			cm.lock.Lock()
+			cm.eventTimes[e.ID] = eventTime(e)
+			cm.lock.Unlock()
+		} else if c, ok := cm.Get(e.ID); ok {
+			attrs := e.Actor.Attributes
+			c.AddPreviousName(resolveName(shortName(attrs["oldName"]), attrs), eventTime(e))
+		}
+		cm.queueRefresh(e.ID)
+		if e.Action == "die" {
+			cm.notifyEvent(e, "die")
 		}
-		switch e.Action {
-		case "start", "die", "pause", "unpause":
-			log.Debugf("handling docker event: action=%s id=%s", e.Action, e.ID)
-			cm.needsRefresh <- e.ID
-		case "destroy":
-			log.Debugf("handling docker event: action=%s id=%s", e.Action, e.ID)
-			cm.delByID(e.ID)
+	case "oom":
+		log.Debugf("handling docker event: action=%s id=%s", e.Action, e.ID)
+		cm.notifyEvent(e, "oom")
+	case "health_status: healthy", "health_status: unhealthy":
+		log.Debugf("handling docker event: action=%s id=%s", e.Action, e.ID)
+		cm.queueRefresh(e.ID)
+		if e.Action == "health_status: unhealthy" {
+			cm.notifyEvent(e, "unhealthy")
 		}
+	case "destroy":
+		log.Debugf("handling docker event: action=%s id=%s", e.Action, e.ID)
+		cm.delByID(e.ID)
 	}
 }
 
+// Record a container event in the timeline, resolving the container
+// name before it is removed on destroy
+func (cm *DockerContainerSource) recordEvent(e *docker.APIEvents) {
+	if !timelineAction(e.Action) {
+		return
+	}
+	attrs := e.Actor.Attributes
+	name := resolveName(attrs["name"], attrs) // event attributes include labels
+	if c, ok := cm.Get(e.ID); ok && name == "" {
+		name = c.GetMeta("name")
+	}
+	if name == "" && len(e.ID) > 12 {
+		name = e.ID[:12]
+	}
+	var detail string
+	switch e.Action {
+	case "die":
+		detail = "exit " + attrs["exitCode"]
+	case "kill":
+		detail = "signal " + attrs["signal"]
+	case "rename":
+		detail = "from " + shortName(attrs["oldName"])
+	}
+	recordEvent(timelineEvent{eventTime(e), sourceLabel(cm.Endpoint()), e.ID, name, e.Action, detail})
+}
+
+// Return the time an event occurred, or the current time if not given
+func eventTime(e *docker.APIEvents) time.Time {
+	switch {
+	case e.TimeNano > 0:
+		return time.Unix(0, e.TimeNano)
+	case e.Time > 0:
+		return time.Unix(e.Time, 0)
+	}
+	return time.Now()
+}
+
+// Notify a container event if watched and run the hooks set for it
+func (cm *DockerContainerSource) notifyEvent(e *docker.APIEvents, event string) {
+	c, ok := cm.Get(e.ID)
+	if !ok {
+		return
+	}
+	containerEvent(c, event)
+	exitCode, _ := strconv.Atoi(e.Actor.Attributes["exitCode"])
+	runHooks(c, event, exitCode)
+}
+
+// Return the user a container process runs as, given as a name or
+// numeric UID, optionally with a group. An empty user means root
+func userFormat(user string) string {
+	if user == "" {
+		return "root"
+	}
+	return user
+}
+
 func portsFormat(ports map[docker.Port][]docker.PortBinding) string {
 	var exposed []string
 	var published []string
@@ -79,82 +549,1120 @@ func portsFormat(ports map[docker.Port][]docker.PortBinding) string {
 	return strings.Join(append(exposed, published...), "\n")
 }
 
-func (cm *DockerContainerSource) refresh(c *Container) {
-	insp := cm.inspect(c.Id)
-	// remove container if no longer exists
-	if insp == nil {
-		cm.delByID(c.Id)
+// Refresh a container, unless a refresh of it is already in progress,
+// in which case that refresh is repeated once complete. Refreshes of
+// the same container are thereby never run concurrently
+func (cm *DockerContainerSource) refreshID(id string) {
+	cm.lock.Lock()
+	if again, ok := cm.inflight[id]; ok {
+		if again {
+			selfRefreshDropped.inc()
+		}
+		cm.inflight[id] = true
+		cm.lock.Unlock()
 		return
 	}
-	c.SetMeta("name", shortName(insp.Name))
+	cm.inflight[id] = false
+	cm.lock.Unlock()
+
+	for {
+		cm.refresh(id)
+
+		cm.lock.Lock()
+		delete(cm.discovering, id)
+		again := cm.inflight[id]
+		if again {
+			cm.inflight[id] = false
+		} else {
+			delete(cm.inflight, id)
+		}
+		cm.lock.Unlock()
+		if !again {
+			return
+		}
+	}
+}
+
+// Inspect a container and update its metadata, adding it if new
+// Containers failing to inspect are retried only once their backoff
+// has elapsed
+func (cm *DockerContainerSource) refresh(id string) {
+	if !cm.inspectDue(id) {
+		return
+	}
+	insp, err := cm.inspect(id)
+	if err != nil {
+		// remove container if no longer exists
+		if _, ok := err.(*docker.NoSuchContainer); ok {
+			cm.delByID(id)
+			return
+		}
+		cm.inspectFailed(id, err)
+		return
+	}
+	c, ok := cm.add(id)
+	if !ok {
+		return // removed while inspecting
+	}
+	cm.inspectSucceeded(c)
+	setName(c, shortName(insp.Name), insp.Config.Labels)
 	c.SetMeta("image", insp.Config.Image)
+	c.SetMeta("stale image", cm.staleImage(insp))
+	cm.setImageSize(c, insp.Image)
+	c.SetMeta("user", userFormat(insp.Config.User))
 	c.SetMeta("ports", portsFormat(insp.NetworkSettings.Ports))
-	c.SetMeta("created", insp.Created.Format("Mon Jan 2 15:04:05 2006"))
+	c.SetMeta("tty", fmt.Sprintf("%t", insp.Config.Tty))
+	pid := ""
+	if insp.State.Pid > 0 {
+		pid = strconv.Itoa(insp.State.Pid)
+	}
+	c.SetMeta("pid", pid)
+	c.SetMeta("namespaces", cm.sharedNamespaces(insp.HostConfig))
+	c.SetMeta("networks", cm.networkPeers(insp))
+	c.SetMeta("health", insp.State.Health.Status)
+	if checks := insp.State.Health.Log; len(checks) > 0 {
+		last := checks[len(checks)-1]
+		c.SetMeta("healthcheck", fmt.Sprintf("exit %d at %s", last.ExitCode, formatTime(last.End)))
+	}
+	c.SetLabels(insp.Config.Labels)
+	c.SetEnv(insp.Config.Env)
+	c.SetMeta("limits", hostLimits(insp.HostConfig).String())
+	c.SetMeta("tmpfs mounts", formatTmpfsMounts(tmpfsMounts(insp.HostConfig)))
+	c.SetMeta("gpu", gpuDetail(insp.HostConfig, insp.Config.Env))
+	posture := classifySecurity(insp.HostConfig, insp.Mounts)
+	c.SetMeta("posture", posture.Level)
+	c.SetMeta("security", posture.Badge())
+	if insp.HostConfig != nil {
+		c.SetMeta("restart", restartFormat(insp.HostConfig.RestartPolicy))
+		c.SetMeta("pids limit", strconv.FormatInt(insp.HostConfig.PidsLimit, 10))
+	}
+	exited := insp.State.Status == "exited" || insp.State.Status == "dead"
+	c.SetLifecycle(Lifecycle{
+		Created:      insp.Created,
+		Started:      insp.State.StartedAt,
+		Finished:     insp.State.FinishedAt,
+		Since:        cm.stateSince(c, insp),
+		Exited:       exited,
+		ExitCode:     insp.State.ExitCode,
+		RestartCount: insp.RestartCount,
+		ImageID:      insp.Image,
+	})
+	// a container removed during the update is not to collect again
 	c.SetState(insp.State.Status)
 }
 
-func (cm *DockerContainerSource) inspect(id string) *docker.Container {
+// Describe the namespaces a container shares with another container,
+// one per line, resolving the name and PID of the container referenced
+func (cm *DockerContainerSource) sharedNamespaces(hc *docker.HostConfig) string {
+	if hc == nil {
+		return ""
+	}
+	modes := []struct{ ns, mode string }{
+		{"pid", hc.PidMode},
+		{"net", hc.NetworkMode},
+		{"ipc", hc.IpcMode},
+	}
+	var lines []string
+	for _, m := range modes {
+		if !strings.HasPrefix(m.mode, "container:") {
+			continue
+		}
+		desc := cm.describePeer(strings.TrimPrefix(m.mode, "container:"))
+		lines = append(lines, fmt.Sprintf("%s of %s", m.ns, desc))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Peer container of a shared namespace, as last inspected
+type peerRef struct {
+	desc string
+	at   time.Time
+}
+
+// Describe a container referenced by ID or name, as by a namespace
+// mode, with its name and PID. Tracked containers are described as
+// last inspected; others are inspected, and looked up again once
+// older than infoInterval
+func (cm *DockerContainerSource) describePeer(ref string) string {
+	cm.lock.RLock()
+	var name, pid, id string
+	for cid, c := range cm.containers {
+		if cid == ref || strings.HasPrefix(cid, ref) && len(ref) >= 12 ||
+			c.GetMeta("name") == ref || c.GetMeta("raw name") == ref {
+			name, pid, id = c.GetMeta("name"), c.GetMeta("pid"), cid
+			break
+		}
+	}
+	p, ok := cm.peers[ref]
+	cm.lock.RUnlock()
+
+	// tracked containers are named once inspected
+	if name != "" {
+		return peerDesc(name, id, pid)
+	}
+	if ok && time.Since(p.at) < infoInterval {
+		return p.desc
+	}
+	desc := shortImageID(ref)
+	if insp, err := cm.client.InspectContainer(ref); err == nil {
+		pid := ""
+		if insp.State.Pid > 0 {
+			pid = strconv.Itoa(insp.State.Pid)
+		}
+		desc = peerDesc(shortName(insp.Name), insp.ID, pid)
+	}
+	cm.lock.Lock()
+	cm.peers[ref] = peerRef{desc, time.Now()}
+	cm.lock.Unlock()
+	return desc
+}
+
+func peerDesc(name, id, pid string) string {
+	desc := fmt.Sprintf("%s (%s)", name, shortImageID(id))
+	if pid != "" {
+		desc += " pid " + pid
+	}
+	return desc
+}
+
+// Return when a container entered its inspected state: the time of
+// the event reporting the change if one was received, or otherwise the
+// matching inspect timestamp, so that changes missed between events
+// are still accurate. Returns a zero time if unknown
+func (cm *DockerContainerSource) stateSince(c *Container, insp *docker.Container) time.Time {
+	cm.lock.Lock()
+	t, ok := cm.eventTimes[c.Id]
+	delete(cm.eventTimes, c.Id)
+	cm.lock.Unlock()
+	if ok {
+		return t
+	}
+	if since := c.Lifecycle().Since; c.State() == insp.State.Status && !since.IsZero() {
+		return since
+	}
+	switch insp.State.Status {
+	case "running", "restarting":
+		return insp.State.StartedAt
+	case "exited", "dead":
+		return insp.State.FinishedAt
+	case "created":
+		return insp.Created
+	}
+	return time.Time{}
+}
+
+func (cm *DockerContainerSource) inspect(id string) (*docker.Container, error) {
 	c, err := cm.client.InspectContainer(id)
 	if err != nil {
 		if _, ok := err.(*docker.NoSuchContainer); ok == false {
-			log.Errorf(err.Error())
+			cm.apiFailed(err)
+		}
+	}
+	return c, err
+}
+
+// Return the full inspect document for a container
+func (cm *DockerContainerSource) Inspect(id string) (interface{}, error) {
+	c, err := cm.inspect(id)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Return the last lines logged by a container, stdout and stderr
+// interleaved
+func (cm *DockerContainerSource) Logs(id string, tail int) ([]string, error) {
+	insp, err := cm.inspect(id)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = cm.client.Logs(docker.LogsOptions{
+		Container:    id,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Stdout:       true,
+		Stderr:       true,
+		Tail:         strconv.Itoa(tail),
+		RawTerminal:  insp.Config.Tty,
+	})
+	if err != nil {
+		driver := ""
+		if insp.HostConfig != nil {
+			driver = insp.HostConfig.LogConfig.Type
+		}
+		return nil, logsError(err, driver)
+	}
+	return outputLines(buf.String()), nil
+}
+
+// Attach to a running container, blocking until detached
+func (cm *DockerContainerSource) Attach(id string, opts AttachOpts) error {
+	success := make(chan struct{})
+	cw, err := cm.client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+		Container:    id,
+		InputStream:  opts.Stdin,
+		OutputStream: opts.Stdout,
+		ErrorStream:  opts.Stderr,
+		RawTerminal:  opts.TTY,
+		Success:      success,
+		Stream:       true,
+		Stdin:        opts.Stdin != nil,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	if err != nil {
+		return err
+	}
+	errCh := make(chan error, 1)
+	safeGo(func() { errCh <- cw.Wait() })
+
+	select {
+	case <-success:
+		success <- struct{}{}
+	case err := <-errCh:
+		return err
+	}
+
+	if opts.TTY && opts.Width > 0 && opts.Height > 0 {
+		if err := cm.client.ResizeContainerTTY(id, opts.Height, opts.Width); err != nil {
+			log.Warningf("failed to resize tty for %s: %s", id, err)
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-opts.Detach:
+		cw.Close()
+		return nil
+	}
+}
+
+// Rename a container; the container is refreshed on the resulting rename event
+func (cm *DockerContainerSource) Rename(id, name string) error {
+	return cm.client.RenameContainer(docker.RenameContainerOptions{ID: id, Name: name})
+}
+
+func restartFormat(p docker.RestartPolicy) string {
+	switch {
+	case p.Name == "":
+		return "no"
+	case p.Name == "on-failure" && p.MaximumRetryCount > 0:
+		return fmt.Sprintf("%s:%d", p.Name, p.MaximumRetryCount)
+	}
+	return p.Name
+}
+
+// Return resource limits set in a container host config
+func hostLimits(hc *docker.HostConfig) (l Limits) {
+	if hc == nil {
+		return l
+	}
+	l.Memory = hc.Memory
+	l.MemorySwap = hc.MemorySwap
+	l.CPUs = cpusFromQuota(hc.CPUQuota, hc.CPUPeriod)
+	if hc.NanoCPUs > 0 {
+		// set with --cpus, rather than as a quota
+		l.CPUs = float64(hc.NanoCPUs) / 1e9
+	}
+	return l
+}
+
+// Return current resource limits for a container
+func (cm *DockerContainerSource) Limits(id string) (Limits, error) {
+	insp, err := cm.inspect(id)
+	if err != nil {
+		return Limits{}, err
+	}
+	return hostLimits(insp.HostConfig), nil
+}
+
+// Update resource limits of a running container. Unset (zero)
+// limits are left unchanged. A CPU limit is set as nano CPUs for
+// containers already limited so, as the daemon refuses to set a
+// quota along with them, and as a quota otherwise
+func (cm *DockerContainerSource) UpdateLimits(id string, l Limits) error {
+	opts := docker.UpdateContainerOptions{
+		Memory:     int(l.Memory),
+		MemorySwap: int(l.MemorySwap),
+	}
+	var nanoCPUs int64
+	if l.CPUs > 0 {
+		insp, err := cm.inspect(id)
+		if err != nil {
+			return err
+		}
+		if insp.HostConfig != nil && insp.HostConfig.NanoCPUs > 0 {
+			nanoCPUs = int64(l.CPUs * 1e9)
+		} else {
+			opts.CPUPeriod = defaultCPUPeriod
+			opts.CPUQuota = int(l.CPUs * defaultCPUPeriod)
+		}
+	}
+	if err := cm.client.UpdateContainer(id, opts); err != nil {
+		return err
+	}
+	if nanoCPUs > 0 {
+		if err := cm.updateNanoCPUs(id, nanoCPUs); err != nil {
+			return err
+		}
+	}
+	if _, ok := cm.Get(id); ok {
+		cm.refreshID(id)
+	}
+	return nil
+}
+
+// Update the CPU limit of a container in nano CPUs. The options of
+// the docker client have no such field, so the update is requested
+// through its HTTP client directly
+func (cm *DockerContainerSource) updateNanoCPUs(id string, nanoCPUs int64) error {
+	u, err := url.Parse(cm.client.Endpoint())
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "unix":
+		// the client transport dials the socket whatever the host
+		u = &url.URL{Scheme: "http", Host: "docker"}
+	case "tcp":
+		u.Scheme = "http"
+		if cm.client.TLSConfig != nil {
+			u.Scheme = "https"
+		}
+	}
+	u.Path = "/containers/" + id + "/update"
+	body, err := json.Marshal(map[string]int64{"NanoCpus": nanoCPUs})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cm.client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		var msg struct{ Message string }
+		json.NewDecoder(resp.Body).Decode(&msg)
+		if msg.Message == "" {
+			msg.Message = resp.Status
+		}
+		return fmt.Errorf("failed to update cpus: %s", msg.Message)
+	}
+	return nil
+}
+
+// Update the restart policy of a container, re-reading the
+// applied policy from the daemon
+func (cm *DockerContainerSource) SetRestartPolicy(id, name string, maxRetry int) error {
+	opts := docker.UpdateContainerOptions{
+		RestartPolicy: docker.RestartPolicy{Name: name, MaximumRetryCount: maxRetry},
+	}
+	if err := cm.client.UpdateContainer(id, opts); err != nil {
+		return err
+	}
+	if _, ok := cm.Get(id); ok {
+		cm.refreshID(id)
+	}
+	return nil
+}
+
+// Remove a stopped container
+func (cm *DockerContainerSource) Remove(id string) error {
+	return cm.remove(id, false)
+}
+
+// Remove a container whether stopped or not, killing it if needed
+func (cm *DockerContainerSource) ForceRemove(id string) error {
+	return cm.remove(id, true)
+}
+
+func (cm *DockerContainerSource) remove(id string, force bool) error {
+	err := cm.client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: force})
+	if err != nil {
+		return err
+	}
+	cm.delByID(id)
+	return nil
+}
+
+// Stop a running container
+func (cm *DockerContainerSource) Stop(id string) error {
+	return cm.client.StopContainer(id, stopTimeout)
+}
+
+// Restart a container
+func (cm *DockerContainerSource) Restart(id string) error {
+	return cm.client.RestartContainer(id, stopTimeout)
+}
+
+// Pause all processes of a running container
+func (cm *DockerContainerSource) Pause(id string) error {
+	return cm.client.PauseContainer(id)
+}
+
+// Resume the processes of a paused container
+func (cm *DockerContainerSource) Unpause(id string) error {
+	return cm.client.UnpauseContainer(id)
+}
+
+// Commit a container to a new image, returning the image ID
+func (cm *DockerContainerSource) Commit(id, repo, tag, comment string) (string, error) {
+	img, err := cm.client.CommitContainer(docker.CommitContainerOptions{
+		Container:  id,
+		Repository: repo,
+		Tag:        tag,
+		Message:    comment,
+	})
+	if err != nil {
+		return "", err
+	}
+	return img.ID, nil
+}
+
+// Pull an image by reference, reporting progress by layer as the pull
+// progresses. Registry credentials are taken from the docker client
+// config or its credential helpers, if any. Once pulled, containers
+// of the reference are refreshed to update their stale image status
+func (cm *DockerContainerSource) PullImage(image string, progress func(PullProgress)) error {
+	if strings.HasPrefix(image, "sha256:") {
+		return fmt.Errorf("%s is an image ID, not a pullable reference", image)
+	}
+	repo, tag := image, ""
+	if !strings.Contains(image, "@") {
+		repo, tag = image, "latest"
+		if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+			repo, tag = image[:idx], image[idx+1:]
+		}
+	}
+	auth, authErr := registryAuth(repo)
+	pw := newPullWriter(progress)
+	err := cm.client.PullImage(docker.PullImageOptions{
+		Repository:    repo,
+		Tag:           tag,
+		OutputStream:  pw,
+		RawJSONStream: true,
+	}, auth)
+	if err == nil {
+		err = pw.Err()
+	} else {
+		err = apiMessage(err)
+	}
+	if err != nil {
+		if authErr != nil {
+			err = fmt.Errorf("%s (%s)", err, authErr)
+		}
+		return err
+	}
+
+	cm.lock.Lock()
+	delete(cm.images, image)
+	var ids []string
+	for id, c := range cm.containers {
+		if c.GetMeta("image") == image {
+			ids = append(ids, id)
 		}
 	}
-	return c
+	cm.lock.Unlock()
+	for _, id := range ids {
+		cm.queueRefresh(id)
+	}
+	return nil
 }
 
-// Mark all container IDs for refresh
-func (cm *DockerContainerSource) refreshAll() {
+// Image ID a reference resolved to, and when
+type imageRef struct {
+	id string
+	at time.Time
+}
+
+// Return the ID of the local image a reference resolves to, looked up
+// again once older than infoInterval. Empty if it does not resolve
+func (cm *DockerContainerSource) imageID(ref string) string {
+	cm.lock.RLock()
+	r, ok := cm.images[ref]
+	cm.lock.RUnlock()
+	if ok && time.Since(r.at) < infoInterval {
+		return r.id
+	}
+	var id string
+	if img, err := cm.client.InspectImage(ref); err == nil {
+		id = img.ID
+	}
+	cm.lock.Lock()
+	cm.images[ref] = imageRef{id, time.Now()}
+	cm.lock.Unlock()
+	return id
+}
+
+// Describe the newer image of the reference a container was created
+// from, if the reference now resolves to an image other than the
+// container's, as after pulling a newer image of its tag
+func (cm *DockerContainerSource) staleImage(insp *docker.Container) string {
+	ref := insp.Config.Image
+	if ref == "" || strings.HasPrefix(ref, "sha256:") || strings.Contains(ref, "@") {
+		return ""
+	}
+	id := cm.imageID(ref)
+	if id == "" || id == insp.Image {
+		return ""
+	}
+	return fmt.Sprintf("%s is now %s", ref, shortImageID(id))
+}
+
+// Return the message of a daemon API error, such as a registry error
+// relayed by the daemon, without the API status and JSON wrapping
+func apiMessage(err error) error {
+	e, ok := err.(*docker.Error)
+	if !ok {
+		return err
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(e.Message), &body) == nil && body.Message != "" {
+		return errors.New(body.Message)
+	}
+	if msg := strings.TrimSpace(e.Message); msg != "" {
+		return errors.New(msg)
+	}
+	return err
+}
+
+// Replace a container with a new one created from its image reference
+// and configuration, returning the new container ID. The old container
+// is stopped and renamed aside until the new one has started, and is
+// renamed back and restarted if creating or starting the new one fails
+func (cm *DockerContainerSource) Recreate(id string) (string, error) {
+	insp, err := cm.client.InspectContainer(id)
+	if err != nil {
+		return "", fmt.Errorf("inspect failed: %s", err)
+	}
+	// the old image tells apart settings of the container from
+	// defaults of the image, which the new image replaces
+	img, err := cm.client.InspectImage(insp.Image)
+	if err != nil {
+		return "", fmt.Errorf("inspecting the current image failed, container left untouched: %s", err)
+	}
+	name := shortName(insp.Name)
+	running := insp.State.Running
+	oldName := fmt.Sprintf("%s-old-%d", name, time.Now().Unix())
+
+	if running {
+		if err := cm.client.StopContainer(id, stopTimeout); err != nil {
+			return "", fmt.Errorf("stop failed, container left running: %s", err)
+		}
+	}
+	if err := cm.Rename(id, oldName); err != nil {
+		return "", cm.restore(id, name, running, fmt.Errorf("rename failed: %s", err))
+	}
+
+	opts := recreateOptions(insp, img.Config)
+	created, err := cm.client.CreateContainer(opts)
+	if err != nil {
+		return "", cm.restore(id, name, running, fmt.Errorf("create failed: %s", err))
+	}
+	for net, ep := range insp.NetworkSettings.Networks {
+		if _, ok := opts.NetworkingConfig.EndpointsConfig[net]; ok {
+			continue
+		}
+		err = cm.client.ConnectNetwork(net, docker.NetworkConnectionOptions{
+			Container:      created.ID,
+			EndpointConfig: &docker.EndpointConfig{Aliases: endpointAliases(ep.Aliases, id)},
+		})
+		if err != nil {
+			cm.client.RemoveContainer(docker.RemoveContainerOptions{ID: created.ID, Force: true})
+			return "", cm.restore(id, name, running, fmt.Errorf("connecting network %s failed: %s", net, err))
+		}
+	}
+	if err := cm.client.StartContainer(created.ID, nil); err != nil {
+		cm.client.RemoveContainer(docker.RemoveContainerOptions{ID: created.ID, Force: true})
+		return "", cm.restore(id, name, running, fmt.Errorf("start failed: %s", err))
+	}
+	if err := cm.Remove(id); err != nil {
+		return created.ID, fmt.Errorf("new container started, but removing the old container %s failed: %s", oldName, err)
+	}
+	return created.ID, nil
+}
+
+// Rename a container back after a failed recreate, restarting it if
+// it was running, and return the cause along with the outcome
+func (cm *DockerContainerSource) restore(id, name string, running bool, cause error) error {
+	if err := cm.Rename(id, name); err != nil {
+		return fmt.Errorf("%s; old container left stopped under another name, rename back failed: %s", cause, err)
+	}
+	if running {
+		if err := cm.client.StartContainer(id, nil); err != nil {
+			return fmt.Errorf("%s; old container restored but failed to start: %s", cause, err)
+		}
+	}
+	return fmt.Errorf("%s; old container restored", cause)
+}
+
+// Return options creating a container with the same name, image
+// reference and configuration as an inspected one. Volumes mounted
+// without a bind or mount in the host config, such as anonymous
+// volumes, are bound to the same destination. Settings the container
+// inherited from its old image, given as imgConfig, are left for the
+// new image to set
+func recreateOptions(insp *docker.Container, imgConfig *docker.Config) docker.CreateContainerOptions {
+	config := containerConfig(insp.Config, imgConfig)
+	if config.Hostname == insp.ID[:12] {
+		config.Hostname = "" // generated from the old ID
+	}
+	hostConfig := *insp.HostConfig
+	hostConfig.Binds = append([]string{}, hostConfig.Binds...)
+	configured := make(map[string]bool)
+	for _, b := range hostConfig.Binds {
+		if parts := strings.Split(b, ":"); len(parts) > 1 {
+			configured[parts[1]] = true
+		}
+	}
+	for _, m := range hostConfig.Mounts {
+		configured[m.Target] = true
+	}
+	for _, m := range insp.Mounts {
+		if m.Name == "" || configured[m.Destination] {
+			continue
+		}
+		bind := m.Name + ":" + m.Destination
+		if !m.RW {
+			bind += ":ro"
+		}
+		hostConfig.Binds = append(hostConfig.Binds, bind)
+	}
+
+	// the network of the network mode is given at creation; any
+	// others are connected after
+	endpoints := make(map[string]*docker.EndpointConfig)
+	mode := hostConfig.NetworkMode
+	if mode == "default" {
+		mode = "bridge"
+	}
+	if ep, ok := insp.NetworkSettings.Networks[mode]; ok {
+		endpoints[mode] = &docker.EndpointConfig{Aliases: endpointAliases(ep.Aliases, insp.ID)}
+	}
+	return docker.CreateContainerOptions{
+		Name:             shortName(insp.Name),
+		Config:           &config,
+		HostConfig:       &hostConfig,
+		NetworkingConfig: &docker.NetworkingConfig{EndpointsConfig: endpoints},
+	}
+}
+
+// Return the config of a container without the defaults of its image
+// merged in at creation, so that creating a container from it with an
+// updated image takes the new image's command, environment and labels
+func containerConfig(c, img *docker.Config) docker.Config {
+	config := *c
+	if img == nil {
+		return config
+	}
+	// an entrypoint set on the container resets the image's command
+	if reflect.DeepEqual(config.Entrypoint, img.Entrypoint) {
+		config.Entrypoint = nil
+		if reflect.DeepEqual(config.Cmd, img.Cmd) {
+			config.Cmd = nil
+		}
+	}
+	if config.WorkingDir == img.WorkingDir {
+		config.WorkingDir = ""
+	}
+	if config.User == img.User {
+		config.User = ""
+	}
+	if config.StopSignal == img.StopSignal {
+		config.StopSignal = ""
+	}
+
+	imgEnv := make(map[string]bool, len(img.Env))
+	for _, e := range img.Env {
+		imgEnv[e] = true
+	}
+	config.Env = nil
+	for _, e := range c.Env {
+		if !imgEnv[e] {
+			config.Env = append(config.Env, e)
+		}
+	}
+	config.Labels = make(map[string]string)
+	for k, v := range c.Labels {
+		if iv, ok := img.Labels[k]; !ok || iv != v {
+			config.Labels[k] = v
+		}
+	}
+	config.ExposedPorts = make(map[docker.Port]struct{})
+	for p := range c.ExposedPorts {
+		if _, ok := img.ExposedPorts[p]; !ok {
+			config.ExposedPorts[p] = struct{}{}
+		}
+	}
+	config.Volumes = make(map[string]struct{})
+	for v := range c.Volumes {
+		if _, ok := img.Volumes[v]; !ok {
+			config.Volumes[v] = struct{}{}
+		}
+	}
+	return config
+}
+
+// Return network aliases, without the short container ID docker adds
+func endpointAliases(aliases []string, id string) (list []string) {
+	for _, a := range aliases {
+		if a != id[:12] {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// Writer parsing a raw JSON pull status stream, summing the progress
+// of each layer downloaded and retaining any error reported
+type pullWriter struct {
+	progress func(PullProgress)
+	state    PullProgress
+	layers   map[string]int // index in state.Layers by layer ID
+	buf      []byte
+	err      error
+}
+
+type pullStatus struct {
+	ID             string
+	Status         string
+	Error          string
+	ProgressDetail struct {
+		Current int64
+		Total   int64
+	}
+}
+
+func newPullWriter(progress func(PullProgress)) *pullWriter {
+	return &pullWriter{progress: progress, layers: make(map[string]int)}
+}
+
+func (pw *pullWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		n := bytes.IndexByte(pw.buf, '\n')
+		if n < 0 {
+			break
+		}
+		pw.line(pw.buf[:n])
+		pw.buf = pw.buf[n+1:]
+	}
+	return len(p), nil
+}
+
+func (pw *pullWriter) line(b []byte) {
+	var st pullStatus
+	if json.Unmarshal(b, &st) != nil {
+		return
+	}
+	if st.Error != "" {
+		pw.err = errors.New(st.Error)
+		return
+	}
+	// of lines not about a layer, that naming the repository pulled
+	// from carries the tag as ID
+	if st.ID == "" || strings.HasPrefix(st.Status, "Pulling from") {
+		pw.state.Status = st.Status
+		pw.report()
+		return
+	}
+	n, ok := pw.layers[st.ID]
+	if !ok {
+		n = len(pw.state.Layers)
+		pw.layers[st.ID] = n
+		pw.state.Layers = append(pw.state.Layers, LayerProgress{ID: st.ID})
+	}
+	l := &pw.state.Layers[n]
+	l.Status = st.Status
+	switch st.Status {
+	case "Downloading":
+		l.Current, l.Total = st.ProgressDetail.Current, st.ProgressDetail.Total
+	case "Download complete", "Pull complete", "Already exists":
+		l.Current = l.Total
+	}
+	pw.report()
+}
+
+// Report a copy of the progress so far
+func (pw *pullWriter) report() {
+	if pw.progress == nil {
+		return
+	}
+	p := pw.state
+	p.Layers = append([]LayerProgress(nil), pw.state.Layers...)
+	pw.progress(p)
+}
+
+// Return the error reported in the pull stream, if any
+func (pw *pullWriter) Err() error {
+	if len(pw.buf) > 0 {
+		pw.line(pw.buf)
+		pw.buf = nil
+	}
+	return pw.err
+}
+
+// Return filesystem changes of a container
+func (cm *DockerContainerSource) Changes(id string) (list []FileChange, err error) {
+	changes, err := cm.client.ContainerChanges(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		kind := 'C'
+		switch c.Kind {
+		case docker.ChangeAdd:
+			kind = 'A'
+		case docker.ChangeDelete:
+			kind = 'D'
+		}
+		list = append(list, FileChange{Path: c.Path, Kind: kind})
+	}
+	return list, nil
+}
+
+// Write a tar archive of a container filesystem to w
+func (cm *DockerContainerSource) Export(ctx context.Context, id string, w io.Writer) error {
+	return cm.client.ExportContainer(docker.ExportContainerOptions{
+		ID:           id,
+		OutputStream: w,
+		Context:      ctx,
+	})
+}
+
+// List all containers, adding or updating each from the fields of the
+// listing. Full details are read by inspecting containers on demand,
+// on state changes, and in the background by the back-fill
+func (cm *DockerContainerSource) refreshAll() error {
 	opts := docker.ListContainersOptions{All: true}
 	allContainers, err := cm.client.ListContainers(opts)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	cm.lock.Lock()
+	cm.discovering = make(map[string]bool, len(allContainers))
+	for _, i := range allContainers {
+		cm.discovering[i.ID] = true
 	}
+	cm.listed = len(allContainers)
+	cm.listedAt = time.Now()
+	cm.lock.Unlock()
 
 	for _, i := range allContainers {
-		c := cm.MustGet(i.ID)
-		c.SetMeta("name", shortName(i.Names[0]))
-		c.SetState(i.State)
-		cm.needsRefresh <- c.Id
+		cm.updateListed(i)
+	}
+	return nil
+}
+
+// Update a container from its entry in a container listing: its name,
+// image, state, ports, labels and creation time. Details only read by
+// inspecting are left as they are
+func (cm *DockerContainerSource) updateListed(i docker.APIContainers) {
+	c, ok := cm.add(i.ID)
+	if !ok {
+		return // removed since listed
 	}
+	setName(c, listedName(i.Names), i.Labels)
+	c.SetMeta("image", i.Image)
+	c.SetMeta("ports", listedPorts(i.Ports))
+	c.SetLabels(i.Labels)
+	life := c.Lifecycle()
+	life.Created = time.Unix(i.Created, 0)
+	life.Exited = i.State == "exited" || i.State == "dead"
+	c.SetLifecycle(life)
+	c.SetState(i.State)
 }
 
+// Set the name of a container. Containers run by the kubelet are named
+// by pod and container, keeping the generated name for commands
+// needing it
+func setName(c *Container, name string, labels map[string]string) {
+	c.SetMeta("name", resolveName(name, labels))
+	if resolved := c.GetMeta("name"); resolved != name {
+		c.SetMeta("raw name", name)
+	} else {
+		c.SetMeta("raw name", "")
+	}
+}
+
+// Return the name of a listed container, of its names including those
+// by which linked containers refer to it
+func listedName(names []string) string {
+	for _, n := range names {
+		if n = shortName(n); !strings.Contains(n, "/") {
+			return n
+		}
+	}
+	if len(names) > 0 {
+		return shortName(names[0])
+	}
+	return ""
+}
+
+// Format the ports of a listed container as portsFormat does
+func listedPorts(ports []docker.APIPort) string {
+	var exposed []string
+	var published []string
+	for _, p := range ports {
+		port := fmt.Sprintf("%d/%s", p.PrivatePort, p.Type)
+		if p.PublicPort == 0 {
+			exposed = append(exposed, port)
+			continue
+		}
+		published = append(published, fmt.Sprintf("%s -> %s:%d", port, p.IP, p.PublicPort))
+	}
+	return strings.Join(append(exposed, published...), "\n")
+}
+
+// Inspect containers not inspected since listed, one at a time, running
+// containers first, back-filling their full details. Containers failing
+// to inspect are retried as their backoff elapses
+func (cm *DockerContainerSource) backfill(beat func() bool) {
+	ticker := time.NewTicker(backfillInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cm.done:
+			return
+		case <-ticker.C:
+		}
+		if !beat() {
+			return
+		}
+		if id := cm.nextBackfill(); id != "" {
+			cm.refreshID(id)
+		}
+	}
+}
+
+// Return the ID of a container to back-fill, preferring running ones,
+// else of a failing container due a retry, or an empty string if none
+func (cm *DockerContainerSource) nextBackfill() string {
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	var next string
+	now := time.Now()
+	for id, f := range cm.failing {
+		if _, busy := cm.inflight[id]; !busy && !now.Before(f.retry) {
+			next = id
+		}
+	}
+	for id := range cm.discovering {
+		if _, busy := cm.inflight[id]; busy {
+			continue
+		}
+		c, ok := cm.containers[id]
+		if ok && c.State() == "running" {
+			return id
+		}
+		next = id
+	}
+	return next
+}
+
+// Inspect a container not yet inspected since listed, in the background,
+// as when selected or expanded
+func (cm *DockerContainerSource) WantDetails(id string) {
+	cm.lock.RLock()
+	_, busy := cm.inflight[id]
+	pending := cm.discovering[id] && !busy
+	cm.lock.RUnlock()
+	if pending {
+		safeGo(func() { cm.refreshID(id) })
+	}
+}
+
+// Return the number of containers inspected of those in the last full
+// listing, and the time of the listing
+func (cm *DockerContainerSource) Discovery() (done, total int, started time.Time) {
+	cm.lock.RLock()
+	defer cm.lock.RUnlock()
+	return cm.listed - len(cm.discovering), cm.listed, cm.listedAt
+}
+
+// Refresh queued containers with a pool of workers
 func (cm *DockerContainerSource) Loop() {
-	for id := range cm.needsRefresh {
-		c := cm.MustGet(id)
-		cm.refresh(c)
+	for n := 0; n < cm.workers; n++ {
+		cm.watchdog.Go(fmt.Sprintf("refresh-worker-%d", n), nil, cm.refreshWorker)
 	}
 }
 
-// Get a single container, creating one anew if not existing
-func (cm *DockerContainerSource) MustGet(id string) *Container {
-	c, ok := cm.Get(id)
-	// append container struct for new containers
+func (cm *DockerContainerSource) refreshWorker(beat func() bool) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cm.done:
+			return
+		case <-ticker.C:
+			if !beat() {
+				return
+			}
+		case id := <-cm.needsRefresh:
+			cm.refreshID(id)
+		}
+	}
+}
+
+// Get a single container, creating one anew if not existing. Returns
+// false if the container was recently removed
+func (cm *DockerContainerSource) add(id string) (*Container, bool) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	if _, ok := cm.removed[id]; ok {
+		return nil, false
+	}
+	c, ok := cm.containers[id]
 	if !ok {
-		// create collector
-		collector := metrics.NewDocker(cm.client, id)
-		// create container
-		c = NewContainer(id, collector)
-		cm.lock.Lock()
+		c = NewContainer(id, sourceLabel(cm.Endpoint()), metrics.NewDocker(cm.client, id))
 		cm.containers[id] = c
-		cm.lock.Unlock()
 	}
-	return c
+	return c, true
 }
 
-// Get a single container, by ID
+// Return a container by ID, or by name or a unique prefix of either
 func (cm *DockerContainerSource) Get(id string) (*Container, bool) {
 	cm.lock.Lock()
-	c, ok := cm.containers[id]
-	cm.lock.Unlock()
-	return c, ok
+	defer cm.lock.Unlock()
+	if c, ok := cm.containers[id]; ok {
+		return c, true
+	}
+	list := make(Containers, 0, len(cm.containers))
+	for _, c := range cm.containers {
+		list = append(list, c)
+	}
+	c, err := matchContainer(list, id)
+	return c, err == nil
 }
 
 // Remove containers by ID
 func (cm *DockerContainerSource) delByID(id string) {
 	cm.lock.Lock()
+	c, ok := cm.containers[id]
 	delete(cm.containers, id)
+	delete(cm.eventTimes, id)
+	delete(cm.failing, id)
+	cm.removed[id] = time.Now()
+	for rid, t := range cm.removed {
+		if time.Since(t) > removedTTL {
+			delete(cm.removed, rid)
+		}
+	}
 	cm.lock.Unlock()
+	if ok {
+		c.Remove()
+	}
 	log.Infof("removed dead container: %s", id)
 }
 
@@ -165,11 +1673,21 @@ func (cm *DockerContainerSource) All() (containers Containers) {
 		containers = append(containers, c)
 	}
 	cm.lock.Unlock()
-	sort.Sort(containers)
+	containers.Sort()
 	containers.Filter()
 	return containers
 }
 
+// Return all containers, neither sorted nor filtered for display
+func (cm *DockerContainerSource) Snapshot() (containers Containers) {
+	cm.lock.RLock()
+	for _, c := range cm.containers {
+		containers = append(containers, c)
+	}
+	cm.lock.RUnlock()
+	return containers
+}
+
 // use primary container name
 func shortName(name string) string {
 	return strings.Replace(name, "/", "", 1)
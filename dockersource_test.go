@@ -0,0 +1,177 @@
+// +build !release
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Fake docker daemon serving inspects and stats of the containers
+// currently existing, and 404s for all others
+type fakeDaemon struct {
+	lock sync.Mutex
+	live map[string]bool
+}
+
+func (d *fakeDaemon) create(id string) {
+	d.lock.Lock()
+	d.live[id] = true
+	d.lock.Unlock()
+}
+
+func (d *fakeDaemon) destroy(id string) {
+	d.lock.Lock()
+	delete(d.live, id)
+	d.lock.Unlock()
+}
+
+func (d *fakeDaemon) exists(id string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.live[id]
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "containers" || !d.exists(parts[1]) {
+		http.Error(w, "no such container", http.StatusNotFound)
+		return
+	}
+	id := parts[1]
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+
+	switch parts[2] {
+	case "json":
+		enc.Encode(map[string]interface{}{
+			"Id":              id,
+			"Name":            "/" + id,
+			"State":           map[string]interface{}{"Status": "running", "Running": true, "Pid": 1},
+			"Config":          map[string]interface{}{"Image": "fake"},
+			"HostConfig":      map[string]interface{}{},
+			"NetworkSettings": map[string]interface{}{},
+		})
+	case "stats":
+		for d.exists(id) {
+			if enc.Encode(map[string]interface{}{"read": time.Now()}) != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			if r.URL.Query().Get("stream") == "false" {
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Create and destroy containers rapidly while refreshes of them are
+// in flight, checking that no destroyed container is left listed or
+// collecting
+func TestDockerRemoveDuringRefresh(t *testing.T) {
+	benchInit()
+	daemon := &fakeDaemon{live: make(map[string]bool)}
+	server := httptest.NewServer(daemon)
+	defer server.Close()
+
+	client, err := docker.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := &DockerContainerSource{
+		client:       client,
+		containers:   make(map[string]*Container),
+		needsRefresh: make(chan string, 60),
+		procHost:     &metrics.ProcHost{},
+		done:         make(chan bool),
+		workers:      defaultRefreshWorkers,
+		inflight:     make(map[string]bool),
+		removed:      make(map[string]time.Time),
+		eventTimes:   make(map[string]time.Time),
+		networks:     make(map[string]*docker.Network),
+		images:       make(map[string]imageRef),
+		peers:        make(map[string]peerRef),
+		imageDocs:    make(map[string]*imageInfo),
+		failing:      make(map[string]*inspectFailure),
+	}
+	baseline := metrics.Goroutines()
+
+	const rounds, churn = 20, 25
+	var seen []*Container
+	var seenLock sync.Mutex
+	for round := 0; round < rounds; round++ {
+		var wg sync.WaitGroup
+		for n := 0; n < churn; n++ {
+			id := fmt.Sprintf("c%d-%d", round, n)
+			daemon.create(id)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cm.refreshID(id)
+				if c, ok := cm.Get(id); ok {
+					seenLock.Lock()
+					seen = append(seen, c)
+					seenLock.Unlock()
+				}
+
+				// refreshes racing the destroy, and the destroy event
+				var inner sync.WaitGroup
+				for k := 0; k < 3; k++ {
+					inner.Add(1)
+					go func() {
+						defer inner.Done()
+						cm.refresh(id)
+					}()
+				}
+				daemon.destroy(id)
+				inner.Add(1)
+				go func() {
+					defer inner.Done()
+					cm.delByID(id)
+				}()
+				inner.Wait()
+			}()
+		}
+		wg.Wait()
+	}
+
+	if n := len(cm.All()); n != 0 {
+		t.Errorf("%d destroyed containers still listed", n)
+	}
+	if len(seen) == 0 {
+		t.Fatal("no containers were added")
+	}
+	for _, c := range seen {
+		if !c.Removed() {
+			t.Errorf("container %s not marked removed", c.Id)
+		}
+		if c.collector.Running() {
+			t.Errorf("collector still running for removed container %s", c.Id)
+		}
+	}
+
+	// collector goroutines exit once their streams are closed
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.Goroutines() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := metrics.Goroutines() - baseline; n > 0 {
+		t.Errorf("%d collector goroutines still running", n)
+	}
+}
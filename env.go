@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+)
+
+// Environment of a container as retained: the names of all variables,
+// in order, and the values of only those whitelisted by envShow. The
+// values of other variables are discarded as read, so that no view or
+// export can reveal them
+type containerEnv struct {
+	names  []string
+	values map[string]string
+}
+
+// Return the names of environment variables whose values are shown
+func envShown() map[string]bool {
+	shown := make(map[string]bool)
+	for _, name := range strings.Split(config.GetVal("envShow"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			shown[name] = true
+		}
+	}
+	return shown
+}
+
+// Check a comma separated list of environment variable names
+func validEnvNames(s string) error {
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if strings.ContainsAny(name, "= \t") {
+			return fmt.Errorf("invalid variable name %s", quote(name))
+		}
+	}
+	return nil
+}
+
+// Retain the environment of a container from "KEY=value" entries, as
+// in a docker inspect
+func newContainerEnv(env []string) containerEnv {
+	shown := envShown()
+	e := containerEnv{values: make(map[string]string)}
+	for _, kv := range env {
+		parts := strings.SplitN(kv, "=", 2)
+		e.names = append(e.names, parts[0])
+		if shown[parts[0]] && len(parts) == 2 {
+			e.values[parts[0]] = parts[1]
+		}
+	}
+	return e
+}
+
+// Return the values of whitelisted variables, for templates
+func (e containerEnv) Values() map[string]string {
+	values := make(map[string]string, len(e.values))
+	for k, v := range e.values {
+		values[k] = v
+	}
+	return values
+}
+
+// Describe an environment for the expanded view: the variable count
+// and whitelisted values, or if listed, all variables with the values
+// of others masked
+func (e containerEnv) Detail(list bool) string {
+	if len(e.names) == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("%d variables", len(e.names))
+	if !list {
+		var shown []string
+		for k, v := range e.values {
+			shown = append(shown, k+"="+v)
+		}
+		sort.Strings(shown)
+		for _, kv := range shown {
+			s += "\n" + kv
+		}
+		return s + "\n[v] to list all"
+	}
+	mask := strings.Repeat(string(cwidgets.Glyphs.Dot), 4)
+	for _, name := range e.names {
+		v, ok := e.values[name]
+		if !ok {
+			v = mask
+		}
+		s += "\n" + name + "=" + v
+	}
+	return s
+}
+
+// Return an inspect document with the values of environment variables
+// not whitelisted by envShow masked, for export. Variables are masked
+// wherever found in the document: in lists of "KEY=value" entries, as
+// docker's Config.Env, and of name and value objects, as the container
+// env of kubernetes pod specs
+func redactInspect(doc interface{}) (interface{}, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	redactEnv(v, envShown())
+	return v, nil
+}
+
+// Mask environment values held under any "Env" or "env" key within a
+// decoded JSON value, in place
+func redactEnv(v interface{}, shown map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if list, ok := val.([]interface{}); ok && strings.EqualFold(k, "env") {
+				redactEnvList(list, shown)
+			}
+			redactEnv(val, shown)
+		}
+	case []interface{}:
+		for _, val := range v {
+			redactEnv(val, shown)
+		}
+	}
+}
+
+func redactEnvList(list []interface{}, shown map[string]bool) {
+	mask := strings.Repeat(string(cwidgets.Glyphs.Dot), 4)
+	for n, item := range list {
+		switch item := item.(type) {
+		case string:
+			if name := strings.SplitN(item, "=", 2)[0]; !shown[name] {
+				list[n] = name + "=" + mask
+			}
+		case map[string]interface{}:
+			name, _ := item["name"].(string)
+			if _, ok := item["value"]; ok && !shown[name] {
+				item["value"] = mask
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// +build !release
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+)
+
+// Redact a JSON document, returning it encoded again
+func redactJSON(t *testing.T, doc string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	redacted, err := redactInspect(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// Check that environment values are masked in docker and kubernetes
+// inspect documents unless whitelisted, and nothing else is changed
+func TestRedactInspect(t *testing.T) {
+	benchInit()
+	config.Update("envShow", "APP_VERSION")
+	defer config.Update("envShow", "")
+	mask := strings.Repeat(string(cwidgets.Glyphs.Dot), 4)
+
+	cases := []struct {
+		name, doc, want string
+	}{
+		{
+			"docker",
+			`{"Config":{"Cmd":["A=B"],"Env":["DB_PASSWORD=hunter2","APP_VERSION=1.2","EMPTY=","BARE"]}}`,
+			`{"Config":{"Cmd":["A=B"],"Env":["DB_PASSWORD=` + mask + `","APP_VERSION=1.2","EMPTY=` + mask + `","BARE=` + mask + `"]}}`,
+		},
+		{
+			"kubernetes",
+			`{"spec":{"containers":[{"env":[{"name":"TOKEN","value":"s3cret"},{"name":"APP_VERSION","value":"1.2"},{"name":"REF","valueFrom":{"secretKeyRef":{"key":"k","name":"n"}}}]}],"initContainers":[{"env":[{"name":"TOKEN","value":"s3cret"}]}]}}`,
+			`{"spec":{"containers":[{"env":[{"name":"TOKEN","value":"` + mask + `"},{"name":"APP_VERSION","value":"1.2"},{"name":"REF","valueFrom":{"secretKeyRef":{"key":"k","name":"n"}}}]}],"initContainers":[{"env":[{"name":"TOKEN","value":"` + mask + `"}]}]}}`,
+		},
+		{
+			"no env",
+			`{"Config":{"Labels":{"PASSWORD":"x"}},"Env":"not a list"}`,
+			`{"Config":{"Labels":{"PASSWORD":"x"}},"Env":"not a list"}`,
+		},
+	}
+	for _, tc := range cases {
+		if got := redactJSON(t, tc.doc); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+// Check that the document given is left unmasked
+func TestRedactInspectCopies(t *testing.T) {
+	benchInit()
+	doc := map[string]interface{}{
+		"Config": map[string]interface{}{"Env": []interface{}{"TOKEN=s3cret"}},
+	}
+	if _, err := redactInspect(doc); err != nil {
+		t.Fatal(err)
+	}
+	env := doc["Config"].(map[string]interface{})["Env"].([]interface{})
+	if env[0] != "TOKEN=s3cret" {
+		t.Errorf("original document modified: %v", env[0])
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/widgets/output"
+	ui "github.com/gizak/termui"
+)
+
+const defaultEventHistory = 500
+
+// Container lifecycle event, retained for the event timeline
+type timelineEvent struct {
+	Time   time.Time
+	Source string // label of the container source, as in Container
+	ID     string
+	Name   string // container name at the time of the event
+	Action string
+	Detail string // e.g. exit code, signal or previous name
+}
+
+var (
+	timeline       []timelineEvent
+	timelineCount  uint64 // events recorded, including those dropped
+	timelineLock   sync.Mutex
+	timelineSignal = make(chan bool, 1) // signalled on each new event
+)
+
+// Past tense descriptions of event actions
+var eventVerbs = map[string]string{
+	"create":                   "created",
+	"start":                    "started",
+	"restart":                  "restarted",
+	"stop":                     "stopped",
+	"kill":                     "killed",
+	"die":                      "died",
+	"oom":                      "ran out of memory",
+	"pause":                    "paused",
+	"unpause":                  "unpaused",
+	"rename":                   "renamed",
+	"update":                   "updated",
+	"destroy":                  "removed",
+	"health_status: healthy":   "healthy",
+	"health_status: unhealthy": "unhealthy",
+}
+
+// Actions not recorded, as they are frequent and do not change
+// container state
+var ignoredEvents = []string{"exec_create", "exec_start", "exec_die", "exec_detach", "attach", "detach", "resize", "top", "archive-path", "extract-to-dir", "export", "copy"}
+
+func (e timelineEvent) format(layout string) string {
+	verb, ok := eventVerbs[e.Action]
+	if !ok {
+		verb = e.Action
+	}
+	s := fmt.Sprintf("%s %s %s", e.Time.Local().Format(layout), e.Name, verb)
+	if e.Detail != "" {
+		s += fmt.Sprintf(" (%s)", e.Detail)
+	}
+	return s
+}
+
+func eventColor(action string) ui.Attribute {
+	switch action {
+	case "die", "oom", "kill", "health_status: unhealthy":
+		return ui.ColorRed
+	case "start", "restart", "unpause", "health_status: healthy":
+		return ui.ColorGreen
+	case "stop", "pause":
+		return ui.ColorYellow
+	case "create", "destroy", "rename", "update":
+		return ui.ColorCyan
+	}
+	return ui.ThemeAttr("par.text.fg")
+}
+
+// Return whether an action is recorded in the timeline
+func timelineAction(action string) bool {
+	for _, a := range ignoredEvents {
+		if action == a || strings.HasPrefix(action, a+":") {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the configured number of events retained
+func eventHistory() int {
+	n, err := strconv.Atoi(config.GetVal("eventHistory"))
+	if err != nil || n < 1 {
+		return defaultEventHistory
+	}
+	return n
+}
+
+// Add an event to the timeline, dropping the oldest beyond the
+// configured length
+func recordEvent(e timelineEvent) {
+	timelineLock.Lock()
+	timeline = append(timeline, e)
+	if over := len(timeline) - eventHistory(); over > 0 {
+		timeline = append(timeline[:0], timeline[over:]...)
+	}
+	timelineCount++
+	timelineLock.Unlock()
+
+	select {
+	case timelineSignal <- true:
+	default:
+	}
+}
+
+// Return retained events, oldest first, and the number of
+// events recorded
+func timelineEvents() ([]timelineEvent, uint64) {
+	timelineLock.Lock()
+	defer timelineLock.Unlock()
+	return append([]timelineEvent{}, timeline...), timelineCount
+}
+
+// Load retained events into the view, only those of the given
+// container if not nil
+func updateEventView(v *output.View, c *Container) {
+	events, _ := timelineEvents()
+	var lines []string
+	var colors []ui.Attribute
+	for _, e := range events {
+		if c != nil && (e.Source != c.Source || e.ID != c.Id) {
+			continue
+		}
+		lines = append(lines, e.format("15:04:05"))
+		colors = append(colors, eventColor(e.Action))
+	}
+	if len(lines) == 0 {
+		lines = []string{"no events recorded"}
+		colors = []ui.Attribute{ui.ThemeAttr("par.text.fg")}
+	}
+	v.SetLines(lines, colors)
+}
+
+// Modes of the event pane, cycled with t
+const (
+	eventPaneHidden = iota
+	eventPaneAll
+	eventPaneSelected // events of the selected container only
+)
+
+var (
+	eventPane     *output.View // created when first shown
+	eventPaneMode = eventPaneHidden
+)
+
+// Cycle the event pane between hidden, showing all events and
+// showing those of the selected container
+func toggleEventPane() {
+	eventPaneMode = (eventPaneMode + 1) % 3
+	switch eventPaneMode {
+	case eventPaneHidden:
+		footer.Flash("event pane hidden", 2*time.Second)
+	case eventPaneAll:
+		footer.Flash("showing all events", 2*time.Second)
+	case eventPaneSelected:
+		footer.Flash("showing events of the selected container", 2*time.Second)
+	}
+	RedrawRows(true)
+}
+
+// Return the height of the event pane, or 0 if hidden
+func eventPaneHeight() int {
+	if eventPaneMode == eventPaneHidden {
+		return 0
+	}
+	h := ui.TermHeight() / 3
+	if h < 5 {
+		h = 5
+	}
+	return h
+}
+
+// Position the event pane above the given number of lines at the
+// bottom of the screen and load events into it
+func alignEventPane(bottom int) {
+	if eventPane == nil {
+		eventPane = output.NewView()
+	}
+	eventPane.X, eventPane.Width = 0, ui.TermWidth()
+	eventPane.Height = eventPaneHeight()
+	eventPane.Y = ui.TermHeight() - bottom - eventPane.Height
+	loadEventPane()
+}
+
+// Load retained events into the event pane, following new events
+// while scrolled to the bottom
+func loadEventPane() {
+	if eventPaneMode != eventPaneSelected {
+		eventPane.Title = "events"
+		updateEventView(eventPane, nil)
+		return
+	}
+	c := cursor.Selected()
+	if c == nil {
+		eventPane.Title = "events"
+		eventPane.SetLines([]string{"no container selected"}, nil)
+		return
+	}
+	eventPane.Title = "events: " + c.GetMeta("name")
+	updateEventView(eventPane, c)
+}
+
+// Show the events of the newly selected container, if the event
+// pane shows those of the selected container
+func eventPaneFollow() {
+	if eventPaneMode == eventPaneSelected {
+		loadEventPane()
+		ui.Render(eventPane)
+	}
+}
+
+// Print container events to stdout as they arrive, without the UI,
+// until interrupted
+func StreamEvents() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	var last uint64
+	for {
+		select {
+		case <-sigs:
+			return
+		case <-timelineSignal:
+		}
+		events, count := timelineEvents()
+		// print events recorded since the last, of those retained
+		n := int(count - last)
+		if n > len(events) {
+			n = len(events)
+		}
+		for _, e := range events[len(events)-n:] {
+			fmt.Println(e.format(streamTimeFormat))
+		}
+		last = count
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+	ui "github.com/gizak/termui"
+)
+
+// Filesystem exports in progress, by container key
+var exports = struct {
+	sync.Mutex
+	cancel map[string]context.CancelFunc
+}{cancel: make(map[string]context.CancelFunc)}
+
+// Writer counting bytes written
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	atomic.AddInt64(&cw.n, int64(n))
+	return n, err
+}
+
+func (cw *countWriter) Written() int64 { return atomic.LoadInt64(&cw.n) }
+
+// Prompt for an export mode and destination for the selected container
+func ExportMenu() {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	name := c.GetMeta("name")
+
+	footer.Flash("export: [j]son inspect data, [t]ar filesystem archive", time.Minute)
+	ui.Render(footer)
+
+	var mode string
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		mode = strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		ui.StopLoop()
+	})
+	ui.Loop()
+	footer.Hide()
+
+	switch mode {
+	case "j":
+		path := fmt.Sprintf("./%s-inspect.json", name)
+		if promptInput("Export inspect JSON to", path, nil, func(s string) error {
+			path = s
+			return checkExportPath(s)
+		}) {
+			goTask(func() { exportInspect(c, path) })
+		}
+	case "t":
+		path := fmt.Sprintf("./%s.tar", name)
+		if promptInput("Export filesystem to", path, nil, func(s string) error {
+			path = s
+			return checkExportPath(s)
+		}) {
+			goTask(func() { exportFilesystem(c, path) })
+		}
+	}
+}
+
+// Ensure an export path is given and does not already exist
+func checkExportPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("no path given")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	return nil
+}
+
+func exportInspect(c *Container, path string) {
+	name := c.GetMeta("name")
+	doc, err := cursor.Source().Inspect(c.Id)
+	if err == nil {
+		doc, err = redactInspect(doc)
+	}
+	if err != nil {
+		log.NotifyError("failed to inspect %s: %s", name, err)
+		return
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(path, b, 0644)
+	}
+	if err != nil {
+		log.NotifyError("failed to export %s: %s", name, err)
+		return
+	}
+	log.Notify("exported inspect data of %s to %s", name, path)
+}
+
+// Stream a container filesystem archive to path, reporting progress in
+// the footer until complete or cancelled
+func exportFilesystem(c *Container, path string) {
+	name := c.GetMeta("name")
+
+	exports.Lock()
+	if _, ok := exports.cancel[c.Key()]; ok {
+		exports.Unlock()
+		log.Notify("export of %s already in progress", name)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exports.cancel[c.Key()] = cancel
+	exports.Unlock()
+
+	defer func() {
+		exports.Lock()
+		delete(exports.cancel, c.Key())
+		exports.Unlock()
+		cancel()
+	}()
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.NotifyError("failed to export %s: %s", name, err)
+		return
+	}
+	cw := &countWriter{w: f}
+
+	done := make(chan error, 1)
+	safeGo(func() { done <- cursor.Source().Export(ctx, c.Id, cw) })
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+wait:
+	for {
+		select {
+		case <-ticker.C:
+			footer.Flash(fmt.Sprintf("exporting %s: %s written (X to cancel)", name, cwidgets.ByteFormat(cw.Written())), 2*time.Second)
+		case err = <-done:
+			break wait
+		}
+	}
+	f.Close()
+
+	switch {
+	case ctx.Err() != nil:
+		os.Remove(path)
+		log.Notify("export of %s cancelled", name)
+	case err != nil:
+		os.Remove(path)
+		log.NotifyError("failed to export %s: %s", name, err)
+	default:
+		log.Notify("exported filesystem of %s to %s (%s)", name, path, cwidgets.ByteFormat(cw.Written()))
+	}
+}
+
+// Cancel all filesystem exports in progress
+func cancelExports() {
+	exports.Lock()
+	defer exports.Unlock()
+	for _, cancel := range exports.cancel {
+		cancel()
+	}
+}
@@ -0,0 +1,49 @@
+// +build !release
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bcicen/ctop/metrics"
+)
+
+func TestUserFilter(t *testing.T) {
+	benchInit()
+	users := []string{"root", "0", "", "1000:1000", "nobody", "www-data:www-data", "rootless"}
+	containers := make(map[string]*Container)
+	for n, user := range users {
+		c := NewContainer(fmt.Sprintf("%012x", n), "test", metrics.NewMock(1))
+		c.SetMeta("name", fmt.Sprintf("c%d", n))
+		c.SetMeta("user", user)
+		containers[user] = c
+	}
+
+	cases := []struct {
+		filter string
+		want   []string // users matched
+	}{
+		{"user:root", []string{"root", "rootless"}},
+		{"user:^root$", []string{"root"}},
+		{"user:^(root|0)(:|$)", []string{"root", "0"}},
+		{"user:1000", []string{"1000:1000"}},
+		{"user::www-data$", []string{"www-data:www-data"}},
+		{"user:ROOT", nil},
+		{"user:^$", []string{""}},
+		{"user:o c4", []string{"nobody"}},
+	}
+	for _, tc := range cases {
+		f, err := parseFilter(tc.filter)
+		if err != nil {
+			t.Errorf("%q: %s", tc.filter, err)
+			continue
+		}
+		for _, user := range users {
+			want := known(tc.want, user)
+			if got := f.match(containers[user]); got != want {
+				t.Errorf("%q matching user %q = %v, want %v", tc.filter, user, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	forwardTimeout    = 2 * time.Second
+	forwardRetries    = 3     // failed attempts before a batch is dropped
+	forwardMaxPending = 10000 // samples held for retry, oldest dropped first
+	statsdPacketSize  = 1432  // keep datagrams within a typical MTU
+)
+
+var (
+	forwarders     []*forwarder
+	metricUnsafeRe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// Metrics read from a single container at a point in time
+type sample struct {
+	name   string
+	image  string
+	time   time.Time
+	fields []field
+}
+
+type field struct {
+	name  string
+	value int64
+}
+
+// Build a sample from a container history entry, omitting metrics
+// not yet read
+func newSample(c *Container, s Sample) sample {
+	smp := sample{
+		name:  c.GetMeta("name"),
+		image: c.GetMeta("image"),
+		time:  s.Time,
+	}
+	add := func(name string, v int64) {
+		if v >= 0 {
+			smp.fields = append(smp.fields, field{name, v})
+		}
+	}
+	add("cpu_percent", int64(s.CPUUtil))
+	add("mem_bytes", s.MemUsage)
+	add("mem_limit_bytes", s.MemLimit)
+	add("mem_percent", int64(s.MemPercent))
+	add("net_rx_bytes", s.NetRx)
+	add("net_tx_bytes", s.NetTx)
+	add("io_read_bytes", s.IOBytesRead)
+	add("io_write_bytes", s.IOBytesWrite)
+	add("pids", int64(s.Pids))
+	return smp
+}
+
+// Destination for metric samples; a protocol writes a batch of
+// samples at once, returning the number of leading samples sent
+// should it fail part way
+type metricSink interface {
+	Write([]sample) (int, error)
+	Close()
+}
+
+// Pushes new container samples to a sink at each refresh interval.
+// Batches that fail to send are retried on following intervals,
+// then dropped and counted, so a slow or failing destination never
+// holds up the UI
+type forwarder struct {
+	name     string
+	sink     metricSink
+	last     map[string]time.Time // time of last sample collected, by container key
+	pending  []sample
+	attempts int
+	dropped  uint64
+	stop     chan bool
+}
+
+// Start forwarding container metrics to a sink until stopForwarders
+// is called
+func startForwarder(name string, sink metricSink) {
+	f := &forwarder{
+		name: name,
+		sink: sink,
+		last: make(map[string]time.Time),
+		stop: make(chan bool),
+	}
+	forwarders = append(forwarders, f)
+	safeGo(f.loop)
+	log.Noticef("forwarding metrics via %s", name)
+}
+
+func stopForwarders() {
+	for _, f := range forwarders {
+		close(f.stop)
+	}
+	forwarders = nil
+}
+
+func (f *forwarder) loop() {
+	defer f.sink.Close()
+	for {
+		// read each time, following changes to the refresh interval
+		select {
+		case <-f.stop:
+			return
+		case <-time.After(refreshInterval()):
+			f.collect()
+			f.push()
+		}
+	}
+}
+
+func (f *forwarder) push() {
+	if len(f.pending) == 0 {
+		return
+	}
+	n, err := f.sink.Write(f.pending)
+	// samples sent are not sent again, if the rest are to be retried
+	f.pending = f.pending[n:]
+	if n > 0 {
+		f.attempts = 0
+	}
+	if err == nil {
+		return
+	}
+	f.attempts++
+	log.Debugf("%s: failed to send %d samples (attempt %d): %s", f.name, len(f.pending), f.attempts, err)
+	if f.attempts >= forwardRetries {
+		f.drop(len(f.pending))
+		f.attempts = 0
+	}
+}
+
+// Drop the n oldest pending samples
+func (f *forwarder) drop(n int) {
+	f.pending = f.pending[n:]
+	total := atomic.AddUint64(&f.dropped, uint64(n))
+	log.Debugf("%s: dropped %d samples (%d total)", f.name, n, total)
+}
+
+// Queue samples read since the last collection for all running
+// containers. Only the latest sample is taken from a newly seen
+// container
+func (f *forwarder) collect() {
+	seen := make(map[string]bool)
+	for _, c := range cursor.Source().All() {
+		if c.State() != "running" {
+			continue
+		}
+		hist := c.History()
+		if len(hist) == 0 {
+			continue
+		}
+		seen[c.Key()] = true
+
+		last, ok := f.last[c.Key()]
+		if !ok {
+			hist = hist[len(hist)-1:]
+		}
+		for _, s := range hist {
+			if s.Time.After(last) {
+				f.pending = append(f.pending, newSample(c, s))
+			}
+		}
+		f.last[c.Key()] = hist[len(hist)-1].Time
+	}
+
+	for id := range f.last {
+		if !seen[id] {
+			delete(f.last, id)
+		}
+	}
+
+	if n := len(f.pending) - forwardMaxPending; n > 0 {
+		f.drop(n)
+	}
+}
+
+// Replace characters not valid within a single metric path segment,
+// including dots and slashes
+func sanitizeMetricPath(s string) string {
+	return metricUnsafeRe.ReplaceAllString(s, "_")
+}
+
+// Sanitize each segment of a dotted metric prefix, e.g. ctop.prod-1,
+// dropping empty segments
+func sanitizeMetricPrefix(s string) string {
+	var segs []string
+	for _, seg := range strings.Split(s, ".") {
+		if seg = sanitizeMetricPath(seg); seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+	return strings.Join(segs, ".")
+}
+
+// Metric path for a sample field, e.g. ctop.web.cpu_percent
+func metricPath(prefix string, s sample, f field) string {
+	return fmt.Sprintf("%s.%s.%s", prefix, sanitizeMetricPath(s.name), f.name)
+}
+
+// StatsD gauges over UDP
+type statsdSink struct {
+	prefix string
+	conn   net.Conn
+}
+
+func newStatsdSink(addr, prefix string) (*statsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{sanitizeMetricPrefix(prefix), conn}, nil
+}
+
+func (s *statsdSink) Write(samples []sample) (int, error) {
+	var buf bytes.Buffer
+	// samples sent, and those with all lines in buf; a sample split
+	// over datagrams is sent again whole, as gauges may be repeated
+	var sent, buffered int
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write(buf.Bytes())
+		buf.Reset()
+		if err == nil {
+			sent = buffered
+		}
+		return err
+	}
+	for n, smp := range samples {
+		for _, f := range smp.fields {
+			line := fmt.Sprintf("%s:%d|g\n", metricPath(s.prefix, smp, f), f.value)
+			if buf.Len()+len(line) > statsdPacketSize {
+				if err := flush(); err != nil {
+					return sent, err
+				}
+			}
+			buf.WriteString(line)
+		}
+		buffered = n + 1
+	}
+	if err := flush(); err != nil {
+		return sent, err
+	}
+	return len(samples), nil
+}
+
+func (s *statsdSink) Close() { s.conn.Close() }
+
+// Graphite plaintext protocol over TCP, reconnecting as needed
+type graphiteSink struct {
+	addr   string
+	prefix string
+	conn   net.Conn
+}
+
+func newGraphiteSink(addr, prefix string) (*graphiteSink, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, err
+	}
+	return &graphiteSink{addr: addr, prefix: sanitizeMetricPrefix(prefix)}, nil
+}
+
+func (s *graphiteSink) Write(samples []sample) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.addr, forwardTimeout)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+
+	var buf bytes.Buffer
+	ends := make([]int, len(samples)) // offset of the end of each sample
+	for n, smp := range samples {
+		for _, f := range smp.fields {
+			fmt.Fprintf(&buf, "%s %d %d\n", metricPath(s.prefix, smp, f), f.value, smp.time.Unix())
+		}
+		ends[n] = buf.Len()
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(forwardTimeout))
+	written, err := s.conn.Write(buf.Bytes())
+	if err != nil {
+		s.Close()
+		sent := 0
+		for sent < len(ends) && ends[sent] <= written {
+			sent++
+		}
+		return sent, err
+	}
+	return len(samples), nil
+}
+
+func (s *graphiteSink) Close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
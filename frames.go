@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Number of mock containers to run the UI against, set by the
+// hidden -loadtest flag of non-release builds
+var loadTestCount int
+
+// Frame draw times, recorded only under -loadtest
+var frames *frameStats
+
+type frameStats struct {
+	times []time.Duration
+	lock  sync.Mutex
+}
+
+// Record the time taken by a frame begun at start
+func (fs *frameStats) since(start time.Time) {
+	d := time.Since(start)
+	fs.lock.Lock()
+	fs.times = append(fs.times, d)
+	fs.lock.Unlock()
+}
+
+// Return the frame time at percentile p, from 0 to 100
+func (fs *frameStats) percentile(p float64) time.Duration {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+	if len(fs.times) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, fs.times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := int(p / 100 * float64(len(sorted)-1))
+	return sorted[n]
+}
+
+// Print the frame count and frame time percentiles
+func (fs *frameStats) print(w io.Writer) {
+	fs.lock.Lock()
+	count := len(fs.times)
+	fs.lock.Unlock()
+	fmt.Fprintf(w, "%d containers, %d frames\n", loadTestCount, count)
+	for _, p := range []float64{50, 90, 99, 100} {
+		fmt.Fprintf(w, "p%-3.0f %s\n", p, fs.percentile(p))
+	}
+}
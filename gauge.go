@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets/compact"
+)
+
+// Apply the configured gauge metric to the gauge column. Sorting by
+// the column follows it to the metric now shown
+func applyGaugeMetric() {
+	prev, cur := compact.ApplyGaugeMetric()
+	if prev != cur && config.GetVal("sortField") == prev {
+		config.Update("sortField", cur)
+	}
+}
+
+// Show the next of the gauge metrics in the gauge column
+func cycleGaugeMetric() {
+	names := compact.GaugeMetrics
+	next := names[0]
+	for n, s := range names {
+		if s == config.GetVal("gaugeMetric") && n+1 < len(names) {
+			next = names[n+1]
+		}
+	}
+	config.Update("gaugeMetric", next)
+	applyGaugeMetric()
+	footer.Flash("gauge column showing "+next, 2*time.Second)
+}
@@ -1,27 +1,47 @@
 package main
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/cwidgets/compact"
 	"github.com/bcicen/ctop/cwidgets/expanded"
+	"github.com/bcicen/ctop/logging"
 	ui "github.com/gizak/termui"
 )
 
 func RedrawRows(clr bool) {
-	// reinit body rows
-	cGrid.Clear()
+	defer selfRenderTime.since(time.Now())
+	if frames != nil {
+		defer frames.since(time.Now())
+	}
 
 	// build layout
 	y := 1
 	if config.GetSwitchVal("enableHeader") {
-		header.SetCount(cursor.Len())
+		header.ShowSummary(config.GetSwitchVal("enableSummary"))
+		if config.GetSwitchVal("enableSummary") {
+			updateSummary()
+		}
+		header.SetCount(len(cursor.filtered), cursor.Total())
 		header.SetFilter(config.GetVal("filterStr"))
+		header.SetColumns(compact.ColumnWindow(cGrid.Width))
 		y += header.Height()
 	}
 	cGrid.SetY(y)
-
-	for _, c := range cursor.filtered {
-		cGrid.AddRows(c.Widgets)
+	// keep the footer line free while shown, and room for the event pane
+	bottom := 0
+	if footer.Active() {
+		bottom = footer.Height
+	}
+	if eventPaneMode != eventPaneHidden {
+		alignEventPane(bottom)
+		bottom += eventPane.Height
 	}
+	cGrid.SetBottom(bottom)
+	buildRows()
 
 	if clr {
 		ui.Clear()
@@ -32,56 +52,307 @@ func RedrawRows(clr bool) {
 	}
 	cGrid.Align()
 	ui.Render(cGrid)
+	if eventPaneMode != eventPaneHidden {
+		ui.Render(eventPane)
+	}
+	if banner.Active() {
+		ui.Render(banner)
+	}
+	if footer.Active() {
+		ui.Render(footer)
+	}
+}
+
+// Reinit grid body rows from the cursor rows
+func buildRows() {
+	cGrid.Clear()
+	cGrid.SetEmpty(emptyStatus())
+	stale := banner.Active()
+	for n, c := range cursor.rows {
+		c.Widgets().SetStale(stale)
+		c.Widgets().SetDivider(n == cursor.pinned-1)
+		c.Widgets().SetSince(containerStateAge(c))
+		c.Widgets().SetCPUTime(containerCPUTime(c))
+		c.Widgets().SetWatched(isWatched(c.Key()))
+		cGrid.AddRows(c.Widgets())
+	}
 }
 
 func ExpandView(c *Container) {
-	ui.Clear()
-	ui.DefaultEvtStream.ResetHandlers()
 	defer ui.DefaultEvtStream.ResetHandlers()
 
+	wantDetails(c)
 	ex := expanded.NewExpanded(c.Id)
+	ex.SetHistory(graphHistory(c), historyLen(), refreshInterval())
 	c.SetUpdater(ex)
+	ex.SetMeta("uptime", containerUptime(c))
+	ex.SetMeta("cpu time", cpuTimeDetail(c))
+	ex.SetMeta("state", containerStateDetail(c))
+	ex.SetMeta("alerts", firingRules(c.Key()))
+	ex.SetMeta("image shared", imageSharing(c))
+	ex.SetMeta("tmpfs", tmpfsDetail(c))
+	// variables other than whitelisted ones are listed on request only
+	var listEnv bool
+	ex.SetMeta("env", c.Env().Detail(listEnv))
+
+	// last output of a stopped container is read once per run
+	output := make(chan []string, 1)
+	if c.Lifecycle().Exited {
+		if lines, ok := cachedOutput(c); ok {
+			ex.SetOutput(lines)
+		} else {
+			goTask(func() {
+				if lines, ok := readLastOutput(c); ok {
+					output <- lines
+				}
+			})
+		}
+	}
 
-	ex.Align()
-	ui.Render(ex)
+	for {
+		var policy, logs bool
+
+		ui.Clear()
+		ui.DefaultEvtStream.ResetHandlers()
+		ex.Align()
+		ui.Render(ex)
+
+		HandleKeys("up", ex.Up)
+		HandleKeys("down", ex.Down)
+		ui.Handle("/sys/kbd/", func(ui.Event) { ui.StopLoop() })
+		ui.Handle("/sys/kbd/p", func(ui.Event) {
+			if checkWritable("restart policy") {
+				policy = true
+				ui.StopLoop()
+			}
+		})
+		ui.Handle("/sys/kbd/l", func(ui.Event) {
+			logs = true
+			ui.StopLoop()
+		})
+		for n := range expanded.Windows {
+			n := n
+			ui.Handle(fmt.Sprintf("/sys/kbd/%d", n+1), func(ui.Event) {
+				if err := ex.SetWindow(n); err != nil {
+					footer.Flash(err.Error(), 3*time.Second)
+					ui.Render(footer)
+					return
+				}
+				ui.Render(ex)
+			})
+		}
+		ui.Handle("/sys/kbd/v", func(ui.Event) {
+			listEnv = !listEnv
+			ex.SetMeta("env", c.Env().Detail(listEnv))
+			ui.Clear()
+			ex.Align()
+			ui.Render(ex)
+		})
+		ui.Handle("/sys/kbd/a", func(ui.Event) {
+			ex.ToggleScale()
+			ui.Render(ex)
+		})
+		ui.Handle("/sys/kbd/c", func(ui.Event) {
+			ex.ToggleCrosshair()
+			ui.Render(ex)
+		})
+		ui.Handle("/sys/kbd/<left>", func(ui.Event) {
+			ex.MoveCrosshair(1)
+			ui.Render(ex)
+		})
+		ui.Handle("/sys/kbd/<right>", func(ui.Event) {
+			ex.MoveCrosshair(-1)
+			ui.Render(ex)
+		})
 
-	HandleKeys("up", ex.Up)
-	HandleKeys("down", ex.Down)
+		ui.Handle("/timer/refresh", func(ui.Event) {
+			ex.SetMeta("uptime", containerUptime(c))
+			ex.SetMeta("cpu time", cpuTimeDetail(c))
+			ex.SetMeta("state", containerStateDetail(c))
+			ex.SetMeta("alerts", firingRules(c.Key()))
+			ex.SetMeta("image shared", imageSharing(c))
+			ex.SetMeta("tmpfs", tmpfsDetail(c))
+			ex.SetMeta("env", c.Env().Detail(listEnv))
+			select {
+			case lines := <-output:
+				ex.SetOutput(lines)
+				ui.Clear()
+				ex.Align()
+			default:
+			}
+			ui.Render(ex)
+			if footer.Active() {
+				ui.Render(footer)
+			}
+		})
+		ui.Handle("/sys/wnd/resize", func(e ui.Event) {
+			ex.SetWidth(ui.TermWidth())
+			ex.Align()
+			log.Infof("resize: width=%v max-rows=%v", ex.Width, cGrid.MaxRows())
+		})
+
+		ui.Loop()
+		if policy {
+			RestartPolicyMenu(c)
+			continue
+		}
+		if !logs {
+			break
+		}
+		ContainerLogView(c)
+	}
+	c.SetUpdater(c.Widgets())
+}
+
+// Return the metric history of a container for expanded view graphs
+func graphHistory(c *Container) (samples []expanded.Sample) {
+	for _, s := range c.History() {
+		samples = append(samples, expanded.Sample{s.Time, s.Metrics})
+	}
+	return samples
+}
+
+// Key of container marked as compare target, if any
+var compareTarget string
+
+// Mark the given container as compare target, returning
+// the previously marked container if one exists
+func markCompare(c *Container) *Container {
+	if compareTarget == c.Key() {
+		compareTarget = ""
+		footer.Flash("compare target cleared", 3*time.Second)
+		RedrawRows(false)
+		return nil
+	}
+	if target, ok := cursor.lookup(compareTarget); ok {
+		return target
+	}
+	compareTarget = c.Key()
+	footer.Flash(fmt.Sprintf("compare target: %s", c.GetMeta("name")), 3*time.Second)
+	RedrawRows(false)
+	return nil
+}
+
+func CompareView(c1, c2 *Container) {
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+	defer func() { compareTarget = "" }()
+
+	cmp := expanded.NewCompare(c1.Id, c2.Id)
+	cmp.Left.SetHistory(graphHistory(c1), historyLen(), refreshInterval())
+	cmp.Right.SetHistory(graphHistory(c2), historyLen(), refreshInterval())
+	c1.SetUpdater(cmp.Left)
+	c2.SetUpdater(cmp.Right)
+
+	cmp.Align()
+	ui.Render(cmp)
+
+	HandleKeys("up", cmp.Up)
+	HandleKeys("down", cmp.Down)
 	ui.Handle("/sys/kbd/", func(ui.Event) { ui.StopLoop() })
 
-	ui.Handle("/timer/1s", func(ui.Event) { ui.Render(ex) })
+	ui.Handle("/timer/refresh", func(ui.Event) { ui.Render(cmp) })
 	ui.Handle("/sys/wnd/resize", func(e ui.Event) {
-		ex.SetWidth(ui.TermWidth())
-		ex.Align()
-		log.Infof("resize: width=%v max-rows=%v", ex.Width, cGrid.MaxRows())
+		ui.Clear()
+		cmp.SetWidth(ui.TermWidth())
+		cmp.Align()
+		log.Infof("resize: width=%v", cmp.Width)
 	})
 
 	ui.Loop()
-	c.SetUpdater(c.Widgets)
+	c1.SetUpdater(c1.Widgets())
+	c2.SetUpdater(c2.Widgets())
+}
+
+// Return a connection status message if the container
+// source is disconnected, or an empty string otherwise
+func connStatus() string {
+	lost := cursor.Source().LostSince()
+	if lost.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("Docker connection lost — data stale since %s, retrying…", lost.Format("15:04:05"))
+}
+
+// Return a message describing why no containers are
+// displayed, or an empty string if the grid is not empty
+func emptyStatus() string {
+	if cursor.Len() > 0 {
+		return ""
+	}
+	if !cursor.Source().LostSince().IsZero() {
+		return "no container data available — docker connection lost"
+	}
+	if p := discoveryProgress(); p != "" && cursor.Total() == 0 {
+		return fmt.Sprintf("connected to docker, discovering containers%c %s", cwidgets.Glyphs.Ellipsis, p)
+	}
+
+	all := cursor.Source().All()
+	var candidates int
+	for _, c := range all {
+		if !config.GetSwitchVal("showSandboxes") && isSandbox(c.Labels()) {
+			continue
+		}
+		if config.GetSwitchVal("allContainers") || c.State() == "running" {
+			candidates++
+		}
+	}
+
+	if filter := config.GetVal("filterStr"); filter != "" && candidates > 0 {
+		return fmt.Sprintf("0 of %d containers match filter '%s' — press f to edit, Esc to clear", candidates, filter)
+	}
+	if len(all) > 0 {
+		return fmt.Sprintf("no running containers (%d stopped) — press a to show all", len(all))
+	}
+	return fmt.Sprintf("no containers found on %s", cursor.Source().Endpoint())
 }
 
 func RefreshDisplay() {
+	applyProfileSource()
 	needsClear := cursor.RefreshContainers()
+	wantDetails(cursor.Selected())
+	if footer.Expired() {
+		needsClear = true
+	}
+	if banner.Set(connStatus()) {
+		needsClear = true
+	}
+	if updateFooterStatus() {
+		needsClear = true
+	}
 	RedrawRows(needsClear)
+	// written once rendered, so as not to interleave with drawing
+	ringBell()
+	updateTitle()
 }
 
 func Display() bool {
 	var menu func()
 	var expand bool
+	var compare *Container
+	var inspect bool
+	var diff bool
 
 	cGrid.SetWidth(ui.TermWidth())
-	ui.DefaultEvtStream.Hook(logEvent)
+	ui.DefaultEvtStream.Hook(func(e ui.Event) {
+		logEvent(e)
+		suspendKey(e)
+	})
+	updateFooterStatus()
 
 	// initial draw
 	header.Align()
+	footer.Align()
+	banner.Align()
 	cursor.RefreshContainers()
 	RedrawRows(true)
 
-	HandleKeys("up", cursor.Up)
-	HandleKeys("down", cursor.Down)
+	HandleKeys("up", func() { cursor.Up(); eventPaneFollow() })
+	HandleKeys("down", func() { cursor.Down(); eventPaneFollow() })
 
-	HandleKeys("pgup", cursor.PgUp)
-	HandleKeys("pgdown", cursor.PgDown)
+	HandleKeys("pgup", func() { cursor.PgUp(); eventPaneFollow() })
+	HandleKeys("pgdown", func() { cursor.PgDown(); eventPaneFollow() })
 
 	HandleKeys("exit", ui.StopLoop)
 	HandleKeys("help", func() {
@@ -90,38 +361,282 @@ func Display() bool {
 	})
 
 	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		if cursor.ToggleGroup() {
+			RefreshDisplay()
+			return
+		}
 		expand = true
 		ui.StopLoop()
 	})
 	ui.Handle("/sys/kbd/a", func(ui.Event) {
 		config.Toggle("allContainers")
+		if config.GetSwitchVal("allContainers") {
+			footer.Flash("showing all containers", 2*time.Second)
+		} else {
+			footer.Flash("showing running containers only", 2*time.Second)
+		}
+		RefreshDisplay()
+	})
+	ui.Handle("/sys/kbd/c", func(ui.Event) {
+		if c := cursor.Selected(); c != nil {
+			compare = markCompare(c)
+			if compare != nil {
+				ui.StopLoop()
+			}
+		}
+	})
+	ui.Handle("/sys/kbd/p", func(ui.Event) {
+		if c := cursor.Selected(); c != nil {
+			togglePin(c)
+			RefreshDisplay()
+		}
+	})
+	ui.Handle("/sys/kbd/u", func(ui.Event) {
+		clearPins()
 		RefreshDisplay()
 	})
 	ui.Handle("/sys/kbd/D", func(ui.Event) {
-		dumpContainer(cursor.Selected())
+		if c := cursor.Selected(); c != nil {
+			dumpContainer(c)
+		}
+	})
+	// esc clears the filter if set, and otherwise exits as bound by HandleKeys
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		if config.GetVal("filterStr") == "" {
+			ui.StopLoop()
+			return
+		}
+		config.Update("filterStr", "")
+		RefreshDisplay()
+	})
+	ui.Handle("/sys/kbd/C", func(ui.Event) {
+		menu = CommitMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/K", func(ui.Event) {
+		menu = StopAllMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/B", func(ui.Event) {
+		menu = RestartAllMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/P", func(ui.Event) {
+		menu = PruneMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/L", func(ui.Event) {
+		menu = LimitsMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/R", func(ui.Event) {
+		menu = RenameMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/U", func(ui.Event) {
+		menu = RecreateMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/M", func(ui.Event) {
+		menu = StopRemoveMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/z", func(ui.Event) {
+		togglePause()
+		RefreshDisplay()
+	})
+	ui.Handle("/sys/kbd/I", func(ui.Event) {
+		menu = PullMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/A", func(ui.Event) {
+		menu = func() { AttachView(cursor.Selected()) }
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/o", func(ui.Event) {
+		menu = BrowseMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/d", func(ui.Event) {
+		diff = true
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/|", func(ui.Event) {
+		menu = PipeMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/:", func(ui.Event) {
+		menu = CommandMenu
+		ui.StopLoop()
+	})
+	if rs, ok := unwrapSource(cursor.Source()).(*ReplaySource); ok {
+		handleReplayKeys(rs)
+	}
+	ui.Handle("/sys/kbd/Z", func(ui.Event) {
+		switch err := saveConfig(); err {
+		case nil:
+		case config.ErrNoChanges:
+			log.Notify("%s, not creating %s", err, configPath)
+			return
+		default:
+			log.NotifyError("failed to save config: %s", err)
+			return
+		}
+		log.Notify("saved settings to %s", configPath)
+	})
+	ui.Handle("/sys/kbd/T", func(ui.Event) {
+		menu = SettingsMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/O", func(ui.Event) {
+		menu = ProfileMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/W", func(ui.Event) {
+		toggleWatch()
+	})
+	ui.Handle("/sys/kbd/E", func(ui.Event) {
+		menu = CSVMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/e", func(ui.Event) {
+		menu = ExportMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/b", func(ui.Event) {
+		menu = BundleMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/X", func(ui.Event) {
+		cancelExports()
+	})
+	ui.Handle("/sys/kbd/y", func(ui.Event) {
+		menu = CopyMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/i", func(ui.Event) {
+		inspect = true
+		ui.StopLoop()
 	})
 	ui.Handle("/sys/kbd/f", func(ui.Event) {
 		menu = FilterMenu
 		ui.StopLoop()
 	})
+	ui.Handle("/sys/kbd/N", func(ui.Event) {
+		menu = NotificationMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/V", func(ui.Event) {
+		menu = LogView
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/t", func(ui.Event) {
+		toggleEventPane()
+	})
+	ui.Handle("/sys/kbd/l", func(ui.Event) {
+		if c := cursor.Selected(); c != nil {
+			menu = func() { ContainerLogView(c) }
+			ui.StopLoop()
+		}
+	})
+	ui.Handle("/sys/kbd/x", func(ui.Event) {
+		logging.DismissNotifications()
+		RefreshDisplay()
+	})
+	ui.Handle("/sys/kbd/g", func(ui.Event) {
+		config.Toggle("groupByImage")
+		if config.GetSwitchVal("groupByImage") {
+			footer.Flash("grouping containers by image", 2*time.Second)
+		} else {
+			footer.Flash("showing containers ungrouped", 2*time.Second)
+		}
+		RefreshDisplay()
+	})
+	ui.Handle("/sys/kbd/G", func(ui.Event) {
+		menu = SummaryLabelMenu
+		ui.StopLoop()
+	})
 	ui.Handle("/sys/kbd/H", func(ui.Event) {
 		config.Toggle("enableHeader")
 		RedrawRows(true)
 	})
+	ui.Handle("/sys/kbd/w", func(ui.Event) {
+		config.Toggle("wideMode")
+		compact.ResetLayout()
+		RedrawRows(true)
+	})
+	ui.Handle("/sys/kbd/m", func(ui.Event) {
+		cycleGaugeMetric()
+		RedrawRows(true)
+	})
+	ui.Handle("/sys/kbd/<left>", func(ui.Event) {
+		compact.ScrollColumns(-1)
+		RedrawRows(true)
+	})
+	ui.Handle("/sys/kbd/<right>", func(ui.Event) {
+		compact.ScrollColumns(1)
+		RedrawRows(true)
+	})
+	ui.Handle("/sys/kbd/S", func(ui.Event) {
+		config.Toggle("enableSummary")
+		RedrawRows(true)
+	})
 	ui.Handle("/sys/kbd/r", func(e ui.Event) {
 		config.Toggle("sortReversed")
 	})
+	ui.Handle("/sys/kbd/C-r", func(ui.Event) {
+		refreshSelected()
+	})
 	ui.Handle("/sys/kbd/s", func(ui.Event) {
 		menu = SortMenu
 		ui.StopLoop()
 	})
+	ui.Handle("/sys/kbd/<tab>", func(ui.Event) {
+		menu = HeaderSortMenu
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/'", func(ui.Event) {
+		menu = LetterJumpMenu
+		ui.StopLoop()
+	})
+	for _, d := range "0123456789" {
+		prefix := string(d)
+		ui.Handle("/sys/kbd/"+prefix, func(ui.Event) {
+			menu = func() { RowJumpMenu(prefix) }
+			ui.StopLoop()
+		})
+	}
+	ui.Handle("/sys/kbd/+", func(ui.Event) {
+		stepRefresh(-1)
+	})
+	ui.Handle("/sys/kbd/-", func(ui.Event) {
+		stepRefresh(1)
+	})
 
-	ui.Handle("/timer/1s", func(e ui.Event) {
+	ui.Handle("/timer/refresh", func(e ui.Event) {
 		RefreshDisplay()
 	})
 
+	handleActions(func(a *config.Action) {
+		if !checkWritable("action " + a.Name) {
+			return
+		}
+		if a.Detach {
+			RunAction(a, cursor.Selected())
+			return
+		}
+		menu = func() { RunAction(a, cursor.Selected()) }
+		ui.StopLoop()
+	})
+
+	if readOnly() {
+		disableMutatingKeys()
+	}
+
 	ui.Handle("/sys/wnd/resize", func(e ui.Event) {
 		header.Align()
+		footer.Align()
+		banner.Align()
 		cursor.ScrollPage()
 		cGrid.SetWidth(ui.TermWidth())
 		log.Infof("resize: width=%v max-rows=%v", cGrid.Width, cGrid.MaxRows())
@@ -133,6 +648,27 @@ func Display() bool {
 		menu()
 		return false
 	}
+	if compare != nil {
+		c := cursor.Selected()
+		if c != nil {
+			CompareView(compare, c)
+		}
+		return false
+	}
+	if diff {
+		c := cursor.Selected()
+		if c != nil {
+			ChangesView(c)
+		}
+		return false
+	}
+	if inspect {
+		c := cursor.Selected()
+		if c != nil {
+			InspectView(c)
+		}
+		return false
+	}
 	if expand {
 		c := cursor.Selected()
 		if c != nil {
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/metrics"
+)
+
+// Value grouping containers whose label is unset
+const noLabelValue = "(none)"
+
+// Containers sharing an image or label value, displayed under a
+// header row showing the container count and aggregate metrics
+type containerGroup struct {
+	value      string     // image or label value shared
+	header     *Container // pseudo-container drawn as the header row
+	containers Containers
+}
+
+func newContainerGroup(id, value string, byImage bool) *containerGroup {
+	header := NewContainer(id, "", nil)
+	header.Widgets().Cid.Set("-")
+	if byImage {
+		header.SetMeta("image", value)
+	}
+	return &containerGroup{value: value, header: header}
+}
+
+// Return the prefix of group header row IDs in the configured
+// grouping, by label value if a summary label is set, else by image
+// if enabled, or an empty string if not grouped. Prefixes distinguish
+// header rows from containers, and groups of different groupings
+func groupPrefix() string {
+	if key := config.GetVal("summaryLabel"); key != "" {
+		return "label:" + key + "="
+	}
+	if config.GetSwitchVal("groupByImage") {
+		return "image:"
+	}
+	return ""
+}
+
+// Ensure a label key, if any, may be summarized by
+func validLabelKey(key string) error {
+	if strings.ContainsAny(key, "= \t") {
+		return fmt.Errorf("invalid label key: %s", key)
+	}
+	return nil
+}
+
+// Return the ID of the header row of the group a container belongs
+// to, and the value it is grouped by
+func groupOf(c *Container, prefix string) (id, value string) {
+	if key := strings.TrimPrefix(prefix, "label:"); key != prefix {
+		value = c.Labels()[strings.TrimSuffix(key, "=")]
+		if value == "" {
+			value = noLabelValue
+		}
+	} else {
+		value = c.GetMeta("image")
+	}
+	return prefix + value, value
+}
+
+// Update the header row from the group's containers
+func (g *containerGroup) update(expanded bool) {
+	m := metrics.NewMetrics()
+	state := ""
+	for _, c := range g.containers {
+		cm := c.Metrics()
+		m.CPUUtil = sumInt(m.CPUUtil, cm.CPUUtil)
+		m.CPUTime = time.Duration(sumInt64(int64(m.CPUTime), int64(cm.CPUTime)))
+		m.MemUsage = sumInt64(m.MemUsage, cm.MemUsage)
+		m.MemLimit = sumInt64(m.MemLimit, cm.MemLimit)
+		m.NetRx = sumInt64(m.NetRx, cm.NetRx)
+		m.NetTx = sumInt64(m.NetTx, cm.NetTx)
+		m.IOBytesRead = sumInt64(m.IOBytesRead, cm.IOBytesRead)
+		m.IOBytesWrite = sumInt64(m.IOBytesWrite, cm.IOBytesWrite)
+		m.Pids = sumInt(m.Pids, cm.Pids)
+		// report the most active state of any container
+		if s := c.State(); state == "" || stateMap[s] > stateMap[state] {
+			state = s
+		}
+	}
+	if m.MemUsage >= 0 && m.MemLimit > 0 {
+		m.MemPercent = int(float64(m.MemUsage) / float64(m.MemLimit) * 100)
+	}
+
+	marker := "+"
+	if expanded {
+		marker = "-"
+	}
+	// name is set to the value grouped by for sorting, and labelled
+	// for display
+	g.header.SetMeta("name", g.value)
+	g.header.SetMeta("state", state)
+	g.header.Widgets().Name.Set(fmt.Sprintf("%s %s (%d)", marker, g.value, len(g.containers)))
+	g.header.setMetrics(m)
+	g.header.Widgets().SetMetrics(m)
+}
+
+// Group containers by the configured grouping, returning group
+// header rows sorted by their aggregate metrics, each followed by its
+// containers if expanded. Containers keep their sorted order within
+// a group
+func (gc *GridCursor) groupRows(prefix string, list Containers) Containers {
+	groups := make(map[string]*containerGroup)
+	var headers Containers
+	for _, c := range list {
+		id, value := groupOf(c, prefix)
+		g, ok := groups[id]
+		if !ok {
+			// reuse header rows from the previous refresh
+			if g, ok = gc.groups[id]; !ok {
+				g = newContainerGroup(id, value, prefix == "image:")
+			}
+			g.containers = Containers{}
+			groups[id] = g
+			headers = append(headers, g.header)
+		}
+		g.containers = append(g.containers, c)
+	}
+	gc.groups = groups
+
+	for id, g := range groups {
+		g.update(gc.expanded[id])
+	}
+	less, _ := configuredSort()
+	sort.Sort(sortable{headers, less})
+
+	rows := make(Containers, 0, len(headers)+len(list))
+	for _, h := range headers {
+		rows = append(rows, h)
+		if gc.expanded[h.Id] {
+			rows = append(rows, groups[h.Id].containers...)
+		}
+	}
+	return rows
+}
+
+// Carry the cursor across a change of grouping. Entering a grouping
+// expands the group of the selected container; leaving one selects the
+// first container of a selected group
+func (gc *GridCursor) switchGrouping(prefix string) {
+	if g, ok := gc.groups[gc.selectedKey]; ok && len(g.containers) > 0 {
+		g.header.Widgets().Name.UnHighlight()
+		gc.selectedKey = g.containers[0].Key()
+		g.containers[0].Widgets().Name.Highlight()
+	}
+	gc.groups = nil
+	if prefix == "" {
+		return
+	}
+	if c, ok := gc.lookup(gc.selectedKey); ok {
+		id, _ := groupOf(c, prefix)
+		gc.expanded[id] = true
+	}
+}
+
+// Container source whose rows may have child rows, such as the
+// containers of a pod, listed below their parent when expanded
+type parentSource interface {
+	Children(id string) Containers
+}
+
+// Insert the child rows of expanded parents after each parent,
+// labelling parents with their child count
+func (gc *GridCursor) nestChildren(ps parentSource, list Containers) Containers {
+	rows := make(Containers, 0, len(list))
+	for _, c := range list {
+		rows = append(rows, c)
+		children := ps.Children(c.Id)
+		if len(children) == 0 {
+			continue
+		}
+		marker := "+"
+		if gc.expanded[c.Key()] {
+			marker = "-"
+		}
+		c.Widgets().Name.Set(fmt.Sprintf("%s %s (%d)", marker, c.GetMeta("name"), len(children)))
+		if !gc.expanded[c.Key()] {
+			continue
+		}
+		for _, child := range children {
+			child.Widgets().Name.Set("  " + child.GetMeta("name"))
+		}
+		rows = append(rows, children...)
+	}
+	return rows
+}
+
+// Return the group whose header row is selected, if any
+func (gc *GridCursor) SelectedGroup() *containerGroup {
+	return gc.groups[gc.selectedKey]
+}
+
+// Expand or collapse the selected group or parent row, returning
+// false if neither is selected
+func (gc *GridCursor) ToggleGroup() bool {
+	if g := gc.SelectedGroup(); g != nil {
+		gc.expanded[g.header.Id] = !gc.expanded[g.header.Id]
+		return true
+	}
+	c := gc.Selected()
+	if ps, ok := unwrapSource(gc.Source()).(parentSource); ok && c != nil && len(ps.Children(c.Id)) > 0 {
+		gc.expanded[c.Key()] = !gc.expanded[c.Key()]
+		return true
+	}
+	return false
+}
+
+// Sum two metric values, either of which may be unread (negative)
+func sumInt(a, b int) int {
+	if b < 0 {
+		return a
+	}
+	if a < 0 {
+		return b
+	}
+	return a + b
+}
+
+func sumInt64(a, b int64) int64 {
+	if b < 0 {
+		return a
+	}
+	if a < 0 {
+		return b
+	}
+	return a + b
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+)
+
+const (
+	hookWorkers  = 2                // hook commands run concurrently
+	hookQueueLen = 64               // hook runs waiting for a worker, beyond which they are dropped
+	hookTimeout  = 30 * time.Second // before a hook command is killed
+	hookOutput   = 200              // bytes of hook output included in notifications
+)
+
+// Container fields available to hook templates, in addition to those
+// of custom actions
+type HookContext struct {
+	ActionContext
+	Event    string
+	ExitCode int               // exit code of the container, for die events
+	Meta     map[string]string // container metadata, as shown in the expanded view
+}
+
+// Event hook command, rendered and queued for a worker
+type hookRun struct {
+	hook *config.Hook
+	name string // container name
+	cmd  string
+}
+
+var (
+	hookQueue    = make(chan hookRun, hookQueueLen)
+	hookStart    sync.Once
+	hookDropped  int64 // runs dropped as the queue was full, since last reported
+	hookDryRun   bool  // notify hook commands rather than run them
+	hookRunTimes = struct {
+		sync.Mutex
+		runs map[*config.Hook][]time.Time // runs within the last minute, by hook
+	}{runs: make(map[*config.Hook][]time.Time)}
+)
+
+// Queue the hooks configured for a container event, never blocking
+// the caller. Runs beyond a hook's rate limit are skipped, and runs
+// beyond the queue length dropped and counted
+func runHooks(c *Container, event string, exitCode int) {
+	var ctx *HookContext
+	for _, h := range config.GlobalHooks {
+		if h.Event != event || !hookAllowed(h) {
+			continue
+		}
+		if ctx == nil {
+			ctx = &HookContext{
+				ActionContext: newActionContext(c),
+				Event:         event,
+				ExitCode:      exitCode,
+				Meta:          c.MetaCopy(),
+			}
+		}
+		cmd, err := h.Render(ctx)
+		if err != nil {
+			log.NotifyError("hook %s: %s", h.Key(), err)
+			continue
+		}
+		hookStart.Do(startHookWorkers)
+		select {
+		case hookQueue <- hookRun{h, c.GetMeta("name"), cmd}:
+		default:
+			atomic.AddInt64(&hookDropped, 1)
+		}
+	}
+}
+
+// Return whether a hook is within its rate limit, recording a run if so
+func hookAllowed(h *config.Hook) bool {
+	limit, _ := strconv.Atoi(config.GetVal("hookRate"))
+	now := time.Now()
+
+	hookRunTimes.Lock()
+	defer hookRunTimes.Unlock()
+	var recent []time.Time
+	for _, t := range hookRunTimes.runs[h] {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		hookRunTimes.runs[h] = recent
+		log.Warningf("hook %s: rate limit of %d runs per minute reached, skipping", h.Key(), limit)
+		return false
+	}
+	hookRunTimes.runs[h] = append(recent, now)
+	return true
+}
+
+func startHookWorkers() {
+	for i := 0; i < hookWorkers; i++ {
+		safeGo(hookWorker)
+	}
+}
+
+func hookWorker() {
+	for run := range hookQueue {
+		if n := atomic.SwapInt64(&hookDropped, 0); n > 0 {
+			log.NotifyError("dropped %d event hook runs, too many events at once", n)
+		}
+		runHook(run)
+	}
+}
+
+// Run a hook command, logging its output and notifying its exit status
+func runHook(run hookRun) {
+	key := run.hook.Key()
+	if hookDryRun {
+		log.Notify("hook %s for %s would run: %s", key, run.name, run.cmd)
+		return
+	}
+	log.Infof("running hook %s for %s: %s", key, run.name, run.cmd)
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "/bin/sh", "-c", run.cmd).CombinedOutput()
+	log.Debugf("hook %s output: %s", key, out)
+
+	switch err := err.(type) {
+	case nil:
+		log.Notify("hook %s for %s exited with status 0", key, run.name)
+	case *exec.ExitError:
+		log.NotifyError("hook %s for %s %s%s", key, run.name, err, hookOutputSummary(out))
+	default:
+		log.NotifyError("hook %s for %s failed: %s", key, run.name, err)
+	}
+}
+
+// Return the start of hook output, for notifications of failures
+func hookOutputSummary(out []byte) string {
+	s := strings.TrimSpace(string(out))
+	if s == "" {
+		return ""
+	}
+	if len(s) > hookOutput {
+		s = s[:hookOutput] + "..."
+	}
+	return fmt.Sprintf(": %s", strings.Replace(s, "\n", " ", -1))
+}
+
+// ensure all hook templates render against an empty container
+func validHooks() error {
+	ctx := &HookContext{
+		ActionContext: ActionContext{Labels: make(map[string]string)},
+		Meta:          make(map[string]string),
+	}
+	for _, h := range config.GlobalHooks {
+		if _, err := h.Render(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bcicen/ctop/cwidgets"
+)
+
+// Update header host summary with host and aggregate container usage
+func updateSummary() {
+	h := cursor.Source().Host()
+
+	memPercent := -1
+	var memLabel string
+	if h.MemUsage >= 0 && h.MemTotal > 0 {
+		memPercent = int(h.MemUsage * 100 / h.MemTotal)
+		memLabel = fmt.Sprintf("%s / %s", cwidgets.ByteFormat(h.MemUsage), cwidgets.ByteFormat(h.MemTotal))
+	}
+	header.SetHost(h.CPUUtil, memPercent, memLabel)
+
+	var cpu int
+	var mem int64
+	for _, c := range cursor.Source().All() {
+		m := c.Metrics()
+		if m.CPUUtil > 0 {
+			cpu += m.CPUUtil
+		}
+		if m.MemUsage > 0 {
+			mem += m.MemUsage
+		}
+	}
+
+	s := fmt.Sprintf("containers: cpu %d%%", cpu)
+	if h.NCPU > 0 {
+		s += fmt.Sprintf(" (%d%% of host)", cpu/h.NCPU)
+	}
+	s += fmt.Sprintf(", mem %s", cwidgets.ByteFormat(mem))
+	if h.MemTotal > 0 {
+		s += fmt.Sprintf(" (%d%% of host)", mem*100/h.MemTotal)
+	}
+	if h.NCPU > 0 {
+		s += fmt.Sprintf("  |  host: %d cpus, %s", h.NCPU, cwidgets.ByteFormat(h.MemTotal))
+	}
+	header.SetSummary(s)
+}
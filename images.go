@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Size of a local image, inspected once however many containers
+// were created from it
+type imageInfo struct {
+	once    sync.Once
+	size    int64 // bytes, all layers included
+	virtual int64 // bytes, as reported by daemons distinguishing it
+	err     error
+}
+
+// Return the size of an image by ID, from cache if inspected since
+// the last image event for it. Failed inspects are not cached
+func (cm *DockerContainerSource) imageInfo(id string) *imageInfo {
+	cm.lock.Lock()
+	info, ok := cm.imageDocs[id]
+	if !ok {
+		info = &imageInfo{}
+		cm.imageDocs[id] = info
+	}
+	cm.lock.Unlock()
+
+	info.once.Do(func() {
+		img, err := cm.client.InspectImage(id)
+		if err != nil {
+			info.err = err
+			return
+		}
+		info.size, info.virtual = img.Size, img.VirtualSize
+	})
+	if info.err != nil {
+		cm.lock.Lock()
+		if cm.imageDocs[id] == info {
+			delete(cm.imageDocs, id)
+		}
+		cm.lock.Unlock()
+	}
+	return info
+}
+
+// Set the image size of a container, formatted for the expanded view
+// and in bytes for the image size column
+func (cm *DockerContainerSource) setImageSize(c *Container, id string) {
+	if id == "" {
+		return
+	}
+	info := cm.imageInfo(id)
+	if info.err != nil {
+		log.Debugf("failed to inspect image %s: %s", shortImageID(id), info.err)
+		return
+	}
+	size := cwidgets.ByteFormat(info.size)
+	if info.virtual > info.size {
+		size += fmt.Sprintf(" (virtual %s)", cwidgets.ByteFormat(info.virtual))
+	}
+	c.SetMeta("image size", size)
+	c.SetMeta("image bytes", strconv.FormatInt(info.size, 10))
+}
+
+// Drop an image from cache on its removal, untagging or retagging,
+// refreshing the containers created from it
+func (cm *DockerContainerSource) imageEvent(e *docker.APIEvents) {
+	switch e.Action {
+	case "delete", "untag", "tag", "import", "load", "pull":
+	default:
+		return
+	}
+	log.Debugf("handling docker image event: action=%s image=%s", e.Action, e.Actor.ID)
+	cm.lock.Lock()
+	_, cached := cm.imageDocs[e.Actor.ID]
+	delete(cm.imageDocs, e.Actor.ID)
+	var ids []string
+	if cached {
+		for id, c := range cm.containers {
+			if c.Lifecycle().ImageID == e.Actor.ID {
+				ids = append(ids, id)
+			}
+		}
+	}
+	cm.lock.Unlock()
+	for _, id := range ids {
+		cm.queueRefresh(id)
+	}
+}
+
+// Describe how many other containers share the image of a container,
+// to tell whether removing it would free its space
+func imageSharing(c *Container) string {
+	id := c.Lifecycle().ImageID
+	if id == "" {
+		return ""
+	}
+	var n int
+	for _, other := range cursor.Source().All() {
+		if other != c && other.Lifecycle().ImageID == id {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return "used by no other container"
+	case 1:
+		return "shared by 1 other container"
+	}
+	return fmt.Sprintf("shared by %d other containers", n)
+}
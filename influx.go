@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const influxMeasurement = "ctop"
+
+var influxTagEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+
+// Encode samples as InfluxDB line protocol, tagged by container
+// name, image and host, with nanosecond timestamps
+func influxLines(samples []sample, host string) []byte {
+	var buf bytes.Buffer
+	for _, s := range samples {
+		if len(s.fields) == 0 {
+			continue
+		}
+		buf.WriteString(influxMeasurement)
+		writeInfluxTag(&buf, "container", s.name)
+		writeInfluxTag(&buf, "image", s.image)
+		writeInfluxTag(&buf, "host", host)
+		for n, f := range s.fields {
+			sep := ","
+			if n == 0 {
+				sep = " "
+			}
+			fmt.Fprintf(&buf, "%s%s=%di", sep, f.name, f.value)
+		}
+		fmt.Fprintf(&buf, " %d\n", s.time.UnixNano())
+	}
+	return buf.Bytes()
+}
+
+// Empty tag values are not permitted and are omitted
+func writeInfluxTag(buf *bytes.Buffer, k, v string) {
+	if v == "" {
+		return
+	}
+	fmt.Fprintf(buf, ",%s=%s", k, influxTagEscaper.Replace(v))
+}
+
+// Host tag value: the remote daemon host, if any, or the local hostname
+func influxHost() string {
+	if host := daemonHost(); host != "" {
+		return host
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// Line protocol appended to a local file
+type influxFileSink struct {
+	f    *os.File
+	host string
+}
+
+func newInfluxFileSink(path string) (*influxFileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &influxFileSink{f, influxHost()}, nil
+}
+
+func (s *influxFileSink) Write(samples []sample) (int, error) {
+	if _, err := s.f.Write(influxLines(samples, s.host)); err != nil {
+		return 0, err
+	}
+	return len(samples), nil
+}
+
+func (s *influxFileSink) Close() { s.f.Close() }
+
+// Line protocol written to the InfluxDB v2 HTTP API
+type influxHTTPSink struct {
+	url    string
+	token  string
+	host   string
+	client *http.Client
+}
+
+func newInfluxHTTPSink(base, token, org, bucket string) (*influxHTTPSink, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme: %s", base)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("a bucket is required")
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/api/v2/write"
+	q := url.Values{}
+	q.Set("org", org)
+	q.Set("bucket", bucket)
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+	return &influxHTTPSink{
+		url:    u.String(),
+		token:  token,
+		host:   influxHost(),
+		client: &http.Client{Timeout: forwardTimeout},
+	}, nil
+}
+
+func (s *influxHTTPSink) Write(samples []sample) (int, error) {
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(influxLines(samples, s.host)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Token "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return len(samples), nil
+}
+
+func (s *influxHTTPSink) Close() {}
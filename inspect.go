@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/widgets/inspector"
+	ui "github.com/gizak/termui"
+)
+
+// Fetch the current inspect document for a container into the viewer,
+// with environment values masked as for exports
+func loadInspect(v *inspector.Viewer, c *Container) {
+	doc, err := cursor.Source().Inspect(c.Id)
+	if err == nil {
+		doc, err = redactInspect(doc)
+	}
+	if err == nil {
+		err = v.Load(doc)
+	}
+	if err != nil {
+		log.NotifyError("failed to inspect %s: %s", c.GetMeta("name"), err)
+	}
+}
+
+func alignInspect(v *inspector.Viewer) {
+	v.X, v.Y = 0, 0
+	v.Width = ui.TermWidth()
+	v.Height = ui.TermHeight() - 1
+	footer.Align()
+}
+
+func renderInspect(v *inspector.Viewer) {
+	if footer.Expired() {
+		ui.Clear()
+	}
+	ui.Render(v)
+	if footer.Active() {
+		ui.Render(footer)
+	}
+}
+
+// Browse the full inspect document of a container
+func InspectView(c *Container) {
+	v := inspector.NewViewer()
+	v.BorderLabel = fmt.Sprintf(" inspect: %s ", c.GetMeta("name"))
+	alignInspect(v)
+	loadInspect(v, c)
+
+	var query string
+	for {
+		var search bool
+
+		ui.Clear()
+		ui.DefaultEvtStream.ResetHandlers()
+		renderInspect(v)
+
+		HandleKeys("up", v.Up)
+		HandleKeys("down", v.Down)
+		HandleKeys("pgup", v.PgUp)
+		HandleKeys("pgdown", v.PgDown)
+		HandleKeys("exit", ui.StopLoop)
+
+		ui.Handle("/sys/kbd/<enter>", func(ui.Event) { v.Toggle() })
+		ui.Handle("/sys/kbd/<space>", func(ui.Event) { v.Toggle() })
+		ui.Handle("/sys/kbd//", func(ui.Event) {
+			search = true
+			ui.StopLoop()
+		})
+		ui.Handle("/sys/kbd/n", func(ui.Event) {
+			if query != "" && !v.Search(query) {
+				footer.Flash(fmt.Sprintf("no keys matching '%s'", query), 2*time.Second)
+			}
+			renderInspect(v)
+		})
+		ui.Handle("/sys/kbd/r", func(ui.Event) {
+			loadInspect(v, c)
+			footer.Flash("inspect document refreshed", 2*time.Second)
+			renderInspect(v)
+		})
+		ui.Handle("/sys/kbd/y", func(ui.Event) {
+			path, val := v.Selected()
+			if path == "" {
+				return
+			}
+			copyValue(path, val)
+			renderInspect(v)
+		})
+
+		ui.Handle("/timer/refresh", func(ui.Event) { renderInspect(v) })
+		ui.Handle("/sys/wnd/resize", func(ui.Event) {
+			ui.Clear()
+			alignInspect(v)
+			renderInspect(v)
+		})
+
+		ui.Loop()
+		if !search {
+			break
+		}
+		query = inspectSearch(v, query)
+	}
+
+	ui.DefaultEvtStream.ResetHandlers()
+	footer.Hide()
+}
+
+// Read a search query in the footer, jumping to the first matching
+// key on enter. Returns the query entered
+func inspectSearch(v *inspector.Viewer, query string) string {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	prev := query
+	update := func() {
+		footer.Flash(fmt.Sprintf("search keys: %s", query), time.Minute)
+		ui.Render(footer)
+	}
+	update()
+
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		key := strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		switch {
+		case key == "C-8" && len(query) > 0:
+			query = query[:len(query)-1]
+		case key == "<space>":
+			query += " "
+		case len(key) == 1:
+			query += key
+		}
+		update()
+	})
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		query = prev
+		footer.Hide()
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		footer.Hide()
+		if query != "" && !v.Search(query) {
+			footer.Flash(fmt.Sprintf("no keys matching '%s'", query), 2*time.Second)
+		}
+		ui.StopLoop()
+	})
+	ui.Loop()
+	return query
+}
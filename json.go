@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+)
+
+// JSON output document; field names are documented in _docs/json.md
+// and must remain stable
+type jsonSnapshot struct {
+	Timestamp  time.Time        `json:"timestamp"`
+	Containers []*jsonContainer `json:"containers"`
+}
+
+type jsonContainer struct {
+	ID           string       `json:"id"`
+	Source       string       `json:"source"`
+	Name         string       `json:"name"`
+	Image        string       `json:"image"`
+	State        string       `json:"state"`
+	Health       *string      `json:"health"`
+	Created      *string      `json:"created"`    // in the configured timeFormat
+	Uptime       *string      `json:"uptime"`     // in the configured durationStyle
+	CreatedAt    *string      `json:"created_at"` // RFC 3339, as are other times
+	StartedAt    *string      `json:"started_at"`
+	FinishedAt   *string      `json:"finished_at"`
+	ExitCode     *int         `json:"exit_code"` // null unless exited
+	RestartCount int          `json:"restart_count"`
+	ImageID      *string      `json:"image_id"`
+	Metrics      *jsonMetrics `json:"metrics"` // null if not running
+}
+
+// Current metric values, null if not yet read
+type jsonMetrics struct {
+	CPUPercent    *int64   `json:"cpu_percent"`
+	CPUSeconds    *float64 `json:"cpu_seconds"` // cumulative since the container started
+	MemUsageBytes *int64   `json:"mem_usage_bytes"`
+	MemLimitBytes *int64   `json:"mem_limit_bytes"`
+	MemPercent    *int64   `json:"mem_percent"`
+	NetRxBytes    *int64   `json:"net_rx_bytes"`
+	NetTxBytes    *int64   `json:"net_tx_bytes"`
+	IOReadBytes   *int64   `json:"io_read_bytes"`
+	IOWriteBytes  *int64   `json:"io_write_bytes"`
+	Pids          *int64   `json:"pids"`
+}
+
+// Return nil for unread(negative) metric values
+func metricVal(n int64) *int64 {
+	if n < 0 {
+		return nil
+	}
+	return &n
+}
+
+// Return a duration in seconds, or nil if unread(negative)
+func secondsVal(d time.Duration) *float64 {
+	if d < 0 {
+		return nil
+	}
+	s := d.Seconds()
+	return &s
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func newJSONContainer(c *Container) *jsonContainer {
+	life := c.Lifecycle()
+	jc := &jsonContainer{
+		ID:           c.Id,
+		Source:       c.Source,
+		Name:         c.GetMeta("name"),
+		Image:        c.GetMeta("image"),
+		State:        c.State(),
+		Health:       optString(c.GetMeta("health")),
+		Created:      optString(c.GetMeta("created")),
+		Uptime:       optString(containerUptime(c)),
+		CreatedAt:    optString(exportTime(life.Created)),
+		StartedAt:    optString(exportTime(life.Started)),
+		FinishedAt:   optString(exportTime(life.Finished)),
+		RestartCount: life.RestartCount,
+		ImageID:      optString(life.ImageID),
+	}
+	if life.Exited {
+		jc.ExitCode = &life.ExitCode
+	}
+	if jc.State != "running" {
+		return jc
+	}
+	jc.Metrics = newJSONMetrics(c.Metrics())
+	return jc
+}
+
+func newJSONMetrics(m metrics.Metrics) *jsonMetrics {
+	return &jsonMetrics{
+		CPUPercent:    metricVal(int64(m.CPUUtil)),
+		CPUSeconds:    secondsVal(m.CPUTime),
+		MemUsageBytes: metricVal(m.MemUsage),
+		MemLimitBytes: metricVal(m.MemLimit),
+		MemPercent:    metricVal(int64(m.MemPercent)),
+		NetRxBytes:    metricVal(m.NetRx),
+		NetTxBytes:    metricVal(m.NetTx),
+		IOReadBytes:   metricVal(m.IOBytesRead),
+		IOWriteBytes:  metricVal(m.IOBytesWrite),
+		Pids:          metricVal(int64(m.Pids)),
+	}
+}
+
+// Encode all displayed containers as JSON, indented if pretty is set
+func jsonStats(pretty bool) ([]byte, error) {
+	snap := jsonSnapshot{
+		Timestamp:  time.Now(),
+		Containers: []*jsonContainer{},
+	}
+	for _, c := range cursor.filtered {
+		snap.Containers = append(snap.Containers, newJSONContainer(c))
+	}
+	if pretty {
+		return json.MarshalIndent(snap, "", "  ")
+	}
+	return json.Marshal(snap)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Service account credentials mounted into pods
+const (
+	serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// Server address and credentials for a kubelet or API server
+type kubeAuth struct {
+	server string
+	token  string
+	tls    *tls.Config
+}
+
+// Fields of a kubeconfig file used to connect to a cluster
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string      `yaml:"name"`
+		Cluster kubeCluster `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string   `yaml:"name"`
+		User kubeUser `yaml:"user"`
+	} `yaml:"users"`
+}
+
+type kubeCluster struct {
+	Server                   string `yaml:"server"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+	CertificateAuthority     string `yaml:"certificate-authority"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data"`
+}
+
+type kubeUser struct {
+	Token                 string      `yaml:"token"`
+	TokenFile             string      `yaml:"tokenFile"`
+	ClientCertificate     string      `yaml:"client-certificate"`
+	ClientCertificateData string      `yaml:"client-certificate-data"`
+	ClientKey             string      `yaml:"client-key"`
+	ClientKeyData         string      `yaml:"client-key-data"`
+	Exec                  interface{} `yaml:"exec"`
+	AuthProvider          interface{} `yaml:"auth-provider"`
+}
+
+// Load the current context of a kubeconfig file. Clusters may give a
+// CA as a file or inline data; users a bearer token or token file, or
+// a client certificate and key as files or inline data. Exec and auth
+// provider plugins are not supported
+func loadKubeconfig(path string) (*kubeAuth, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if kc.CurrentContext == "" {
+		return nil, fmt.Errorf("%s: no current-context set", path)
+	}
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == kc.CurrentContext {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: context %s not found", path, kc.CurrentContext)
+	}
+	var cluster *kubeCluster
+	for i := range kc.Clusters {
+		if kc.Clusters[i].Name == clusterName {
+			cluster = &kc.Clusters[i].Cluster
+			break
+		}
+	}
+	if cluster == nil {
+		return nil, fmt.Errorf("%s: cluster %s not found", path, clusterName)
+	}
+	user := &kubeUser{}
+	for i := range kc.Users {
+		if kc.Users[i].Name == userName {
+			user = &kc.Users[i].User
+			break
+		}
+	}
+	if user.Exec != nil || user.AuthProvider != nil {
+		return nil, fmt.Errorf("%s: exec and auth-provider credentials are not supported", path)
+	}
+
+	auth := &kubeAuth{
+		server: cluster.Server,
+		token:  user.Token,
+		tls:    &tls.Config{InsecureSkipVerify: cluster.InsecureSkipTLSVerify},
+	}
+	if auth.token == "" && user.TokenFile != "" {
+		if auth.token, err = readToken(user.TokenFile); err != nil {
+			return nil, err
+		}
+	}
+	ca, err := kubeconfigData(cluster.CertificateAuthority, cluster.CertificateAuthorityData)
+	if err != nil {
+		return nil, err
+	}
+	if ca != nil {
+		if auth.tls.RootCAs, err = certPool(ca); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	cert, err := kubeconfigData(user.ClientCertificate, user.ClientCertificateData)
+	if err != nil {
+		return nil, err
+	}
+	key, err := kubeconfigData(user.ClientKey, user.ClientKeyData)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil && key != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		auth.tls.Certificates = []tls.Certificate{pair}
+	}
+	return auth, nil
+}
+
+// Return the contents of a kubeconfig file reference, given as
+// either a path or inline base64 data, or nil if neither is set
+func kubeconfigData(path, data string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return ioutil.ReadFile(path)
+	}
+	return nil, nil
+}
+
+func readToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func certPool(pem []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid CA certificates found")
+	}
+	return pool, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
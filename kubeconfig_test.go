@@ -0,0 +1,106 @@
+// +build !release
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// as written by kubectl config, e.g. for a kind cluster
+const kubectlConfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: ""
+    server: https://127.0.0.1:40123
+  name: kind-kind
+contexts:
+- context:
+    cluster: kind-kind
+    user: kind-kind
+  name: kind-kind
+current-context: kind-kind
+kind: Config
+preferences: {}
+users:
+- name: kind-kind
+  user:
+    token: abc.def
+`
+
+// hand-written, with comments, quoting, flow mappings and an exec user
+const handConfig = `# cluster access
+apiVersion: v1
+kind: Config
+current-context: "prod"  # default context
+clusters:
+  - name: 'prod'
+    cluster:
+      server: "https://k8s.example.com:6443"
+      insecure-skip-tls-verify: true
+contexts:
+  - name: prod
+    context: {cluster: prod, user: admin}
+users:
+  - name: admin
+    user:
+      token: "tab\there \"quoted\" \u00e9\x41"
+      username: 'it''s me'
+  - name: eks
+    user:
+      exec:
+        apiVersion: client.authentication.k8s.io/v1beta1
+        command: aws
+        args:
+          - eks
+          - get-token
+          - "--cluster-name"
+          - prod
+`
+
+func TestLoadKubeconfig(t *testing.T) {
+	cases := []struct {
+		name     string
+		doc      string
+		server   string
+		token    string
+		insecure bool
+		err      string
+	}{
+		{name: "kubectl", doc: kubectlConfig, server: "https://127.0.0.1:40123", token: "abc.def"},
+		{name: "hand-written", doc: handConfig,
+			server: "https://k8s.example.com:6443", token: "tab\there \"quoted\" \u00e9A", insecure: true},
+		{name: "exec user", doc: strings.Replace(handConfig, "user: admin}", "user: eks}", 1),
+			err: "exec and auth-provider credentials are not supported"},
+		{name: "missing context", doc: strings.Replace(kubectlConfig, "current-context: kind-kind", "current-context: other", 1),
+			err: "context other not found"},
+		{name: "invalid", doc: "clusters: [\n", err: "yaml:"},
+	}
+	for _, tc := range cases {
+		f, err := ioutil.TempFile("", "kubeconfig")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(tc.doc)
+		f.Close()
+		auth, err := loadKubeconfig(f.Name())
+		os.Remove(f.Name())
+
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: error %v, want %q", tc.name, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if auth.server != tc.server || auth.token != tc.token || auth.tls.InsecureSkipVerify != tc.insecure {
+			t.Errorf("%s: server %q, token %q, insecure %v; want %q, %q, %v", tc.name,
+				auth.server, auth.token, auth.tls.InsecureSkipVerify, tc.server, tc.token, tc.insecure)
+		}
+	}
+}
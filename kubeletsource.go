@@ -0,0 +1,680 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/metrics"
+)
+
+const (
+	defaultKubeletEndpoint = "https://127.0.0.1:10250"
+	defaultKubeletInterval = 10 * time.Second
+	kubeletTimeout         = 10 * time.Second
+)
+
+var kubeletConnectorKeys = []string{"endpoint", "kubeconfig", "node", "tokenFile", "caFile", "insecure", "namespace", "interval"}
+
+// Namespace given with -namespace, overriding the connector section
+var kubeNamespace string
+
+var errKubelet = fmt.Errorf("not supported by the kubelet connector")
+
+// Container source listing the pods of a kubernetes node as rows,
+// each expandable to its containers, polled from the kubelet /pods
+// and /stats/summary endpoints
+type KubeletSource struct {
+	client     *http.Client
+	base       string // kubelet URL, or API server proxy URL for the node
+	token      string
+	namespace  string // only pods in this namespace, if set
+	interval   time.Duration
+	rows       map[string]*Container // pods and their containers, by ID
+	children   map[string]Containers // containers of each pod, by pod UID
+	parents    map[string]string     // pod UID of each container row
+	collectors map[string]*metrics.Replay
+	docs       map[string]json.RawMessage // last pod document, by pod UID
+	host       metrics.HostMetrics
+	lostAt     time.Time
+	done       chan bool
+	lock       sync.RWMutex
+}
+
+// Subset of the kubelet /pods response
+type kubePod struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		Namespace         string            `json:"namespace"`
+		UID               string            `json:"uid"`
+		CreationTimestamp time.Time         `json:"creationTimestamp"`
+		Labels            map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Name      string `json:"name"`
+			Image     string `json:"image"`
+			Resources struct {
+				Limits map[string]string `json:"limits"`
+			} `json:"resources"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase             string               `json:"phase"`
+		StartTime         time.Time            `json:"startTime"`
+		ContainerStatuses []kubeContainerState `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type kubeContainerState struct {
+	Name         string `json:"name"`
+	ImageID      string `json:"imageID"`
+	RestartCount int    `json:"restartCount"`
+	State        struct {
+		Running *struct {
+			StartedAt time.Time `json:"startedAt"`
+		} `json:"running"`
+		Waiting *struct {
+			Reason string `json:"reason"`
+		} `json:"waiting"`
+		Terminated *struct {
+			ExitCode   int       `json:"exitCode"`
+			Reason     string    `json:"reason"`
+			StartedAt  time.Time `json:"startedAt"`
+			FinishedAt time.Time `json:"finishedAt"`
+		} `json:"terminated"`
+	} `json:"state"`
+}
+
+// Subset of the kubelet /stats/summary response
+type kubeSummary struct {
+	Node struct {
+		Memory kubeMemStats `json:"memory"`
+	} `json:"node"`
+	Pods []struct {
+		PodRef struct {
+			UID string `json:"uid"`
+		} `json:"podRef"`
+		Containers []struct {
+			Name   string       `json:"name"`
+			CPU    kubeCPUStats `json:"cpu"`
+			Memory kubeMemStats `json:"memory"`
+		} `json:"containers"`
+		CPU     kubeCPUStats `json:"cpu"`
+		Memory  kubeMemStats `json:"memory"`
+		Network *struct {
+			RxBytes *int64 `json:"rxBytes"`
+			TxBytes *int64 `json:"txBytes"`
+		} `json:"network"`
+		ProcessStats *struct {
+			ProcessCount *int `json:"process_count"`
+		} `json:"process_stats"`
+	} `json:"pods"`
+}
+
+type kubeCPUStats struct {
+	UsageNanoCores       *int64 `json:"usageNanoCores"`
+	UsageCoreNanoSeconds *int64 `json:"usageCoreNanoSeconds"`
+}
+
+type kubeMemStats struct {
+	AvailableBytes  *int64 `json:"availableBytes"`
+	WorkingSetBytes *int64 `json:"workingSetBytes"`
+}
+
+func newKubeletSource(section *config.ConnectorSection) (*KubeletSource, error) {
+	ks := &KubeletSource{
+		client:     &http.Client{Timeout: kubeletTimeout},
+		namespace:  section.Get("namespace"),
+		interval:   defaultKubeletInterval,
+		rows:       make(map[string]*Container),
+		children:   make(map[string]Containers),
+		parents:    make(map[string]string),
+		collectors: make(map[string]*metrics.Replay),
+		docs:       make(map[string]json.RawMessage),
+		host:       metrics.NewHostMetrics(),
+		done:       make(chan bool),
+	}
+	if kubeNamespace != "" {
+		ks.namespace = kubeNamespace
+	}
+	if s := section.Get("interval"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, section.Errorf("interval", "invalid duration: %s", s)
+		}
+		ks.interval = d
+	}
+	tlsConfig, err := ks.configureAuth(section)
+	if err != nil {
+		return nil, err
+	}
+	if s := section.Get("insecure"); s != "" {
+		insecure, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, section.Errorf("insecure", "expected true or false, got %s", s)
+		}
+		tlsConfig.InsecureSkipVerify = insecure
+	}
+	ks.client.Transport = &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}
+
+	if err := ks.refresh(); err != nil {
+		return nil, fmt.Errorf("kubelet %s: %s", ks.base, err)
+	}
+	safeGo(ks.Loop)
+	return ks, nil
+}
+
+// Set the kubelet URL and credentials, either from a kubeconfig file,
+// reaching the kubelet through the API server, or directly from the
+// endpoint and service account token and CA, if mounted
+func (ks *KubeletSource) configureAuth(section *config.ConnectorSection) (*tls.Config, error) {
+	if path := section.Get("kubeconfig"); path != "" {
+		auth, err := loadKubeconfig(path)
+		if err != nil {
+			return nil, section.Errorf("kubeconfig", "%s", err)
+		}
+		node := section.Get("node")
+		if node == "" {
+			if node, err = os.Hostname(); err != nil {
+				return nil, section.Errorf("node", "%s", err)
+			}
+		}
+		ks.base = strings.TrimRight(auth.server, "/") + "/api/v1/nodes/" + node + "/proxy"
+		ks.token = auth.token
+		return auth.tls, nil
+	}
+
+	ks.base = strings.TrimRight(section.Get("endpoint"), "/")
+	if ks.base == "" {
+		ks.base = defaultKubeletEndpoint
+	}
+	tlsConfig := &tls.Config{}
+	tokenFile := section.Get("tokenFile")
+	if tokenFile == "" && fileExists(serviceAccountToken) {
+		tokenFile = serviceAccountToken
+	}
+	if tokenFile != "" {
+		token, err := readToken(tokenFile)
+		if err != nil {
+			return nil, section.Errorf("tokenFile", "%s", err)
+		}
+		ks.token = token
+	}
+	caFile := section.Get("caFile")
+	if caFile == "" && fileExists(serviceAccountCA) {
+		caFile = serviceAccountCA
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err == nil {
+			tlsConfig.RootCAs, err = certPool(pem)
+		}
+		if err != nil {
+			return nil, section.Errorf("caFile", "%s", err)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// Poll the kubelet at the configured interval, as it has no event
+// stream, until closed
+func (ks *KubeletSource) Loop() {
+	ticker := time.NewTicker(ks.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ks.done:
+			return
+		case <-ticker.C:
+		}
+		err := ks.refresh()
+
+		ks.lock.Lock()
+		lost := !ks.lostAt.IsZero()
+		switch {
+		case err != nil && !lost:
+			ks.lostAt = time.Now()
+			log.NotifyError("kubelet connection lost: %s", err)
+		case err == nil && lost:
+			ks.lostAt = time.Time{}
+			log.Notify("kubelet connection restored")
+		case err != nil:
+			log.Debugf("kubelet poll failed: %s", err)
+		}
+		ks.lock.Unlock()
+	}
+}
+
+func (ks *KubeletSource) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", ks.base+path, nil)
+	if err != nil {
+		return err
+	}
+	if ks.token != "" {
+		req.Header.Set("Authorization", "Bearer "+ks.token)
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Update pods, their containers and metrics from the kubelet
+func (ks *KubeletSource) refresh() error {
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := ks.get("/pods", &list); err != nil {
+		return err
+	}
+	var summary kubeSummary
+	if err := ks.get("/stats/summary", &summary); err != nil {
+		return err
+	}
+
+	host := metrics.NewHostMetrics()
+	if mem := summary.Node.Memory; mem.WorkingSetBytes != nil && mem.AvailableBytes != nil {
+		host.MemUsage = *mem.WorkingSetBytes
+		host.MemTotal = *mem.WorkingSetBytes + *mem.AvailableBytes
+	}
+	stats := make(map[string]int)
+	for n, ps := range summary.Pods {
+		stats[ps.PodRef.UID] = n
+	}
+
+	ks.lock.Lock()
+	ks.host = host
+	ks.lock.Unlock()
+
+	seen := make(map[string]bool)
+	for _, raw := range list.Items {
+		var pod kubePod
+		if err := json.Unmarshal(raw, &pod); err != nil {
+			return err
+		}
+		if ks.namespace != "" && pod.Metadata.Namespace != ks.namespace {
+			continue
+		}
+		uid := pod.Metadata.UID
+		seen[uid] = true
+
+		ks.lock.Lock()
+		ks.docs[uid] = raw
+		ks.lock.Unlock()
+
+		if n, ok := stats[uid]; ok {
+			ks.updatePod(pod, &summary, n, host)
+		} else {
+			ks.updatePod(pod, nil, 0, host)
+		}
+	}
+
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	for id, c := range ks.rows {
+		uid, ok := ks.parents[id]
+		if !ok {
+			uid = id
+		}
+		if !seen[uid] {
+			ks.removeRow(c)
+			delete(ks.children, uid)
+			delete(ks.docs, uid)
+		}
+	}
+	return nil
+}
+
+// Update a pod row and its container rows, pushing metrics from the
+// pod's stats, if any, at index n of the summary
+func (ks *KubeletSource) updatePod(pod kubePod, summary *kubeSummary, n int, host metrics.HostMetrics) {
+	uid := pod.Metadata.UID
+	name := pod.Metadata.Namespace + "/" + pod.Metadata.Name
+	c := ks.row(uid, "")
+
+	var images, spec []string
+	var restarts int
+	var podLimit int64
+	statuses := make(map[string]kubeContainerState)
+	for _, st := range pod.Status.ContainerStatuses {
+		statuses[st.Name] = st
+		restarts += st.RestartCount
+	}
+	children := make(Containers, 0, len(pod.Spec.Containers))
+	limits := make(map[string]int64)
+	for _, cs := range pod.Spec.Containers {
+		spec = append(spec, cs.Name)
+		if !known(images, cs.Image) {
+			images = append(images, cs.Image)
+		}
+		limit, _ := parseQuantity(cs.Resources.Limits["memory"])
+		limits[cs.Name] = limit
+		if podLimit >= 0 && limit > 0 {
+			podLimit += limit
+		} else {
+			podLimit = -1 // unlimited, if any container is
+		}
+
+		child := ks.row(uid, cs.Name)
+		st := statuses[cs.Name]
+		setChangedMeta(child, "name", cs.Name)
+		setChangedMeta(child, "image", cs.Image)
+		setChangedMeta(child, "pod", name)
+		setChangedMeta(child, "namespace", pod.Metadata.Namespace)
+		setChangedMeta(child, "restarts", strconv.Itoa(st.RestartCount))
+		state, since := kubeContainerStatus(st)
+		life := Lifecycle{Since: since, RestartCount: st.RestartCount, ImageID: st.ImageID}
+		if r := st.State.Running; r != nil {
+			life.Started = r.StartedAt
+		}
+		if t := st.State.Terminated; t != nil {
+			life.Started, life.Finished = t.StartedAt, t.FinishedAt
+			life.Exited, life.ExitCode = true, t.ExitCode
+		}
+		child.SetLifecycle(life)
+		child.SetLabels(pod.Metadata.Labels)
+		child.SetState(state)
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].GetMeta("name") < children[j].GetMeta("name") })
+
+	setChangedMeta(c, "name", name)
+	setChangedMeta(c, "pod", pod.Metadata.Name)
+	setChangedMeta(c, "namespace", pod.Metadata.Namespace)
+	setChangedMeta(c, "phase", pod.Status.Phase)
+	setChangedMeta(c, "image", strings.Join(images, ","))
+	setChangedMeta(c, "node", pod.Spec.NodeName)
+	setChangedMeta(c, "restarts", strconv.Itoa(restarts))
+	c.SetLifecycle(Lifecycle{
+		Created:      pod.Metadata.CreationTimestamp,
+		Started:      pod.Status.StartTime,
+		Since:        pod.Status.StartTime,
+		RestartCount: restarts,
+	})
+	c.SetLabels(pod.Metadata.Labels)
+	c.SetState(kubePhaseState(pod.Status.Phase))
+
+	ks.lock.Lock()
+	for _, child := range ks.children[uid] {
+		if !known(spec, child.GetMeta("name")) {
+			ks.removeRow(child) // no longer in the pod spec
+		}
+	}
+	ks.children[uid] = children
+	ks.lock.Unlock()
+
+	if summary == nil {
+		return
+	}
+	ps := summary.Pods[n]
+	m := kubeMetrics(ps.CPU, ps.Memory, podLimit, host)
+	if ps.Network != nil && ps.Network.RxBytes != nil && ps.Network.TxBytes != nil {
+		m.NetRx, m.NetTx = *ps.Network.RxBytes, *ps.Network.TxBytes
+	}
+	if ps.ProcessStats != nil && ps.ProcessStats.ProcessCount != nil {
+		m.Pids = *ps.ProcessStats.ProcessCount
+	}
+	ks.push(c, m)
+	for _, cs := range ps.Containers {
+		for _, child := range children {
+			if child.GetMeta("name") == cs.Name {
+				ks.push(child, kubeMetrics(cs.CPU, cs.Memory, limits[cs.Name], host))
+			}
+		}
+	}
+}
+
+// Return the row for a pod, or for one of its containers if named,
+// creating it if needed
+func (ks *KubeletSource) row(uid, container string) *Container {
+	id := uid
+	if container != "" {
+		id = uid + "/" + container
+	}
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	if c, ok := ks.rows[id]; ok {
+		return c
+	}
+	collector := metrics.NewReplay()
+	c := NewContainer(id, sourceLabel(ks.base), collector)
+	ks.rows[id] = c
+	ks.collectors[id] = collector
+	if container != "" {
+		ks.parents[id] = uid
+	}
+	return c
+}
+
+// Stop and forget a row. Must be called with the lock held
+func (ks *KubeletSource) removeRow(c *Container) {
+	c.Remove()
+	delete(ks.rows, c.Id)
+	delete(ks.collectors, c.Id)
+	delete(ks.parents, c.Id)
+}
+
+// Emit metrics for a running row
+func (ks *KubeletSource) push(c *Container, m metrics.Metrics) {
+	ks.lock.RLock()
+	collector := ks.collectors[c.Id]
+	ks.lock.RUnlock()
+	if collector != nil && c.State() == "running" {
+		collector.Push(m)
+	}
+}
+
+// Return metrics from kubelet CPU and memory stats, with memory
+// relative to a limit, or to the node memory if unlimited
+func kubeMetrics(cpu kubeCPUStats, mem kubeMemStats, limit int64, host metrics.HostMetrics) metrics.Metrics {
+	m := metrics.NewMetrics()
+	if cpu.UsageNanoCores != nil {
+		// percent of a single core, as for docker containers
+		m.CPUUtil = int(float64(*cpu.UsageNanoCores)/1e7 + 0.5)
+	}
+	if cpu.UsageCoreNanoSeconds != nil {
+		m.CPUTime = time.Duration(*cpu.UsageCoreNanoSeconds)
+	}
+	if limit <= 0 {
+		limit = host.MemTotal
+	}
+	if mem.WorkingSetBytes != nil {
+		m.MemUsage = *mem.WorkingSetBytes
+		if limit > 0 {
+			m.MemLimit = limit
+			m.MemPercent = int(float64(m.MemUsage) / float64(limit) * 100)
+		}
+	}
+	return m
+}
+
+// Map a pod phase to a container state
+func kubePhaseState(phase string) string {
+	switch phase {
+	case "Running":
+		return "running"
+	case "Pending":
+		return "created"
+	case "Succeeded", "Failed":
+		return "exited"
+	}
+	return ""
+}
+
+// Return the state of a pod container, and the time it was entered
+func kubeContainerStatus(st kubeContainerState) (string, time.Time) {
+	switch {
+	case st.State.Running != nil:
+		return "running", st.State.Running.StartedAt
+	case st.State.Terminated != nil:
+		return "exited", st.State.Terminated.FinishedAt
+	}
+	return "created", time.Time{}
+}
+
+var quantitySuffixes = []struct {
+	suffix string
+	mult   float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50}, {"Ei", 1 << 60},
+	{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15}, {"E", 1e18},
+}
+
+// Parse a kubernetes resource quantity, e.g. 512Mi or 1.5G, in bytes
+func parseQuantity(s string) (int64, error) {
+	mult := 1.0
+	for _, q := range quantitySuffixes {
+		if strings.HasSuffix(s, q.suffix) {
+			s, mult = strings.TrimSuffix(s, q.suffix), q.mult
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity: %s", s)
+	}
+	return int64(f * mult), nil
+}
+
+// Set container metadata only if changed, keeping row names marked
+// by the cursor until an actual change
+func setChangedMeta(c *Container, k, v string) {
+	if c.GetMeta(k) != v {
+		c.SetMeta(k, v)
+	}
+}
+
+// Return the containers of a pod, sorted by name
+func (ks *KubeletSource) Children(id string) Containers {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	return append(Containers{}, ks.children[id]...)
+}
+
+// Stop polling and all collectors
+func (ks *KubeletSource) Close() {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	select {
+	case <-ks.done:
+		return
+	default:
+	}
+	close(ks.done)
+	for _, c := range ks.rows {
+		c.Remove()
+	}
+}
+
+// Return all pods, with their containers returned by Children
+func (ks *KubeletSource) All() (containers Containers) {
+	ks.lock.RLock()
+	for id, c := range ks.rows {
+		if _, ok := ks.parents[id]; !ok {
+			containers = append(containers, c)
+		}
+	}
+	ks.lock.RUnlock()
+	containers.Sort()
+	containers.Filter()
+	return containers
+}
+
+// Return all pods, neither sorted nor filtered for display
+func (ks *KubeletSource) Snapshot() (containers Containers) {
+	ks.lock.RLock()
+	for id, c := range ks.rows {
+		if _, ok := ks.parents[id]; !ok {
+			containers = append(containers, c)
+		}
+	}
+	ks.lock.RUnlock()
+	return containers
+}
+
+// Return a row by ID, or by name or a unique prefix of either
+func (ks *KubeletSource) Get(id string) (*Container, bool) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	if c, ok := ks.rows[id]; ok {
+		return c, true
+	}
+	list := make(Containers, 0, len(ks.rows))
+	for _, c := range ks.rows {
+		list = append(list, c)
+	}
+	c, err := matchContainer(list, id)
+	return c, err == nil
+}
+
+func (ks *KubeletSource) LostSince() time.Time {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	return ks.lostAt
+}
+
+func (ks *KubeletSource) Endpoint() string { return ks.base }
+
+func (ks *KubeletSource) Host() metrics.HostMetrics {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+	return ks.host
+}
+
+// Return the pod document of a pod, or of the pod of a container
+func (ks *KubeletSource) Inspect(id string) (interface{}, error) {
+	ks.lock.RLock()
+	uid, ok := ks.parents[id]
+	if !ok {
+		uid = id
+	}
+	raw, ok := ks.docs[uid]
+	ks.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no such pod: %s", id)
+	}
+	var doc interface{}
+	err := json.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func (ks *KubeletSource) Attach(string, AttachOpts) error            { return errKubelet }
+func (ks *KubeletSource) Rename(string, string) error                { return errKubelet }
+func (ks *KubeletSource) Limits(string) (Limits, error)              { return Limits{}, errKubelet }
+func (ks *KubeletSource) UpdateLimits(string, Limits) error          { return errKubelet }
+func (ks *KubeletSource) SetRestartPolicy(string, string, int) error { return errKubelet }
+func (ks *KubeletSource) Remove(string) error                        { return errKubelet }
+func (ks *KubeletSource) ForceRemove(string) error                   { return errKubelet }
+func (ks *KubeletSource) Stop(string) error                          { return errKubelet }
+func (ks *KubeletSource) Restart(string) error                       { return errKubelet }
+func (ks *KubeletSource) Pause(string) error                         { return errKubelet }
+func (ks *KubeletSource) Unpause(string) error                       { return errKubelet }
+
+func (ks *KubeletSource) Commit(id, repo, tag, comment string) (string, error) {
+	return "", errKubelet
+}
+
+func (ks *KubeletSource) PullImage(string, func(PullProgress)) error { return errKubelet }
+func (ks *KubeletSource) Recreate(string) (string, error)            { return "", errKubelet }
+
+func (ks *KubeletSource) Changes(string) ([]FileChange, error) { return nil, errKubelet }
+
+func (ks *KubeletSource) Export(context.Context, string, io.Writer) error { return errKubelet }
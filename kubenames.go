@@ -0,0 +1,56 @@
+package main
+
+// Labels set on containers run by the kubelet, through dockershim or
+// containerd, naming the pod and container they run
+const (
+	kubePodNamespaceLabel = "io.kubernetes.pod.namespace"
+	kubePodNameLabel      = "io.kubernetes.pod.name"
+	kubeContainerLabel    = "io.kubernetes.container.name"
+)
+
+// Labels and values marking the sandbox container holding the
+// namespaces of a pod, e.g. running the pause image
+var kubeSandboxLabels = map[string]string{
+	"io.kubernetes.docker.type": "podsandbox",
+	"io.cri-containerd.kind":    "sandbox",
+	kubeContainerLabel:          "POD",
+}
+
+// Return the display name of a container: "namespace/pod/container"
+// for a container run by the kubelet, whose raw name is generated,
+// or the raw name for any other
+func resolveName(raw string, labels map[string]string) string {
+	pod, ctr := labels[kubePodNameLabel], labels[kubeContainerLabel]
+	if pod == "" {
+		return raw
+	}
+	name := pod
+	if ns := labels[kubePodNamespaceLabel]; ns != "" {
+		name = ns + "/" + pod
+	}
+	if isSandbox(labels) {
+		return name + "/pause"
+	}
+	if ctr != "" {
+		name += "/" + ctr
+	}
+	return name
+}
+
+// Return whether a container is the sandbox of a kubernetes pod
+func isSandbox(labels map[string]string) bool {
+	for k, v := range kubeSandboxLabels {
+		if labels[k] == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the raw name of a container, as known to its runtime
+func rawName(c *Container) string {
+	if s := c.GetMeta("raw name"); s != "" {
+		return s
+	}
+	return c.GetMeta("name")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bcicen/ctop/cwidgets"
+)
+
+const defaultCPUPeriod = 100000 // microseconds
+
+// Container resource limits; zero values are unset
+type Limits struct {
+	Memory     int64   // bytes
+	MemorySwap int64   // bytes, -1 for unlimited
+	CPUs       float64 // fractional number of CPUs
+}
+
+// Return a short description of set limits
+func (l Limits) String() string {
+	var parts []string
+	if l.Memory > 0 {
+		parts = append(parts, fmt.Sprintf("mem %s", cwidgets.ByteFormat(l.Memory)))
+	}
+	if l.MemorySwap != 0 {
+		parts = append(parts, fmt.Sprintf("swap %s", formatBytesLimit(l.MemorySwap)))
+	}
+	if l.CPUs > 0 {
+		parts = append(parts, fmt.Sprintf("cpus %s", formatCPUs(l.CPUs)))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Return CPU limit for the given CFS quota and period
+func cpusFromQuota(quota, period int64) float64 {
+	if quota <= 0 {
+		return 0
+	}
+	if period <= 0 {
+		period = defaultCPUPeriod
+	}
+	return float64(quota) / float64(period)
+}
+
+var byteUnits = map[string]int64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+// Parse a human readable byte size, e.g. "512m" or "1.5g".
+// "-1" and "unlimited" return -1
+func parseBytes(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "-1" || s == "unlimited" {
+		return -1, nil
+	}
+	s = strings.TrimSuffix(s, "b")
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	num := strings.TrimRight(s, "kmgt")
+	mult, ok := byteUnits[s[len(num):]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit: %s", s[len(num):])
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return int64(f * float64(mult)), nil
+}
+
+// Parse a number of CPUs, e.g. "1.5" or "1.5 cpus"
+func parseCPUs(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "s"), "cpu"))
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid cpu count: %s", s)
+	}
+	return f, nil
+}
+
+func formatBytesLimit(n int64) string {
+	switch {
+	case n < 0:
+		return "unlimited"
+	case n == 0:
+		return "unset"
+	}
+	return cwidgets.ByteFormat(n)
+}
+
+func formatCPUs(f float64) string {
+	if f == 0 {
+		return "unset"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
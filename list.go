@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/metrics"
+)
+
+const defaultListFormat = `{{.Name}}\t{{.ShortID}}\t{{.State}}\t{{.CPU}}\t{{.Mem | bytes}}`
+
+var fieldErrRe = regexp.MustCompile(`can't evaluate field (\w+)`)
+
+// Container fields available to -list format templates, documented
+// in _docs/list.md. Metrics are -1 if not available
+type listContext struct {
+	ID         string
+	ShortID    string
+	Source     string
+	Name       string
+	Image      string
+	State      string
+	Health     string
+	Ports      string
+	User       string
+	Created    string
+	Uptime     string
+	CreatedAt  string
+	StartedAt  string
+	FinishedAt string
+	ExitCode   int
+	Restarts   int
+	ImageID    string
+	Pid        int
+	Restart    string
+	Limits     string
+	Labels     map[string]string
+	Meta       map[string]string
+	Env        map[string]string // whitelisted environment variables only
+	CPU        int
+	CPUTime    float64 // seconds, or -1 if unread
+	Mem        int64
+	MemLimit   int64
+	MemPercent int
+	NetRx      int64
+	NetTx      int64
+	IORead     int64
+	IOWrite    int64
+	Pids       int
+}
+
+func newListContext(c *Container) listContext {
+	meta := c.MetaCopy()
+	pid, _ := strconv.Atoi(meta["pid"])
+	labels := c.Labels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	life := c.Lifecycle()
+	ctx := listContext{
+		ID:         c.Id,
+		ShortID:    c.Id,
+		Source:     c.Source,
+		Name:       meta["name"],
+		Image:      meta["image"],
+		State:      meta["state"],
+		Health:     meta["health"],
+		Ports:      meta["ports"],
+		User:       meta["user"],
+		Created:    meta["created"],
+		Uptime:     containerUptime(c),
+		Pid:        pid,
+		CreatedAt:  exportTime(life.Created),
+		StartedAt:  exportTime(life.Started),
+		FinishedAt: exportTime(life.Finished),
+		ExitCode:   -1,
+		Restarts:   life.RestartCount,
+		ImageID:    life.ImageID,
+		Restart:    meta["restart"],
+		Limits:     meta["limits"],
+		Labels:     labels,
+		Meta:       meta,
+		Env:        c.Env().Values(),
+	}
+	if life.Exited {
+		ctx.ExitCode = life.ExitCode
+	}
+	if len(ctx.ShortID) > 12 {
+		ctx.ShortID = ctx.ShortID[:12]
+	}
+
+	m := c.Metrics()
+	if ctx.State != "running" {
+		m = metrics.NewMetrics()
+	}
+	ctx.CPU, ctx.Mem, ctx.MemLimit, ctx.MemPercent = m.CPUUtil, m.MemUsage, m.MemLimit, m.MemPercent
+	ctx.NetRx, ctx.NetTx = m.NetRx, m.NetTx
+	ctx.IORead, ctx.IOWrite = m.IOBytesRead, m.IOBytesWrite
+	ctx.Pids = m.Pids
+	ctx.CPUTime = m.CPUTime.Seconds()
+	if m.CPUTime < 0 {
+		ctx.CPUTime = -1
+	}
+	return ctx
+}
+
+var listFuncs = template.FuncMap{
+	// human-readable byte count, e.g. 12M
+	"bytes": func(n int64) string {
+		if n < 0 {
+			return "-"
+		}
+		return cwidgets.ByteFormat(n)
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// Parse a list format, unescaping \t and \n, and validate it against
+// an empty container context so field errors are reported up front
+func parseListFormat(s string) (*template.Template, error) {
+	s = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(s)
+	tmpl, err := template.New("list").Funcs(listFuncs).Option("missingkey=zero").Parse(s + "\n")
+	if err != nil {
+		return nil, fmt.Errorf("invalid format: %s", err)
+	}
+	if err := tmpl.Execute(ioutil.Discard, listContext{}); err != nil {
+		return nil, listFormatErr(err)
+	}
+	return tmpl, nil
+}
+
+// Report template execution errors by field name where possible
+func listFormatErr(err error) error {
+	if m := fieldErrRe.FindStringSubmatch(err.Error()); m != nil {
+		return fmt.Errorf("invalid format: unknown field %s", m[1])
+	}
+	return fmt.Errorf("invalid format: %s", err)
+}
+
+// Print displayed containers using a format template after one
+// refresh interval, aligning tab-separated columns
+func ListContainers(format string) {
+	tmpl, err := parseListFormat(format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	time.Sleep(refreshInterval())
+	cursor.RefreshContainers()
+
+	var buf bytes.Buffer
+	for _, c := range cursor.filtered {
+		if err := tmpl.Execute(&buf, newListContext(c)); err != nil {
+			fmt.Fprintln(os.Stderr, listFormatErr(err))
+			os.Exit(1)
+		}
+	}
+
+	if strings.Contains(tmpl.Root.String(), "\t") {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		w.Write(buf.Bytes())
+		w.Flush()
+		return
+	}
+	os.Stdout.Write(buf.Bytes())
+}
@@ -0,0 +1,20 @@
+// +build !release
+
+package main
+
+import (
+	"flag"
+
+	"github.com/bcicen/ctop/config"
+)
+
+func init() {
+	flag.IntVar(&loadTestCount, "loadtest", 0, "run the UI against the given `number` of mock containers, printing frame time percentiles on exit")
+	hiddenFlags["loadtest"] = true
+
+	connectors["mock"] = connector{
+		new: func(*config.ConnectorSection) (ContainerSource, error) {
+			return NewMockContainerSource(), nil
+		},
+	}
+}
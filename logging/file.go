@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/op/go-logging"
+)
+
+var fileDone chan bool // closed once queued lines are written
+
+// Raise the log level to debug and write all entries to the
+// file at path, appending if it exists
+func (log *CTopLogger) EnableDebug(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	lines := make(chan string, fileBuffer)
+	fileDone = make(chan bool)
+	go func() {
+		defer close(fileDone)
+		defer f.Close()
+		for line := range lines {
+			fmt.Fprintln(f, line)
+		}
+	}()
+
+	ring.lock.Lock()
+	ring.file = lines
+	ring.lock.Unlock()
+
+	level = logging.DEBUG
+	log.leveled.SetLevel(level, "")
+	log.Noticef("debug logging to %s", path)
+	return nil
+}
+
+// Flush and close the debug log file, if open
+func closeFile() {
+	ring.lock.Lock()
+	lines, dropped := ring.file, ring.dropped
+	ring.file = nil
+	ring.lock.Unlock()
+	if lines == nil {
+		return
+	}
+	if dropped > 0 {
+		lines <- fmt.Sprintf("%d log lines dropped while writing was behind", dropped)
+	}
+	close(lines)
+	<-fileDone
+}
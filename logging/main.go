@@ -23,6 +23,7 @@ var (
 type CTopLogger struct {
 	*logging.Logger
 	backend *logging.MemoryBackend
+	leveled logging.LeveledBackend
 }
 
 func Init() *CTopLogger {
@@ -32,6 +33,7 @@ func Init() *CTopLogger {
 		Log = &CTopLogger{
 			logging.MustGetLogger("ctop"),
 			logging.NewMemoryBackend(size),
+			nil,
 		}
 
 		if debugMode() {
@@ -39,10 +41,8 @@ func Init() *CTopLogger {
 			StartServer()
 		}
 
-		backendLvl := logging.AddModuleLevel(Log.backend)
-		backendLvl.SetLevel(level, "")
-
-		logging.SetBackend(backendLvl)
+		Log.leveled = logging.SetBackend(Log.backend, ring)
+		Log.leveled.SetLevel(level, "")
 		Log.Notice("logger initialized")
 	}
 	return Log
@@ -76,6 +76,7 @@ func (log *CTopLogger) tail() chan string {
 func (log *CTopLogger) Exit() {
 	exited = true
 	StopServer()
+	closeFile()
 }
 
 func debugMode() bool    { return os.Getenv("CTOP_DEBUG") == "1" }
@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	maxNotifications = 100
+	notifyTimeout    = 5 * time.Second // display time for non-error notifications
+)
+
+// User-facing message for background events
+type Notification struct {
+	Time      time.Time
+	Msg       string
+	Error     bool
+	key       string // identifies notifications replacing earlier ones
+	dismissed bool
+}
+
+// Notification is displayed until it times out or, for errors, until dismissed
+func (n *Notification) Active() bool {
+	if n.Error {
+		return !n.dismissed
+	}
+	return time.Since(n.Time) < notifyTimeout
+}
+
+var notifications struct {
+	sync.RWMutex
+	list []*Notification
+}
+
+func addNotification(n *Notification) {
+	notifications.Lock()
+	defer notifications.Unlock()
+	notifications.list = append(notifications.list, n)
+	if len(notifications.list) > maxNotifications {
+		notifications.list = notifications.list[1:]
+	}
+}
+
+// Log and display a notification
+func (log *CTopLogger) Notify(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Notice(msg)
+	addNotification(&Notification{Time: time.Now(), Msg: msg})
+}
+
+// Log and display an error notification, persisting until dismissed
+func (log *CTopLogger) NotifyError(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Error(msg)
+	addNotification(&Notification{Time: time.Now(), Msg: msg, Error: true})
+}
+
+// Log and display an error notification as NotifyError, replacing
+// any earlier notification with the same key rather than adding to
+// those displayed, e.g. for a condition recurring
+func (log *CTopLogger) NotifyErrorKey(key, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Error(msg)
+	removeNotifications(key)
+	addNotification(&Notification{Time: time.Now(), Msg: msg, Error: true, key: key})
+}
+
+// Dismiss error notifications with the given key, once the
+// condition notified has cleared
+func DismissKey(key string) {
+	notifications.Lock()
+	defer notifications.Unlock()
+	for _, n := range notifications.list {
+		if n.key == key {
+			n.dismissed = true
+		}
+	}
+}
+
+func removeNotifications(key string) {
+	notifications.Lock()
+	defer notifications.Unlock()
+	list := notifications.list[:0]
+	for _, n := range notifications.list {
+		if n.key != key {
+			list = append(list, n)
+		}
+	}
+	notifications.list = list
+}
+
+// Return all notifications, oldest first
+func Notifications() (list []Notification) {
+	notifications.RLock()
+	defer notifications.RUnlock()
+	for _, n := range notifications.list {
+		list = append(list, *n)
+	}
+	return list
+}
+
+// Return currently active notifications, oldest first
+func ActiveNotifications() (list []Notification) {
+	notifications.RLock()
+	defer notifications.RUnlock()
+	for _, n := range notifications.list {
+		if n.Active() {
+			list = append(list, *n)
+		}
+	}
+	return list
+}
+
+// Dismiss all error notifications
+func DismissNotifications() {
+	notifications.Lock()
+	defer notifications.Unlock()
+	for _, n := range notifications.list {
+		n.dismissed = true
+	}
+}
@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+const (
+	ringSize   = 1000
+	fileBuffer = 1024 // lines queued for the debug log file before dropping
+)
+
+// Log entry, as kept in memory and written to the debug log file
+type Entry struct {
+	Time      time.Time
+	Level     logging.Level
+	Component string // source file the entry was logged from
+	Msg       string
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s %-7s %-12s %s", e.Time.Format("2006-01-02T15:04:05.000Z07:00"), e.Level, e.Component, e.Msg)
+}
+
+// Backend keeping the most recent entries in a fixed-size ring,
+// and queueing them for the debug log file, if enabled. Logging
+// never blocks on file writes; lines are dropped if the queue is full
+type ringBackend struct {
+	lock    sync.RWMutex
+	entries []Entry
+	next    int    // index of next entry to write
+	count   uint64 // total entries logged
+	file    chan string
+	dropped uint64
+}
+
+var ring = &ringBackend{entries: make([]Entry, 0, ringSize)}
+
+func (b *ringBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	e := Entry{
+		Time:      rec.Time,
+		Level:     level,
+		Component: component(calldepth + 1),
+		Msg:       rec.Message(),
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if len(b.entries) < ringSize {
+		b.entries = append(b.entries, e)
+	} else {
+		b.entries[b.next] = e
+	}
+	b.next = (b.next + 1) % ringSize
+	b.count++
+
+	if b.file != nil {
+		select {
+		case b.file <- e.String():
+		default:
+			b.dropped++
+		}
+	}
+	return nil
+}
+
+// Return the name of the source file logged from, without extension
+func component(calldepth int) string {
+	_, file, _, ok := runtime.Caller(calldepth + 1)
+	if !ok {
+		return "-"
+	}
+	return strings.TrimSuffix(filepath.Base(file), ".go")
+}
+
+// Return retained log entries, oldest first, and the total
+// number of entries logged, which increases on each new entry
+func Entries() ([]Entry, uint64) {
+	ring.lock.RLock()
+	defer ring.lock.RUnlock()
+	list := make([]Entry, 0, len(ring.entries))
+	if len(ring.entries) == ringSize {
+		list = append(list, ring.entries[ring.next:]...)
+		list = append(list, ring.entries[:ring.next]...)
+	} else {
+		list = append(list, ring.entries...)
+	}
+	return list, ring.count
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/bcicen/ctop/logging"
+	"github.com/bcicen/ctop/widgets/output"
+	ui "github.com/gizak/termui"
+	gologging "github.com/op/go-logging"
+)
+
+// Return the default debug log path, under the XDG cache directory
+func defaultDebugPath() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".cache")
+	}
+	return filepath.Join(dir, "ctop", "ctop.log")
+}
+
+func levelColor(l gologging.Level) ui.Attribute {
+	switch l {
+	case gologging.CRITICAL, gologging.ERROR:
+		return ui.ColorRed
+	case gologging.WARNING:
+		return ui.ColorYellow
+	case gologging.NOTICE:
+		return ui.ColorGreen
+	case gologging.DEBUG:
+		return ui.ColorCyan
+	}
+	return ui.ThemeAttr("par.text.fg")
+}
+
+// Load retained log entries into the view, if any were logged since
+// the last update. Returns the updated entry count
+func updateLogView(v *output.View, last uint64) uint64 {
+	entries, count := logging.Entries()
+	if count == last {
+		return last
+	}
+	lines := make([]string, len(entries))
+	colors := make([]ui.Attribute, len(entries))
+	for n, e := range entries {
+		lines[n] = e.String()
+		colors[n] = levelColor(e.Level)
+	}
+	v.SetLines(lines, colors)
+	return count
+}
+
+// Show recent log entries, following new entries while
+// scrolled to the bottom
+func LogView() {
+	v := output.NewView()
+	v.Title = "ctop log"
+	alignPipe(v)
+	count := updateLogView(v, 0)
+
+	runOutputView(v, func() {
+		ui.Handle("/timer/refresh", func(ui.Event) {
+			count = updateLogView(v, count)
+			ui.Render(v)
+		})
+	})
+}
@@ -4,10 +4,16 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"text/tabwriter"
 
 	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
 	"github.com/bcicen/ctop/cwidgets/compact"
 	"github.com/bcicen/ctop/logging"
+	"github.com/bcicen/ctop/metrics"
 	"github.com/bcicen/ctop/widgets"
 	ui "github.com/gizak/termui"
 )
@@ -20,6 +26,11 @@ var (
 	cursor *GridCursor
 	cGrid  *compact.CompactGrid
 	header *widgets.CTopHeader
+	footer *widgets.CTopFooter
+	banner *widgets.CTopBanner
+
+	configPath string // config file loaded, or to save to
+	uiStarted  bool
 
 	versionStr = fmt.Sprintf("ctop version %v, build %v", version, build)
 )
@@ -28,13 +39,68 @@ func main() {
 	defer panicExit()
 
 	// parse command line arguments
+	var configFlag = flag.String("config", "", "load configuration from the given `file`")
+	var noSaveFlag = flag.Bool("no-save", false, "do not save settings to the config file on exit")
 	var versionFlag = flag.Bool("v", false, "output version information and exit")
 	var helpFlag = flag.Bool("h", false, "display this help dialog")
-	var filterFlag = flag.String("f", "", "filter containers")
+	var filterFlag = flag.String("f", "", "filter containers, as in the interactive filter (e.g. `web image:nginx state:running`)")
 	var activeOnlyFlag = flag.Bool("a", false, "show active containers only")
+	var showFlag = flag.String("show", "", "containers shown at startup: running or all (default from config, else all)")
 	var sortFieldFlag = flag.String("s", "", "select container sort field")
 	var reverseSortFlag = flag.Bool("r", false, "reverse container sort order")
+	var connectorFlag = flag.String("connector", "", "container source to connect to: "+strings.Join(connectorNames(), ", ")+" (default docker)")
+	flag.StringVar(&kubeNamespace, "namespace", "", "with the kubelet connector, show only pods in the given `namespace`")
+	var profileFlag = flag.String("p", "", "apply the named profile from the config file")
+	flag.StringVar(profileFlag, "profile", "", "alias for -p")
+	var exportConfigFlag = flag.String("export-config", "", "write the complete effective configuration to the given `file` (- for stdout) and exit")
+	var importConfigFlag = flag.String("import-config", "", "validate a config `file` and install it to the config path, after confirming the changes")
+	var forceFlag = flag.Bool("force", false, "with -import-config, install without confirmation")
+	var helpEnvFlag = flag.Bool("help-env", false, "list environment variables overriding config, with effective values and their source")
+	flag.StringVar(filterFlag, "filter", "", "alias for -f")
+	flag.StringVar(sortFieldFlag, "sort", "", "alias for -s")
+	flag.BoolVar(reverseSortFlag, "reverse", false, "alias for -r")
 	var invertFlag = flag.Bool("i", false, "invert default colors")
+	var asciiFlag = flag.Bool("ascii", false, "use ASCII-only drawing characters")
+	var setTitleFlag = flag.Bool("set-title", false, "set the terminal title to a summary of running containers and firing alerts, see titleFormat")
+	var hostRootFlag = flag.String("host-root", "", "`path` the host filesystem is mounted at when running in a container, prefixing /proc and /sys/fs/cgroup (default /, or /hostfs or /rootfs if mounted)")
+	var selectFlag = flag.String("select", "", "start with the cursor on the container with the given `ID or name`, or a unique prefix of either")
+	var readOnlyFlag = flag.Bool("read-only", false, "disable all actions changing containers, such as stop, rename, attach and custom actions")
+	var stdoutFlag = flag.Bool("stdout", false, "print container stats to stdout at each refresh, without the UI")
+	var onceFlag = flag.Bool("once", false, "with -stdout, print a single refresh interval and exit")
+	var iterFlag = flag.Int("iterations", 0, "with -stdout, print the given number of refresh intervals and exit")
+	var intervalFlag = flag.Duration("interval", 0, "set the refresh and collection interval, e.g. 5s")
+	var csvFlag = flag.String("export-csv", "", "write the container table to the given CSV file and exit, without the UI")
+	var bundleFlag = flag.String("bundle", "", "write a support bundle of displayed containers to the given `file` (.tar.gz) and exit, without the UI")
+	var formatFlag = flag.String("format", "", "output format for stats printed without the UI: table, json or json-pretty; with -list, a Go template")
+	var eventsFlag = flag.Bool("events", false, "print container events to stdout as they arrive, without the UI")
+	var listFlag = flag.Bool("list", false, "print displayed containers after one refresh interval and exit, formatted by -format")
+	var exp exporterOpts
+	flag.StringVar(&exp.api, "listen", "", "serve a read-only JSON API on the given `address` (e.g. 127.0.0.1:8080)")
+	flag.StringVar(&exp.prometheus, "prometheus", "", "serve Prometheus metrics on the given `address` (e.g. :9323)")
+	flag.StringVar(&exp.statsd, "statsd", "", "push metrics to a StatsD server at the given `host:port`")
+	flag.StringVar(&exp.graphite, "graphite", "", "push metrics to a Graphite server at the given `host:port`")
+	flag.StringVar(&exp.prefix, "metric-prefix", "ctop", "metric name prefix for StatsD and Graphite")
+	flag.StringVar(&exp.influxFile, "influx-file", "", "append metrics as InfluxDB line protocol to the given `path`")
+	flag.StringVar(&exp.influxURL, "influx-url", "", "write metrics to an InfluxDB v2 server at the given `url`")
+	flag.StringVar(&exp.influxToken, "influx-token", "", "InfluxDB API token")
+	flag.StringVar(&exp.influxOrg, "influx-org", "", "InfluxDB organization")
+	flag.StringVar(&exp.influxBucket, "influx-bucket", "", "InfluxDB bucket")
+	flag.StringVar(&exp.record, "record", "", "record container snapshots and metrics to the given `file`")
+	flag.StringVar(&exp.debug, "debug-listen", "", "serve pprof profiles and internal state on the given loopback `address` (e.g. 127.0.0.1:6060)")
+	flag.StringVar(&replayPath, "replay", "", "play back a recording `file` in place of the docker daemon")
+	var bellFlag = flag.Bool("bell", false, "ring the terminal bell on watched container events and alerts")
+	var desktopFlag = flag.Bool("desktop-notify", false, "send desktop notifications on watched container events and alerts")
+	var notifyEventsFlag = flag.String("notify-events", "", "container events notified for watched containers (default die,oom,unhealthy)")
+	var pipeCmdFlag = flag.String("pipe-cmd", "", "default command `template` offered when piping a container to a command")
+	var debugFlag = flag.Bool("debug", false, "log at debug level to a file, see -debug-file")
+	var debugFileFlag = flag.String("debug-file", "", "debug log `path` (default $XDG_CACHE_HOME/ctop/ctop.log)")
+	var testWebhookFlag = flag.String("test-webhook", "", "send a sample alert payload to the given `url` and exit")
+	flag.BoolVar(&hookDryRun, "dry-run-hooks", false, "notify the commands event hooks would run instead of running them")
+	var checkAlertsFlag = flag.Bool("check-alert-config", false, "validate the alert rules of the config file and -alert options, then exit")
+	var ruleFlags stringList
+	flag.Var(&ruleFlags, "alert", "define a threshold alert as `metric>value[,cooldown=duration][,scope=filter][,webhook=url]` (repeatable)")
+	var actionFlags stringList
+	flag.Var(&actionFlags, "action", "define a custom action as `name,key[,detach]=command` (repeatable)")
 	flag.Parse()
 
 	if *versionFlag {
@@ -49,71 +115,497 @@ func main() {
 
 	// init logger
 	log = logging.Init()
+	if *debugFlag {
+		path := *debugFileFlag
+		if path == "" {
+			path = defaultDebugPath()
+		}
+		if err := log.EnableDebug(path); err != nil {
+			fmt.Printf("failed to open debug log: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *testWebhookFlag != "" {
+		TestWebhook(*testWebhookFlag)
+		os.Exit(0)
+	}
+
+	if *checkAlertsFlag {
+		if !CheckAlertConfig(*configFlag, ruleFlags) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
 	// init global config
 	config.Init()
+	loadConfigFile(*configFlag)
+	for _, w := range config.LoadEnv() {
+		fmt.Fprintf(os.Stderr, "config: %s\n", w)
+		log.Notify("config: %s", w)
+	}
+
+	// apply the selected profile over config file values
+	if *profileFlag != "" {
+		config.UpdateFrom("profile", *profileFlag, config.SourceFlag)
+	}
+	if err := config.ApplyProfile(config.GetVal("profile")); err != nil {
+		fmt.Printf("%s, expected one of: %s\n", err, strings.Join(config.ProfileNames(), ", "))
+		os.Exit(1)
+	}
+	for _, w := range checkLoadedParams() {
+		fmt.Fprintf(os.Stderr, "config: %s\n", w)
+		log.Notify("config: %s", w)
+	}
 
-	// override default config values with command line flags
+	if *noSaveFlag {
+		config.SetSwitchFrom("saveState", false, config.SourceFlag)
+	}
+
+	// override default and config file values with command line flags
 	if *filterFlag != "" {
-		config.Update("filterStr", *filterFlag)
+		if _, err := parseFilter(*filterFlag); err != nil {
+			fmt.Printf("invalid filter: %s\n", err)
+			os.Exit(1)
+		}
+		config.UpdateFrom("filterStr", *filterFlag, config.SourceFlag)
+	}
+
+	if *connectorFlag != "" {
+		config.UpdateFrom("connector", *connectorFlag, config.SourceFlag)
+	}
+	if loadTestCount > 0 {
+		config.UpdateFrom("connector", "mock", config.SourceFlag)
+		config.SetSwitchFrom("saveState", false, config.SourceFlag)
+		frames = &frameStats{}
+	}
+	validConnector()
+
+	switch *showFlag {
+	case "":
+	case "all", "running":
+		config.SetSwitchFrom("allContainers", *showFlag == "all", config.SourceFlag)
+	default:
+		fmt.Printf("invalid value for -show: %s, expected running or all\n", *showFlag)
+		os.Exit(1)
 	}
 
 	if *activeOnlyFlag {
-		config.Toggle("allContainers")
+		config.SetSwitchFrom("allContainers", false, config.SourceFlag)
 	}
 
 	if *sortFieldFlag != "" {
 		validSort(*sortFieldFlag)
-		config.Update("sortField", *sortFieldFlag)
+		config.UpdateFrom("sortField", *sortFieldFlag, config.SourceFlag)
+	} else if s := config.GetVal("sortField"); Sorters[s] == nil {
+		log.Notify("config: invalid sort field %s, using default", s)
+		config.UpdateFrom("sortField", "state", config.SourceDefault)
 	}
 
 	if *reverseSortFlag {
-		config.Toggle("sortReversed")
+		config.SetSwitchFrom("sortReversed", true, config.SourceFlag)
+	}
+
+	if *bellFlag {
+		config.SetSwitchFrom("notifyBell", true, config.SourceFlag)
+	}
+
+	if *desktopFlag {
+		config.SetSwitchFrom("notifyDesktop", true, config.SourceFlag)
+	}
+
+	if *pipeCmdFlag != "" {
+		config.UpdateFrom("pipeCmd", *pipeCmdFlag, config.SourceFlag)
+	}
+
+	if *notifyEventsFlag != "" {
+		config.UpdateFrom("notifyEvents", *notifyEventsFlag, config.SourceFlag)
+	}
+
+	for _, s := range actionFlags {
+		if err := config.ParseAction(s, config.SourceFlag); err != nil {
+			fmt.Printf("invalid action: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	validActions()
+
+	for _, s := range ruleFlags {
+		if err := config.ParseRule(s, config.SourceFlag); err != nil {
+			fmt.Printf("invalid alert: %s\n", err)
+			os.Exit(1)
+		}
+	}
+	validRules()
+
+	if err := validHooks(); err != nil {
+		fmt.Printf("invalid hook: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *intervalFlag != 0 {
+		if *intervalFlag < 0 {
+			fmt.Printf("invalid interval: %s\n", *intervalFlag)
+			os.Exit(1)
+		}
+		config.UpdateFrom("refreshInterval", intervalFlag.String(), config.SourceFlag)
 	}
 
-	// init ui
 	if *invertFlag {
-		InvertColorMap()
+		config.SetSwitchFrom("invertColors", true, config.SourceFlag)
+	}
+
+	if *setTitleFlag {
+		config.SetSwitchFrom("setTitle", true, config.SourceFlag)
+	}
+
+	if err := validTimeFormat(config.GetVal("timeFormat")); err != nil {
+		fmt.Printf("invalid timeFormat: %s\n", err)
+		os.Exit(1)
+	}
+	if err := validDurationStyle(config.GetVal("durationStyle")); err != nil {
+		fmt.Printf("invalid durationStyle: %s\n", err)
+		os.Exit(1)
+	}
+	if err := validTitleFormat(config.GetVal("titleFormat")); err != nil {
+		fmt.Printf("invalid titleFormat: %s\n", err)
+		os.Exit(1)
+	}
+	if err := compact.ValidGaugeMetric(config.GetVal("gaugeMetric")); err != nil {
+		fmt.Printf("invalid gaugeMetric: %s\n", err)
+		os.Exit(1)
+	}
+	if err := compact.ValidChangeColumns(config.GetVal("highlightColumns")); err != nil {
+		fmt.Printf("invalid highlightColumns: %s\n", err)
+		os.Exit(1)
+	}
+
+	hostRoot := *hostRootFlag
+	if hostRoot == "" {
+		hostRoot = metrics.DetectHostRoot()
+	} else if fi, err := os.Stat(hostRoot); err != nil || !fi.IsDir() {
+		fmt.Printf("invalid host root: %s is not a directory\n", hostRoot)
+		os.Exit(1)
+	}
+	metrics.SetHostRoot(hostRoot)
+	if metrics.HostRoot() != "/" {
+		log.Noticef("reading host files under %s", metrics.HostRoot())
+	}
+
+	if *readOnlyFlag {
+		config.SetSwitchFrom("readOnly", true, config.SourceFlag)
+	}
+
+	if *asciiFlag {
+		config.SetSwitchFrom("asciiMode", true, config.SourceFlag)
+	} else if !utf8Locale() {
+		config.SetSwitchFrom("asciiMode", true, config.SourceDefault)
+	}
+
+	if *helpEnvFlag {
+		printEnvHelp()
+		os.Exit(0)
+	}
+
+	if *exportConfigFlag != "" {
+		if err := ExportConfig(*exportConfigFlag); err != nil {
+			fmt.Printf("failed to export config: %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *importConfigFlag != "" {
+		if err := ImportConfig(*importConfigFlag, *forceFlag); err != nil {
+			fmt.Printf("failed to import config: %s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
-	ui.ColorMap = ColorMap // override default colormap
+
+	if *listFlag {
+		format := *formatFlag
+		if format == "" {
+			format = defaultListFormat
+		}
+		applyRefreshInterval()
+		cursor = NewGridCursor()
+		cGrid = compact.NewCompactGrid() // load column config
+		ListContainers(format)
+		log.Exit()
+		return
+	}
+
+	if *eventsFlag {
+		// events need no metrics, so collectors are never started
+		atomic.StoreInt32(&collectHeld, 1)
+		applyRefreshInterval()
+		cursor = NewGridCursor()
+		StreamEvents()
+		log.Exit()
+		return
+	}
+
+	if *csvFlag != "" {
+		applyRefreshInterval()
+		cursor = NewGridCursor()
+		cGrid = compact.NewCompactGrid() // load column config
+		ExportCSV(*csvFlag)
+		log.Exit()
+		return
+	}
+
+	if *bundleFlag != "" {
+		applyRefreshInterval()
+		cursor = NewGridCursor()
+		cGrid = compact.NewCompactGrid() // load column config
+		ExportBundle(*bundleFlag)
+		log.Exit()
+		return
+	}
+
+	// print stats without the ui; a format given without -stdout
+	// prints a single snapshot
+	if *stdoutFlag || *formatFlag != "" {
+		format := *formatFlag
+		if format == "" {
+			format = "table"
+		}
+		if !streamFormats[format] {
+			fmt.Printf("invalid output format: %s\n", format)
+			os.Exit(1)
+		}
+		applyRefreshInterval()
+		cursor = NewGridCursor()
+		cGrid = compact.NewCompactGrid() // load column config
+		if err := exp.start(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		iterations := *iterFlag
+		if iterations < 0 {
+			fmt.Printf("invalid iterations: %d\n", iterations)
+			os.Exit(1)
+		}
+		if *onceFlag || !*stdoutFlag {
+			iterations = 1
+		}
+		ok := StreamStats(format, iterations)
+		stopExporters()
+		log.Exit()
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// init grid and cursor, starting exporters before the ui so
+	// that errors such as a busy port are printed as they are
+	applyRefreshInterval()
+	cursor = NewGridCursor()
+	cGrid = compact.NewCompactGrid()
+	if err := exp.start(); err != nil {
+		stopExporters() // those started before the error
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// init ui
+	cwidgets.SetASCII(config.GetSwitchVal("asciiMode"))
+	applyTheme() // override default colormap
 	if err := ui.Init(); err != nil {
+		stopExporters()
 		panic(err)
 	}
+	uiStarted = true
 
 	defer Shutdown()
-	// init grid, cursor, header
-	cursor = NewGridCursor()
-	cGrid = compact.NewCompactGrid()
+	safeGo(handleSignals)
+	safeGo(handleSuspend)
+	// init refresh timer
+	ui.Merge("refresh", refreshTimer())
+
+	// init header, footer
 	header = widgets.NewCTopHeader()
+	footer = widgets.NewCTopFooter()
+	banner = widgets.NewCTopBanner()
+
+	if *selectFlag != "" {
+		selectStartup(*selectFlag)
+	}
 
 	for {
 		exit := Display()
-		if exit {
+		if exit || quitRequested() {
 			return
 		}
 	}
 }
 
-func Shutdown() {
-	log.Notice("shutting down")
-	log.Exit()
-	ui.Close()
+// load the config file given, or the first found in the search paths.
+// Problems with individual settings are warned about, not fatal
+func loadConfigFile(path string) {
+	if path == "" {
+		if path = config.FindFile(); path == "" {
+			if paths := config.FilePaths(); len(paths) > 0 {
+				configPath = paths[0]
+			}
+			return
+		}
+	}
+	configPath = path
+	warnings, err := config.LoadFile(path)
+	if err != nil {
+		fmt.Printf("failed to load config: %s\n", err)
+		os.Exit(1)
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "config: %s\n", w)
+		log.Notify("config: %s", w)
+	}
+}
+
+// UI settings persisted to the config file, along with any
+// changed from the settings menu
+var savedKeys = []string{"sortField", "sortReversed", "filterStr", "allContainers", "columns", "gaugeMetric", "refreshInterval"}
+
+// write current UI settings to the config file
+func saveConfig() error {
+	if configPath == "" {
+		return fmt.Errorf("no config file path")
+	}
+	keys := savedKeys
+	if config.GetSwitchVal("savePins") {
+		keys = append(keys, "pins")
+	}
+	for _, s := range config.Settings() {
+		if changedSettings[s.Key] && !known(keys, s.Key) {
+			keys = append(keys, s.Key)
+		}
+	}
+	err := config.SaveFile(configPath, keys)
+	if err != nil && err != config.ErrNoChanges {
+		log.Errorf("failed to save config: %s", err)
+	}
+	return err
+}
+
+// print environment variables overriding config keys, with their
+// effective values and the source of each
+func printEnvHelp() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VARIABLE\tVALUE\tSOURCE")
+	for _, v := range config.EnvVars() {
+		if v.Source == "" {
+			v.Source = "-" // unset
+		}
+		name := strings.Join(append([]string{v.Name}, v.Aliases...), ", ")
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, strconv.Quote(v.Val), v.Source)
+	}
+	w.Flush()
+	fmt.Println("\nprecedence: flag > env > file > default")
 }
 
 // ensure a given sort field is valid
 func validSort(s string) {
 	if _, ok := Sorters[s]; !ok {
-		fmt.Printf("invalid sort field: %s\n", s)
+		fmt.Printf("invalid sort field: %s, expected one of: %s\n", s, strings.Join(SortFields(), ", "))
 		os.Exit(1)
 	}
 }
 
-func panicExit() {
-	if r := recover(); r != nil {
-		Shutdown()
-		fmt.Printf("panic: %s\n", r)
-		os.Exit(1)
+// Addresses and options for the api server and metric exporters
+type exporterOpts struct {
+	api, prometheus        string
+	statsd, graphite       string
+	prefix                 string
+	influxFile, influxURL  string
+	influxToken, influxOrg string
+	influxBucket           string
+	record                 string
+	debug                  string
+}
+
+// start the api server and metric exporters for each address given
+func (o exporterOpts) start() error {
+	if o.debug != "" {
+		if err := startDebugServer(o.debug); err != nil {
+			return fmt.Errorf("failed to start debug listener: %s", err)
+		}
+	}
+	if o.api != "" {
+		if err := startAPI(o.api); err != nil {
+			return fmt.Errorf("failed to start api listener: %s", err)
+		}
+	}
+	if o.prometheus != "" {
+		if err := startPrometheus(o.prometheus); err != nil {
+			return fmt.Errorf("failed to start prometheus listener: %s", err)
+		}
 	}
+	if o.statsd != "" {
+		sink, err := newStatsdSink(o.statsd, o.prefix)
+		if err != nil {
+			return fmt.Errorf("invalid statsd address: %s", err)
+		}
+		startForwarder("statsd", sink)
+	}
+	if o.graphite != "" {
+		sink, err := newGraphiteSink(o.graphite, o.prefix)
+		if err != nil {
+			return fmt.Errorf("invalid graphite address: %s", err)
+		}
+		startForwarder("graphite", sink)
+	}
+	if o.influxFile != "" {
+		sink, err := newInfluxFileSink(o.influxFile)
+		if err != nil {
+			return fmt.Errorf("failed to open influx file: %s", err)
+		}
+		startForwarder("influx-file", sink)
+	}
+	if o.influxURL != "" {
+		sink, err := newInfluxHTTPSink(o.influxURL, o.influxToken, o.influxOrg, o.influxBucket)
+		if err != nil {
+			return fmt.Errorf("invalid influx options: %s", err)
+		}
+		startForwarder("influx", sink)
+	}
+	if o.record != "" {
+		if err := startRecorder(o.record); err != nil {
+			return fmt.Errorf("failed to start recording: %s", err)
+		}
+	}
+	return nil
+}
+
+func stopExporters() {
+	stopAPI()
+	stopPrometheus()
+	stopForwarders()
+	stopRecorder()
+	stopDebugServer()
+}
+
+// ensure all custom action templates render with container fields
+func validActions() {
+	for _, a := range config.GlobalActions {
+		if _, err := a.Render(ActionContext{Labels: make(map[string]string)}); err != nil {
+			fmt.Printf("invalid action: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// determine whether the locale set in the environment, if any, uses UTF-8
+func utf8Locale() bool {
+	for _, k := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := strings.ToUpper(os.Getenv(k)); v != "" {
+			return strings.Contains(v, "UTF-8") || strings.Contains(v, "UTF8")
+		}
+	}
+	return true
 }
 
 var helpMsg = `ctop - container metric viewer
@@ -123,7 +615,24 @@ usage: ctop [options]
 options:
 `
 
+// Repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ", ") }
+func (l *stringList) Set(s string) error { *l = append(*l, s); return nil }
+
+// Flags left out of the help dialog, for development use
+var hiddenFlags = map[string]bool{}
+
 func printHelp() {
 	fmt.Println(helpMsg)
-	flag.PrintDefaults()
+	shown := flag.NewFlagSet("ctop", flag.ContinueOnError)
+	shown.SetOutput(os.Stdout)
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			shown.Var(f.Value, f.Name, f.Usage)
+			shown.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	shown.PrintDefaults()
 }
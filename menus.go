@@ -1,7 +1,16 @@
 package main
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/bcicen/ctop/logging"
 	"github.com/bcicen/ctop/widgets"
 	"github.com/bcicen/ctop/widgets/menu"
 	ui "github.com/gizak/termui"
@@ -9,11 +18,56 @@ import (
 
 var helpDialog = []menu.Item{
 	menu.Item{"[a] - toggle display of all containers", ""},
-	menu.Item{"[f] - filter displayed containers", ""},
+	menu.Item{"[c] - mark container for comparison / compare with marked", ""},
+	menu.Item{"[enter] - expanded view ([p] to change restart policy, [v] to list env)", ""},
+	menu.Item{"[i] - inspect selected container", ""},
+	menu.Item{"[d] - show filesystem changes of selected container", ""},
+	menu.Item{"[C] - commit selected container to an image", ""},
+	menu.Item{"[K] - stop all displayed running containers", ""},
+	menu.Item{"[B] - restart all displayed running containers", ""},
+	menu.Item{"[P] - remove all displayed stopped containers", ""},
+	menu.Item{"[L] - update resource limits of selected container", ""},
+	menu.Item{"[R] - rename selected container", ""},
+	menu.Item{"[U] - pull image and recreate selected container", ""},
+	menu.Item{"[M] - stop, then remove selected container or group", ""},
+	menu.Item{"[I] - pull image of selected container", ""},
+	menu.Item{"[z] - pause / unpause selected container", ""},
+	menu.Item{"[A] - attach to selected container", ""},
+	menu.Item{"[o] - open published port in browser", ""},
+	menu.Item{"[e] - export inspect data or filesystem of selected container", ""},
+	menu.Item{"[E] - export displayed table to CSV", ""},
+	menu.Item{"[b] - write support bundle of displayed containers", ""},
+	menu.Item{"[X] - cancel filesystem exports in progress", ""},
+	menu.Item{"[|] - run a command on selected container, showing its output", ""},
+	menu.Item{"[:] - run a command on a container by ID or name prefix, e.g. :stop 3f2a", ""},
+	menu.Item{"[W] - watch selected container for bell/desktop notifications", ""},
+	menu.Item{"[p] - pin selected container to the top / unpin", ""},
+	menu.Item{"[u] - clear all pins", ""},
+	menu.Item{"[y] - copy container id, name or exec command", ""},
+	menu.Item{"[f] - filter displayed containers ([esc] to clear)", ""},
+	menu.Item{"[T] - adjust settings", ""},
+	menu.Item{"[Z] - save current settings to config file", ""},
+	menu.Item{"[O] - switch between config file profiles", ""},
 	menu.Item{"[h] - open this help dialog", ""},
 	menu.Item{"[H] - toggle ctop header", ""},
-	menu.Item{"[s] - select container sort field", ""},
+	menu.Item{"[S] - toggle host summary in header", ""},
+	menu.Item{"[N] - show notification history", ""},
+	menu.Item{"[V] - view recent log entries", ""},
+	menu.Item{"[l] - view logs of selected container", ""},
+	menu.Item{"[t] - show recent container events, of all or the selected container", ""},
+	menu.Item{"[x] - dismiss error notifications", ""},
+	menu.Item{"[s] - select container sort field (again to reverse)", ""},
+	menu.Item{"[Tab] - select sort column in the header (enter to sort, again to reverse)", ""},
 	menu.Item{"[r] - reverse container sort order", ""},
+	menu.Item{"[ctrl-r] - refresh selected container now, retrying a failed inspect", ""},
+	menu.Item{"[w] - toggle wide mode (all columns, scroll with left/right)", ""},
+	menu.Item{"[m] - cycle gauge column metric", ""},
+	menu.Item{"[g] - toggle grouping by image ([enter] on a group to expand)", ""},
+	menu.Item{"[G] - summarize by label value, e.g. team", ""},
+	menu.Item{"[+/-] - increase/decrease refresh rate", ""},
+	menu.Item{"[0-9] - jump to row number", ""},
+	menu.Item{"['] - jump to next container by first letter", ""},
+	menu.Item{"[ctrl-z] - suspend to the shell", ""},
 	menu.Item{"[q] - exit ctop", ""},
 }
 
@@ -24,7 +78,13 @@ func HelpMenu() {
 
 	m := menu.NewMenu()
 	m.BorderLabel = "Help"
-	m.AddItems(helpDialog...)
+	m.AddItems(helpItems()...)
+	for _, a := range config.GlobalActions {
+		if readOnly() {
+			break
+		}
+		m.AddItems(menu.Item{Val: fmt.Sprintf("[%s] - %s (custom action)", a.Key, a.Name)})
+	}
 	ui.Render(m)
 	ui.Handle("/sys/kbd/", func(ui.Event) {
 		ui.StopLoop()
@@ -36,32 +96,339 @@ func FilterMenu() {
 	ui.DefaultEvtStream.ResetHandlers()
 	defer ui.DefaultEvtStream.ResetHandlers()
 
+	prev := config.GetVal("filterStr")
 	i := widgets.NewInput()
 	i.BorderLabel = "Filter"
-	i.SetY(ui.TermHeight() - i.Height)
-	i.Data = config.GetVal("filterStr")
-	ui.Render(i)
+	i.Data = prev
+
+	// filter container rows on each keystroke, showing the match
+	// count and any error in the prompt
+	update := func(s string) {
+		config.Update("filterStr", s)
+		RefreshDisplay()
+		i.BorderLabel = fmt.Sprintf("Filter: %d matches", len(cursor.filtered))
+		if _, err := parseFilter(s); err != nil {
+			i.SetError(err.Error())
+		} else {
+			i.SetError("")
+		}
+		i.SetY(ui.TermHeight() - i.Height)
+		ui.Render(i)
+	}
+	update(prev)
 
-	// refresh container rows on input
 	stream := i.Stream()
-	go func() {
+	done := make(chan bool)
+	safeGo(func() {
 		for s := range stream {
-			config.Update("filterStr", s)
-			RefreshDisplay()
-			ui.Render(i)
+			update(s)
 		}
-	}()
+		close(done)
+	})
 
 	i.InputHandlers()
 	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
-		config.Update("filterStr", "")
 		ui.StopLoop()
 	})
 	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
-		config.Update("filterStr", i.Data)
+		prev = i.Data
+		ui.StopLoop()
+	})
+	ui.Loop()
+	close(stream)
+	<-done
+	// restore the filter in effect before editing, unless committed
+	config.Update("filterStr", prev)
+}
+
+// Valid docker container name
+var containerNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// Prompt for a new name for the selected container. On failure,
+// the error is shown in the prompt and input is kept for editing
+func RenameMenu() {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+
+	i := widgets.NewInput()
+	i.BorderLabel = "Rename"
+	i.SetMaxLen(48)
+	i.Data = rawName(c)
+	align := func() {
+		ui.Clear()
+		RedrawRows(false)
+		i.SetY(ui.TermHeight() - i.Height)
+		ui.Render(i)
+	}
+	align()
+
+	i.InputHandlers()
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		name := i.Data
+		switch {
+		case name == rawName(c):
+			ui.StopLoop()
+			return
+		case !containerNameRe.MatchString(name):
+			i.SetError("invalid name: use [a-zA-Z0-9][a-zA-Z0-9_.-]+")
+		default:
+			err := cursor.Source().Rename(c.Id, name)
+			if err == nil {
+				log.Notify("renamed %s to %s", c.GetMeta("name"), name)
+				ui.StopLoop()
+				return
+			}
+			i.SetError(err.Error())
+		}
+		align()
+	})
+	ui.Loop()
+}
+
+// Prompt for a label key to summarize containers by, one row per
+// value of the label. An empty key returns to the usual rows
+func SummaryLabelMenu() {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	i := widgets.NewInput()
+	i.BorderLabel = "Summarize by label (empty for none)"
+	i.SetMaxLen(64)
+	i.Data = config.GetVal("summaryLabel")
+	align := func() {
+		ui.Clear()
+		RedrawRows(false)
+		i.SetY(ui.TermHeight() - i.Height)
+		ui.Render(i)
+	}
+	align()
+
+	i.InputHandlers()
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		key := strings.TrimSpace(i.Data)
+		if err := validLabelKey(key); err != nil {
+			i.SetError(err.Error())
+			align()
+			return
+		}
+		config.Update("summaryLabel", key)
+		if key != "" {
+			footer.Flash(fmt.Sprintf("summarizing containers by label %s", key), 2*time.Second)
+		} else {
+			footer.Flash("showing containers unsummarized", 2*time.Second)
+		}
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
+
+// Editable resource limit field
+type limitField struct {
+	label  string
+	format func(Limits) string
+	parse  func(*Limits, string) error
+}
+
+var limitFields = []limitField{
+	{
+		label:  "memory",
+		format: func(l Limits) string { return formatBytesLimit(l.Memory) },
+		parse: func(l *Limits, s string) (err error) {
+			l.Memory, err = parseBytes(s)
+			if err == nil && l.Memory < 0 {
+				err = fmt.Errorf("memory limit cannot be unlimited")
+			}
+			return err
+		},
+	},
+	{
+		label:  "memory+swap",
+		format: func(l Limits) string { return formatBytesLimit(l.MemorySwap) },
+		parse: func(l *Limits, s string) (err error) {
+			l.MemorySwap, err = parseBytes(s)
+			return err
+		},
+	},
+	{
+		label:  "cpus",
+		format: func(l Limits) string { return formatCPUs(l.CPUs) },
+		parse: func(l *Limits, s string) (err error) {
+			l.CPUs, err = parseCPUs(s)
+			return err
+		},
+	},
+}
+
+// Edit and apply resource limits of the selected container
+func LimitsMenu() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	if c.State() != "running" {
+		log.Notify("cannot update limits of %s: container not running", c.GetMeta("name"))
+		return
+	}
+
+	cur, err := cursor.Source().Limits(c.Id)
+	if err != nil {
+		log.NotifyError("failed to read limits of %s: %s", c.GetMeta("name"), err)
+		return
+	}
+	pending := cur
+	edit := -1
+
+	for {
+		ui.Clear()
+		ui.DefaultEvtStream.ResetHandlers()
+
+		m := menu.NewMenu()
+		m.Selectable = true
+		m.BorderLabel = fmt.Sprintf("Limits: %s", c.GetMeta("name"))
+		for n, f := range limitFields {
+			label := fmt.Sprintf("%-12s %s", f.label, f.format(pending))
+			if f.format(pending) != f.format(cur) {
+				label += " *"
+			}
+			m.AddItems(menu.Item{Val: strconv.Itoa(n), Label: label})
+		}
+		m.AddItems(menu.Item{Val: "apply", Label: "[apply changes]"})
+		if edit >= 0 {
+			m.SetCursor(strconv.Itoa(edit))
+		}
+		edit = -1
+
+		HandleKeys("up", m.Up)
+		HandleKeys("down", m.Down)
+		HandleKeys("exit", ui.StopLoop)
+		ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+			val := m.SelectedItem().Val
+			if val != "apply" {
+				edit, _ = strconv.Atoi(val)
+				ui.StopLoop()
+				return
+			}
+			err := cursor.Source().UpdateLimits(c.Id, pending)
+			if err != nil {
+				log.NotifyError("failed to update limits of %s: %s", c.GetMeta("name"), err)
+				ui.Render(footer)
+				return
+			}
+			c.SetMemLimit(pending.Memory)
+			log.Notify("updated limits of %s: %s", c.GetMeta("name"), pending)
+			ui.StopLoop()
+		})
+
+		ui.Render(m)
+		if footer.Active() {
+			ui.Render(footer)
+		}
+		ui.Loop()
+		if edit < 0 {
+			break
+		}
+		editLimit(&pending, limitFields[edit])
+	}
+	ui.DefaultEvtStream.ResetHandlers()
+}
+
+// Prompt for a new value of a single limit field
+func editLimit(l *Limits, f limitField) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	i := widgets.NewInput()
+	i.BorderLabel = f.label
+	i.SetMaxLen(32)
+	i.Data = f.format(*l)
+	if i.Data == "unset" {
+		i.Data = ""
+	}
+	align := func() {
+		i.SetY(ui.TermHeight() - i.Height)
+		ui.Render(i)
+	}
+	align()
+
+	i.InputHandlers()
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		parsed := *l
+		if err := f.parse(&parsed, i.Data); err != nil {
+			i.SetError(err.Error())
+			align()
+			return
+		}
+		*l = parsed
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
+
+var restartPolicies = []string{"no", "on-failure", "always", "unless-stopped"}
+
+// Select a new restart policy for a container
+func RestartPolicyMenu(c *Container) {
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+
+	m := menu.NewMenu()
+	m.Selectable = true
+	m.BorderLabel = fmt.Sprintf("Restart Policy: %s", c.GetMeta("name"))
+	for _, p := range restartPolicies {
+		m.AddItems(menu.Item{Val: p})
+	}
+	current := strings.Split(c.GetMeta("restart"), ":")[0]
+	m.SetCursor(current)
+
+	var selected string
+	HandleKeys("up", m.Up)
+	HandleKeys("down", m.Down)
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		selected = m.SelectedItem().Val
 		ui.StopLoop()
 	})
+	ui.Render(m)
 	ui.Loop()
+	ui.DefaultEvtStream.ResetHandlers()
+
+	if selected == "" {
+		return
+	}
+
+	var maxRetry int
+	if selected == "on-failure" {
+		ok := promptInput("Maximum retry count (0 for unlimited)", "0", nil, func(s string) (err error) {
+			maxRetry, err = strconv.Atoi(s)
+			if err != nil || maxRetry < 0 {
+				return fmt.Errorf("invalid retry count: %s", s)
+			}
+			return nil
+		})
+		if !ok {
+			return
+		}
+	}
+
+	if err := cursor.Source().SetRestartPolicy(c.Id, selected, maxRetry); err != nil {
+		log.NotifyError("failed to update restart policy of %s: %s", c.GetMeta("name"), err)
+		return
+	}
+	log.Notify("restart policy of %s is now %s", c.GetMeta("name"), c.GetMeta("restart"))
 }
 
 func SortMenu() {
@@ -71,25 +438,179 @@ func SortMenu() {
 
 	m := menu.NewMenu()
 	m.Selectable = true
-	m.SortItems = true
 	m.BorderLabel = "Sort Field"
 
+	current := config.GetVal("sortField")
 	for _, field := range SortFields() {
-		m.AddItems(menu.Item{field, ""})
+		item := menu.Item{Val: field}
+		// show sort direction for current field
+		if field == current {
+			arrow := cwidgets.Glyphs.DownArrow
+			if config.GetSwitchVal("sortReversed") {
+				arrow = cwidgets.Glyphs.UpArrow
+			}
+			item.Label = fmt.Sprintf("%s %c", field, arrow)
+		}
+		m.AddItems(item)
 	}
 
 	// set cursor position to current sort field
-	m.SetCursor(config.GetVal("sortField"))
+	m.SetCursor(current)
 
 	HandleKeys("up", m.Up)
 	HandleKeys("down", m.Down)
 	HandleKeys("exit", ui.StopLoop)
 
+	// selecting the current sort field again flips sort direction
 	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
-		config.Update("sortField", m.SelectedItem().Val)
+		sortBy(m.SelectedItem().Val)
 		ui.StopLoop()
 	})
 
 	ui.Render(m)
 	ui.Loop()
 }
+
+// Move a cursor along the column headers, starting at the sort column.
+// Enter sorts by the focused column, and again reverses the order
+func HeaderSortMenu() {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	var cols []*compact.Column
+	n := 0
+	for _, c := range compact.EnabledColumns() {
+		if c.Sort == "" {
+			continue
+		}
+		if c.Sort == config.GetVal("sortField") {
+			n = len(cols)
+		}
+		cols = append(cols, c)
+	}
+	if len(cols) == 0 {
+		return
+	}
+
+	defer compact.SetHeaderFocus("")
+	defer footer.Hide()
+	update := func() {
+		compact.SetHeaderFocus(cols[n].Name)
+		footer.Flash("sort by column: left/right to move, enter to sort or reverse, esc to leave", time.Minute)
+		RefreshDisplay()
+	}
+	update()
+
+	ui.Handle("/sys/kbd/<left>", func(ui.Event) {
+		if n > 0 {
+			n--
+			update()
+		}
+	})
+	ui.Handle("/sys/kbd/<right>", func(ui.Event) {
+		if n < len(cols)-1 {
+			n++
+			update()
+		}
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		sortBy(cols[n].Sort)
+		update()
+	})
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/sys/kbd/<tab>", func(ui.Event) { ui.StopLoop() })
+	ui.Handle("/timer/refresh", func(ui.Event) { RefreshDisplay() })
+	ui.Loop()
+}
+
+// Read a row number, jumping to the given row on enter
+func RowJumpMenu(prefix string) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	cursor.ShowIndex(true)
+	defer cursor.ShowIndex(false)
+	defer footer.Hide()
+
+	num := prefix
+	update := func() {
+		footer.Flash(fmt.Sprintf("jump to row: %s", num), time.Minute)
+		ui.Render(footer)
+	}
+	update()
+
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		key := strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		if strings.Index("0123456789", key) > -1 && len(key) == 1 {
+			num += key
+		}
+		if key == "C-8" && len(num) > 0 {
+			num = num[:len(num)-1]
+		}
+		update()
+	})
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		if n, err := strconv.Atoi(num); err == nil {
+			cursor.Jump(n - 1)
+		}
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
+
+// Jump to the next container whose name begins with the next key pressed
+func LetterJumpMenu() {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+	defer footer.Hide()
+
+	footer.Flash("jump to name: '", time.Minute)
+	ui.Render(footer)
+
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		key := strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		if len(key) == 1 {
+			cursor.JumpPrefix(key)
+		}
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
+
+func NotificationMenu() {
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	m := menu.NewMenu()
+	m.Selectable = true
+	m.BorderLabel = "Notifications"
+
+	all := logging.Notifications()
+	if len(all) == 0 {
+		m.AddItems(menu.Item{Val: "no notifications"})
+	}
+	// newest first
+	for i := len(all) - 1; i >= 0; i-- {
+		n := all[i]
+		level := "info"
+		if n.Error {
+			level = "error"
+		}
+		text := fmt.Sprintf("%s %-5s %s", n.Time.Format("15:04:05"), level, n.Msg)
+		m.AddItems(menu.Item{Val: text})
+	}
+
+	HandleKeys("up", m.Up)
+	HandleKeys("down", m.Down)
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/sys/kbd/x", func(ui.Event) {
+		logging.DismissNotifications()
+	})
+
+	ui.Render(m)
+	ui.Loop()
+}
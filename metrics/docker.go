@@ -1,9 +1,20 @@
 package metrics
 
 import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	api "github.com/fsouza/go-dockerclient"
 )
 
+const statsJitter = 500 * time.Millisecond
+
 // Docker collector
 type Docker struct {
 	Metrics
@@ -11,9 +22,13 @@ type Docker struct {
 	client     *api.Client
 	running    bool
 	stream     chan Metrics
-	done       chan bool
+	done       chan bool  // closed to stop the current run
+	exited     chan bool  // closed once the last run has ended
+	lock       sync.Mutex // guards running, stream, done and exited
 	lastCpu    float64
 	lastSysCpu float64
+	errors     int64
+	failed     int32 // set after a panic
 }
 
 func NewDocker(client *api.Client, id string) *Docker {
@@ -24,49 +39,259 @@ func NewDocker(client *api.Client, id string) *Docker {
 	}
 }
 
+// Start a run of the collector. A run stopped just before, as when a
+// container is paused and unpaused in quick succession, is let end
+// before the new run reads any stats
 func (c *Docker) Start() {
-	c.done = make(chan bool)
-	c.stream = make(chan Metrics)
+	done := make(chan bool)
+	exited := make(chan bool)
+	stream := make(chan Metrics)
+	c.lock.Lock()
+	prev := c.exited
+	c.done, c.exited, c.stream = done, exited, stream
+	c.running = true
+	c.lock.Unlock()
 	stats := make(chan *api.Stats)
 
 	go func() {
-		opts := api.StatsOptions{
-			ID:     c.id,
-			Stats:  stats,
-			Stream: true,
-			Done:   c.done,
+		defer trackGoroutine()()
+		defer close(stats)
+		if prev != nil {
+			select {
+			case <-prev:
+			case <-done:
+				return
+			}
 		}
-		c.client.Stats(opts)
-		c.running = false
+		for c.streamStats(stats, done) && c.pollStats(stats, done) {
+			atomic.AddInt64(&streamReconnects, 1)
+		}
+		c.lock.Lock()
+		if c.done == done {
+			c.running = false
+		}
+		c.lock.Unlock()
 	}()
 
 	go func() {
-		defer close(c.stream)
+		defer trackGoroutine()()
+		defer close(exited)
+		defer close(stream)
+		defer c.recoverPanic(stats)
+		var last time.Time
 		for s := range stats {
+			// downsample stats to the current collection interval, allowing
+			// for some jitter in the (roughly 1s) docker stats stream
+			if time.Since(last) < Interval()-statsJitter {
+				continue
+			}
+			last = time.Now()
 			c.ReadCPU(s)
 			c.ReadMem(s)
 			c.ReadNet(s)
 			c.ReadIO(s)
-			c.stream <- c.Metrics
+			stream <- c.Metrics
 		}
 		log.Infof("collector stopped for container: %s", c.id)
 	}()
 
-	c.running = true
 	log.Infof("collector started for container: %s", c.id)
 }
 
+// Stream stats until stopped or the stream ends. Returns true if stats
+// are to be polled instead: when at the stream limit, when moved to
+// polling to keep under it, or when the stream failed to open while
+// others are open, as when the daemon or a proxy caps connections
+func (c *Docker) streamStats(stats chan *api.Stats, done chan bool) bool {
+	demote, ok := streams.add(c)
+	if !ok {
+		return true
+	}
+	defer streams.remove(c)
+
+	ch := make(chan *api.Stats)
+	quit := make(chan bool)
+	errc := make(chan error, 1)
+	go func() {
+		defer trackGoroutine()()
+		errc <- c.client.Stats(api.StatsOptions{
+			ID:     c.id,
+			Stats:  ch,
+			Stream: true,
+			Done:   quit,
+		})
+	}()
+	// close the stream, waiting for it to end
+	stop := func() {
+		close(quit)
+		for range ch {
+		}
+		<-errc
+	}
+
+	var opened bool
+	for {
+		select {
+		case <-done:
+			stop()
+			return false
+		case <-demote:
+			stop()
+			log.Infof("stats stream closed for container %s, polling to keep under the stream limit", c.id)
+			return true
+		case s, ok := <-ch:
+			if !ok {
+				return c.streamFailed(<-errc, opened)
+			}
+			opened = true
+			select {
+			case stats <- s:
+			case <-done:
+				stop()
+				return false
+			}
+		}
+	}
+}
+
+// Return whether an error is due to a lack of permission, such as
+// EACCES on connecting to the daemon socket, as reported within the
+// URL and network errors of the docker client
+func PermissionDenied(err error) bool {
+	for err != nil {
+		if os.IsPermission(err) {
+			return true
+		}
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// Handle the end of a stream, returning true if stats are to be
+// polled instead
+func (c *Docker) streamFailed(err error, opened bool) bool {
+	if err == nil {
+		return false
+	}
+	atomic.AddInt64(&c.errors, 1)
+	if PermissionDenied(err) {
+		// reported once by the source as a lost connection
+		log.Infof("stats collector stopped for container %s: %s", c.id, err)
+		return false
+	}
+	if _, gone := err.(*api.NoSuchContainer); !opened && !gone && streams.failed(c) {
+		log.Warningf("stats stream failed to open for container %s, polling instead: %s", c.id, err)
+		return true
+	}
+	log.NotifyError("stats collector failed for container %s: %s", c.id, err)
+	return false
+}
+
+// Poll one-shot stats when due in the shared rotation, until stopped.
+// Returns true if stats may be streamed again
+func (c *Docker) pollStats(stats chan *api.Stats, done chan bool) bool {
+	due, promote := streams.addPolled(c)
+	defer streams.removePolled(c)
+	for {
+		select {
+		case <-done:
+			return false
+		case <-promote:
+			return true
+		case <-due:
+		}
+		s, err := c.statsOnce()
+		if err != nil {
+			atomic.AddInt64(&c.errors, 1)
+			if _, gone := err.(*api.NoSuchContainer); gone {
+				return false
+			}
+			log.Debugf("stats poll failed for container %s: %s", c.id, err)
+			continue
+		}
+		select {
+		case stats <- s:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// Read a single stats sample
+func (c *Docker) statsOnce() (*api.Stats, error) {
+	ch := make(chan *api.Stats, 1)
+	done := make(chan bool)
+	defer close(done)
+	err := c.client.Stats(api.StatsOptions{
+		ID:      c.id,
+		Stats:   ch,
+		Done:    done,
+		Timeout: pollTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s, ok := <-ch
+	if !ok {
+		return nil, fmt.Errorf("no stats returned")
+	}
+	return s, nil
+}
+
 func (c *Docker) Running() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.running
 }
 
 func (c *Docker) Stream() chan Metrics {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.stream
 }
 
-// Stop collector
+func (c *Docker) Errors() int {
+	return int(atomic.LoadInt64(&c.errors))
+}
+
+func (c *Docker) Failed() bool {
+	return atomic.LoadInt32(&c.failed) == 1
+}
+
+// Recover a panic while reading stats, stopping the stats stream
+// and marking the collector failed. Must be deferred directly
+func (c *Docker) recoverPanic(stats chan *api.Stats) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	log.Criticalf("collector panic for container %s: %v\n%s", c.id, r, debug.Stack())
+	log.NotifyError("collector for container %s stopped after an internal error: %v", c.id, r)
+	atomic.AddInt64(&c.errors, 1)
+	atomic.StoreInt32(&c.failed, 1)
+
+	// drain stats until the stream is closed
+	go c.Stop()
+	for range stats {
+	}
+}
+
+// Stop collector, if running. Stopping does not block on the run
+// ending, and may be repeated
 func (c *Docker) Stop() {
-	c.done <- true
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.running {
+		c.running = false
+		close(c.done)
+	}
 }
 
 func (c *Docker) ReadCPU(stats *api.Stats) {
@@ -78,6 +303,7 @@ func (c *Docker) ReadCPU(stats *api.Stats) {
 	syscpudiff := system - c.lastSysCpu
 
 	c.CPUUtil = round((cpudiff / syscpudiff * 100) * ncpus)
+	c.CPUTime = time.Duration(stats.CPUStats.CPUUsage.TotalUsage)
 	c.lastCpu = total
 	c.lastSysCpu = system
 	c.Pids = int(stats.PidsStats.Current)
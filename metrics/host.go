@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Host-wide metrics
+type HostMetrics struct {
+	CPUUtil  int   // -1 if unavailable
+	MemUsage int64 // -1 if unavailable
+	MemTotal int64
+	NCPU     int
+}
+
+func NewHostMetrics() HostMetrics {
+	return HostMetrics{
+		CPUUtil:  -1,
+		MemUsage: -1,
+	}
+}
+
+// Host metrics reader for the /proc filesystem under the host root
+type ProcHost struct {
+	lastTotal uint64
+	lastIdle  uint64
+}
+
+func (h *ProcHost) Read() HostMetrics {
+	m := NewHostMetrics()
+	m.NCPU = runtime.NumCPU()
+	h.readCPU(&m)
+	h.readMem(&m)
+	return m
+}
+
+func (h *ProcHost) readCPU(m *HostMetrics) {
+	f, err := os.Open(HostPath("/proc/stat"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return
+	}
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return
+	}
+
+	var total, idle uint64
+	for n, s := range fields[1:] {
+		v, _ := strconv.ParseUint(s, 10, 64)
+		total += v
+		// idle and iowait
+		if n == 3 || n == 4 {
+			idle += v
+		}
+	}
+
+	if h.lastTotal > 0 && total > h.lastTotal {
+		diff := float64(total - h.lastTotal)
+		busy := diff - float64(idle-h.lastIdle)
+		m.CPUUtil = round(busy / diff * 100)
+	}
+	h.lastTotal, h.lastIdle = total, idle
+}
+
+func (h *ProcHost) readMem(m *HostMetrics) {
+	f, err := os.Open(HostPath("/proc/meminfo"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var total, avail int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		v, _ := strconv.ParseInt(fields[1], 10, 64)
+		switch fields[0] {
+		case "MemTotal:":
+			total = v * 1024
+		case "MemAvailable:":
+			avail = v * 1024
+		}
+	}
+
+	if total > 0 {
+		m.MemTotal = total
+		m.MemUsage = total - avail
+	}
+}
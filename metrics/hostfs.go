@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Mount points of the host filesystem checked for when ctop runs in a
+// container, e.g. with -v /:/hostfs:ro
+var hostRootMounts = []string{"/hostfs", "/rootfs"}
+
+// Prefix of all host /proc and /sys/fs/cgroup paths
+var hostRoot = "/"
+
+// Set the path the host filesystem is mounted at
+func SetHostRoot(root string) {
+	hostRoot = filepath.Clean(root)
+}
+
+func HostRoot() string { return hostRoot }
+
+// Return the first known mount point holding a host /proc, or "/"
+func DetectHostRoot() string {
+	for _, root := range hostRootMounts {
+		if exists(filepath.Join(root, "proc", "stat")) {
+			return root
+		}
+	}
+	return "/"
+}
+
+// Return a host path under the host root
+func HostPath(p string) string {
+	return filepath.Join(hostRoot, p)
+}
+
+// Return the host cgroup version, 2 for a unified hierarchy
+func CgroupVersion() int {
+	if exists(HostPath("/sys/fs/cgroup/cgroup.controllers")) {
+		return 2
+	}
+	return 1
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
@@ -0,0 +1,90 @@
+// +build !release
+
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Create empty files under a temporary root, returning the root
+func hostTree(t *testing.T, files ...string) string {
+	root, err := ioutil.TempDir("", "ctop-hostfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCgroupVersion(t *testing.T) {
+	defer SetHostRoot("/")
+	cases := []struct {
+		name  string
+		files []string
+		want  int
+	}{
+		{"v1 per-controller", []string{"sys/fs/cgroup/memory/memory.usage_in_bytes", "sys/fs/cgroup/cpu/cpu.shares"}, 1},
+		{"v1 hybrid", []string{"sys/fs/cgroup/memory/memory.usage_in_bytes", "sys/fs/cgroup/unified/cgroup.controllers"}, 1},
+		{"v2 unified", []string{"sys/fs/cgroup/cgroup.controllers", "sys/fs/cgroup/system.slice/cgroup.procs"}, 2},
+		{"no cgroup fs", nil, 1},
+	}
+	for _, tc := range cases {
+		root := hostTree(t, tc.files...)
+		SetHostRoot(root)
+		if got := CgroupVersion(); got != tc.want {
+			t.Errorf("%s: CgroupVersion() = %d, want %d", tc.name, got, tc.want)
+		}
+		os.RemoveAll(root)
+	}
+}
+
+func TestHostPath(t *testing.T) {
+	defer SetHostRoot("/")
+	cases := []struct {
+		root, path, want string
+	}{
+		{"/", "/proc/stat", "/proc/stat"},
+		{"/hostfs", "/proc/stat", "/hostfs/proc/stat"},
+		{"/rootfs/", "/sys/fs/cgroup/cgroup.controllers", "/rootfs/sys/fs/cgroup/cgroup.controllers"},
+		{"/hostfs", "sys/fs/cgroup", "/hostfs/sys/fs/cgroup"},
+	}
+	for _, tc := range cases {
+		SetHostRoot(tc.root)
+		if got := HostPath(tc.path); got != tc.want {
+			t.Errorf("root %s: HostPath(%q) = %q, want %q", tc.root, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestDetectHostRoot(t *testing.T) {
+	defer func(mounts []string) { hostRootMounts = mounts }(hostRootMounts)
+	root := hostTree(t, "rootfs/proc/stat", "empty/sys/fs/cgroup/cgroup.controllers")
+	defer os.RemoveAll(root)
+
+	cases := []struct {
+		name   string
+		mounts []string
+		want   string
+	}{
+		{"mounted", []string{filepath.Join(root, "hostfs"), filepath.Join(root, "rootfs")}, filepath.Join(root, "rootfs")},
+		{"without proc", []string{filepath.Join(root, "empty")}, "/"},
+		{"not mounted", []string{filepath.Join(root, "hostfs")}, "/"},
+	}
+	for _, tc := range cases {
+		hostRootMounts = tc.mounts
+		if got := DetectHostRoot(); got != tc.want {
+			t.Errorf("%s: DetectHostRoot() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
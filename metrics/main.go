@@ -2,14 +2,20 @@ package metrics
 
 import (
 	"math"
+	"sync/atomic"
+	"time"
 
 	"github.com/bcicen/ctop/logging"
 )
 
-var log = logging.Init()
+var (
+	log           = logging.Init()
+	intervalNanos = int64(time.Second) // collection interval, shared by all collectors
+)
 
 type Metrics struct {
 	CPUUtil      int
+	CPUTime      time.Duration // cumulative CPU time since the container started
 	NetTx        int64
 	NetRx        int64
 	MemLimit     int64
@@ -23,6 +29,7 @@ type Metrics struct {
 func NewMetrics() Metrics {
 	return Metrics{
 		CPUUtil:           -1,
+		CPUTime:           -1,
 		NetTx:             -1,
 		NetRx:             -1,
 		MemUsage:          -1,
@@ -38,6 +45,41 @@ type Collector interface {
 	Running() bool
 	Start()
 	Stop()
+	Errors() int  // failures since the collector was created
+	Failed() bool // stopped after a panic, and not to be restarted
+}
+
+var goroutines int64 // running collector goroutines
+
+var streamReconnects int64 // stats streams reopened after polling
+
+// Return the number of times stats streams were reopened after
+// collectors fell back to polling
+func StreamReconnects() int64 {
+	return atomic.LoadInt64(&streamReconnects)
+}
+
+// Return the number of goroutines run by all collectors
+func Goroutines() int64 {
+	return atomic.LoadInt64(&goroutines)
+}
+
+// Count a collector goroutine as running, returning a
+// func to be deferred on its exit
+func trackGoroutine() func() {
+	atomic.AddInt64(&goroutines, 1)
+	return func() { atomic.AddInt64(&goroutines, -1) }
+}
+
+// Set the interval at which collectors emit metrics
+func SetInterval(d time.Duration) {
+	log.Noticef("collection interval: %s", d)
+	atomic.StoreInt64(&intervalNanos, int64(d))
+}
+
+// Return the interval at which collectors emit metrics
+func Interval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&intervalNanos))
 }
 
 func round(num float64) int {
@@ -11,7 +11,7 @@ import (
 type Mock struct {
 	Metrics
 	stream     chan Metrics
-	done       bool
+	done       chan bool // closed to stop the current run
 	running    bool
 	aggression int64
 }
@@ -30,29 +30,42 @@ func (c *Mock) Running() bool {
 }
 
 func (c *Mock) Start() {
-	c.done = false
+	c.done = make(chan bool)
+	c.running = true // set ahead of run, so a second Start is not made
 	c.stream = make(chan Metrics)
-	go c.run()
+	go c.run(c.stream, c.done)
 }
 
 func (c *Mock) Stop() {
-	c.done = true
+	if c.running {
+		c.running = false
+		close(c.done)
+	}
 }
 
 func (c *Mock) Stream() chan Metrics {
 	return c.stream
 }
 
-func (c *Mock) run() {
-	c.running = true
+func (c *Mock) Errors() int {
+	return 0
+}
+
+func (c *Mock) Failed() bool {
+	return false
+}
+
+func (c *Mock) run(stream chan Metrics, done chan bool) {
+	defer trackGoroutine()()
 	rand.Seed(int64(time.Now().Nanosecond()))
-	defer close(c.stream)
+	defer close(stream)
 
 	for {
 		c.CPUUtil += rand.Intn(2) * int(c.aggression)
 		if c.CPUUtil >= 100 {
 			c.CPUUtil = 0
 		}
+		c.CPUTime += Interval() * time.Duration(c.CPUUtil) / 100
 
 		c.NetTx += rand.Int63n(60) * c.aggression
 		c.NetRx += rand.Int63n(60) * c.aggression
@@ -61,12 +74,11 @@ func (c *Mock) run() {
 			c.MemUsage = 0
 		}
 		c.MemPercent = round((float64(c.MemUsage) / float64(c.MemLimit)) * 100)
-		c.stream <- c.Metrics
-		if c.done {
-			break
+		stream <- c.Metrics
+		select {
+		case <-done:
+			return
+		case <-time.After(Interval()):
 		}
-		time.Sleep(1 * time.Second)
 	}
-
-	c.running = false
 }
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// Collector for recorded metrics, emitting samples as they are pushed
+type Replay struct {
+	stream  chan Metrics
+	done    chan bool // closed on stop, ending pushes in progress
+	running bool
+	pushes  sync.WaitGroup // pushes in progress, finished before the stream is closed
+	lock    sync.Mutex
+}
+
+func NewReplay() *Replay {
+	return &Replay{}
+}
+
+func (c *Replay) Running() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.running
+}
+
+func (c *Replay) Start() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stream = make(chan Metrics)
+	c.done = make(chan bool)
+	c.running = true
+}
+
+func (c *Replay) Stop() {
+	c.lock.Lock()
+	if !c.running {
+		c.lock.Unlock()
+		return
+	}
+	c.running = false
+	close(c.done)
+	c.lock.Unlock()
+	c.pushes.Wait()
+	close(c.stream)
+}
+
+func (c *Replay) Stream() chan Metrics {
+	return c.stream
+}
+
+func (c *Replay) Errors() int {
+	return 0
+}
+
+func (c *Replay) Failed() bool {
+	return false
+}
+
+// Emit a recorded sample, if started. The sample is sent unlocked,
+// so the collector can be stopped while it is waiting to be read
+func (c *Replay) Push(m Metrics) {
+	c.lock.Lock()
+	if !c.running {
+		c.lock.Unlock()
+		return
+	}
+	c.pushes.Add(1)
+	stream, done := c.stream, c.done
+	c.lock.Unlock()
+	defer c.pushes.Done()
+
+	select {
+	case stream <- m:
+	case <-done:
+	}
+}
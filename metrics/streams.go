@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Docker stats streams each hold an API connection open, which some
+// daemons and proxies cap. Streams are limited to a configured maximum,
+// or to fewer than were open when a burst of streams failed to open;
+// collectors over the limit poll one-shot stats in turn instead
+const (
+	streamBurst       = 5                // open failures making a burst
+	streamBurstWindow = 10 * time.Second // period within which a burst counts
+	pollConcurrency   = 4                // collectors polled each interval
+	pollTimeout       = 10 * time.Second
+)
+
+type streamLimits struct {
+	sync.Mutex
+	max      int                   // configured limit, 0 for none
+	auto     int                   // limit set after open failures, 0 if none
+	open     []*Docker             // collectors streaming, oldest first
+	demote   map[*Docker]chan bool // closed to move a stream to polling
+	failures []time.Time           // recent stream open failures
+	polled   []*Docker             // collectors polling, in rotation order
+	due      map[*Docker]chan bool // signalled when a poll is due
+	promote  map[*Docker]chan bool // closed to move a poll to streaming
+	next     int                   // rotation position in polled
+	polling  bool                  // poll scheduler running
+}
+
+var streams = &streamLimits{
+	demote:  make(map[*Docker]chan bool),
+	due:     make(map[*Docker]chan bool),
+	promote: make(map[*Docker]chan bool),
+}
+
+// Set the maximum number of stats streams open at once, 0 for no limit
+func SetMaxStreams(n int) {
+	streams.Lock()
+	defer streams.Unlock()
+	streams.max = n
+	streams.rebalance()
+}
+
+// Return the number of collectors polling stats rather than streaming,
+// and the stream limit in effect, 0 if none
+func Polled() (n, limit int) {
+	streams.Lock()
+	defer streams.Unlock()
+	return len(streams.polled), streams.limit()
+}
+
+// Return the lowest of the configured and automatic limits
+func (l *streamLimits) limit() int {
+	if l.auto > 0 && (l.max == 0 || l.auto < l.max) {
+		return l.auto
+	}
+	return l.max
+}
+
+// Register a stream about to be opened, returning a channel closed
+// if it is to be moved to polling, or false if at the limit
+func (l *streamLimits) add(c *Docker) (chan bool, bool) {
+	l.Lock()
+	defer l.Unlock()
+	if n := l.limit(); n > 0 && len(l.open) >= n {
+		return nil, false
+	}
+	l.open = append(l.open, c)
+	l.demote[c] = make(chan bool)
+	return l.demote[c], true
+}
+
+func (l *streamLimits) remove(c *Docker) {
+	l.Lock()
+	defer l.Unlock()
+	l.drop(c)
+	l.rebalance()
+}
+
+func (l *streamLimits) drop(c *Docker) {
+	for n, o := range l.open {
+		if o == c {
+			l.open = append(l.open[:n], l.open[n+1:]...)
+			break
+		}
+	}
+	delete(l.demote, c)
+}
+
+// Record a failure to open the stream of a collector, limiting streams
+// on a burst of failures. Returns true if the collector is to poll
+// instead: if other streams are open, so that the daemon is reachable
+func (l *streamLimits) failed(c *Docker) bool {
+	l.Lock()
+	defer l.Unlock()
+	l.drop(c)
+
+	now := time.Now()
+	recent := l.failures[:0]
+	for _, t := range l.failures {
+		if now.Sub(t) < streamBurstWindow {
+			recent = append(recent, t)
+		}
+	}
+	l.failures = append(recent, now)
+
+	if l.auto == 0 && len(l.failures) >= streamBurst && len(l.open) > 0 {
+		// leave connections free for polling
+		l.auto = len(l.open) - pollConcurrency
+		if l.auto < 1 {
+			l.auto = 1
+		}
+		log.Warningf("%d stats streams failed to open within %s with %d open, limiting to %d streams",
+			len(l.failures), streamBurstWindow, len(l.open), l.auto)
+		l.rebalance()
+	}
+	return len(l.open) > 0
+}
+
+// Move the newest streams to polling while over the limit, and
+// polling collectors to streaming while a limit leaves room
+func (l *streamLimits) rebalance() {
+	n := l.limit()
+	for n > 0 && len(l.open) > n {
+		c := l.open[len(l.open)-1]
+		l.open = l.open[:len(l.open)-1]
+		close(l.demote[c])
+		delete(l.demote, c)
+	}
+	for i := 0; n > 0 && i < len(l.polled) && len(l.open)+i < n; i++ {
+		if ch, ok := l.promote[l.polled[i]]; ok {
+			close(ch)
+			delete(l.promote, l.polled[i])
+		}
+	}
+}
+
+// Add a collector to the poll rotation, returning channels signalled
+// when a poll is due, and closed when it may stream again
+func (l *streamLimits) addPolled(c *Docker) (due, promote chan bool) {
+	l.Lock()
+	defer l.Unlock()
+	l.polled = append(l.polled, c)
+	l.due[c] = make(chan bool, 1)
+	l.promote[c] = make(chan bool)
+	if !l.polling {
+		l.polling = true
+		go l.schedule()
+	}
+	return l.due[c], l.promote[c]
+}
+
+func (l *streamLimits) removePolled(c *Docker) {
+	l.Lock()
+	defer l.Unlock()
+	for n, p := range l.polled {
+		if p == c {
+			l.polled = append(l.polled[:n], l.polled[n+1:]...)
+			break
+		}
+	}
+	delete(l.due, c)
+	delete(l.promote, c)
+}
+
+// Signal polls due in rotation, a few each interval, until no
+// collectors are polling
+func (l *streamLimits) schedule() {
+	defer trackGoroutine()()
+	for {
+		time.Sleep(Interval())
+
+		l.Lock()
+		if len(l.polled) == 0 {
+			l.polling = false
+			l.Unlock()
+			return
+		}
+		for i := 0; i < pollConcurrency && i < len(l.polled); i++ {
+			l.next = (l.next + 1) % len(l.polled)
+			select {
+			case l.due[l.polled[l.next]] <- true:
+			default:
+			}
+		}
+		l.Unlock()
+	}
+}
@@ -3,9 +3,12 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"math/rand"
-	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bcicen/ctop/metrics"
@@ -15,34 +18,55 @@ import (
 
 type MockContainerSource struct {
 	containers Containers
+	procHost   *metrics.ProcHost
+	limits     map[string]Limits
+	lock       sync.Mutex // serializes container removal
 }
 
 func NewMockContainerSource() *MockContainerSource {
-	cs := &MockContainerSource{}
-	go cs.Init()
-	go cs.Loop()
+	cs := &MockContainerSource{
+		procHost: &metrics.ProcHost{},
+		limits:   make(map[string]Limits),
+	}
+	cs.Init()
+	safeGo(cs.Loop)
 	return cs
 }
 
-// Create Mock containers
+// Create Mock containers, a fifth of them with aggressive metrics
 func (cs *MockContainerSource) Init() {
 	rand.Seed(int64(time.Now().Nanosecond()))
 
-	for i := 0; i < 4; i++ {
-		cs.makeContainer(3)
+	n := mockContainerCount()
+	for i := 0; i < n; i++ {
+		if i < n/5 {
+			cs.makeContainer(3)
+		} else {
+			cs.makeContainer(1)
+		}
 	}
+}
 
-	for i := 0; i < 16; i++ {
-		cs.makeContainer(1)
+// Return the number of mock containers created, as given
+// by -loadtest or a default of 20
+func mockContainerCount() int {
+	if loadTestCount > 0 {
+		return loadTestCount
 	}
-
+	return 20
 }
 
 func (cs *MockContainerSource) makeContainer(aggression int64) {
 	collector := metrics.NewMock(aggression)
-	c := NewContainer(makeID(), collector)
+	c := NewContainer(makeID(), sourceLabel(cs.Endpoint()), collector)
 	c.SetMeta("name", makeName())
-	c.SetState(makeState())
+	c.SetMeta("user", []string{"root", "nobody", "1000"}[rand.Intn(3)])
+	if rand.Intn(3) == 0 {
+		c.SetMeta("ports", fmt.Sprintf("80/tcp -> 0.0.0.0:%d", 8000+rand.Intn(1000)))
+	}
+	c.SetLifecycle(Lifecycle{Created: time.Now().Add(-time.Duration(rand.Intn(720)) * time.Hour)})
+	c.SetEnv(mockEnv)
+	setMockState(c, makeState())
 	cs.containers = append(cs.containers, c)
 }
 
@@ -52,30 +76,280 @@ func (cs *MockContainerSource) Loop() {
 		// Change state for random container
 		if iter%5 == 0 && len(cs.containers) > 0 {
 			randC := cs.containers[rand.Intn(len(cs.containers))]
-			randC.SetState(makeState())
+			setMockState(randC, makeState())
 		}
 		iter++
 		time.Sleep(3 * time.Second)
 	}
 }
 
-// Get a single container, by ID
+// Environment of all mock containers
+var mockEnv = []string{"PATH=/usr/bin", "APP_VERSION=1.4.2", "GIT_SHA=3f9c2e1", "DB_PASSWORD=hunter2"}
+
+// Event actions recorded on entering a mock container state
+var mockActions = map[string]string{
+	"running": "start",
+	"exited":  "die",
+	"paused":  "pause",
+}
+
+// Set a mock container state, recording the time of any change
+func setMockState(c *Container, state string) {
+	if c.State() != state {
+		now := time.Now()
+		life := c.Lifecycle()
+		life.Since = now
+		switch state {
+		case "running":
+			life.Started, life.Exited = now, false
+		case "exited":
+			life.Finished, life.Exited, life.ExitCode = now, true, []int{0, 1, 137}[rand.Intn(3)]
+		}
+		c.SetLifecycle(life)
+		action := mockActions[state]
+		if state == "running" && c.State() == "paused" {
+			action = "unpause"
+		}
+		if action != "" && c.State() != "" {
+			recordEvent(timelineEvent{time.Now(), c.Source, c.Id, c.GetMeta("name"), action, ""})
+		}
+	}
+	c.SetState(state)
+}
+
+// Return a container by ID, or by name or a unique prefix of either
 func (cs *MockContainerSource) Get(id string) (*Container, bool) {
+	c, err := matchContainer(cs.containers, id)
+	return c, err == nil
+}
+
+// Mock source is always connected
+func (cs *MockContainerSource) LostSince() time.Time {
+	return time.Time{}
+}
+
+func (cs *MockContainerSource) Endpoint() string {
+	return "mock"
+}
+
+// Return a minimal inspect document built from container metadata
+func (cs *MockContainerSource) Inspect(id string) (interface{}, error) {
+	c, ok := cs.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("no such container: %s", id)
+	}
+	return map[string]interface{}{
+		"Id":     c.Id,
+		"Name":   "/" + c.GetMeta("name"),
+		"Config": map[string]interface{}{"Image": c.GetMeta("image"), "Env": mockEnv},
+		"State":  map[string]interface{}{"Status": c.State(), "Running": c.State() == "running", "Pid": 1},
+	}, nil
+}
+
+// Return generated log lines, ending with an exit for stopped containers
+func (cs *MockContainerSource) Logs(id string, tail int) ([]string, error) {
+	c, ok := cs.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("no such container: %s", id)
+	}
+	var lines []string
+	for i := 0; i < tail; i++ {
+		lines = append(lines, fmt.Sprintf("%s: mock log line %d", c.GetMeta("name"), i+1))
+	}
+	if l := c.Lifecycle(); l.Exited && tail > 0 {
+		lines[len(lines)-1] = fmt.Sprintf("%s: exiting with code %d", c.GetMeta("name"), l.ExitCode)
+	}
+	return lines, nil
+}
+
+func (cs *MockContainerSource) Attach(id string, opts AttachOpts) error {
+	return fmt.Errorf("attach not supported for mock containers")
+}
+
+func (cs *MockContainerSource) Rename(id, name string) error {
 	for _, c := range cs.containers {
-		if c.Id == id {
-			return c, true
+		if c.GetMeta("name") == name {
+			return fmt.Errorf("name %q is already in use", name)
 		}
 	}
-	return nil, false
+	if c, ok := cs.Get(id); ok {
+		c.AddPreviousName(c.GetMeta("name"), time.Now())
+		c.SetMeta("name", name)
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Limits(id string) (Limits, error) {
+	return cs.limits[id], nil
+}
+
+func (cs *MockContainerSource) UpdateLimits(id string, l Limits) error {
+	cur := cs.limits[id]
+	if l.Memory != 0 {
+		cur.Memory = l.Memory
+	}
+	if l.MemorySwap != 0 {
+		cur.MemorySwap = l.MemorySwap
+	}
+	if l.CPUs != 0 {
+		cur.CPUs = l.CPUs
+	}
+	cs.limits[id] = cur
+	if c, ok := cs.Get(id); ok {
+		c.SetMeta("limits", cur.String())
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Remove(id string) error {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.delByID(id)
+	return nil
+}
+
+func (cs *MockContainerSource) ForceRemove(id string) error {
+	return cs.Remove(id)
+}
+
+func (cs *MockContainerSource) Stop(id string) error {
+	if c, ok := cs.Get(id); ok {
+		c.SetState("exited")
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Restart(id string) error {
+	time.Sleep(500 * time.Millisecond)
+	if c, ok := cs.Get(id); ok {
+		c.SetState("running")
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Pause(id string) error {
+	if c, ok := cs.Get(id); ok {
+		setMockState(c, "paused")
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Unpause(id string) error {
+	if c, ok := cs.Get(id); ok {
+		setMockState(c, "running")
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Commit(id, repo, tag, comment string) (string, error) {
+	return "sha256:" + makeID() + makeID(), nil
+}
+
+// Simulate pulling an image of a few layers, downloaded in turn. The
+// image of containers created from it is then stale
+func (cs *MockContainerSource) PullImage(image string, progress func(PullProgress)) error {
+	var p PullProgress
+	p.Status = "Pulling from " + image
+	for i := rand.Intn(4) + 2; i > 0; i-- {
+		p.Layers = append(p.Layers, LayerProgress{
+			ID:     makeID(),
+			Status: "Waiting",
+			Total:  int64(rand.Intn(100)+5) << 20,
+		})
+	}
+	report := func() { progress(PullProgress{p.Status, append([]LayerProgress(nil), p.Layers...)}) }
+	for n := range p.Layers {
+		l := &p.Layers[n]
+		l.Status = "Downloading"
+		for l.Current = 0; l.Current < l.Total; l.Current += l.Total / 10 {
+			report()
+			time.Sleep(50 * time.Millisecond)
+		}
+		l.Current, l.Status = l.Total, "Pull complete"
+		report()
+	}
+	p.Status = "Status: Downloaded newer image for " + image
+	report()
+
+	id := "sha256:" + makeID() + makeID()
+	for _, c := range cs.containers {
+		if c.GetMeta("image") == image {
+			c.SetMeta("stale image", fmt.Sprintf("%s is now %s", image, shortImageID(id)))
+		}
+	}
+	return nil
+}
+
+// Simulate recreating a container, keeping its ID
+func (cs *MockContainerSource) Recreate(id string) (string, error) {
+	c, ok := cs.Get(id)
+	if !ok {
+		return "", fmt.Errorf("no such container: %s", id)
+	}
+	setMockState(c, "exited")
+	time.Sleep(500 * time.Millisecond)
+	c.SetMeta("stale image", "")
+	setMockState(c, "running")
+	return id, nil
+}
+
+func (cs *MockContainerSource) Changes(id string) (list []FileChange, err error) {
+	kinds := []rune{'A', 'C', 'D'}
+	for _, dir := range []string{"/etc", "/tmp", "/var/log"} {
+		list = append(list, FileChange{Path: dir, Kind: 'C'})
+		for i := 0; i < rand.Intn(20); i++ {
+			p := fmt.Sprintf("%s/%s", dir, makeName())
+			list = append(list, FileChange{Path: p, Kind: kinds[rand.Intn(len(kinds))]})
+		}
+	}
+	return list, nil
+}
+
+// Write random data in place of a filesystem archive
+func (cs *MockContainerSource) Export(ctx context.Context, id string, w io.Writer) error {
+	buf := make([]byte, 1<<20)
+	for i := 0; i < 64; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+		rand.Read(buf)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) SetRestartPolicy(id, name string, maxRetry int) error {
+	if c, ok := cs.Get(id); ok {
+		if name == "on-failure" && maxRetry > 0 {
+			name = fmt.Sprintf("%s:%d", name, maxRetry)
+		}
+		c.SetMeta("restart", name)
+	}
+	return nil
+}
+
+func (cs *MockContainerSource) Host() metrics.HostMetrics {
+	return cs.procHost.Read()
 }
 
 // Return array of all containers, sorted by field
 func (cs *MockContainerSource) All() Containers {
-	sort.Sort(cs.containers)
+	cs.containers.Sort()
 	cs.containers.Filter()
 	return cs.containers
 }
 
+// Return all containers, neither sorted nor filtered for display
+func (cs *MockContainerSource) Snapshot() Containers {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	return append(Containers{}, cs.containers...)
+}
+
 // Remove containers by ID
 func (cs *MockContainerSource) delByID(id string) {
 	for n, c := range cs.containers {
@@ -123,3 +397,12 @@ func makeState() string {
 	}
 	return "running"
 }
+
+// Stop all container collectors
+func (cs *MockContainerSource) Close() {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	for _, c := range cs.containers {
+		c.Remove()
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Return an inspected network, from cache if inspected since the
+// last connect or disconnect on it
+func (cm *DockerContainerSource) network(id string) (*docker.Network, error) {
+	cm.lock.RLock()
+	n, ok := cm.networks[id]
+	cm.lock.RUnlock()
+	if ok {
+		return n, nil
+	}
+	n, err := cm.client.NetworkInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	cm.lock.Lock()
+	cm.networks[id] = n
+	cm.lock.Unlock()
+	return n, nil
+}
+
+// Describe the other containers attached to each network of a
+// container, one network per line, sorted by name. The container's
+// own aliases on a network follow its name
+func (cm *DockerContainerSource) networkPeers(insp *docker.Container) string {
+	var lines []string
+	for name, ep := range insp.NetworkSettings.Networks {
+		if ep.NetworkID == "" || name == "none" {
+			continue
+		}
+		line := name
+		if aliases := endpointAliases(ep.Aliases, insp.ID); len(aliases) > 0 {
+			line += fmt.Sprintf(" (as %s)", strings.Join(aliases, ", "))
+		}
+
+		n, err := cm.network(ep.NetworkID)
+		if err != nil {
+			log.Debugf("failed to inspect network %s: %s", name, err)
+			lines = append(lines, line+": unknown")
+			continue
+		}
+		var peers []string
+		for id, peer := range n.Containers {
+			if id != insp.ID {
+				peers = append(peers, peer.Name)
+			}
+		}
+		sort.Strings(peers)
+		if len(peers) == 0 {
+			peers = []string{"no other containers"}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", line, strings.Join(peers, ", ")))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// Drop a network from cache on a container connecting or
+// disconnecting, refreshing the containers attached before and after
+func (cm *DockerContainerSource) networkEvent(e *docker.APIEvents) {
+	if e.Action != "connect" && e.Action != "disconnect" {
+		return
+	}
+	log.Debugf("handling docker network event: action=%s network=%s", e.Action, e.Actor.ID)
+	cm.lock.Lock()
+	var ids []string
+	if n, ok := cm.networks[e.Actor.ID]; ok {
+		for id := range n.Containers {
+			ids = append(ids, id)
+		}
+	}
+	delete(cm.networks, e.Actor.ID)
+	cm.lock.Unlock()
+
+	if id := e.Actor.Attributes["container"]; id != "" {
+		ids = append(ids, id)
+	}
+	for _, id := range ids {
+		if _, ok := cm.Get(id); ok {
+			cm.queueRefresh(id)
+		}
+	}
+}
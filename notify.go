@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+)
+
+// Keys of containers opted into bell and desktop notifications
+var watched = struct {
+	sync.RWMutex
+	ids map[string]bool
+}{ids: make(map[string]bool)}
+
+func isWatched(id string) bool {
+	watched.RLock()
+	defer watched.RUnlock()
+	return watched.ids[id]
+}
+
+// Toggle bell and desktop notifications for the selected container
+func toggleWatch() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	watched.Lock()
+	on := !watched.ids[c.Key()]
+	if on {
+		watched.ids[c.Key()] = true
+	} else {
+		delete(watched.ids, c.Key())
+	}
+	watched.Unlock()
+
+	msg := fmt.Sprintf("stopped watching %s", c.GetMeta("name"))
+	if on {
+		msg = fmt.Sprintf("watching %s", c.GetMeta("name"))
+		if !config.GetSwitchVal("notifyBell") && !config.GetSwitchVal("notifyDesktop") {
+			msg += " (enable bell or desktop notifications in options)"
+		}
+	}
+	footer.Flash(msg, 3*time.Second)
+	RedrawRows(false)
+}
+
+// Container events which may be notified for watched containers
+var notifyEventNames = []string{"die", "oom", "unhealthy"}
+
+// Handle a container event from the daemon, alerting the user if
+// the container is watched and the event is enabled in notifyEvents
+func containerEvent(c *Container, event string) {
+	if !isWatched(c.Key()) {
+		return
+	}
+	for _, e := range strings.Split(config.GetVal("notifyEvents"), ",") {
+		if strings.TrimSpace(e) == event {
+			alertUser(fmt.Sprintf("%s: %s", c.GetMeta("name"), event))
+			return
+		}
+	}
+}
+
+// Set by alerts to ring the terminal bell from the UI loop, as
+// alerts are raised by collector and event goroutines
+var bellPending int32
+
+// Ring the terminal bell and send a desktop notification, as enabled
+func alertUser(msg string) {
+	if config.GetSwitchVal("notifyBell") {
+		atomic.StoreInt32(&bellPending, 1)
+	}
+	if config.GetSwitchVal("notifyDesktop") {
+		safeGo(func() {
+			if err := desktopNotify("ctop", msg); err != nil {
+				log.Errorf("desktop notification failed: %s", err)
+			}
+		})
+	}
+}
+
+// Ring the terminal bell if an alert is pending
+func ringBell() {
+	if atomic.SwapInt32(&bellPending, 0) == 1 {
+		os.Stdout.WriteString("\a")
+	}
+}
+
+func desktopNotify(title, msg string) error {
+	if _, err := exec.LookPath("notify-send"); err == nil {
+		return exec.Command("notify-send", title, msg).Run()
+	}
+	if _, err := exec.LookPath("osascript"); err == nil {
+		// passed as arguments rather than quoted into the script
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 1 of argv) with title (item 2 of argv)",
+			"-e", "end run",
+			msg, title).Run()
+	}
+	return fmt.Errorf("no notification command available")
+}
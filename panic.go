@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	ui "github.com/gizak/termui"
+)
+
+var panicOnce sync.Once
+
+// Recover a panic, exiting via handlePanic. Must be deferred directly
+func panicExit() {
+	if r := recover(); r != nil {
+		handlePanic(r, debug.Stack())
+	}
+}
+
+// Restore the terminal, then report a panic and its stack trace to
+// stderr and the log, and exit non-zero. Panics in other goroutines
+// while exiting wait for the first to complete
+func handlePanic(r interface{}, stack []byte) {
+	panicOnce.Do(func() {
+		ui.Close()
+		if log != nil {
+			log.Criticalf("panic: %v\n%s", r, stack)
+			// a failed shutdown must not mask the original panic
+			func() {
+				defer func() { recover() }()
+				Shutdown()
+			}()
+		}
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", r, stack)
+		os.Exit(1)
+	})
+	select {}
+}
+
+// Run f in a new goroutine, exiting cleanly should it panic
+func safeGo(f func()) {
+	go func() {
+		defer panicExit()
+		f()
+	}()
+}
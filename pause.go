@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Pause the selected container if running, or unpause it if paused.
+// Its collector is suspended and resumed as the change is refreshed
+func togglePause() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	name := c.GetMeta("name")
+	switch c.State() {
+	case "running":
+		goTask(func() {
+			if err := cursor.Source().Pause(c.Id); err != nil {
+				log.NotifyError("failed to pause %s: %s", name, err)
+				return
+			}
+			log.Notify("paused %s", name)
+		})
+	case "paused":
+		goTask(func() {
+			if err := cursor.Source().Unpause(c.Id); err != nil {
+				log.NotifyError("failed to unpause %s: %s", name, err)
+				return
+			}
+			log.Notify("unpaused %s", name)
+		})
+	default:
+		footer.Flash(fmt.Sprintf("%s is not running", name), 2*time.Second)
+	}
+}
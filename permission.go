@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/metrics"
+	ui "github.com/gizak/termui"
+)
+
+// Exit status when the docker daemon socket cannot be accessed,
+// as EX_NOPERM of sysexits(3)
+const exitPermission = 77
+
+// Failure to connect to the docker daemon for lack of permission
+// on its socket
+type permissionError struct {
+	endpoint string
+	err      error
+}
+
+func (e *permissionError) Error() string {
+	path := strings.TrimPrefix(e.endpoint, "unix://")
+	return fmt.Sprintf("cannot access %s: permission denied — add your user to the docker group or run with sudo", path)
+}
+
+// Return whether an error is due to a lack of permission, such as
+// EACCES on connecting to the daemon socket
+func permissionDenied(err error) bool {
+	return metrics.PermissionDenied(err)
+}
+
+// Report a permission error on connecting at startup, full-screen
+// until a key is pressed if the UI is started, and exit with
+// exitPermission
+func exitPermissionDenied(err *permissionError) {
+	if log != nil {
+		log.Errorf("%s (%s)", err, err.err)
+	}
+	if uiStarted {
+		permissionScreen(err.Error())
+		Shutdown()
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(exitPermission)
+}
+
+// Full-screen message, ASCII-only in ASCII mode
+type messageScreen struct {
+	*ui.Par
+}
+
+func (m messageScreen) Buffer() ui.Buffer {
+	return cwidgets.ASCIIBuffer(m.Par.Buffer())
+}
+
+func permissionScreen(msg string) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	p := messageScreen{ui.NewPar(fmt.Sprintf("\n %s\n\n press any key to exit", msg))}
+	p.BorderLabel = " ctop "
+	p.BorderFg = ui.ColorRed
+	p.WrapLength = -1 // wrap to width
+	p.Width = ui.TermWidth()
+	p.Height = ui.TermHeight()
+
+	ui.Handle("/sys/kbd/", func(ui.Event) { ui.StopLoop() })
+	ui.Handle("/sys/wnd/resize", func(ui.Event) {
+		p.Width = ui.TermWidth()
+		p.Height = ui.TermHeight()
+		ui.Clear()
+		ui.Render(p)
+	})
+
+	ui.Clear()
+	ui.Render(p)
+	ui.Loop()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/metrics"
+)
+
+// Return keys of pinned containers, in pin order. Pins saved before
+// containers were keyed by source are bare container IDs
+func pinnedIDs() (ids []string) {
+	for _, id := range strings.Split(config.GetVal("pins"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Return whether a pin is of a container, by key or bare ID
+func pinMatches(pin string, c *Container) bool {
+	return pin == c.Key() || pin == c.Id
+}
+
+// Pin or unpin a container. Pins are kept by container key, and
+// so survive restarts of the container
+func togglePin(c *Container) {
+	ids := pinnedIDs()
+	pinned := false
+	for n, id := range ids {
+		if pinMatches(id, c) {
+			ids = append(ids[:n], ids[n+1:]...)
+			pinned = true
+			break
+		}
+	}
+	if pinned {
+		footer.Flash(fmt.Sprintf("unpinned %s", c.GetMeta("name")), 2*time.Second)
+	} else {
+		ids = append(ids, c.Key())
+		footer.Flash(fmt.Sprintf("pinned %s", c.GetMeta("name")), 2*time.Second)
+	}
+	config.Update("pins", strings.Join(ids, ","))
+	updateFooterStatus()
+}
+
+func clearPins() {
+	if len(pinnedIDs()) == 0 {
+		return
+	}
+	config.Update("pins", "")
+	footer.Flash("cleared all pins", 2*time.Second)
+	updateFooterStatus()
+}
+
+// Move pinned containers to the front, in pin order, keeping the
+// order of the rest. Returns the number of pinned containers
+func (a Containers) pinFirst() int {
+	ids := pinnedIDs()
+	if len(ids) == 0 {
+		return 0
+	}
+	byID := make(map[string]*Container, len(ids))
+	rest := make(Containers, 0, len(a))
+	for _, c := range a {
+		switch {
+		case known(ids, c.Key()):
+			byID[c.Key()] = c
+		case known(ids, c.Id):
+			byID[c.Id] = c
+		default:
+			rest = append(rest, c)
+		}
+	}
+	n := 0
+	for _, id := range ids {
+		if c, ok := byID[id]; ok {
+			a[n] = c
+			n++
+		}
+	}
+	copy(a[n:], rest)
+	return n
+}
+
+// Persistent footer status, as last set
+var footerStatus string
+
+// Set the persistent footer status from read-only mode, pins, discovery
+// progress once the grid has rows and stats polling. Returns true if
+// the status changed
+func updateFooterStatus() bool {
+	var parts []string
+	if readOnly() {
+		parts = append(parts, "read-only mode")
+	}
+	if n := len(pinnedIDs()); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d pinned", n))
+	}
+	if p := discoveryProgress(); p != "" && cursor.Len() > 0 {
+		parts = append(parts, "discovering "+p)
+	}
+	if n, limit := metrics.Polled(); n > 0 {
+		s := fmt.Sprintf("stats polled for %d containers", n)
+		if limit > 0 {
+			s += fmt.Sprintf(", over the %d stream limit", limit)
+		}
+		parts = append(parts, s)
+	}
+
+	status := strings.Join(parts, " | ")
+	if status == footerStatus {
+		return false
+	}
+	footerStatus = status
+	footer.SetStatus(status)
+	return true
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/widgets"
+	"github.com/bcicen/ctop/widgets/output"
+	ui "github.com/gizak/termui"
+)
+
+// Last command piped to, offered as the default for the next
+var lastPipeCmd string
+
+// Render a pipe command template with container fields, quoted as
+// for custom actions
+func renderPipeCmd(s string, c *Container) (string, error) {
+	tmpl, err := config.ShellTemplate("pipe", s)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newActionContext(c)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Prompt for a command template and run it against the selected
+// container, showing its output without leaving the UI
+func PipeMenu() {
+	c := cursor.Selected()
+	if c == nil || !checkWritable("pipe to command") {
+		return
+	}
+
+	cmdStr := lastPipeCmd
+	if cmdStr == "" {
+		cmdStr = config.GetVal("pipeCmd")
+	}
+
+	i := widgets.NewInput()
+	i.BorderLabel = fmt.Sprintf("Pipe %s to command", c.GetMeta("name"))
+	i.Chars = widgets.CommandChars
+	i.SetMaxLen(ui.TermWidth() - 10)
+	i.Data = cmdStr
+
+	var rendered string
+	ok := runPrompt(i, func(s string) (err error) {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("command must not be empty")
+		}
+		rendered, err = renderPipeCmd(s, c)
+		cmdStr = s
+		return err
+	})
+	if !ok {
+		return
+	}
+	lastPipeCmd = cmdStr
+	PipeView(c, rendered)
+}
+
+func alignPipe(v *output.View) {
+	v.X, v.Y = 0, 0
+	v.Width = ui.TermWidth()
+	v.Height = ui.TermHeight() - 1
+	footer.Align()
+}
+
+// Run a shell command with the container ID on stdin, displaying
+// its combined output, exit status and duration once complete. The
+// command is killed if the view is closed while it is running
+func PipeView(c *Container, cmdStr string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v := output.NewView()
+	v.Title = cmdStr
+	v.Status = "running..."
+	alignPipe(v)
+
+	// the output and status, once complete, are applied from the UI loop
+	type result struct{ out, status string }
+	done := make(chan result, 1)
+
+	log.Infof("running pipe command for %s: %s", c.GetMeta("name"), cmdStr)
+	safeGo(func() {
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", cmdStr)
+		cmd.Stdin = strings.NewReader(c.Id + "\n")
+		start := time.Now()
+		out, err := cmd.CombinedOutput()
+		if ctx.Err() != nil {
+			return // view closed
+		}
+		elapsed := time.Since(start)
+		elapsed -= elapsed % time.Millisecond
+
+		r := result{out: string(out)}
+		switch err := err.(type) {
+		case nil:
+			r.status = fmt.Sprintf("exit 0 in %s", elapsed)
+		case *exec.ExitError:
+			r.status = fmt.Sprintf("%s in %s", err, elapsed)
+		default:
+			r.status = fmt.Sprintf("failed: %s", err)
+		}
+		done <- r
+	})
+
+	runOutputView(v, func() {
+		ui.Handle("/timer/refresh", func(ui.Event) {
+			select {
+			case r := <-done:
+				v.SetText(r.out)
+				v.Status = r.status
+				ui.Render(v)
+			default:
+			}
+		})
+	})
+}
+
+// Show a scrollable, searchable view until closed. bind registers
+// handlers particular to the view, again after each search
+func runOutputView(v *output.View, bind func()) {
+	for {
+		var search bool
+
+		ui.Clear()
+		ui.DefaultEvtStream.ResetHandlers()
+		ui.Render(v)
+
+		HandleKeys("up", v.Up)
+		HandleKeys("down", v.Down)
+		HandleKeys("pgup", v.PgUp)
+		HandleKeys("pgdown", v.PgDown)
+		HandleKeys("exit", ui.StopLoop)
+		ui.Handle("/sys/kbd/<home>", func(ui.Event) { v.Top() })
+		ui.Handle("/sys/kbd/<end>", func(ui.Event) { v.Bottom() })
+		ui.Handle("/sys/kbd//", func(ui.Event) {
+			search = true
+			ui.StopLoop()
+		})
+		ui.Handle("/sys/kbd/n", func(ui.Event) { v.Next() })
+		ui.Handle("/sys/kbd/N", func(ui.Event) { v.Prev() })
+		ui.Handle("/sys/wnd/resize", func(ui.Event) {
+			ui.Clear()
+			alignPipe(v)
+			ui.Render(v)
+		})
+		bind()
+
+		ui.Loop()
+		if !search {
+			break
+		}
+		pipeSearch(v)
+	}
+	ui.DefaultEvtStream.ResetHandlers()
+}
+
+// Read a search string in the footer, highlighting matches as it is typed
+func pipeSearch(v *output.View) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+	defer footer.Hide()
+
+	prev := v.Search()
+	query := prev
+	update := func() {
+		v.SetSearch(query)
+		footer.Flash(fmt.Sprintf("search: %s", query), time.Minute)
+		ui.Render(v, footer)
+	}
+	update()
+
+	ui.Handle("/sys/kbd/", func(e ui.Event) {
+		key := strings.Replace(e.Path, "/sys/kbd/", "", -1)
+		switch {
+		case key == "C-8" && len(query) > 0:
+			query = query[:len(query)-1]
+		case key == "<space>":
+			query += " "
+		case len(key) == 1:
+			query += key
+		}
+		update()
+	})
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		v.SetSearch(prev)
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/bcicen/ctop/widgets/menu"
+	ui "github.com/gizak/termui"
+)
+
+// Menu value selecting top-level settings only
+const noProfile = "(none)"
+
+// Select a profile from those defined in the config file,
+// switching to it on enter
+func ProfileMenu() {
+	names := config.ProfileNames()
+	if len(names) == 0 {
+		log.Notify("no profiles defined in config file")
+		return
+	}
+
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	m := menu.NewMenu()
+	m.Selectable = true
+	m.BorderLabel = "Profiles"
+
+	current := config.GetVal("profile")
+	for _, name := range append([]string{noProfile}, names...) {
+		item := menu.Item{Val: name}
+		if name == current || (name == noProfile && current == "") {
+			item.Label = name + " *"
+		}
+		m.AddItems(item)
+	}
+	if current == "" {
+		current = noProfile
+	}
+	m.SetCursor(current)
+
+	HandleKeys("up", m.Up)
+	HandleKeys("down", m.Down)
+	HandleKeys("exit", ui.StopLoop)
+
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		name := m.SelectedItem().Val
+		if name == noProfile {
+			name = ""
+		}
+		if name != config.GetVal("profile") {
+			if err := switchProfile(name); err != nil {
+				log.NotifyError("failed to switch profile: %s", err)
+			}
+		}
+		ui.StopLoop()
+	})
+
+	ui.Render(m)
+	ui.Loop()
+}
+
+// Container source of a profile, created in the background
+type profileSource struct {
+	name, prev string
+	cs         ContainerSource
+	err        error
+}
+
+var (
+	profileSources = make(chan profileSource, 1)
+	switching      bool // a profile source is being created, UI loop only
+)
+
+// Apply the named profile and start connecting to the docker daemon
+// it configures. The new source replaces the current one from the
+// UI loop once created, see applyProfileSource
+func switchProfile(name string) error {
+	if replayPath != "" {
+		return errReplay
+	}
+	if switching {
+		return fmt.Errorf("already switching profile")
+	}
+	prev := config.GetVal("profile")
+	if err := config.ApplyProfile(name); err != nil {
+		return err
+	}
+	for _, w := range checkLoadedParams() {
+		log.Notify("config: %s", w)
+	}
+	applyRefreshInterval()
+
+	switching = true
+	safeGo(func() {
+		cs, err := newContainerSource()
+		profileSources <- profileSource{name, prev, cs, err}
+	})
+	return nil
+}
+
+// Replace the container source with that of a profile switched
+// to, once created, closing the previous one. The previous profile
+// is restored if the new source could not be created
+func applyProfileSource() {
+	var ps profileSource
+	select {
+	case ps = <-profileSources:
+		switching = false
+	default:
+		return
+	}
+	if ps.err != nil {
+		config.ApplyProfile(ps.prev)
+		applyRefreshInterval()
+		log.NotifyError("failed to switch profile: %s", ps.err)
+		return
+	}
+	cursor.SetSource(ps.cs)
+	compact.ResetLayout()
+	compact.ApplyGaugeMetric()
+
+	if ps.name == "" {
+		ps.name = noProfile
+	}
+	log.Notify("switched to profile %s: %s", ps.name, ps.cs.Endpoint())
+}
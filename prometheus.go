@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var promServer *http.Server
+
+type promMetric struct {
+	name  string
+	help  string
+	kind  string
+	value func(*Container) int64
+}
+
+// Exported per-container metrics, in output order
+var promMetrics = []promMetric{
+	{"ctop_cpu_percent", "CPU utilization percent", "gauge", func(c *Container) int64 { return int64(c.Metrics().CPUUtil) }},
+	{"ctop_memory_bytes", "Memory usage in bytes", "gauge", func(c *Container) int64 { return c.Metrics().MemUsage }},
+	{"ctop_memory_limit_bytes", "Memory limit in bytes", "gauge", func(c *Container) int64 { return c.Metrics().MemLimit }},
+	{"ctop_memory_percent", "Memory usage percent of limit", "gauge", func(c *Container) int64 { return int64(c.Metrics().MemPercent) }},
+	{"ctop_net_rx_bytes_total", "Network bytes received", "counter", func(c *Container) int64 { return c.Metrics().NetRx }},
+	{"ctop_net_tx_bytes_total", "Network bytes sent", "counter", func(c *Container) int64 { return c.Metrics().NetTx }},
+	{"ctop_io_read_bytes_total", "Block IO bytes read", "counter", func(c *Container) int64 { return c.Metrics().IOBytesRead }},
+	{"ctop_io_write_bytes_total", "Block IO bytes written", "counter", func(c *Container) int64 { return c.Metrics().IOBytesWrite }},
+	{"ctop_pids", "Number of processes", "gauge", func(c *Container) int64 { return int64(c.Metrics().Pids) }},
+}
+
+// Exported lifecycle metrics, of all containers rather than only those
+// running. Not applicable values are -1, and omitted
+var promLifecycleMetrics = []promMetric{
+	{"ctop_created_timestamp_seconds", "Creation time as a Unix timestamp", "gauge", func(c *Container) int64 { return promTime(c.Lifecycle().Created) }},
+	{"ctop_started_timestamp_seconds", "Last start time as a Unix timestamp", "gauge", func(c *Container) int64 { return promTime(c.Lifecycle().Started) }},
+	{"ctop_finished_timestamp_seconds", "Last exit time as a Unix timestamp", "gauge", func(c *Container) int64 { return promTime(c.Lifecycle().Finished) }},
+	{"ctop_exit_code", "Exit code of an exited container", "gauge", func(c *Container) int64 {
+		if l := c.Lifecycle(); l.Exited {
+			return int64(l.ExitCode)
+		}
+		return -1
+	}},
+	{"ctop_restarts_total", "Restarts by the restart policy", "counter", func(c *Container) int64 { return int64(c.Lifecycle().RestartCount) }},
+}
+
+func promTime(t time.Time) int64 {
+	if t.IsZero() {
+		return -1
+	}
+	return t.Unix()
+}
+
+// Start serving Prometheus metrics on the given address until
+// stopPrometheus is called. Returns an error if the address
+// cannot be bound
+func startPrometheus(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", promHandler)
+	promServer = &http.Server{Handler: mux}
+	safeGo(func() {
+		if err := promServer.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("prometheus listener: %s", err)
+		}
+	})
+	log.Noticef("serving prometheus metrics on %s", l.Addr())
+	return nil
+}
+
+func stopPrometheus() {
+	if promServer == nil {
+		return
+	}
+	shutdownServer(promServer)
+	promServer = nil
+}
+
+func promHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(promExposition(cursor.Source().Snapshot()))
+	w.Write(selfExposition())
+}
+
+// Render metrics for all running containers in the Prometheus text
+// format. Series are generated from current containers on each
+// scrape, so removed containers drop out automatically
+func promExposition(containers Containers) []byte {
+	var running Containers
+	for _, c := range containers {
+		if c.State() == "running" {
+			running = append(running, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, m := range promMetrics {
+		promWrite(&buf, m, running)
+	}
+
+	promHeader(&buf, "ctop_container_info", "Container state, health and image ID", "gauge")
+	for _, c := range containers {
+		fmt.Fprintf(&buf, "ctop_container_info{%s,state=%s,health=%s,image_id=%s} 1\n", promLabels(c),
+			promQuote(c.State()), promQuote(c.GetMeta("health")), promQuote(c.Lifecycle().ImageID))
+	}
+	for _, m := range promLifecycleMetrics {
+		promWrite(&buf, m, containers)
+	}
+	return buf.Bytes()
+}
+
+// Write a metric for each of the given containers, omitting
+// negative values, not yet read or not applicable
+func promWrite(buf *bytes.Buffer, m promMetric, containers Containers) {
+	promHeader(buf, m.name, m.help, m.kind)
+	for _, c := range containers {
+		if v := m.value(c); v >= 0 {
+			fmt.Fprintf(buf, "%s{%s} %d\n", m.name, promLabels(c), v)
+		}
+	}
+}
+
+func promLabels(c *Container) string {
+	id := c.Id
+	if len(id) > 12 {
+		id = id[:12]
+	}
+	return fmt.Sprintf("name=%s,id=%s,image=%s,source=%s",
+		promQuote(c.GetMeta("name")), promQuote(id), promQuote(c.GetMeta("image")), promQuote(c.Source))
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promQuote(s string) string {
+	return `"` + promEscaper.Replace(s) + `"`
+}
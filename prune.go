@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Container states eligible for pruning
+var pruneStates = map[string]bool{
+	"exited":  true,
+	"created": true,
+}
+
+// Return all currently displayed containers eligible for pruning
+func pruneCandidates() (list Containers) {
+	for _, c := range cursor.filtered {
+		if pruneStates[c.State()] {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// Confirm and remove all displayed, stopped containers
+func PruneMenu() {
+	targets := pruneCandidates()
+	if len(targets) == 0 {
+		log.Notify("no stopped containers to remove")
+		return
+	}
+	label := fmt.Sprintf("Remove %d stopped containers?", len(targets))
+	if !confirmContainers(label, targets) {
+		return
+	}
+	runBulk("removed", targets, cursor.Source().Remove)
+}
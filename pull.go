@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/widgets/output"
+	ui "github.com/gizak/termui"
+)
+
+// Progress of an image pull
+type PullProgress struct {
+	Status string          // latest status not specific to a layer
+	Layers []LayerProgress // in the order first reported
+}
+
+// Progress of a single image layer
+type LayerProgress struct {
+	ID      string
+	Status  string // e.g. Waiting, Downloading, Extracting, Pull complete
+	Current int64  // bytes downloaded
+	Total   int64  // bytes to download, or 0 if unknown
+}
+
+// Return bytes downloaded and to download, of all layers
+func (p PullProgress) Bytes() (current, total int64) {
+	for _, l := range p.Layers {
+		current += l.Current
+		total += l.Total
+	}
+	return current, total
+}
+
+// A pull started from the pull view, followed in the view while open
+// and in the footer once closed
+type imagePull struct {
+	image    string
+	lock     sync.Mutex
+	progress PullProgress
+	done     bool
+	err      error
+	detached bool // view closed before the pull completed
+}
+
+// Images being pulled, by reference
+var pulling = struct {
+	sync.Mutex
+	images map[string]bool
+}{images: make(map[string]bool)}
+
+// Pull the image of the selected container, showing progress by layer
+func PullMenu() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	name := c.GetMeta("name")
+	image := c.GetMeta("image")
+	if image == "" || strings.HasPrefix(image, "sha256:") {
+		log.NotifyError("cannot pull image of %s: created from an image ID rather than a pullable reference", name)
+		return
+	}
+
+	pulling.Lock()
+	if pulling.images[image] {
+		pulling.Unlock()
+		log.Notify("pull of %s already in progress", image)
+		return
+	}
+	pulling.images[image] = true
+	pulling.Unlock()
+
+	p := &imagePull{image: image}
+	goTask(p.run)
+	PullView(p)
+}
+
+// Pull the image, notifying the outcome
+func (p *imagePull) run() {
+	defer func() {
+		pulling.Lock()
+		delete(pulling.images, p.image)
+		pulling.Unlock()
+	}()
+
+	err := cursor.Source().PullImage(p.image, func(progress PullProgress) {
+		p.lock.Lock()
+		p.progress = progress
+		detached := p.detached
+		p.lock.Unlock()
+		if detached {
+			footer.Flash(fmt.Sprintf("pulling %s %s", p.image, pullBar(progress.Bytes())), time.Minute)
+		}
+	})
+
+	p.lock.Lock()
+	p.done, p.err = true, err
+	detached := p.detached
+	p.lock.Unlock()
+	if detached {
+		footer.Hide()
+	}
+	if err != nil {
+		log.NotifyError("pull of %s failed: %s", p.image, err)
+		return
+	}
+	log.Notify("pulled %s", p.image)
+}
+
+// Return the lines of the pull view, with their colors
+func (p *imagePull) lines() ([]string, []ui.Attribute) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	text := ui.ThemeAttr("par.text.fg")
+	var lines []string
+	var colors []ui.Attribute
+	add := func(s string, color ui.Attribute) {
+		lines = append(lines, s)
+		colors = append(colors, color)
+	}
+
+	if p.progress.Status != "" {
+		add(p.progress.Status, text)
+		add("", text)
+	}
+	for _, l := range p.progress.Layers {
+		line := fmt.Sprintf("%-12s  %-18s", l.ID, l.Status)
+		if l.Total > 0 {
+			line += "  " + pullBar(l.Current, l.Total)
+		}
+		add(line, text)
+	}
+	if len(p.progress.Layers) > 0 {
+		add("", text)
+		add(fmt.Sprintf("%d layers  %s", len(p.progress.Layers), pullBar(p.progress.Bytes())), text)
+	}
+
+	switch {
+	case !p.done:
+		add(fmt.Sprintf("pulling%c [esc] to continue in the background", cwidgets.Glyphs.Ellipsis), ui.ThemeAttr("par.text.dim"))
+	case p.err != nil:
+		add(fmt.Sprintf("pull failed: %s", p.err), ui.ColorRed)
+	default:
+		add(fmt.Sprintf("pulled %s", p.image), ui.ColorGreen)
+	}
+	return lines, colors
+}
+
+// Show the progress of a pull by layer until closed, leaving the pull
+// to complete in the background if closed before then
+func PullView(p *imagePull) {
+	v := output.NewView()
+	v.Title = fmt.Sprintf("Pulling %s", p.image)
+	alignPipe(v)
+	update := func() {
+		v.SetLines(p.lines())
+		ui.Render(v)
+	}
+
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+	update()
+
+	HandleKeys("up", v.Up)
+	HandleKeys("down", v.Down)
+	HandleKeys("pgup", v.PgUp)
+	HandleKeys("pgdown", v.PgDown)
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/timer/refresh", func(ui.Event) { update() })
+	ui.Handle("/sys/wnd/resize", func(ui.Event) {
+		ui.Clear()
+		alignPipe(v)
+		update()
+	})
+	ui.Loop()
+
+	p.lock.Lock()
+	p.detached = !p.done
+	p.lock.Unlock()
+}
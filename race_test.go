@@ -0,0 +1,85 @@
+// +build !release
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/bcicen/ctop/widgets"
+)
+
+// Containers refreshed and rendered concurrently by TestRefreshRenderRace
+const raceContainers = 300
+
+// Refresh container metadata and states from several goroutines, as
+// refresh workers and collectors do, while sorting, filtering and
+// drawing rows as the UI loop does and scraping metrics as the
+// Prometheus handler does. Meant to be run with -race
+func TestRefreshRenderRace(t *testing.T) {
+	benchInit()
+	duration := 3 * time.Second
+	if testing.Short() {
+		duration = 500 * time.Millisecond
+	}
+
+	cs := benchSource(raceContainers)
+	defer cs.Close()
+	cursor = &GridCursor{cSource: cs, expanded: make(map[string]bool)}
+	cGrid = compact.NewCompactGrid()
+	banner = widgets.NewCTopBanner()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(f func(r *rand.Rand)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(time.Now().UnixNano()))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					f(r)
+				}
+			}
+		}()
+	}
+
+	// refresh workers, starting and stopping collectors
+	for i := 0; i < 4; i++ {
+		run(func(r *rand.Rand) {
+			c := cs.containers[r.Intn(len(cs.containers))]
+			c.SetMeta("name", fmt.Sprintf("race-%d", r.Intn(raceContainers)))
+			c.SetMeta("image", fmt.Sprintf("image-%d:latest", r.Intn(20)))
+			c.SetLabels(map[string]string{"n": fmt.Sprint(r.Intn(10))})
+			c.SetLifecycle(Lifecycle{Created: time.Now()})
+			c.SetState([]string{"running", "exited", "paused"}[r.Intn(3)])
+		})
+	}
+	// metrics scrapes
+	run(func(*rand.Rand) {
+		promExposition(cursor.Source().Snapshot())
+	})
+
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		cursor.RefreshContainers()
+		buildRows()
+		for n, row := range cGrid.Rows {
+			if n == 50 {
+				break
+			}
+			row.SetY(n + 2)
+			row.SetWidth(200)
+			row.Buffer()
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/widgets/menu"
+	ui "github.com/gizak/termui"
+)
+
+var errReadOnly = fmt.Errorf("not available in read-only mode")
+
+// Keys of the main view bound to actions changing container or
+// daemon state, or the config file, disabled in read-only mode along
+// with custom actions
+var mutatingKeys = map[string]string{
+	"C": "commit",
+	"K": "stop all",
+	"B": "restart all",
+	"P": "prune",
+	"L": "limits",
+	"R": "rename",
+	"U": "recreate",
+	"z": "pause",
+	"I": "pull image",
+	"A": "attach",
+	"|": "pipe to command",
+	"M": "stop and remove",
+	"Z": "saving settings",
+}
+
+// Settings holding commands run by ctop, not to be changed from the
+// settings menu in read-only mode
+var commandSettings = []string{"openCmd", "pipeCmd"}
+
+func readOnly() bool { return config.GetSwitchVal("readOnly") }
+
+// Return true if the named action may run, or flash a brief
+// explanation in the footer otherwise
+func checkWritable(action string) bool {
+	if !readOnly() {
+		return true
+	}
+	footer.Flash(fmt.Sprintf("read-only mode: %s is disabled", action), 3*time.Second)
+	ui.Render(footer)
+	return false
+}
+
+// Rebind all mutating keys of the main view to an explanation
+func disableMutatingKeys() {
+	for key, action := range mutatingKeys {
+		action := action
+		ui.Handle("/sys/kbd/"+key, func(ui.Event) { checkWritable(action) })
+	}
+}
+
+// Return help dialog items, omitting mutating keys in read-only mode
+func helpItems() (items []menu.Item) {
+	for _, item := range helpDialog {
+		if readOnly() && isMutatingItem(item.Val) {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func isMutatingItem(s string) bool {
+	for key := range mutatingKeys {
+		if strings.HasPrefix(s, "["+key+"]") {
+			return true
+		}
+	}
+	return false
+}
+
+// Container source refusing all changes to containers, enforcing
+// read-only mode regardless of how an action is reached
+type readOnlySource struct {
+	ContainerSource
+}
+
+// Wrap a container source if read-only mode is enabled
+func guardSource(cs ContainerSource, err error) (ContainerSource, error) {
+	if err != nil || !readOnly() {
+		return cs, err
+	}
+	log.Notice("read-only mode: container changes disabled")
+	return readOnlySource{cs}, nil
+}
+
+// Return the container source wrapped by read-only mode, if any
+func unwrapSource(cs ContainerSource) ContainerSource {
+	if ro, ok := cs.(readOnlySource); ok {
+		return ro.ContainerSource
+	}
+	return cs
+}
+
+func (readOnlySource) Attach(string, AttachOpts) error                      { return errReadOnly }
+func (readOnlySource) Rename(string, string) error                          { return errReadOnly }
+func (readOnlySource) UpdateLimits(string, Limits) error                    { return errReadOnly }
+func (readOnlySource) SetRestartPolicy(id, name string, maxRetry int) error { return errReadOnly }
+func (readOnlySource) Remove(string) error                                  { return errReadOnly }
+func (readOnlySource) ForceRemove(string) error                             { return errReadOnly }
+func (readOnlySource) Stop(string) error                                    { return errReadOnly }
+func (readOnlySource) Restart(string) error                                 { return errReadOnly }
+func (readOnlySource) Pause(string) error                                   { return errReadOnly }
+func (readOnlySource) Unpause(string) error                                 { return errReadOnly }
+func (readOnlySource) Commit(id, repo, tag, comment string) (string, error) { return "", errReadOnly }
+func (readOnlySource) PullImage(string, func(PullProgress)) error           { return errReadOnly }
+func (readOnlySource) Recreate(string) (string, error)                      { return "", errReadOnly }
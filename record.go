@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+)
+
+const (
+	recordFormat  = "ctop-recording"
+	recordVersion = 1
+)
+
+// Recordings are gzip-compressed JSON lines: a recordHeader followed
+// by one recordFrame per refresh interval
+type recordHeader struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Endpoint string    `json:"endpoint"`
+}
+
+// Snapshot of all containers at a point in time
+type recordFrame struct {
+	Time       time.Time           `json:"t"`
+	Host       metrics.HostMetrics `json:"host"`
+	Containers []recordContainer   `json:"containers"`
+}
+
+type recordContainer struct {
+	ID        string            `json:"id"`
+	Meta      map[string]string `json:"meta"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Lifecycle *Lifecycle        `json:"lifecycle,omitempty"` // absent in older recordings
+	Metrics   metrics.Metrics   `json:"metrics"`
+}
+
+var recorder *sessionRecorder
+
+// Writes a frame to a recording at each refresh interval
+type sessionRecorder struct {
+	f    *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+	stop chan bool
+	done chan bool
+}
+
+// Start recording container snapshots to the given path until
+// stopRecorder is called
+func startRecorder(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	r := &sessionRecorder{
+		f:    f,
+		gz:   gz,
+		enc:  json.NewEncoder(gz),
+		stop: make(chan bool),
+		done: make(chan bool),
+	}
+	hdr := recordHeader{recordFormat, recordVersion, time.Now(), cursor.Source().Endpoint()}
+	if err := r.enc.Encode(hdr); err != nil {
+		f.Close()
+		return err
+	}
+	recorder = r
+	safeGo(r.loop)
+	log.Noticef("recording session to %s", path)
+	return nil
+}
+
+// Stop recording, flushing and closing the recording file
+func stopRecorder() {
+	if recorder == nil {
+		return
+	}
+	close(recorder.stop)
+	<-recorder.done
+	recorder = nil
+}
+
+func (r *sessionRecorder) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(refreshInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			r.close()
+			return
+		case now := <-ticker.C:
+			err := r.enc.Encode(newRecordFrame(now))
+			if err == nil {
+				// flush each frame, so an interrupted recording remains readable
+				err = r.gz.Flush()
+			}
+			if err != nil {
+				log.NotifyError("recording stopped: %s", err)
+				r.close()
+				<-r.stop
+				return
+			}
+		}
+	}
+}
+
+func (r *sessionRecorder) close() {
+	if err := r.gz.Close(); err != nil {
+		log.Errorf("failed to write recording: %s", err)
+	}
+	r.f.Close()
+}
+
+func newRecordFrame(t time.Time) recordFrame {
+	frame := recordFrame{
+		Time:       t,
+		Host:       cursor.Source().Host(),
+		Containers: []recordContainer{},
+	}
+	for _, c := range cursor.Source().All() {
+		life := c.Lifecycle()
+		frame.Containers = append(frame.Containers, recordContainer{
+			ID:        c.Id,
+			Meta:      c.MetaCopy(),
+			Labels:    c.Labels(),
+			Lifecycle: &life,
+			Metrics:   c.Metrics(),
+		})
+	}
+	return frame
+}
+
+// Read all frames from a recording, oldest first
+func readRecording(path string) ([]recordFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%s: not a ctop recording", path)
+	}
+	dec := json.NewDecoder(gz)
+
+	var hdr recordHeader
+	if err := dec.Decode(&hdr); err != nil || hdr.Format != recordFormat {
+		return nil, fmt.Errorf("%s: not a ctop recording", path)
+	}
+	if hdr.Version > recordVersion {
+		return nil, fmt.Errorf("%s: unsupported recording version %d", path, hdr.Version)
+	}
+
+	var frames []recordFrame
+	for {
+		var frame recordFrame
+		err := dec.Decode(&frame)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break // tolerate recordings cut short
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%s: recording is empty", path)
+	}
+	return frames, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/widgets/menu"
+	ui "github.com/gizak/termui"
+)
+
+const pullBarWidth = 20
+
+// Containers being recreated, by key
+var recreating = struct {
+	sync.Mutex
+	ids map[string]bool
+}{ids: make(map[string]bool)}
+
+// Confirm and recreate the selected container from a freshly pulled
+// image, with the same configuration
+func RecreateMenu() {
+	c := cursor.Selected()
+	if c == nil {
+		return
+	}
+	name := c.GetMeta("name")
+	image := c.GetMeta("image")
+	if image == "" || strings.HasPrefix(image, "sha256:") {
+		log.NotifyError("cannot recreate %s: created from an image ID rather than a pullable reference", name)
+		return
+	}
+	if !confirmRecreate(c, image) {
+		return
+	}
+	goTask(func() { recreateContainer(c, image) })
+}
+
+// Display the steps of a recreate, returning true if confirmed
+func confirmRecreate(c *Container, image string) (confirmed bool) {
+	ui.Clear()
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	name := c.GetMeta("name")
+	steps := []string{fmt.Sprintf("1. pull %s (%s is left untouched if this fails)", image, name)}
+	if c.State() == "running" {
+		steps = append(steps, fmt.Sprintf("2. stop %s", name))
+	} else {
+		steps = append(steps, fmt.Sprintf("2. %s is not running, no stop needed", name))
+	}
+	steps = append(steps,
+		fmt.Sprintf("3. rename %s to %s-old-<timestamp>", name, name),
+		fmt.Sprintf("4. create %s from %s with the env, labels, command, mounts,", name, image),
+		"   networks and host config it was given; anonymous volumes are",
+		"   reattached, and defaults of the new image replace the old ones",
+		fmt.Sprintf("5. start the new %s", name),
+		fmt.Sprintf("6. remove the old %s; changes to its filesystem are lost", name),
+		"",
+		"If create or start fails, the new container is removed and",
+		"the old one is renamed back and restarted if it was running",
+	)
+	if project := c.Labels()["com.docker.compose.project"]; project != "" {
+		steps = append(steps, "", fmt.Sprintf("Compose labels are kept: the new container stays in project %s", project))
+	}
+
+	m := menu.NewMenu()
+	m.BorderLabel = fmt.Sprintf("Recreate %s? [y/n]", name)
+	for _, s := range steps {
+		m.AddItems(menu.Item{Val: s, Label: s})
+	}
+
+	HandleKeys("exit", ui.StopLoop)
+	ui.Handle("/sys/kbd/n", func(ui.Event) { ui.StopLoop() })
+	ui.Handle("/sys/kbd/y", func(ui.Event) {
+		confirmed = true
+		ui.StopLoop()
+	})
+
+	ui.Render(m)
+	ui.Loop()
+	return confirmed
+}
+
+// Pull the image of a container, then recreate it, notifying the
+// outcome of each stage
+func recreateContainer(c *Container, image string) {
+	name := c.GetMeta("name")
+
+	recreating.Lock()
+	if recreating.ids[c.Key()] {
+		recreating.Unlock()
+		log.Notify("recreate of %s already in progress", name)
+		return
+	}
+	recreating.ids[c.Key()] = true
+	recreating.Unlock()
+	defer func() {
+		recreating.Lock()
+		delete(recreating.ids, c.Key())
+		recreating.Unlock()
+	}()
+
+	footer.Flash(fmt.Sprintf("pulling %s%c", image, cwidgets.Glyphs.Ellipsis), time.Minute)
+	err := cursor.Source().PullImage(image, func(p PullProgress) {
+		footer.Flash(fmt.Sprintf("pulling %s %s", image, pullBar(p.Bytes())), time.Minute)
+	})
+	if err != nil {
+		footer.Hide()
+		log.NotifyError("recreate of %s aborted, pull of %s failed, container left untouched: %s", name, image, err)
+		return
+	}
+	log.Notify("pulled %s", image)
+
+	footer.Flash(fmt.Sprintf("recreating %s%c", name, cwidgets.Glyphs.Ellipsis), time.Minute)
+	id, err := cursor.Source().Recreate(c.Id)
+	footer.Hide()
+	switch {
+	case err != nil && id == "":
+		log.NotifyError("recreate of %s failed: %s", name, err)
+	case err != nil:
+		log.NotifyError("recreated %s (%s): %s", name, shortImageID(id), err)
+	default:
+		log.Notify("recreated %s from %s (%s)", name, image, shortImageID(id))
+	}
+}
+
+// Format pull progress as a text bar with a percentage and byte counts
+func pullBar(current, total int64) string {
+	if total <= 0 {
+		return fmt.Sprintf("%s%c", cwidgets.ByteFormat(current), cwidgets.Glyphs.Ellipsis)
+	}
+	n := int(current * pullBarWidth / total)
+	if n > pullBarWidth {
+		n = pullBarWidth
+	}
+	return fmt.Sprintf("[%s%s] %d%% %s/%s",
+		strings.Repeat("#", n), strings.Repeat("-", pullBarWidth-n),
+		current*100/total, cwidgets.ByteFormat(current), cwidgets.ByteFormat(total))
+}
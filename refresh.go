@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/metrics"
+	ui "github.com/gizak/termui"
+)
+
+// Selectable UI refresh intervals, fastest first
+var refreshIntervals = []time.Duration{
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+}
+
+// Refresh interval in nanoseconds as last applied, read by the
+// refresh timer, exporters and forwarders off the UI loop
+var refreshNanos int64
+
+// Return the refresh interval, as last applied
+func refreshInterval() time.Duration {
+	if d := atomic.LoadInt64(&refreshNanos); d > 0 {
+		return time.Duration(d)
+	}
+	return configRefreshInterval()
+}
+
+func configRefreshInterval() time.Duration {
+	d, err := time.ParseDuration(config.GetVal("refreshInterval"))
+	if err != nil {
+		log.Errorf("invalid refresh interval: %s", err)
+		return time.Second
+	}
+	return d
+}
+
+// Apply the configured refresh interval to the refresh timer and
+// metrics collectors
+func applyRefreshInterval() {
+	d := configRefreshInterval()
+	atomic.StoreInt64(&refreshNanos, int64(d))
+	metrics.SetInterval(d)
+}
+
+// Move the refresh interval n steps slower(positive) or faster(negative)
+func stepRefresh(n int) {
+	cur := refreshInterval()
+
+	var idx int
+	for i, d := range refreshIntervals {
+		if d <= cur {
+			idx = i
+		}
+	}
+
+	idx += n
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(refreshIntervals) {
+		idx = len(refreshIntervals) - 1
+	}
+
+	d := refreshIntervals[idx]
+	config.Update("refreshInterval", d.String())
+	applyRefreshInterval()
+	footer.Flash(fmt.Sprintf("refresh interval: %s", d), 2*time.Second)
+	RedrawRows(false)
+}
+
+// Emit a refresh timer event at the configured interval
+func refreshTimer() chan ui.Event {
+	ch := make(chan ui.Event)
+	safeGo(func() {
+		for {
+			time.Sleep(refreshInterval())
+			ch <- ui.Event{
+				Type: "timer",
+				Path: "/timer/refresh",
+				Time: time.Now().Unix(),
+			}
+		}
+	})
+	return ch
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	dockerHubRegistry = "https://index.docker.io/v1/"
+	helperTimeout     = 10 * time.Second
+)
+
+// Credential settings of the docker client config
+type dockerClientConfig struct {
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// Credentials as returned by a docker credential helper
+type helperCredentials struct {
+	Username string
+	Secret   string
+}
+
+// Return the path of the docker client config
+func dockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".docker", "config.json")
+}
+
+// Return the registry of a repository, as keyed in the docker client
+// config: its host if named, else Docker Hub
+func registryOf(repo string) string {
+	if parts := strings.SplitN(repo, "/", 2); len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return dockerHubRegistry
+}
+
+// Return credentials for the registry of a repository as the docker
+// CLI would: from the credential helper configured for the registry
+// or the default credential store, else from the auths of the docker
+// client config. A failing helper is returned as an error, along with
+// any credentials found otherwise
+func registryAuth(repo string) (docker.AuthConfiguration, error) {
+	registry := registryOf(repo)
+
+	var cfg dockerClientConfig
+	if b, err := ioutil.ReadFile(dockerConfigPath()); err == nil {
+		json.Unmarshal(b, &cfg)
+	}
+	helper := cfg.CredHelpers[registry]
+	if helper == "" {
+		helper = cfg.CredsStore
+	}
+	var helperErr error
+	if helper != "" {
+		auth, found, err := helperAuth(helper, registry)
+		if found {
+			return auth, nil
+		}
+		helperErr = err
+	}
+
+	auths, err := docker.NewAuthConfigurationsFromDockerCfg()
+	if err != nil {
+		return docker.AuthConfiguration{}, helperErr
+	}
+	for _, key := range []string{registry, "https://" + registry, "docker.io"} {
+		if auth, ok := auths.Configs[key]; ok {
+			return auth, helperErr
+		}
+	}
+	return docker.AuthConfiguration{}, helperErr
+}
+
+// Get credentials for a registry from a docker credential helper.
+// Returns false without an error if it has none for the registry
+func helperAuth(helper, registry string) (docker.AuthConfiguration, bool, error) {
+	name := "docker-credential-" + helper
+	ctx, cancel := context.WithTimeout(context.Background(), helperTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(msg, "credentials not found") {
+			return docker.AuthConfiguration{}, false, nil
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return docker.AuthConfiguration{}, false, fmt.Errorf("credential helper %s failed: %s", name, msg)
+	}
+
+	var creds helperCredentials
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return docker.AuthConfiguration{}, false, fmt.Errorf("credential helper %s: invalid output: %s", name, err)
+	}
+	if creds.Username == "<token>" {
+		return docker.AuthConfiguration{}, false, fmt.Errorf("credential helper %s: identity tokens are not supported", name)
+	}
+	return docker.AuthConfiguration{
+		Username:      creds.Username,
+		Password:      creds.Secret,
+		ServerAddress: registry,
+	}, true, nil
+}
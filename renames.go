@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const maxPreviousNames = 5 // previous names retained per container
+
+// Name a container had until renamed
+type previousName struct {
+	Name  string
+	Until time.Time
+}
+
+// Describe previous names for the expanded view, most recent first,
+// e.g. "web_blue until 14:02"
+func previousNamesDetail(names []previousName) string {
+	var lines []string
+	for n := len(names) - 1; n >= 0; n-- {
+		lines = append(lines, fmt.Sprintf("%s until %s", names[n].Name, renameTime(names[n].Until)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Format the time of a rename, as a time of day if today
+func renameTime(t time.Time) string {
+	if y, m, d := t.Local().Date(); y == time.Now().Year() && m == time.Now().Month() && d == time.Now().Day() {
+		return t.Local().Format("15:04")
+	}
+	return formatTime(t)
+}
@@ -0,0 +1,109 @@
+// +build !release
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+)
+
+// Write a recording of the given frames to a temporary file,
+// returning its path
+func writeRecording(t *testing.T, frames ...recordFrame) string {
+	f, err := ioutil.TempFile("", "ctop-recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	if err := enc.Encode(recordHeader{recordFormat, recordVersion, time.Now(), "unix:///var/run/docker.sock"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, frame := range frames {
+		if err := enc.Encode(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func recorded(id, name, state string, cpu int) recordContainer {
+	return recordContainer{
+		ID:      id,
+		Meta:    map[string]string{"name": name, "state": state, "image": "nginx:latest"},
+		Labels:  map[string]string{"app": name},
+		Metrics: metrics.Metrics{CPUUtil: cpu},
+	}
+}
+
+// Wait for a condition set by the replay loop, failing after a timeout
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	benchInit()
+	start := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	// frames an hour apart, so playback only advances by stepping
+	path := writeRecording(t,
+		recordFrame{Time: start, Containers: []recordContainer{
+			recorded("aaaaaaaaaaaa", "web", "running", 42),
+			recorded("bbbbbbbbbbbb", "db", "running", 7),
+		}},
+		recordFrame{Time: start.Add(time.Hour), Containers: []recordContainer{
+			recorded("aaaaaaaaaaaa", "web", "exited", 0),
+		}},
+	)
+	defer os.Remove(path)
+
+	frames, err := readRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || len(frames[0].Containers) != 2 || !frames[1].Time.Equal(start.Add(time.Hour)) {
+		t.Fatalf("read %d frames, want 2 as recorded", len(frames))
+	}
+
+	rs := NewReplaySource(path)
+	defer rs.Close()
+
+	web, ok := rs.Get("web")
+	if !ok {
+		t.Fatal("container web of the first frame not replayed")
+	}
+	if _, ok := rs.Get("db"); !ok {
+		t.Fatal("container db of the first frame not replayed")
+	}
+	if web.State() != "running" || web.Labels()["app"] != "web" || web.GetMeta("image") != "nginx:latest" {
+		t.Errorf("web replayed as state %q, labels %v, image %q", web.State(), web.Labels(), web.GetMeta("image"))
+	}
+	waitFor(t, "recorded metrics", func() bool { return web.Metrics().CPUUtil == 42 })
+
+	rs.Step()
+	waitFor(t, "the second frame", func() bool { return len(rs.Snapshot()) == 1 })
+	if _, ok := rs.Get("db"); ok {
+		t.Error("container db absent from the second frame still replayed")
+	}
+	if web.State() != "exited" {
+		t.Errorf("web replayed as state %q in the second frame, want exited", web.State())
+	}
+	if running, _ := web.CollectorState(); running {
+		t.Error("collector of exited container still running")
+	}
+}
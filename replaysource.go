@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+	ui "github.com/gizak/termui"
+)
+
+// Selectable playback speeds, slowest first
+var replaySpeeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// Path of a recording to play back in place of the docker daemon, if any
+var replayPath string
+
+var errReplay = fmt.Errorf("not available when replaying a recording")
+
+// Container source playing back a recorded session
+type ReplaySource struct {
+	path       string
+	frames     []recordFrame
+	containers map[string]*Container
+	collectors map[string]*metrics.Replay
+	host       metrics.HostMetrics
+	pos        int // index of current frame
+	speed      int // index in replaySpeeds
+	paused     bool
+	step       bool // advance a single frame while paused
+	closed     bool
+	wake       chan bool
+	lock       sync.RWMutex
+}
+
+func NewReplaySource(path string) *ReplaySource {
+	frames, err := readRecording(path)
+	if err != nil {
+		panic(err)
+	}
+	rs := &ReplaySource{
+		path:       path,
+		frames:     frames,
+		containers: make(map[string]*Container),
+		collectors: make(map[string]*metrics.Replay),
+		speed:      2,
+		wake:       make(chan bool, 1),
+	}
+	rs.apply(frames[0])
+	safeGo(rs.Loop)
+	return rs
+}
+
+// Advance through recorded frames at their recorded intervals,
+// scaled by the playback speed. Playback pauses at the final frame
+func (rs *ReplaySource) Loop() {
+	for {
+		rs.lock.Lock()
+		if rs.closed {
+			rs.lock.Unlock()
+			return
+		}
+		if rs.pos >= len(rs.frames)-1 && !rs.paused {
+			rs.paused = true
+			log.Notify("replay finished")
+		}
+		paused, step := rs.paused, rs.step
+		rs.step = false
+		var delay time.Duration
+		if !paused {
+			d := rs.frames[rs.pos+1].Time.Sub(rs.frames[rs.pos].Time)
+			delay = time.Duration(float64(d) / replaySpeeds[rs.speed])
+		}
+		rs.lock.Unlock()
+
+		if paused && !step {
+			<-rs.wake
+			continue
+		}
+		if !paused {
+			select {
+			case <-time.After(delay):
+			case <-rs.wake:
+				continue // playback state changed
+			}
+		}
+		rs.advance()
+	}
+}
+
+func (rs *ReplaySource) advance() {
+	rs.lock.Lock()
+	if rs.pos >= len(rs.frames)-1 {
+		rs.lock.Unlock()
+		return
+	}
+	rs.pos++
+	frame := rs.frames[rs.pos]
+	rs.lock.Unlock()
+	rs.apply(frame)
+}
+
+// Update containers and metrics to those of a recorded frame
+func (rs *ReplaySource) apply(frame recordFrame) {
+	seen := make(map[string]bool)
+	for _, rc := range frame.Containers {
+		seen[rc.ID] = true
+
+		rs.lock.Lock()
+		c, ok := rs.containers[rc.ID]
+		if !ok {
+			rs.collectors[rc.ID] = metrics.NewReplay()
+			c = NewContainer(rc.ID, sourceLabel(rs.Endpoint()), rs.collectors[rc.ID])
+			rs.containers[rc.ID] = c
+		}
+		collector := rs.collectors[rc.ID]
+		rs.host = frame.Host
+		rs.lock.Unlock()
+
+		for k, v := range rc.Meta {
+			if k != "state" {
+				c.SetMeta(k, v)
+			}
+		}
+		c.SetLabels(rc.Labels)
+		if rc.Lifecycle != nil {
+			c.SetLifecycle(*rc.Lifecycle)
+		} else {
+			c.SetLifecycle(legacyLifecycle(rc.Meta))
+		}
+		c.SetState(rc.Meta["state"])
+		if rc.Meta["state"] == "running" {
+			collector.Push(rc.Metrics)
+		}
+	}
+
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	for id, c := range rs.containers {
+		if !seen[id] {
+			c.Remove()
+			delete(rs.containers, id)
+			delete(rs.collectors, id)
+		}
+	}
+}
+
+func (rs *ReplaySource) signal() {
+	select {
+	case rs.wake <- true:
+	default:
+	}
+}
+
+// Pause or resume playback
+func (rs *ReplaySource) TogglePlayback() {
+	rs.lock.Lock()
+	if rs.paused && rs.pos >= len(rs.frames)-1 {
+		rs.lock.Unlock()
+		return // finished
+	}
+	rs.paused = !rs.paused
+	rs.lock.Unlock()
+	rs.signal()
+}
+
+// Advance a single frame, pausing playback
+func (rs *ReplaySource) Step() {
+	rs.lock.Lock()
+	rs.paused = true
+	rs.step = true
+	rs.lock.Unlock()
+	rs.signal()
+}
+
+// Change playback speed by n steps faster(positive) or slower(negative)
+func (rs *ReplaySource) ChangeSpeed(n int) {
+	rs.lock.Lock()
+	rs.speed += n
+	if rs.speed < 0 {
+		rs.speed = 0
+	}
+	if rs.speed >= len(replaySpeeds) {
+		rs.speed = len(replaySpeeds) - 1
+	}
+	rs.lock.Unlock()
+	rs.signal()
+}
+
+// Bind playback controls, flashing playback status in the footer
+func handleReplayKeys(rs *ReplaySource) {
+	control := func(f func()) func(ui.Event) {
+		return func(ui.Event) {
+			f()
+			footer.Flash(rs.Status(), 3*time.Second)
+		}
+	}
+	ui.Handle("/sys/kbd/<space>", control(rs.TogglePlayback))
+	ui.Handle("/sys/kbd/.", control(rs.Step))
+	ui.Handle("/sys/kbd/]", control(func() { rs.ChangeSpeed(1) }))
+	ui.Handle("/sys/kbd/[", control(func() { rs.ChangeSpeed(-1) }))
+}
+
+// Describe playback position and state
+func (rs *ReplaySource) Status() string {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	s := fmt.Sprintf("replay %d/%d %s %gx", rs.pos+1, len(rs.frames),
+		rs.frames[rs.pos].Time.Format("15:04:05"), replaySpeeds[rs.speed])
+	if rs.paused {
+		s += " paused"
+	}
+	return s
+}
+
+// Stop playback and all container collectors
+func (rs *ReplaySource) Close() {
+	rs.lock.Lock()
+	rs.closed = true
+	for _, c := range rs.containers {
+		c.Remove()
+	}
+	rs.lock.Unlock()
+	rs.signal()
+}
+
+func (rs *ReplaySource) All() (containers Containers) {
+	rs.lock.RLock()
+	for _, c := range rs.containers {
+		containers = append(containers, c)
+	}
+	rs.lock.RUnlock()
+	containers.Sort()
+	containers.Filter()
+	return containers
+}
+
+// Return all containers, neither sorted nor filtered for display
+func (rs *ReplaySource) Snapshot() (containers Containers) {
+	rs.lock.RLock()
+	for _, c := range rs.containers {
+		containers = append(containers, c)
+	}
+	rs.lock.RUnlock()
+	return containers
+}
+
+// Return a container by ID, or by name or a unique prefix of either
+func (rs *ReplaySource) Get(id string) (*Container, bool) {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	if c, ok := rs.containers[id]; ok {
+		return c, true
+	}
+	list := make(Containers, 0, len(rs.containers))
+	for _, c := range rs.containers {
+		list = append(list, c)
+	}
+	c, err := matchContainer(list, id)
+	return c, err == nil
+}
+
+// Replay source is always connected
+func (rs *ReplaySource) LostSince() time.Time { return time.Time{} }
+
+func (rs *ReplaySource) Endpoint() string { return "replay://" + rs.path }
+
+func (rs *ReplaySource) Host() metrics.HostMetrics {
+	rs.lock.RLock()
+	defer rs.lock.RUnlock()
+	return rs.host
+}
+
+// Return lifecycle times and exit code of a container recorded before
+// they were recorded apart from metadata, where they were kept as
+// strings. The creation time was only recorded formatted for display
+func legacyLifecycle(meta map[string]string) Lifecycle {
+	var l Lifecycle
+	l.Started, _ = time.Parse(time.RFC3339Nano, meta["startedAt"])
+	l.Since, _ = time.Parse(time.RFC3339Nano, meta["stateSince"])
+	if code, err := strconv.Atoi(meta["exitCode"]); err == nil {
+		l.Exited, l.ExitCode = true, code
+	}
+	return l
+}
+
+// Return recorded container metadata as a minimal inspect document
+func (rs *ReplaySource) Inspect(id string) (interface{}, error) {
+	c, ok := rs.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("no such container: %s", id)
+	}
+	return map[string]interface{}{
+		"Id":     c.Id,
+		"Meta":   c.MetaCopy(),
+		"Labels": c.Labels(),
+	}, nil
+}
+
+func (rs *ReplaySource) Attach(string, AttachOpts) error            { return errReplay }
+func (rs *ReplaySource) Rename(string, string) error                { return errReplay }
+func (rs *ReplaySource) Limits(string) (Limits, error)              { return Limits{}, errReplay }
+func (rs *ReplaySource) UpdateLimits(string, Limits) error          { return errReplay }
+func (rs *ReplaySource) SetRestartPolicy(string, string, int) error { return errReplay }
+func (rs *ReplaySource) Remove(string) error                        { return errReplay }
+func (rs *ReplaySource) ForceRemove(string) error                   { return errReplay }
+func (rs *ReplaySource) Stop(string) error                          { return errReplay }
+func (rs *ReplaySource) Restart(string) error                       { return errReplay }
+func (rs *ReplaySource) Pause(string) error                         { return errReplay }
+func (rs *ReplaySource) Unpause(string) error                       { return errReplay }
+
+func (rs *ReplaySource) Commit(id, repo, tag, comment string) (string, error) {
+	return "", errReplay
+}
+
+func (rs *ReplaySource) PullImage(string, func(PullProgress)) error { return errReplay }
+func (rs *ReplaySource) Recreate(string) (string, error)            { return "", errReplay }
+
+func (rs *ReplaySource) Changes(string) ([]FileChange, error) { return nil, errReplay }
+
+func (rs *ReplaySource) Export(context.Context, string, io.Writer) error { return errReplay }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const maxListedMatches = 5 // ambiguous matches named in errors
+
+// Return the container whose ID or name is s, else the only one whose
+// ID or name begins with s, as the docker CLI resolves containers.
+// An exact ID match wins over all others, and an exact name match over
+// prefixes. More than one match is an error naming them
+func matchContainer(list Containers, s string) (*Container, error) {
+	if s == "" {
+		return nil, fmt.Errorf("no container given")
+	}
+	var named, prefixed Containers
+	for _, c := range list {
+		if c.Id == s {
+			return c, nil
+		}
+		name := c.GetMeta("name")
+		switch {
+		case name == s:
+			named = append(named, c)
+		case strings.HasPrefix(c.Id, s), strings.HasPrefix(name, s):
+			prefixed = append(prefixed, c)
+		}
+	}
+	matches := named
+	if len(matches) == 0 {
+		matches = prefixed
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no container matches %s", s)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%s matches %d containers: %s", s, len(matches), describeMatches(matches))
+}
+
+// Return the names and short IDs of containers, sorted by name
+func describeMatches(list Containers) string {
+	var names []string
+	for _, c := range list {
+		id := c.Id
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		names = append(names, fmt.Sprintf("%s (%s)", c.GetMeta("name"), id))
+	}
+	sort.Strings(names)
+	if len(names) > maxListedMatches {
+		names = append(names[:maxListedMatches], fmt.Sprintf("and %d more", len(names)-maxListedMatches))
+	}
+	return strings.Join(names, ", ")
+}
+
+// Resolve a container ID, name or unique prefix of either among all
+// containers tracked, displayed or not
+func resolveContainer(s string) (*Container, error) {
+	return matchContainer(cursor.Source().All(), s)
+}
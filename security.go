@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Security posture levels, from least to most privileged
+var postureLevels = []string{"ok", "elevated", "privileged"}
+
+// Host paths which, bind mounted, give a container control of the host
+var sensitiveMounts = []string{"/", "/proc", "/var/run/docker.sock", "/run/docker.sock"}
+
+// Security posture of a container and what raised it above ok
+type securityPosture struct {
+	Level   string   // one of postureLevels
+	Reasons []string // e.g. "caps: SYS_ADMIN"
+}
+
+// Classify a container by its host config and mounts. Privileged
+// containers are privileged; added capabilities, sensitive host
+// mounts and host pid, network or ipc namespaces are elevated
+func classifySecurity(hc *docker.HostConfig, mounts []docker.Mount) securityPosture {
+	p := securityPosture{Level: "ok"}
+	if hc == nil {
+		return p
+	}
+	if hc.Privileged {
+		p.Level = "privileged"
+		p.Reasons = append(p.Reasons, "privileged")
+	}
+	if len(hc.CapAdd) > 0 {
+		p.elevate("caps: " + strings.Join(hc.CapAdd, ", "))
+	}
+	var hostNS []string
+	for _, m := range []struct{ ns, mode string }{
+		{"pid", hc.PidMode},
+		{"net", hc.NetworkMode},
+		{"ipc", hc.IpcMode},
+	} {
+		if m.mode == "host" {
+			hostNS = append(hostNS, m.ns)
+		}
+	}
+	if len(hostNS) > 0 {
+		p.elevate(fmt.Sprintf("host %s namespace", strings.Join(hostNS, ", ")))
+	}
+	for _, m := range mounts {
+		if m.Source != "" && known(sensitiveMounts, path.Clean(m.Source)) {
+			p.elevate("mounts " + m.Source)
+		}
+	}
+	return p
+}
+
+// Raise the posture to elevated, if not already higher, for a reason
+func (p *securityPosture) elevate(reason string) {
+	if p.Level == "ok" {
+		p.Level = "elevated"
+	}
+	p.Reasons = append(p.Reasons, reason)
+}
+
+// Return a badge for the expanded view, listing reasons one per line,
+// or an empty string if ok
+func (p securityPosture) Badge() string {
+	return strings.Join(p.Reasons, "\n")
+}
+
+// Return the rank of a posture level, 0 if unknown
+func postureRank(level string) int {
+	for n, l := range postureLevels {
+		if l == level {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+// +build !release
+
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bcicen/ctop/config"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestClassifySecurity(t *testing.T) {
+	cases := []struct {
+		name   string
+		hc     *docker.HostConfig
+		mounts []docker.Mount
+		want   securityPosture
+	}{
+		{"no host config", nil, nil, securityPosture{Level: "ok"}},
+		{"default", &docker.HostConfig{NetworkMode: "bridge"}, []docker.Mount{{Source: "/srv/data"}}, securityPosture{Level: "ok"}},
+		{
+			"privileged",
+			&docker.HostConfig{Privileged: true, CapAdd: []string{"SYS_ADMIN"}},
+			nil,
+			securityPosture{"privileged", []string{"privileged", "caps: SYS_ADMIN"}},
+		},
+		{
+			"capabilities",
+			&docker.HostConfig{CapAdd: []string{"SYS_ADMIN", "NET_ADMIN"}},
+			nil,
+			securityPosture{"elevated", []string{"caps: SYS_ADMIN, NET_ADMIN"}},
+		},
+		{
+			"host namespaces",
+			&docker.HostConfig{PidMode: "host", NetworkMode: "host", IpcMode: "container:abc"},
+			nil,
+			securityPosture{"elevated", []string{"host pid, net namespace"}},
+		},
+		{
+			"sensitive mounts",
+			&docker.HostConfig{},
+			[]docker.Mount{{Source: "/var/run/docker.sock"}, {Source: "/proc/"}, {Source: "/"}, {Source: "/procfs"}},
+			securityPosture{"elevated", []string{"mounts /var/run/docker.sock", "mounts /proc/", "mounts /"}},
+		},
+	}
+	for _, tc := range cases {
+		if got := classifySecurity(tc.hc, tc.mounts); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestShellTemplateQuoting(t *testing.T) {
+	ctx := &HookContext{
+		ActionContext: ActionContext{
+			ID:     "3f2a",
+			Name:   "web; touch /tmp/pwned",
+			Pid:    42,
+			Labels: map[string]string{"team": "$(id)", "tier": "it's"},
+		},
+		Meta: map[string]string{"user": "`id`"},
+	}
+	cases := []struct{ tmpl, want string }{
+		{"docker logs {{.ID}}", "docker logs 3f2a"},
+		{"nsenter -t {{.Pid}}", "nsenter -t 42"},
+		{"echo {{.Name}}", "echo 'web; touch /tmp/pwned'"},
+		{"echo {{.Labels.team}} {{.Labels.tier}}", `echo '$(id)' 'it'\''s'`},
+		{"echo {{.Labels.missing}}", "echo ''"},
+		{"echo {{.Meta.user}}", "echo '`id`'"},
+		{`echo {{printf "%s/%s" .ID .Name}}`, "echo '3f2a/web; touch /tmp/pwned'"},
+		{"{{if .Labels.team}}echo {{.Labels.team}}{{end}}", "echo '$(id)'"},
+		{"{{range $k, $v := .Labels}}{{$k}}={{$v}} {{end}}", `team='$(id)' tier='it'\''s' `},
+		{"echo {{.Name | shquote}}", "echo 'web; touch /tmp/pwned'"},
+		{"echo {{.Name | raw}}", "echo web; touch /tmp/pwned"},
+	}
+	for _, tc := range cases {
+		tmpl, err := config.ShellTemplate("test", tc.tmpl)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.tmpl, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, ctx); err != nil {
+			t.Fatalf("%s: %s", tc.tmpl, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.tmpl, got, tc.want)
+		}
+	}
+}
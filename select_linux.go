@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// Wait for a descriptor of set to be readable, leaving those readable
+func selectRead(n int, set *syscall.FdSet) error {
+	_, err := syscall.Select(n, set, nil, nil, nil)
+	return err
+}
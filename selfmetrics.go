@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcicen/ctop/metrics"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Collector of a ctop self-metric, writing its series in the
+// Prometheus text format on each scrape
+type selfCollector interface {
+	collect(buf *bytes.Buffer)
+}
+
+// Counter, incremented from the code path it counts
+type selfCounter struct {
+	name, help string
+	value      int64
+}
+
+func (c *selfCounter) inc() { atomic.AddInt64(&c.value, 1) }
+
+func (c *selfCounter) collect(buf *bytes.Buffer) {
+	promHeader(buf, c.name, c.help, "counter")
+	fmt.Fprintf(buf, "%s %d\n", c.name, atomic.LoadInt64(&c.value))
+}
+
+// Counters partitioned by the value of a single label
+type selfCounterVec struct {
+	name, help, label string
+	lock              sync.Mutex
+	values            map[string]int64
+}
+
+func (c *selfCounterVec) inc(value string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]int64)
+	}
+	c.values[value]++
+}
+
+func (c *selfCounterVec) collect(buf *bytes.Buffer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	promHeader(buf, c.name, c.help, "counter")
+	var keys []string
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s{%s=%s} %d\n", c.name, c.label, promQuote(k), c.values[k])
+	}
+}
+
+// Gauge or counter read when scraped, from state kept elsewhere
+type selfFunc struct {
+	name, help, kind string
+	value            func() int64
+}
+
+func (f *selfFunc) collect(buf *bytes.Buffer) {
+	promHeader(buf, f.name, f.help, f.kind)
+	fmt.Fprintf(buf, "%s %d\n", f.name, f.value())
+}
+
+// Histogram of durations, in seconds, with cumulative buckets
+type selfHistogram struct {
+	name, help string
+	bounds     []float64 // bucket upper bounds, ascending
+	lock       sync.Mutex
+	counts     []int64 // observations by bucket, the last over all bounds
+	sum        float64
+	count      int64
+}
+
+// Observe the time taken since start
+func (h *selfHistogram) since(start time.Time) {
+	v := time.Since(start).Seconds()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.counts == nil {
+		h.counts = make([]int64, len(h.bounds)+1)
+	}
+	n := sort.SearchFloat64s(h.bounds, v)
+	h.counts[n]++
+	h.sum += v
+	h.count++
+}
+
+func (h *selfHistogram) collect(buf *bytes.Buffer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	promHeader(buf, h.name, h.help, "histogram")
+	var cumulative int64
+	for n, b := range h.bounds {
+		if h.counts != nil {
+			cumulative += h.counts[n]
+		}
+		fmt.Fprintf(buf, "%s_bucket{le=\"%s\"} %d\n", h.name, strconv.FormatFloat(b, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(buf, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(buf, "%s_sum %s\n", h.name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(buf, "%s_count %d\n", h.name, h.count)
+}
+
+var (
+	selfRefreshDropped = &selfCounter{name: "ctop_self_refresh_requests_dropped_total",
+		help: "Container refresh requests dropped as duplicates of a refresh already pending"}
+	selfEventRestarts = &selfCounter{name: "ctop_self_event_listener_restarts_total",
+		help: "Restarts of the docker event listener"}
+	selfAPIErrors = &selfCounterVec{name: "ctop_self_api_errors_total",
+		help: "Failed container source API calls, by type of error", label: "type"}
+	selfRenderTime = &selfHistogram{name: "ctop_self_frame_render_seconds",
+		help:   "Time taken to render the container list",
+		bounds: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}}
+)
+
+// Self-metrics exported along with container metrics, in output order
+var selfMetrics = []selfCollector{
+	&selfFunc{"ctop_self_containers", "Containers tracked", "gauge", func() int64 {
+		return int64(len(cursor.Source().Snapshot()))
+	}},
+	&selfFunc{"ctop_self_collectors_running", "Metric collectors running", "gauge", func() int64 {
+		var n int64
+		for _, c := range cursor.Source().Snapshot() {
+			if running, _ := c.CollectorState(); running {
+				n++
+			}
+		}
+		return n
+	}},
+	&selfFunc{"ctop_self_stats_stream_reconnects_total", "Stats streams reopened after polling", "counter", metrics.StreamReconnects},
+	selfRefreshDropped,
+	selfEventRestarts,
+	selfRenderTime,
+	selfAPIErrors,
+}
+
+// Render self-metrics in the Prometheus text format
+func selfExposition() []byte {
+	var buf bytes.Buffer
+	for _, m := range selfMetrics {
+		m.collect(&buf)
+	}
+	return buf.Bytes()
+}
+
+func promHeader(buf *bytes.Buffer, name, help, kind string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+// Classify a failed API call for ctop_self_api_errors_total
+func apiErrorType(err error) string {
+	switch e := err.(type) {
+	case *docker.Error:
+		return fmt.Sprintf("http_%dxx", e.Status/100)
+	case *docker.NoSuchContainer:
+		return "not_found"
+	case net.Error:
+		if e.Timeout() {
+			return "timeout"
+		}
+		return "connection"
+	}
+	switch {
+	case permissionDenied(err):
+		return "permission"
+	case err == docker.ErrConnectionRefused:
+		return "connection"
+	}
+	return "other"
+}
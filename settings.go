@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/bcicen/ctop/widgets"
+	"github.com/bcicen/ctop/widgets/menu"
+	ui "github.com/gizak/termui"
+)
+
+// Validation and side effects of changing a setting at runtime.
+// Either may be nil
+type settingHook struct {
+	validate func(string) error
+	apply    func()
+}
+
+var settingHooks = map[string]settingHook{
+	"filterStr": {
+		validate: func(s string) error {
+			_, err := parseFilter(s)
+			return err
+		},
+	},
+	"sortField": {
+		validate: func(s string) error {
+			if Sorters[s] == nil {
+				return fmt.Errorf("expected one of: %s", strings.Join(SortFields(), ", "))
+			}
+			return nil
+		},
+	},
+	"refreshInterval": {
+		validate: func(s string) error {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid duration: %s", s)
+			}
+			return nil
+		},
+		apply: applyRefreshInterval,
+	},
+	"summaryLabel": {
+		validate: validLabelKey,
+	},
+	"envShow": {
+		validate: validEnvNames,
+	},
+	"columns": {
+		validate: validColumns,
+		apply:    compact.ResetLayout,
+	},
+	"wideMode": {
+		apply: compact.ResetLayout,
+	},
+	"titleFormat": {
+		validate: validTitleFormat,
+	},
+	"gaugeMetric": {
+		validate: compact.ValidGaugeMetric,
+		apply:    applyGaugeMetric,
+	},
+	"gaugeWarn": {
+		validate: validPercent,
+	},
+	"gaugeCrit": {
+		validate: validPercent,
+	},
+	"notifyEvents": {
+		validate: func(s string) error {
+			for _, e := range strings.Split(s, ",") {
+				if !known(notifyEventNames, strings.TrimSpace(e)) {
+					return fmt.Errorf("unknown event %s, expected: %s", e, strings.Join(notifyEventNames, ", "))
+				}
+			}
+			return nil
+		},
+	},
+	"highlightColumns": {
+		validate: compact.ValidChangeColumns,
+	},
+	"highlightDelta": {
+		validate: func(s string) error {
+			if n, err := strconv.ParseFloat(s, 64); err != nil || n < 0 {
+				return fmt.Errorf("expected a percentage of the previous value")
+			}
+			return nil
+		},
+	},
+	"hookRate": {
+		validate: func(s string) error {
+			if n, err := strconv.Atoi(s); err != nil || n < 1 {
+				return fmt.Errorf("expected a positive number of runs per minute")
+			}
+			return nil
+		},
+	},
+	"eventHistory": {
+		validate: func(s string) error {
+			if n, err := strconv.Atoi(s); err != nil || n < 1 {
+				return fmt.Errorf("expected a positive number of events")
+			}
+			return nil
+		},
+	},
+	"netAnomalyFactor": {
+		validate: func(s string) error {
+			if n, err := strconv.ParseFloat(s, 64); err != nil || n < 0 {
+				return fmt.Errorf("expected a multiple of the baseline rate, or 0 to disable")
+			}
+			return nil
+		},
+	},
+	"netAnomalySamples": {
+		validate: func(s string) error {
+			if n, err := strconv.Atoi(s); err != nil || n < 1 {
+				return fmt.Errorf("expected a positive number of samples")
+			}
+			return nil
+		},
+	},
+	"historyLen": {
+		validate: func(s string) error {
+			if n, err := strconv.Atoi(s); err != nil || n < 2 {
+				return fmt.Errorf("expected a number of samples, 2 or more")
+			}
+			return nil
+		},
+	},
+	"timeFormat": {
+		validate: validTimeFormat,
+	},
+	"durationStyle": {
+		validate: validDurationStyle,
+	},
+	"invertColors": {
+		apply: func() {
+			applyTheme()
+			rebuildWidgets()
+		},
+	},
+	"asciiMode": {
+		apply: func() {
+			cwidgets.SetASCII(config.GetSwitchVal("asciiMode"))
+			rebuildWidgets()
+		},
+	},
+}
+
+// Keys set by command line flags which are deliberately not adjustable
+// from the settings menu. All others set by flags are given a Group
+var startupKeys = []string{
+	"profile",   // switched from the profile menu
+	"connector", // selects the container source
+	"readOnly",  // not to be lifted from within the UI
+}
+
+// Settings changed from the settings menu, saved to the
+// config file along with savedKeys
+var changedSettings = make(map[string]bool)
+
+func validPercent(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 100 {
+		return fmt.Errorf("expected a percentage from 0 to 100")
+	}
+	return nil
+}
+
+func validColumns(s string) error {
+	var names []string
+	for _, c := range compact.Columns {
+		names = append(names, c.Name)
+	}
+	for _, name := range strings.Split(s, ",") {
+		if !known(names, strings.TrimSpace(name)) {
+			return fmt.Errorf("unknown column %s, expected: %s", name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// Apply a new value for a setting, after validation
+func updateSetting(s config.Setting, val string) error {
+	if readOnly() && known(commandSettings, s.Key) {
+		return errReadOnly
+	}
+	hook := settingHooks[s.Key]
+	if hook.validate != nil {
+		if err := hook.validate(val); err != nil {
+			return err
+		}
+	}
+	if s.Switch {
+		config.SetSwitchVal(s.Key, val == "true")
+	} else {
+		config.Update(s.Key, val)
+	}
+	if hook.apply != nil {
+		hook.apply()
+	}
+	changedSettings[s.Key] = true
+	return nil
+}
+
+func settingValue(s config.Setting) string {
+	if s.Switch {
+		if config.GetSwitchVal(s.Key) {
+			return "on"
+		}
+		return "off"
+	}
+	return config.GetVal(s.Key)
+}
+
+// List runtime-adjustable settings by category, toggling switches
+// and editing other values inline on enter
+func SettingsMenu() {
+	settings := config.Settings()
+	selected := 0
+	edit := -1
+
+	for {
+		ui.Clear()
+		ui.DefaultEvtStream.ResetHandlers()
+
+		m := menu.NewMenu()
+		m.Selectable = true
+		m.BorderLabel = "Settings"
+		var group string
+		for n, s := range settings {
+			if s.Group != group {
+				group = s.Group
+				m.AddItems(menu.Item{Label: fmt.Sprintf("[%s]", group)})
+			}
+			label := fmt.Sprintf("  %-32s %s", s.Label, settingValue(s))
+			m.AddItems(menu.Item{Val: strconv.Itoa(n), Label: label})
+		}
+		m.SetCursor(strconv.Itoa(selected))
+
+		// skip category headings, which have no value
+		HandleKeys("up", func() {
+			m.Up()
+			if m.SelectedItem().Val == "" {
+				m.Up()
+			}
+			if m.SelectedItem().Val == "" {
+				m.Down()
+			}
+		})
+		HandleKeys("down", func() {
+			m.Down()
+			if m.SelectedItem().Val == "" {
+				m.Down()
+			}
+		})
+		HandleKeys("exit", ui.StopLoop)
+		ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+			edit, _ = strconv.Atoi(m.SelectedItem().Val)
+			ui.StopLoop()
+		})
+
+		ui.Render(m)
+		if footer.Active() {
+			ui.Render(footer)
+		}
+		ui.Loop()
+		if edit < 0 {
+			break
+		}
+
+		s := settings[edit]
+		selected, edit = edit, -1
+		if s.Switch {
+			val := strconv.FormatBool(!config.GetSwitchVal(s.Key))
+			if err := updateSetting(s, val); err != nil {
+				log.NotifyError("invalid %s: %s", s.Label, err)
+			}
+			continue
+		}
+		editSetting(s)
+	}
+	ui.DefaultEvtStream.ResetHandlers()
+}
+
+// Prompt for a new value of a single setting
+func editSetting(s config.Setting) {
+	ui.DefaultEvtStream.ResetHandlers()
+	defer ui.DefaultEvtStream.ResetHandlers()
+
+	i := widgets.NewInput()
+	i.BorderLabel = s.Label
+	i.Chars = widgets.CommandChars
+	i.SetMaxLen(128)
+	i.Data = config.GetVal(s.Key)
+	align := func() {
+		i.SetY(ui.TermHeight() - i.Height)
+		ui.Render(i)
+	}
+	align()
+
+	i.InputHandlers()
+	ui.Handle("/sys/kbd/<escape>", func(ui.Event) {
+		ui.StopLoop()
+	})
+	ui.Handle("/sys/kbd/<enter>", func(ui.Event) {
+		if err := updateSetting(s, i.Data); err != nil {
+			i.SetError(err.Error())
+			align()
+			return
+		}
+		ui.StopLoop()
+	})
+	ui.Loop()
+}
@@ -0,0 +1,73 @@
+// +build !release
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/bcicen/ctop/config"
+)
+
+// Ensure every key set by a command line flag is adjustable from the
+// settings menu, unless listed in startupKeys
+func TestFlagKeysInSettings(t *testing.T) {
+	benchInit()
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inMenu := make(map[string]bool)
+	for _, s := range config.Settings() {
+		inMenu[s.Key] = true
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 3 {
+			return true
+		}
+		fn, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (fn.Sel.Name != "UpdateFrom" && fn.Sel.Name != "SetSwitchFrom") {
+			return true
+		}
+		source, ok := call.Args[2].(*ast.SelectorExpr)
+		lit, isLit := call.Args[0].(*ast.BasicLit)
+		if !ok || source.Sel.Name != "SourceFlag" || !isLit {
+			return true
+		}
+		key, _ := strconv.Unquote(lit.Value)
+		if !inMenu[key] && !known(startupKeys, key) {
+			t.Errorf("%s is set by a flag but missing from the settings menu: give it a Group, or list it in startupKeys", key)
+		}
+		return true
+	})
+}
+
+// Invalid values loaded from files and the environment fall back to
+// their defaults, as the settings menu would refuse them
+func TestCheckLoadedParams(t *testing.T) {
+	benchInit()
+	defer config.UpdateFrom("refreshInterval", "1s", config.SourceDefault)
+	defer config.UpdateFrom("hookRate", "10", config.SourceDefault)
+	defer config.UpdateFrom("historyLen", config.GetVal("historyLen"), config.SourceDefault)
+
+	config.UpdateFrom("refreshInterval", "0s", config.SourceEnv)
+	config.UpdateFrom("hookRate", "0", config.SourceFile)
+	config.UpdateFrom("historyLen", "30", config.SourceFile)
+
+	if warnings := checkLoadedParams(); len(warnings) != 2 {
+		t.Errorf("got warnings %q, want 2", warnings)
+	}
+	for key, want := range map[string]string{"refreshInterval": "1s", "hookRate": "10", "historyLen": "30"} {
+		if got := config.GetVal(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if s := config.Get("refreshInterval").Source; s != config.SourceDefault {
+		t.Errorf("refreshInterval source %s, want %s", s, config.SourceDefault)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/metrics"
+	ui "github.com/gizak/termui"
+)
+
+const (
+	shutdownTimeout = 3 * time.Second // wait for collectors and tasks
+	quitTimeout     = 2 * time.Second // wait for the UI to exit after a signal
+)
+
+var (
+	tasks        sync.WaitGroup // detached actions and exports in progress
+	shutdownOnce sync.Once
+	quitting     int32
+)
+
+// Run f in a new goroutine, as a task waited for on shutdown
+func goTask(f func()) {
+	tasks.Add(1)
+	safeGo(func() {
+		defer tasks.Done()
+		f()
+	})
+}
+
+// Stop the UI loop and any view open, exiting ctop
+func requestQuit() {
+	atomic.StoreInt32(&quitting, 1)
+	ui.StopLoop()
+}
+
+func quitRequested() bool { return atomic.LoadInt32(&quitting) == 1 }
+
+// Quit on SIGINT or SIGTERM, shutting down directly should the UI
+// not exit in time. A second signal exits immediately, at any point
+// of the shutdown
+func handleSignals() {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	var sig os.Signal
+	for sig = range sigs {
+		// ctrl-c detaches from a container attached without a TTY
+		if sig != os.Interrupt || !detachAttached() {
+			break
+		}
+	}
+	log.Noticef("received %s, shutting down", sig)
+	requestQuit()
+
+	go func() {
+		<-sigs
+		log.Notice("received second signal, exiting immediately")
+		forceExit()
+	}()
+
+	time.Sleep(quitTimeout)
+	Shutdown()
+	os.Exit(0)
+}
+
+// Exit without waiting for collectors or tasks, restoring the
+// terminal unless that too is stuck
+func forceExit() {
+	closed := make(chan bool)
+	go func() {
+		ui.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(200 * time.Millisecond):
+	}
+	os.Exit(1)
+}
+
+// Save settings, stop all exporters, exports, collectors and the
+// container source, waiting briefly for them to finish before closing the UI
+// and flushing the log. Only the first call has any effect
+func Shutdown() {
+	shutdownOnce.Do(func() {
+		log.Notice("shutting down")
+		if uiStarted {
+			ui.DefaultEvtStream.ResetHandlers() // stop accepting input
+			// read-only terminals leave the config file as it was
+			if config.GetSwitchVal("saveState") && !readOnly() {
+				saveConfig()
+			}
+		}
+		stopExporters()
+		cancelExports()
+		cancelStopRemoves()
+		waitShutdown(shutdownTimeout)
+		ui.Close()
+		restoreTitle()
+		if frames != nil {
+			frames.print(os.Stdout)
+		}
+		log.Exit()
+	})
+}
+
+// Close the container source and wait for collectors and background
+// tasks to finish, up to the given timeout
+func waitShutdown(timeout time.Duration) {
+	done := make(chan bool)
+	go func() {
+		if cursor != nil {
+			cursor.Source().Close()
+		}
+		tasks.Wait()
+		for metrics.Goroutines() > 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Warningf("shutdown timed out, abandoning %d collector goroutines and pending tasks", metrics.Goroutines())
+	}
+}
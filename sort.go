@@ -3,8 +3,14 @@ package main
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bcicen/ctop/config"
+	"github.com/bcicen/ctop/cwidgets/compact"
 )
 
 type sortMethod func(c1, c2 *Container) bool
@@ -23,26 +29,40 @@ var nameSorter = func(c1, c2 *Container) bool { return c1.GetMeta("name") < c2.G
 var Sorters = map[string]sortMethod{
 	"id":   idSorter,
 	"name": nameSorter,
+	"image": func(c1, c2 *Container) bool {
+		// Use secondary sort method if equal values
+		if c1.GetMeta("image") == c2.GetMeta("image") {
+			return nameSorter(c1, c2)
+		}
+		return c1.GetMeta("image") < c2.GetMeta("image")
+	},
+	"user": func(c1, c2 *Container) bool {
+		// Use secondary sort method if equal values
+		if c1.GetMeta("user") == c2.GetMeta("user") {
+			return nameSorter(c1, c2)
+		}
+		return c1.GetMeta("user") < c2.GetMeta("user")
+	},
 	"cpu": func(c1, c2 *Container) bool {
 		// Use secondary sort method if equal values
-		if c1.CPUUtil == c2.CPUUtil {
+		if c1.Metrics().CPUUtil == c2.Metrics().CPUUtil {
 			return nameSorter(c1, c2)
 		}
-		return c1.CPUUtil > c2.CPUUtil
+		return c1.Metrics().CPUUtil > c2.Metrics().CPUUtil
 	},
 	"mem": func(c1, c2 *Container) bool {
 		// Use secondary sort method if equal values
-		if c1.MemUsage == c2.MemUsage {
+		if c1.Metrics().MemUsage == c2.Metrics().MemUsage {
 			return nameSorter(c1, c2)
 		}
-		return c1.MemUsage > c2.MemUsage
+		return c1.Metrics().MemUsage > c2.Metrics().MemUsage
 	},
 	"mem %": func(c1, c2 *Container) bool {
 		// Use secondary sort method if equal values
-		if c1.MemPercent == c2.MemPercent {
+		if c1.Metrics().MemPercent == c2.Metrics().MemPercent {
 			return nameSorter(c1, c2)
 		}
-		return c1.MemPercent > c2.MemPercent
+		return c1.Metrics().MemPercent > c2.Metrics().MemPercent
 	},
 	"net": func(c1, c2 *Container) bool {
 		sum1 := sumNet(c1)
@@ -53,12 +73,21 @@ var Sorters = map[string]sortMethod{
 		}
 		return sum1 > sum2
 	},
+	"net rate": func(c1, c2 *Container) bool {
+		r1 := netRate(c1)
+		r2 := netRate(c2)
+		// Use secondary sort method if equal values
+		if r1 == r2 {
+			return nameSorter(c1, c2)
+		}
+		return r1 > r2
+	},
 	"pids": func(c1, c2 *Container) bool {
 		// Use secondary sort method if equal values
-		if c1.Pids == c2.Pids {
+		if c1.Metrics().Pids == c2.Metrics().Pids {
 			return nameSorter(c1, c2)
 		}
-		return c1.Pids > c2.Pids
+		return c1.Metrics().Pids > c2.Metrics().Pids
 	},
 	"io": func(c1, c2 *Container) bool {
 		sum1 := sumIO(c1)
@@ -69,10 +98,44 @@ var Sorters = map[string]sortMethod{
 		}
 		return sum1 > sum2
 	},
+	"cputime": func(c1, c2 *Container) bool {
+		// Use secondary sort method if equal values
+		if c1.Metrics().CPUTime == c2.Metrics().CPUTime {
+			return nameSorter(c1, c2)
+		}
+		return c1.Metrics().CPUTime > c2.Metrics().CPUTime
+	},
+	"since": func(c1, c2 *Container) bool {
+		// Use secondary sort method if equal values
+		s1 := c1.Lifecycle().Since
+		s2 := c2.Lifecycle().Since
+		if s1.Equal(s2) {
+			return nameSorter(c1, c2)
+		}
+		return s1.After(s2)
+	},
+	"security": func(c1, c2 *Container) bool {
+		// Use secondary sort method if equal values
+		r1 := postureRank(c1.GetMeta("posture"))
+		r2 := postureRank(c2.GetMeta("posture"))
+		if r1 == r2 {
+			return nameSorter(c1, c2)
+		}
+		return r1 > r2
+	},
+	"imagesize": func(c1, c2 *Container) bool {
+		s1 := imageBytes(c1)
+		s2 := imageBytes(c2)
+		// Use secondary sort method if equal values
+		if s1 == s2 {
+			return nameSorter(c1, c2)
+		}
+		return s1 > s2
+	},
 	"state": func(c1, c2 *Container) bool {
 		// Use secondary sort method if equal values
-		c1state := c1.GetMeta("state")
-		c2state := c2.GetMeta("state")
+		c1state := c1.State()
+		c2state := c2.State()
 		if c1state == c2state {
 			return nameSorter(c1, c2)
 		}
@@ -80,42 +143,338 @@ var Sorters = map[string]sortMethod{
 	},
 }
 
+// Sort by the given field, or reverse the sort order if already
+// sorted by it
+func sortBy(field string) {
+	if field == config.GetVal("sortField") {
+		config.Toggle("sortReversed")
+	} else {
+		config.Update("sortField", field)
+	}
+}
+
+// Return all sort fields, ordered by column
 func SortFields() (fields []string) {
+	seen := make(map[string]bool)
+	for _, c := range compact.Columns {
+		if _, ok := Sorters[c.Sort]; ok && !seen[c.Sort] {
+			fields = append(fields, c.Sort)
+			seen[c.Sort] = true
+		}
+	}
+
+	// append any fields without a column
+	var extra []string
 	for k := range Sorters {
-		fields = append(fields, k)
+		if !seen[k] {
+			extra = append(extra, k)
+		}
 	}
-	return fields
+	sort.Strings(extra)
+
+	return append(fields, extra...)
 }
 
 type Containers []*Container
 
+const (
+	// display evaluations for containers with changed metadata are
+	// limited to this time per call, the rest carried to the next
+	filterBudget = 5 * time.Millisecond
+	// swaps allowed in sorting incrementally, per container, before
+	// falling back to a full sort
+	sortBudget = 8
+)
+
+// Previous sort order and filter, shared by all container sources
+var (
+	lastOrder struct {
+		sync.Mutex
+		key  string // sort field and direction
+		rank map[string]int
+	}
+	lastFilter struct {
+		sync.Mutex
+		key    string // filter string and state toggle
+		filter containerFilter
+	}
+)
+
+// Containers sorted by a sort method
+type sortable struct {
+	Containers
+	less sortMethod
+}
+
+func (s sortable) Less(i, j int) bool { return s.less(s.Containers[i], s.Containers[j]) }
+
+// Containers sorted by a previous order, new containers last
+type ranked struct {
+	Containers
+	rank map[string]int
+}
+
+func (r ranked) Less(i, j int) bool {
+	ri, ok := r.rank[r.Containers[i].Key()]
+	if !ok {
+		ri = len(r.rank)
+	}
+	rj, ok := r.rank[r.Containers[j].Key()]
+	if !ok {
+		rj = len(r.rank)
+	}
+	return ri < rj
+}
+
 func (a Containers) Len() int      { return len(a) }
 func (a Containers) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
-func (a Containers) Less(i, j int) bool {
-	f := Sorters[config.GetVal("sortField")]
-	if config.GetSwitchVal("sortReversed") {
-		return f(a[j], a[i])
+
+// Sort by the configured field and direction. Starting from the
+// previous order, containers are sorted incrementally, which is
+// linear in the number of containers while their order is unchanged
+func (a Containers) Sort() {
+	less, key := configuredSort()
+
+	lastOrder.Lock()
+	defer lastOrder.Unlock()
+	if lastOrder.key != key || !a.insertionSort(lastOrder.rank, less) {
+		sort.Sort(sortable{a, less})
+	}
+
+	lastOrder.key = key
+	lastOrder.rank = make(map[string]int, len(a))
+	for n, c := range a {
+		lastOrder.rank[c.Key()] = n
 	}
-	return f(a[i], a[j])
 }
 
+// Return the sort method for the configured field and direction,
+// and a key identifying both
+func configuredSort() (sortMethod, string) {
+	field, reversed := config.GetVal("sortField"), config.GetSwitchVal("sortReversed")
+	f := Sorters[field]
+	less := f
+	if reversed {
+		less = func(c1, c2 *Container) bool { return f(c2, c1) }
+	}
+	return less, fmt.Sprintf("%s/%t", field, reversed)
+}
+
+// Sort from the previous order by insertion, returning false if
+// more swaps than allowed by sortBudget were needed
+func (a Containers) insertionSort(rank map[string]int, less sortMethod) bool {
+	sort.Sort(ranked{a, rank})
+	budget := sortBudget * len(a)
+	for i := 1; i < len(a); i++ {
+		for j := i; j > 0 && less(a[j], a[j-1]); j-- {
+			a.Swap(j, j-1)
+			if budget--; budget < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Evaluate the configured filter and state toggle for containers whose
+// metadata changed since last evaluated, or all containers if either
+// setting changed
 func (a Containers) Filter() {
-	filter := config.GetVal("filterStr")
-	re := regexp.MustCompile(fmt.Sprintf(".*%s", filter))
+	str, all := config.GetVal("filterStr"), config.GetSwitchVal("allContainers")
+	sandboxes := config.GetSwitchVal("showSandboxes")
+	previous := config.GetSwitchVal("filterPreviousNames")
+	key := fmt.Sprintf("%s/%t/%t/%t", str, all, sandboxes, previous)
 
-	for _, c := range a {
-		c.display = true
-		// Apply name filter
-		if re.FindAllString(c.GetMeta("name"), 1) == nil {
-			c.display = false
+	lastFilter.Lock()
+	defer lastFilter.Unlock()
+	changed := lastFilter.key != key
+	if changed {
+		lastFilter.key = key
+		lastFilter.filter, _ = parseFilter(str)
+	}
+
+	deadline := time.Now().Add(filterBudget)
+	for n, c := range a {
+		stale, version := c.displayStale()
+		if !changed && !stale {
+			continue
 		}
+		if !changed && n%16 == 0 && time.Now().After(deadline) {
+			return
+		}
+		display := lastFilter.filter.match(c) || (previous && lastFilter.filter.matchPrevious(c))
 		// Apply state filter
-		if !config.GetSwitchVal("allContainers") && c.GetMeta("state") != "running" {
-			c.display = false
+		if !all && c.State() != "running" {
+			display = false
+		}
+		if !sandboxes && isSandbox(c.Labels()) {
+			display = false
+		}
+		c.setDisplay(display, version)
+	}
+}
+
+// Metadata fields a filter term may be scoped to, as "scope:pattern".
+// Unscoped terms match container names; label terms match any label
+// given as "key=value", source terms the container source, and priv
+// terms "true" for containers elevated or privileged, else "false"
+var filterScopes = []string{"name", "image", "state", "user", "label", "source", "priv"}
+
+type filterTerm struct {
+	scope string
+	re    *regexp.Regexp
+	lower bool // pattern has no upper case letters, matched ignoring case
+}
+
+// Container filter of space-separated terms, all of which must match
+type containerFilter []filterTerm
+
+// Parse a filter string. Patterns are regular expressions, matched
+// literally if invalid; terms with an unknown scope are matched
+// against names in full. Patterns in lower case match regardless of
+// case. Returns an error describing the first invalid term, if any
+func parseFilter(s string) (f containerFilter, err error) {
+	for _, term := range strings.Fields(s) {
+		scope, pattern := "name", term
+		if idx := strings.Index(term, ":"); idx > 0 {
+			if known(filterScopes, term[:idx]) {
+				scope, pattern = term[:idx], term[idx+1:]
+			} else if err == nil {
+				err = fmt.Errorf("unknown filter scope %s, expected one of: %s", term[:idx], strings.Join(filterScopes, ", "))
+			}
+		}
+		re, reErr := regexp.Compile(pattern)
+		if reErr != nil {
+			if err == nil {
+				err = fmt.Errorf("invalid filter pattern %s: %s", pattern, reErr)
+			}
+			re = regexp.MustCompile(regexp.QuoteMeta(pattern))
 		}
+		f = append(f, filterTerm{scope, re, strings.ToLower(pattern) == pattern})
 	}
+	return f, err
+}
+
+// Container metadata matched by filter terms, as is and lowercased,
+// as of a metadata version
+type filterFields struct {
+	version     uint64
+	meta        map[string]string // by scope, other than label
+	lower       map[string]string
+	labels      []string // as "key=value"
+	lowerLabels []string
 }
 
-func sumNet(c *Container) int64 { return c.NetRx + c.NetTx }
+func newFilterFields(version uint64, meta, labels map[string]string, source string) *filterFields {
+	f := &filterFields{
+		version: version,
+		meta:    make(map[string]string, len(filterScopes)),
+		lower:   make(map[string]string, len(filterScopes)),
+	}
+	for _, scope := range filterScopes {
+		v := meta[scope]
+		switch scope {
+		case "source":
+			v = source
+		case "priv":
+			v = strconv.FormatBool(postureRank(meta["posture"]) > 0)
+		}
+		f.meta[scope] = v
+		f.lower[scope] = strings.ToLower(v)
+	}
+	for k, v := range labels {
+		f.labels = append(f.labels, k+"="+v)
+		f.lowerLabels = append(f.lowerLabels, strings.ToLower(k+"="+v))
+	}
+	return f
+}
 
-func sumIO(c *Container) int64 { return c.IOBytesRead + c.IOBytesWrite }
+func (f containerFilter) match(c *Container) bool {
+	if len(f) == 0 {
+		return true
+	}
+	fields := c.filterFields()
+	return f.matchAs(fields, fields.meta["name"])
+}
+
+// Return whether the filter matches a container under any of the
+// names it had before renames
+func (f containerFilter) matchPrevious(c *Container) bool {
+	fields := c.filterFields()
+	for _, p := range c.PreviousNames() {
+		if f.matchAs(fields, p.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match container metadata as if the container had the given name
+func (f containerFilter) matchAs(fields *filterFields, name string) bool {
+	lowerName := strings.ToLower(name)
+	for _, t := range f {
+		meta, labels, n := fields.meta, fields.labels, name
+		if t.lower {
+			meta, labels, n = fields.lower, fields.lowerLabels, lowerName
+		}
+		switch t.scope {
+		case "label":
+			if !t.matchAny(labels) {
+				return false
+			}
+		case "name":
+			if !t.re.MatchString(n) {
+				return false
+			}
+		default:
+			if !t.re.MatchString(meta[t.scope]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (t filterTerm) matchAny(list []string) bool {
+	for _, s := range list {
+		if t.re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func known(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sumNet(c *Container) int64 {
+	m := c.Metrics()
+	return m.NetRx + m.NetTx
+}
+
+// Return the size of a container's image, 0 if not yet inspected
+func imageBytes(c *Container) int64 {
+	n, _ := strconv.ParseInt(c.GetMeta("image bytes"), 10, 64)
+	return n
+}
+
+func sumIO(c *Container) int64 {
+	m := c.Metrics()
+	return m.IOBytesRead + m.IOBytesWrite
+}
+
+// Return the latest network rate of a container in bytes/s, or 0 if
+// not yet known
+func netRate(c *Container) float64 {
+	rates := netRates(c.Recent(2))
+	if len(rates) == 0 {
+		return 0
+	}
+	return rates[len(rates)-1]
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+)
+
+const (
+	exitGrace    = 10 * time.Second       // beyond the stop timeout, for the exit to be seen
+	exitPollRate = 250 * time.Millisecond // state checks while waiting for an exit
+)
+
+// Containers being stopped and removed, by ID, and those which did not
+// exit in time and may be force removed
+var stopRemoves = struct {
+	sync.Mutex
+	ids    map[string]bool
+	stuck  map[string]bool
+	ctx    context.Context
+	cancel context.CancelFunc
+}{ids: make(map[string]bool), stuck: make(map[string]bool)}
+
+func init() {
+	stopRemoves.ctx, stopRemoves.cancel = context.WithCancel(context.Background())
+}
+
+// Abandon all stop and remove sequences in progress, leaving their
+// containers as they are
+func cancelStopRemoves() {
+	stopRemoves.cancel()
+}
+
+// Return the containers of the selected group, or the selected container
+func selectedTargets() Containers {
+	if g := cursor.SelectedGroup(); g != nil {
+		return append(Containers{}, g.containers...)
+	}
+	if c := cursor.Selected(); c != nil {
+		return Containers{c}
+	}
+	return nil
+}
+
+// Confirm and stop, then remove the selected container or all those of
+// the selected group. A container which did not exit in time is offered
+// for force removal when selected again
+func StopRemoveMenu() {
+	targets := selectedTargets()
+	if len(targets) == 0 {
+		return
+	}
+
+	var stuck, rest Containers
+	stopRemoves.Lock()
+	for _, c := range targets {
+		switch {
+		case stopRemoves.stuck[c.Id]:
+			stuck = append(stuck, c)
+		case !stopRemoves.ids[c.Id]:
+			rest = append(rest, c)
+		}
+	}
+	stopRemoves.Unlock()
+
+	if len(stuck) > 0 {
+		if confirmContainers(fmt.Sprintf("Force remove %d containers which did not exit?", len(stuck)), stuck) {
+			goTask(func() { forceRemoveAll(stuck) })
+		}
+		return
+	}
+	if len(rest) == 0 {
+		log.Notify("already stopping and removing %d containers", len(targets))
+		return
+	}
+	if confirmContainers(fmt.Sprintf("Stop and remove %d containers?", len(rest)), rest) {
+		goTask(func() { stopRemoveAll(rest) })
+	}
+}
+
+// Stop and remove all target containers with bounded concurrency
+func stopRemoveAll(targets Containers) {
+	var (
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, bulkWorkers)
+	)
+	stopRemoves.Lock()
+	for _, c := range targets {
+		stopRemoves.ids[c.Id] = true
+	}
+	stopRemoves.Unlock()
+
+	for _, c := range targets {
+		wg.Add(1)
+		workers <- struct{}{}
+		c := c
+		safeGo(func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			stopRemove(stopRemoves.ctx, c)
+
+			stopRemoves.Lock()
+			delete(stopRemoves.ids, c.Id)
+			stopRemoves.Unlock()
+		})
+	}
+	wg.Wait()
+}
+
+// Stop a container, wait for it to exit and remove it, flashing each
+// step in the footer and notifying the outcome
+func stopRemove(ctx context.Context, c *Container) {
+	name := c.GetMeta("name")
+	ellipsis := string(cwidgets.Glyphs.Ellipsis)
+	steps := "stopping" + ellipsis
+	progress := func() { footer.Flash(fmt.Sprintf("%s: %s", name, steps), 5*time.Second) }
+	progress()
+
+	if isRunning(c) {
+		stopped := make(chan error, 1)
+		safeGo(func() { stopped <- cursor.Source().Stop(c.Id) })
+		select {
+		case err := <-stopped:
+			if err != nil {
+				log.NotifyError("%s: %s failed to stop: %s", name, steps, err)
+				return
+			}
+		case <-ctx.Done():
+			log.Noticef("abandoned stop and remove of %s", name)
+			return
+		}
+	}
+
+	exitCode, ok := waitExit(ctx, c, time.Duration(stopTimeout)*time.Second+exitGrace)
+	switch {
+	case ctx.Err() != nil:
+		log.Noticef("abandoned stop and remove of %s", name)
+		return
+	case !ok:
+		stopRemoves.Lock()
+		stopRemoves.stuck[c.Id] = true
+		stopRemoves.Unlock()
+		log.NotifyError("%s: %s did not exit, press [M] on it to force remove", name, steps)
+		return
+	}
+	steps += fmt.Sprintf(" stopped (exit %d)%s", exitCode, ellipsis)
+	progress()
+
+	if err := cursor.Source().Remove(c.Id); err != nil {
+		log.NotifyError("%s: %s failed to remove: %s", name, steps, err)
+		return
+	}
+	log.Notify("%s: %s removed", name, steps)
+}
+
+// Wait up to timeout for a container to exit, returning its exit code
+// and false if it is still running
+func waitExit(ctx context.Context, c *Container, timeout time.Duration) (int, bool) {
+	deadline := time.After(timeout)
+	tick := time.NewTicker(exitPollRate)
+	defer tick.Stop()
+	for {
+		current, ok := cursor.Source().Get(c.Id)
+		if !ok {
+			return c.Lifecycle().ExitCode, true // removed meanwhile
+		}
+		if !isRunning(current) {
+			return current.Lifecycle().ExitCode, true
+		}
+		select {
+		case <-tick.C:
+		case <-deadline:
+			return 0, false
+		case <-ctx.Done():
+			return 0, false
+		}
+	}
+}
+
+func isRunning(c *Container) bool {
+	switch c.State() {
+	case "running", "paused", "restarting":
+		return true
+	}
+	return false
+}
+
+// Force remove containers which did not exit when stopped
+func forceRemoveAll(targets Containers) {
+	for _, c := range targets {
+		name := c.GetMeta("name")
+		err := cursor.Source().ForceRemove(c.Id)
+		if err != nil {
+			log.NotifyError("failed to force remove %s: %s", name, err)
+			continue
+		}
+		stopRemoves.Lock()
+		delete(stopRemoves.stuck, c.Id)
+		stopRemoves.Unlock()
+		log.Notify("force removed %s", name)
+	}
+}
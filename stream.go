@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets/compact"
+	"github.com/docker/docker/pkg/term"
+)
+
+const streamTimeFormat = "2006-01-02T15:04:05"
+
+// Output formats for stats printed without the UI
+var streamFormats = map[string]bool{
+	"table":       true,
+	"json":        true,
+	"json-pretty": true,
+}
+
+// Print container stats to stdout at each refresh interval, without
+// the UI, until interrupted. Table output is redrawn in place when
+// stdout is a terminal, and appended as timestamped lines otherwise.
+// JSON formats print one document per interval. If iterations is
+// non-zero, only that many intervals are printed. Intervals where the
+// daemon is unreachable are skipped; returns false if every interval
+// was skipped
+func StreamStats(format string, iterations int) (ok bool) {
+	tty := term.IsTerminal(os.Stdout.Fd())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ticker := time.NewTicker(refreshInterval())
+	defer ticker.Stop()
+	defer stopCollectors()
+
+	for n := 0; iterations == 0 || n < iterations; n++ {
+		select {
+		case <-sigs:
+			return ok
+		case <-ticker.C:
+		}
+
+		if lost := cursor.Source().LostSince(); !lost.IsZero() {
+			fmt.Fprintf(os.Stderr, "connection lost since %s\n", lost.Format(streamTimeFormat))
+			continue
+		}
+		ok = true
+
+		cursor.RefreshContainers()
+		if format != "table" {
+			b, err := jsonStats(format == "json-pretty")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to encode stats: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(b))
+			continue
+		}
+
+		now := time.Now().Format(streamTimeFormat)
+
+		var lines []string
+		if tty {
+			lines = append(lines, streamHeader())
+		}
+		for _, c := range cursor.filtered {
+			line := streamRow(c)
+			if !tty {
+				line = fmt.Sprintf("%s %s", now, line)
+			}
+			lines = append(lines, line)
+		}
+
+		if tty {
+			// move cursor home and clear screen
+			fmt.Print("\033[H\033[2J")
+		}
+		fmt.Println(strings.Join(lines, "\n"))
+	}
+	return ok
+}
+
+// Stop metric collectors for all containers, closing their streams
+func stopCollectors() {
+	for _, c := range cursor.Source().All() {
+		c.StopCollector()
+	}
+}
+
+// Column width for stream output
+func streamWidth(col *compact.Column) int {
+	if col.Name == "status" {
+		return 8
+	}
+	return col.NaturalWidth()
+}
+
+// Return header text for a column, in plain text output
+func columnLabel(col *compact.Column) string {
+	if col.Name == "status" {
+		return "STATUS"
+	}
+	return col.Label
+}
+
+// Return the text of a container column, in plain text output
+func columnText(c *Container, col *compact.Column) string {
+	if col.Name == "status" {
+		return c.State()
+	}
+	return c.Widgets().ColumnText(col.Name)
+}
+
+func streamHeader() string {
+	var fields []string
+	for _, col := range compact.EnabledColumns() {
+		fields = append(fields, padRight(columnLabel(col), streamWidth(col)))
+	}
+	return strings.TrimRight(strings.Join(fields, " "), " ")
+}
+
+func streamRow(c *Container) string {
+	var fields []string
+	for _, col := range compact.EnabledColumns() {
+		fields = append(fields, padRight(columnText(c, col), streamWidth(col)))
+	}
+	return strings.TrimRight(strings.Join(fields, " "), " ")
+}
+
+// Pad or truncate s to exactly n characters
+func padRight(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s + strings.Repeat(" ", n-len(r))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/bcicen/ctop/config"
+	ui "github.com/gizak/termui"
+	"github.com/nsf/termbox-go"
+)
+
+var (
+	termLock     sync.Mutex // serializes suspends and releases of the terminal
+	termReleased bool       // terminal released to a command by suspendUI
+	collectHeld  int32      // collectors stopped while suspended
+	suspendSigs  = make(chan os.Signal, 1)
+)
+
+// Return whether collectors are held stopped while ctop is suspended
+func collectionHeld() bool { return atomic.LoadInt32(&collectHeld) == 1 }
+
+// ctrl-z is read as a key while the terminal is in raw mode, and
+// suspends as SIGTSTP would, whichever view has the handlers
+func suspendKey(e ui.Event) {
+	if e.Path == "/sys/kbd/C-z" {
+		safeGo(suspend)
+	}
+}
+
+// Suspend on SIGTSTP, and repaint the UI on SIGCONT, as after being
+// stopped by SIGSTOP
+func handleSuspend() {
+	signal.Notify(suspendSigs, syscall.SIGTSTP, syscall.SIGCONT)
+	for sig := range suspendSigs {
+		switch sig {
+		case syscall.SIGTSTP:
+			suspend()
+		case syscall.SIGCONT:
+			repaint()
+		}
+	}
+}
+
+// Restore the terminal and stop the process group, as the shell does
+// on ctrl-z, reinitializing the UI once continued. Collectors keep
+// running meanwhile unless collectWhileSuspended is disabled
+func suspend() {
+	// without a job control shell, as when run as the init process of
+	// a container or orphaned, the stop signal would be discarded
+	if quitRequested() || os.Getpid() == 1 || os.Getppid() == 1 {
+		return
+	}
+	termLock.Lock()
+	defer termLock.Unlock()
+	// wait for handlers rendering, blocking further ones until resumed
+	ui.DefaultEvtStream.Lock()
+	defer ui.DefaultEvtStream.Unlock()
+
+	if !termReleased {
+		termbox.Close()
+	}
+	// the shell's title is shown while suspended, and ctop's set
+	// again on the next refresh
+	restoreTitle()
+	hold := !config.GetSwitchVal("collectWhileSuspended")
+	if hold {
+		holdCollectors()
+	}
+	log.Notice("suspended")
+
+	// the Go runtime leaves SIGTSTP without its default action once
+	// notified, so the process group is stopped with SIGSTOP instead
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+	syscall.Kill(0, syscall.SIGSTOP)
+	<-cont
+	signal.Stop(cont)
+	log.Notice("resumed")
+
+	if hold {
+		resumeCollectors()
+	}
+	if !termReleased {
+		if err := termbox.Init(); err != nil {
+			panic(err)
+		}
+		ui.Clear()
+	}
+}
+
+// Redraw the current view in full at its current size, refreshing
+// it at once rather than on the next tick
+func repaint() {
+	safeGo(func() {
+		ui.SendCustomEvt("/sys/wnd/resize", nil)
+		ui.SendCustomEvt("/timer/refresh", nil)
+	})
+}
+
+func holdCollectors() {
+	atomic.StoreInt32(&collectHeld, 1)
+	for _, c := range cursor.Source().Snapshot() {
+		c.StopCollector()
+	}
+}
+
+func resumeCollectors() {
+	atomic.StoreInt32(&collectHeld, 0)
+	for _, c := range cursor.Source().Snapshot() {
+		c.SetState(c.State())
+	}
+}
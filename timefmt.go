@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcicen/ctop/config"
+)
+
+// Sample time used to check that a layout contains time fields
+var layoutCheckTime = time.Date(2017, 11, 28, 9, 30, 45, 0, time.UTC)
+
+var durationStyles = []string{"compact", "long"}
+
+// Ensure a timestamp layout, in Go reference time syntax, formats
+// at least one time field and can be read back
+func validTimeFormat(layout string) error {
+	s := layoutCheckTime.Format(layout)
+	if strings.TrimSpace(layout) == "" || s == layout {
+		return fmt.Errorf("layout %q contains no time fields, e.g. 2006-01-02T15:04:05Z07:00", layout)
+	}
+	if _, err := time.Parse(layout, s); err != nil {
+		return fmt.Errorf("layout %q cannot be parsed: %s", layout, err)
+	}
+	return nil
+}
+
+func validDurationStyle(s string) error {
+	if !known(durationStyles, s) {
+		return fmt.Errorf("duration style %q, expected one of: %s", s, strings.Join(durationStyles, ", "))
+	}
+	return nil
+}
+
+// Format a timestamp using the configured layout
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(config.GetVal("timeFormat"))
+}
+
+var durationUnits = []struct {
+	d    time.Duration
+	abbr string
+	name string
+}{
+	{24 * time.Hour, "d", "day"},
+	{time.Hour, "h", "hour"},
+	{time.Minute, "m", "minute"},
+	{time.Second, "s", "second"},
+}
+
+// Format a duration in the configured style, to its two largest units:
+// compact as "3d4h", or long as "3 days 4 hours"
+func formatDuration(d time.Duration) string {
+	long := config.GetVal("durationStyle") == "long"
+	var parts []string
+	for i, u := range durationUnits {
+		n := int64(d / u.d)
+		d -= time.Duration(n) * u.d
+		if n == 0 && (len(parts) > 0 || i < len(durationUnits)-1) {
+			if len(parts) > 0 {
+				break // omit zero second unit
+			}
+			continue
+		}
+		switch {
+		case !long:
+			parts = append(parts, fmt.Sprintf("%d%s", n, u.abbr))
+		case n == 1:
+			parts = append(parts, fmt.Sprintf("%d %s", n, u.name))
+		default:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, u.name))
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if long {
+		return strings.Join(parts, " ")
+	}
+	return strings.Join(parts, "")
+}
+
+// Format a time as RFC 3339 in UTC for export, or an empty string
+// if zero
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Return the formatted uptime of a running container, if known
+func containerUptime(c *Container) string {
+	started := c.Lifecycle().Started
+	if started.IsZero() || c.State() != "running" {
+		return ""
+	}
+	return formatDuration(time.Since(started))
+}
+
+// Return the formatted CPU time used by a running container since
+// it started, if read
+func containerCPUTime(c *Container) string {
+	d := c.Metrics().CPUTime
+	if d < 0 || c.State() != "running" {
+		return ""
+	}
+	return formatDuration(d)
+}
+
+// Return the CPU time used by a running container, e.g.
+// "4h12m since start"
+func cpuTimeDetail(c *Container) string {
+	if s := containerCPUTime(c); s != "" {
+		return s + " since start"
+	}
+	return ""
+}
+
+// Return the time elapsed since a container entered its current
+// state, formatted, if known
+func containerStateAge(c *Container) string {
+	since := c.Lifecycle().Since
+	if since.IsZero() {
+		return ""
+	}
+	// daemon and local clocks may differ slightly
+	d := time.Since(since)
+	if d < 0 {
+		d = 0
+	}
+	return formatDuration(d)
+}
+
+// Return a container state with the time since it was entered and
+// any exit code, e.g. "exited 2h ago (code 137)"
+func containerStateDetail(c *Container) string {
+	s := c.State()
+	if age := containerStateAge(c); age != "" {
+		s += fmt.Sprintf(" %s ago", age)
+	}
+	if l := c.Lifecycle(); l.Exited {
+		s += fmt.Sprintf(" (code %d)", l.ExitCode)
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/bcicen/ctop/config"
+	"github.com/docker/docker/pkg/term"
+)
+
+// Fields available to titleFormat templates
+type titleFields struct {
+	Running    int    // running containers
+	Containers int    // all containers, whatever their state
+	Alerts     int    // alert rules currently firing, over all containers
+	Endpoint   string // address of the container source
+}
+
+// Terminal title set while setTitle is enabled. The title in effect
+// before is saved on the terminal's title stack when first set, and
+// restored from it when ctop exits or releases the terminal
+var title struct {
+	sync.Mutex
+	format string // template source tmpl was parsed from
+	tmpl   *template.Template
+	pushed bool   // previous title saved on the stack
+	last   string // title last set
+}
+
+func parseTitleFormat(s string) (*template.Template, error) {
+	return template.New("title").Option("missingkey=zero").Parse(s)
+}
+
+func validTitleFormat(s string) error {
+	_, err := parseTitleFormat(s)
+	return err
+}
+
+// Set the terminal title from titleFormat if enabled and changed,
+// restoring the previous title if disabled since set. Called from the
+// refresh handler once the grid is rendered
+func updateTitle() {
+	title.Lock()
+	defer title.Unlock()
+	if quitRequested() || !term.IsTerminal(os.Stdout.Fd()) {
+		return
+	}
+	if !config.GetSwitchVal("setTitle") {
+		restoreTitleLocked()
+		return
+	}
+	s, err := formatTitle()
+	if err != nil {
+		log.Errorf("invalid titleFormat: %s", err)
+		return
+	}
+	if !title.pushed {
+		fmt.Fprint(os.Stdout, "\033[22;0t")
+		title.pushed = true
+	}
+	if s != title.last {
+		fmt.Fprintf(os.Stdout, "\033]0;%s\a", s)
+		title.last = s
+	}
+}
+
+// Restore the title in effect before ctop set one, if it did
+func restoreTitle() {
+	title.Lock()
+	defer title.Unlock()
+	restoreTitleLocked()
+}
+
+func restoreTitleLocked() {
+	if !title.pushed {
+		return
+	}
+	fmt.Fprint(os.Stdout, "\033[23;0t")
+	title.pushed = false
+	title.last = ""
+}
+
+// Execute the configured title template, without control characters
+func formatTitle() (string, error) {
+	if s := config.GetVal("titleFormat"); s != title.format || title.tmpl == nil {
+		tmpl, err := parseTitleFormat(s)
+		if err != nil {
+			return "", err
+		}
+		title.format, title.tmpl = s, tmpl
+	}
+
+	var f titleFields
+	for _, c := range cursor.Source().Snapshot() {
+		f.Containers++
+		if c.State() == "running" {
+			f.Running++
+		}
+	}
+	f.Alerts = firingCount()
+	f.Endpoint = cursor.Source().Endpoint()
+
+	var buf bytes.Buffer
+	if err := title.tmpl.Execute(&buf, f); err != nil {
+		return "", err
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, buf.String()), nil
+}
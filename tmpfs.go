@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/metrics"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	defaultShmSize = 64 << 20 // of /dev/shm when not configured
+	tmpfsWarn      = 80       // percent full at which a mount is flagged
+)
+
+// Memory-backed mount of a container, with its configured size in
+// bytes, 0 if unlimited or unknown
+type tmpfsMount struct {
+	Path string
+	Size int64
+}
+
+// Return the tmpfs mounts configured for a container, including its
+// own /dev/shm, sorted by path
+func tmpfsMounts(hc *docker.HostConfig) (mounts []tmpfsMount) {
+	if hc == nil {
+		return nil
+	}
+	// /dev/shm is that of the host or another container when shared
+	if hc.IpcMode == "" || hc.IpcMode == "private" || hc.IpcMode == "shareable" {
+		size := hc.ShmSize
+		if size <= 0 {
+			size = defaultShmSize
+		}
+		mounts = append(mounts, tmpfsMount{"/dev/shm", size})
+	}
+	for p, opts := range hc.Tmpfs {
+		mounts = append(mounts, tmpfsMount{p, tmpfsSizeOpt(opts)})
+	}
+	for _, m := range hc.Mounts {
+		if m.Type == "tmpfs" {
+			mounts = append(mounts, tmpfsMount{m.Target, 0})
+		}
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Path < mounts[j].Path })
+	return mounts
+}
+
+// Return the size given in tmpfs mount options, e.g. "rw,size=64m",
+// in bytes, or 0 if not given in bytes
+func tmpfsSizeOpt(opts string) int64 {
+	for _, opt := range strings.Split(opts, ",") {
+		if !strings.HasPrefix(opt, "size=") {
+			continue
+		}
+		s := strings.ToLower(strings.TrimPrefix(opt, "size="))
+		var shift uint
+		switch {
+		case strings.HasSuffix(s, "k"):
+			shift = 10
+		case strings.HasSuffix(s, "m"):
+			shift = 20
+		case strings.HasSuffix(s, "g"):
+			shift = 30
+		}
+		if shift > 0 {
+			s = s[:len(s)-1]
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		return n << shift
+	}
+	return 0
+}
+
+// Encode tmpfs mounts as container metadata, one "size path" per line
+func formatTmpfsMounts(mounts []tmpfsMount) string {
+	var lines []string
+	for _, m := range mounts {
+		lines = append(lines, fmt.Sprintf("%d %s", m.Size, m.Path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseTmpfsMounts(s string) (mounts []tmpfsMount) {
+	for _, line := range strings.Split(s, "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		size, _ := strconv.ParseInt(parts[0], 10, 64)
+		mounts = append(mounts, tmpfsMount{parts[1], size})
+	}
+	return mounts
+}
+
+// Describe the tmpfs mounts of a container, with their usage read
+// through its mount namespace when the daemon is local and ctop may
+// read the container's /proc entries, or their configured sizes only
+// otherwise. Mounts at least tmpfsWarn percent full are flagged
+func tmpfsDetail(c *Container) string {
+	mounts := parseTmpfsMounts(c.GetMeta("tmpfs mounts"))
+	if len(mounts) == 0 {
+		return ""
+	}
+	var mounted map[string]bool
+	pid := c.GetMeta("pid")
+	if pid != "" && strings.HasPrefix(cursor.Source().Endpoint(), "unix://") {
+		mounted = tmpfsMountpoints(pid)
+	}
+
+	var lines []string
+	for _, m := range mounts {
+		size := "unlimited"
+		if m.Size > 0 {
+			size = cwidgets.ByteFormat(m.Size)
+		}
+		used, total, ok := int64(0), int64(0), mounted[m.Path]
+		if ok {
+			used, total, ok = tmpfsUsage(pid, m.Path)
+		}
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%s %s configured, usage unavailable", m.Path, size))
+			continue
+		}
+		percent := 0
+		if total > 0 {
+			percent = int(used * 100 / total)
+		}
+		line := fmt.Sprintf("%s %s of %s (%d%%)", m.Path, cwidgets.ByteFormat(used), cwidgets.ByteFormat(total), percent)
+		if percent >= tmpfsWarn {
+			line += fmt.Sprintf(" %c nearly full", cwidgets.Glyphs.UpArrow)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Return the tmpfs mount points in the mount namespace of a process,
+// from its mountinfo, or nil if it cannot be read
+func tmpfsMountpoints(pid string) map[string]bool {
+	f, err := os.Open(metrics.HostPath(path.Join("/proc", pid, "mountinfo")))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	mounted := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// optional fields precede the filesystem type, after a "-"
+		fields := strings.Fields(scanner.Text())
+		for i, field := range fields {
+			if field == "-" && i+1 < len(fields) && len(fields) > 4 {
+				if fields[i+1] == "tmpfs" {
+					mounted[fields[4]] = true
+				}
+				break
+			}
+		}
+	}
+	return mounted
+}
+
+// Return the bytes used and available in total on a mount in the mount
+// namespace of a process, reached through its root
+func tmpfsUsage(pid, mountpoint string) (used, total int64, ok bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(metrics.HostPath(path.Join("/proc", pid, "root", mountpoint)), &st); err != nil {
+		return 0, 0, false
+	}
+	bsize := uint64(st.Bsize)
+	total = int64(st.Blocks * bsize)
+	used = total - int64(st.Bfree*bsize)
+	return used, total, true
+}
+
+// Describe the GPUs made available to a container, as devices or
+// through the nvidia runtime
+func gpuDetail(hc *docker.HostConfig, env []string) string {
+	var gpus []string
+	for _, e := range env {
+		if strings.HasPrefix(e, "NVIDIA_VISIBLE_DEVICES=") {
+			if v := strings.TrimPrefix(e, "NVIDIA_VISIBLE_DEVICES="); v != "" && v != "void" && v != "none" {
+				gpus = append(gpus, "nvidia: "+v)
+			}
+		}
+	}
+	if hc != nil {
+		for _, d := range hc.Devices {
+			if strings.HasPrefix(d.PathOnHost, "/dev/nvidia") || strings.HasPrefix(d.PathOnHost, "/dev/dri") {
+				gpus = append(gpus, d.PathInContainer)
+			}
+		}
+	}
+	return strings.Join(gpus, "\n")
+}
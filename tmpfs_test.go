@@ -0,0 +1,39 @@
+// +build !release
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+func TestTmpfsMounts(t *testing.T) {
+	cases := []struct {
+		name string
+		hc   *docker.HostConfig
+		want []tmpfsMount
+	}{
+		{"no host config", nil, nil},
+		{"default shm", &docker.HostConfig{}, []tmpfsMount{{"/dev/shm", defaultShmSize}}},
+		{"shared ipc", &docker.HostConfig{IpcMode: "host"}, nil},
+		{
+			"configured",
+			&docker.HostConfig{
+				ShmSize: 1 << 30,
+				Tmpfs:   map[string]string{"/run": "rw,noexec,size=65536k", "/tmp": "rw", "/cache": "size=50%"},
+			},
+			[]tmpfsMount{{"/cache", 0}, {"/dev/shm", 1 << 30}, {"/run", 64 << 20}, {"/tmp", 0}},
+		},
+	}
+	for _, tc := range cases {
+		got := tmpfsMounts(tc.hc)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+		if parsed := parseTmpfsMounts(formatTmpfsMounts(got)); !reflect.DeepEqual(parsed, tc.want) {
+			t.Errorf("%s: round trip got %+v, want %+v", tc.name, parsed, tc.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	heartbeatInterval = 2 * time.Second  // interval of watched goroutine beats and watchdog checks
+	heartbeatTimeout  = 10 * time.Second // time without a beat after which a goroutine is restarted
+	restartWarn       = 3                // restarts within restartWindow before warning
+	restartWindow     = 5 * time.Minute
+)
+
+// Long-running goroutine restarted by a watchdog if stopped
+type watchedTask struct {
+	name string
+	// run until stopped, calling beat at least every heartbeatInterval
+	// and returning once beat returns false
+	run    func(beat func() bool)
+	hold   func() bool // restarts are held while true, if set
+	last   time.Time   // time of last beat
+	gen    int         // incremented on each start; earlier generations are replaced
+	exited bool
+}
+
+// Watchdog tracking heartbeats of long-running goroutines, restarting
+// any that exit or stop beating until done is closed
+type Watchdog struct {
+	lock     sync.Mutex
+	tasks    map[string]*watchedTask
+	restarts []time.Time // recent unplanned restarts
+	done     chan bool
+}
+
+func NewWatchdog(done chan bool) *Watchdog {
+	w := &Watchdog{
+		tasks: make(map[string]*watchedTask),
+		done:  done,
+	}
+	safeGo(w.Loop)
+	return w
+}
+
+// Run a watched goroutine. While hold returns true, if given, the
+// goroutine is not restarted once stopped
+func (w *Watchdog) Go(name string, hold func() bool, run func(beat func() bool)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	t := &watchedTask{name: name, run: run, hold: hold}
+	w.tasks[name] = t
+	w.start(t)
+}
+
+// Restart a watched goroutine if exited, regardless of hold
+func (w *Watchdog) Restart(name string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if t, ok := w.tasks[name]; ok && t.exited {
+		w.start(t)
+	}
+}
+
+// Start a new generation of a task. Must be called with lock held
+func (w *Watchdog) start(t *watchedTask) {
+	t.gen++
+	t.last = time.Now()
+	t.exited = false
+	gen := t.gen
+
+	beat := func() bool {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		if t.gen != gen {
+			return false
+		}
+		t.last = time.Now()
+		return true
+	}
+	safeGo(func() {
+		t.run(beat)
+		w.lock.Lock()
+		if t.gen == gen {
+			t.exited = true
+		}
+		w.lock.Unlock()
+	})
+}
+
+// Check watched goroutines every heartbeatInterval until done
+func (w *Watchdog) Loop() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// Restart watched goroutines which exited or stopped beating. Holds
+// are evaluated without lock held, as they may take locks of their
+// own, which are also held while reading heartbeats
+func (w *Watchdog) check() {
+	// tasks exit deliberately once done is closed
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+
+	type candidate struct {
+		t   *watchedTask
+		gen int
+	}
+	var stale []candidate
+	w.lock.Lock()
+	for _, t := range w.tasks {
+		if t.exited || time.Since(t.last) >= heartbeatTimeout {
+			stale = append(stale, candidate{t, t.gen})
+		}
+	}
+	w.lock.Unlock()
+
+	for _, s := range stale {
+		t := s.t
+		if t.hold != nil && t.hold() {
+			continue
+		}
+		w.lock.Lock()
+		age := time.Since(t.last)
+		// skip tasks restarted or beating again meanwhile
+		if t.gen != s.gen || (!t.exited && age < heartbeatTimeout) {
+			w.lock.Unlock()
+			continue
+		}
+		if t.exited {
+			log.Warningf("watchdog: %s stopped, restarting", t.name)
+		} else {
+			log.Warningf("watchdog: %s unresponsive for %s, restarting", t.name, age-age%time.Second)
+		}
+		w.start(t)
+		w.restarted()
+		w.lock.Unlock()
+	}
+}
+
+// Record an unplanned restart, warning if restarts keep
+// happening. Must be called with lock held
+func (w *Watchdog) restarted() {
+	now := time.Now()
+	recent := w.restarts[:0]
+	for _, t := range w.restarts {
+		if now.Sub(t) < restartWindow {
+			recent = append(recent, t)
+		}
+	}
+	w.restarts = append(recent, now)
+	if len(w.restarts) >= restartWarn {
+		log.NotifyError("%d internal tasks restarted in the last %s, press V to view the log", len(w.restarts), formatDuration(restartWindow))
+		w.restarts = w.restarts[:0]
+	}
+}
+
+// Return the time since each watched goroutine last beat, in seconds
+func (w *Watchdog) Ages() map[string]float64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	ages := make(map[string]float64, len(w.tasks))
+	for name, t := range w.tasks {
+		ages[name] = time.Since(t.last).Seconds()
+	}
+	return ages
+}
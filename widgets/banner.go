@@ -0,0 +1,45 @@
+package widgets
+
+import (
+	"fmt"
+
+	"github.com/bcicen/ctop/cwidgets"
+	ui "github.com/gizak/termui"
+)
+
+// Full width alert banner, displayed across the top of the screen
+type CTopBanner struct {
+	*ui.Par
+}
+
+func NewCTopBanner() *CTopBanner {
+	p := ui.NewPar("")
+	p.Border = false
+	p.Height = 1
+	p.Bg = ui.ColorRed
+	p.TextFgColor = ui.ColorWhite | ui.AttrBold
+	p.TextBgColor = ui.ColorRed
+	return &CTopBanner{p}
+}
+
+// Set banner text, returning true if banner visibility changed
+func (b *CTopBanner) Set(s string) bool {
+	if s != "" {
+		s = fmt.Sprintf(" %s", s)
+	}
+	changed := (b.Text == "") != (s == "")
+	b.Text = s
+	return changed
+}
+
+func (b *CTopBanner) Active() bool {
+	return b.Text != ""
+}
+
+func (b *CTopBanner) Align() {
+	b.SetWidth(ui.TermWidth())
+}
+
+func (b *CTopBanner) Buffer() ui.Buffer {
+	return cwidgets.ASCIIBuffer(b.Par.Buffer())
+}
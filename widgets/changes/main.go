@@ -0,0 +1,150 @@
+package changes
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bcicen/ctop/cwidgets"
+	ui "github.com/gizak/termui"
+)
+
+// Single filesystem change, of kind 'A'(added), 'C'(changed) or 'D'(deleted)
+type Entry struct {
+	Path string
+	Kind rune
+}
+
+func (e Entry) depth() int { return strings.Count(strings.Trim(e.Path, "/"), "/") }
+
+type byPath []Entry
+
+func (a byPath) Len() int           { return len(a) }
+func (a byPath) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byPath) Less(i, j int) bool { return a[i].Path < a[j].Path }
+
+var kindColors = map[rune]string{
+	'A': "changes.added.fg",
+	'C': "changes.changed.fg",
+	'D': "changes.deleted.fg",
+}
+
+// Scrollable, color-coded tree of container filesystem changes.
+// Only visible lines are rendered, so large change sets remain responsive
+type View struct {
+	ui.Block
+	Title     string
+	Status    string // message displayed in place of entries, e.g. while loading
+	entries   []Entry
+	counts    map[rune]int // entries by kind
+	lines     []Entry      // entries matching filter
+	filter    string
+	cursorPos int
+	offset    int
+}
+
+func NewView() *View {
+	v := &View{Block: *ui.NewBlock()}
+	v.BorderFg = ui.ThemeAttr("menu.border.fg")
+	v.BorderLabelFg = ui.ThemeAttr("menu.label.fg")
+	return v
+}
+
+// Set changes to display, sorted by path
+func (v *View) SetEntries(entries []Entry) {
+	sort.Sort(byPath(entries))
+	v.entries = entries
+	v.counts = make(map[rune]int)
+	for _, e := range entries {
+		v.counts[e.Kind]++
+	}
+	v.Status = ""
+	v.refresh()
+}
+
+// Display only entries whose path contains s
+func (v *View) SetFilter(s string) {
+	v.filter = s
+	v.refresh()
+}
+
+func (v *View) Filter() string { return v.filter }
+
+func (v *View) refresh() {
+	v.lines = v.lines[:0]
+	for _, e := range v.entries {
+		if v.filter == "" || strings.Contains(e.Path, v.filter) {
+			v.lines = append(v.lines, e)
+		}
+	}
+	v.move(0)
+}
+
+func (v *View) rows() int { return v.Height - 2 }
+
+func (v *View) move(n int) {
+	v.cursorPos += n
+	if v.cursorPos >= len(v.lines) {
+		v.cursorPos = len(v.lines) - 1
+	}
+	if v.cursorPos < 0 {
+		v.cursorPos = 0
+	}
+	if v.cursorPos < v.offset {
+		v.offset = v.cursorPos
+	}
+	if v.cursorPos >= v.offset+v.rows() {
+		v.offset = v.cursorPos - v.rows() + 1
+	}
+}
+
+func (v *View) Up()     { v.move(-1); ui.Render(v) }
+func (v *View) Down()   { v.move(1); ui.Render(v) }
+func (v *View) PgUp()   { v.move(-v.rows()); ui.Render(v) }
+func (v *View) PgDown() { v.move(v.rows()); ui.Render(v) }
+
+func (v *View) Buffer() ui.Buffer {
+	v.BorderLabel = fmt.Sprintf(" %s: %d added, %d changed, %d deleted ", v.Title, v.counts['A'], v.counts['C'], v.counts['D'])
+	if v.filter != "" {
+		v.BorderLabel += fmt.Sprintf("(filter: %s, %d shown) ", v.filter, len(v.lines))
+	}
+
+	buf := v.Block.Buffer()
+	maxX := v.X + v.Width - 1
+	write := func(x, y int, s string, fg, bg ui.Attribute) {
+		for _, ch := range s {
+			if x >= maxX {
+				break
+			}
+			buf.Set(x, y, ui.Cell{Ch: ch, Fg: fg, Bg: bg})
+			x++
+		}
+	}
+
+	if v.Status != "" || len(v.lines) == 0 {
+		msg := v.Status
+		if msg == "" {
+			msg = "no changes"
+		}
+		write(v.X+2, v.Y+1, msg, ui.ThemeAttr("par.text.fg"), ui.ThemeAttr("par.text.bg"))
+		return cwidgets.ASCIIBuffer(buf)
+	}
+
+	for i := 0; i < v.rows() && v.offset+i < len(v.lines); i++ {
+		e := v.lines[v.offset+i]
+		bg := ui.ThemeAttr("par.text.bg")
+		if v.offset+i == v.cursorPos {
+			bg = ui.ThemeAttr("inspect.cursor.bg")
+		}
+
+		// show full paths when filtered, otherwise indent by depth
+		text := e.Path
+		if v.filter == "" {
+			text = strings.Repeat("  ", e.depth()) + path.Base(e.Path)
+		}
+		write(v.X+2, v.Y+1+i, fmt.Sprintf("%c %s", e.Kind, text), ui.ThemeAttr(kindColors[e.Kind]), bg)
+	}
+
+	return cwidgets.ASCIIBuffer(buf)
+}
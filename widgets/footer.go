@@ -0,0 +1,117 @@
+package widgets
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bcicen/ctop/cwidgets"
+	"github.com/bcicen/ctop/logging"
+	ui "github.com/gizak/termui"
+)
+
+// Single line footer for displaying short-lived messages,
+// active notifications and a persistent status. Messages may be
+// flashed from any goroutine
+type CTopFooter struct {
+	*ui.Par
+	lock    sync.Mutex
+	flash   string
+	expires time.Time
+	status  string // displayed while no message or notification is
+	shown   bool   // footer displayed on last render
+}
+
+func NewCTopFooter() *CTopFooter {
+	p := ui.NewPar("")
+	p.X = 1
+	p.Border = false
+	p.Height = 1
+	return &CTopFooter{Par: p}
+}
+
+// Display a message in the footer for the given duration
+func (f *CTopFooter) Flash(s string, d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.flash = s
+	f.expires = time.Now().Add(d)
+}
+
+// Set a status displayed while no message or notification is
+func (f *CTopFooter) SetStatus(s string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.status = s
+}
+
+// Remove any currently displayed message
+func (f *CTopFooter) Hide() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.expires = time.Now()
+}
+
+func (f *CTopFooter) Active() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.active()
+}
+
+// Must be called with lock held
+func (f *CTopFooter) active() bool {
+	return f.status != "" || f.flashing() || len(logging.ActiveNotifications()) > 0
+}
+
+// Must be called with lock held
+func (f *CTopFooter) flashing() bool {
+	return time.Now().Before(f.expires)
+}
+
+// Return true once after the footer is no longer displayed
+func (f *CTopFooter) Expired() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.shown && !f.active() {
+		f.shown = false
+		return true
+	}
+	return false
+}
+
+func (f *CTopFooter) Align() {
+	f.SetY(ui.TermHeight() - 1)
+	f.SetWidth(ui.TermWidth() - 1)
+}
+
+func (f *CTopFooter) Buffer() ui.Buffer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.Bg = ui.ThemeAttr("header.bg")
+	f.TextFgColor = ui.ThemeAttr("header.fg")
+	f.TextBgColor = ui.ThemeAttr("header.bg")
+
+	active := logging.ActiveNotifications()
+	switch {
+	case f.flashing():
+		f.Text = fmt.Sprintf(" %s", f.flash)
+	case len(active) > 0:
+		n := active[len(active)-1]
+		f.Text = fmt.Sprintf(" %s %s", n.Time.Format("15:04:05"), n.Msg)
+		if len(active) > 1 {
+			f.Text += fmt.Sprintf(" (+%d more)", len(active)-1)
+		}
+		if n.Error {
+			f.Bg = ui.ColorRed
+			f.TextFgColor = ui.ColorWhite
+			f.TextBgColor = ui.ColorRed
+		}
+	case f.status != "":
+		f.Text = fmt.Sprintf(" %s", f.status)
+	default:
+		return ui.NewBuffer()
+	}
+
+	f.shown = true
+	return cwidgets.ASCIIBuffer(f.Par.Buffer())
+}
@@ -4,22 +4,33 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
 type CTopHeader struct {
-	Time   *ui.Par
-	Count  *ui.Par
-	Filter *ui.Par
-	bg     *ui.Par
+	Time    *ui.Par
+	Count   *ui.Par
+	Filter  *ui.Par
+	Columns *ui.Par
+	HostCpu *ui.Gauge
+	HostMem *ui.Gauge
+	Summary *ui.Par
+	bg      *ui.Par
+
+	showSummary bool
 }
 
 func NewCTopHeader() *CTopHeader {
 	return &CTopHeader{
-		Time:   headerPar(2, timeStr()),
-		Count:  headerPar(27, "-"),
-		Filter: headerPar(47, ""),
-		bg:     headerBg(),
+		Time:    headerPar(2, timeStr()),
+		Count:   headerPar(27, "-"),
+		Filter:  headerPar(47, ""),
+		Columns: headerPar(67, ""),
+		HostCpu: summaryGauge(),
+		HostMem: summaryGauge(),
+		Summary: summaryPar(),
+		bg:      headerBg(),
 	}
 }
 
@@ -30,17 +41,56 @@ func (c *CTopHeader) Buffer() ui.Buffer {
 	buf.Merge(c.Time.Buffer())
 	buf.Merge(c.Count.Buffer())
 	buf.Merge(c.Filter.Buffer())
-	return buf
+	buf.Merge(c.Columns.Buffer())
+	if c.showSummary {
+		buf.Merge(c.HostCpu.Buffer())
+		buf.Merge(c.HostMem.Buffer())
+		buf.Merge(c.Summary.Buffer())
+	}
+	return cwidgets.ASCIIBuffer(buf)
 }
 
 func (c *CTopHeader) Align() {
-	c.bg.SetWidth(ui.TermWidth() - 1)
+	width := ui.TermWidth() - 1
+	c.bg.SetWidth(width)
+
+	// host gauges share the line below header bar
+	y := c.bg.Y + c.bg.Height
+	gaugeWidth := (width - 1) / 2
+	c.HostCpu.SetX(1)
+	c.HostCpu.SetY(y)
+	c.HostCpu.SetWidth(gaugeWidth)
+	c.HostMem.SetX(gaugeWidth + 2)
+	c.HostMem.SetY(y)
+	c.HostMem.SetWidth(gaugeWidth)
+
+	c.Summary.SetY(y + 1)
+	c.Summary.SetWidth(width)
 }
 
 func (c *CTopHeader) Height() int {
+	if c.showSummary {
+		return c.bg.Height + c.HostCpu.Height + c.Summary.Height
+	}
 	return c.bg.Height
 }
 
+// Enable or disable display of host summary
+func (c *CTopHeader) ShowSummary(b bool) {
+	c.showSummary = b
+}
+
+// Set host summary gauge values. A negative percent indicates
+// the value is unavailable
+func (c *CTopHeader) SetHost(cpu int, mem int, memLabel string) {
+	setSummaryGauge(c.HostCpu, "CPU", cpu, fmt.Sprintf("%d%%", cpu))
+	setSummaryGauge(c.HostMem, "MEM", mem, memLabel)
+}
+
+func (c *CTopHeader) SetSummary(s string) {
+	c.Summary.Text = fmt.Sprintf(" %s", s)
+}
+
 func headerBgBordered() *ui.Par {
 	bg := ui.NewPar("")
 	bg.X = 1
@@ -58,8 +108,18 @@ func headerBg() *ui.Par {
 	return bg
 }
 
-func (c *CTopHeader) SetCount(val int) {
-	c.Count.Text = fmt.Sprintf("%d containers", val)
+// Set count of displayed containers, out of the total tracked
+func (c *CTopHeader) SetCount(val, total int) {
+	if val == total {
+		c.Count.Text = fmt.Sprintf("%d containers", val)
+		return
+	}
+	c.Count.Text = fmt.Sprintf("%d/%d containers", val, total)
+}
+
+// Set description of visible columns, if horizontally scrolled
+func (c *CTopHeader) SetColumns(val string) {
+	c.Columns.Text = val
 }
 
 func (c *CTopHeader) SetFilter(val string) {
@@ -75,12 +135,42 @@ func timeStr() string {
 	return fmt.Sprintf("ctop - %s", ts)
 }
 
+func summaryGauge() *ui.Gauge {
+	g := ui.NewGauge()
+	g.Height = 1
+	g.Border = false
+	g.PaddingBottom = 0
+	g.LabelAlign = ui.AlignLeft
+	return g
+}
+
+func setSummaryGauge(g *ui.Gauge, name string, percent int, label string) {
+	if percent < 0 {
+		g.Percent = 0
+		g.Label = fmt.Sprintf(" %s n/a", name)
+		return
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	g.Percent = percent
+	g.Label = fmt.Sprintf(" %s %s", name, label)
+}
+
+func summaryPar() *ui.Par {
+	p := ui.NewPar("")
+	p.X = 1
+	p.Border = false
+	p.Height = 1
+	return p
+}
+
 func headerPar(x int, s string) *ui.Par {
 	p := ui.NewPar(fmt.Sprintf(" %s", s))
 	p.X = x
 	p.Border = false
 	p.Height = 1
-	p.Width = 20
+	p.Width = 25
 	p.Bg = ui.ThemeAttr("header.bg")
 	p.TextFgColor = ui.ThemeAttr("header.fg")
 	p.TextBgColor = ui.ThemeAttr("header.bg")
@@ -3,11 +3,15 @@ package widgets
 import (
 	"strings"
 
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
 var (
-	input_chars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_."
+	input_chars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_. :/"
+
+	// Characters accepted when entering shell commands
+	CommandChars = input_chars + "{}[]()<>|&;$=,'\"*~!?#%@+^`"
 )
 
 type Padding [2]int // x,y padding
@@ -19,6 +23,8 @@ type Input struct {
 	MaxLen      int
 	TextFgColor ui.Attribute
 	TextBgColor ui.Attribute
+	Error       string      // error message displayed below input
+	Chars       string      // characters accepted as input
 	stream      chan string // stream text as it changes
 	padding     Padding
 }
@@ -28,6 +34,7 @@ func NewInput() *Input {
 		Block:       *ui.NewBlock(),
 		Label:       "input",
 		MaxLen:      20,
+		Chars:       input_chars,
 		TextFgColor: ui.ThemeAttr("menu.text.fg"),
 		TextBgColor: ui.ThemeAttr("menu.text.bg"),
 		padding:     Padding{4, 2},
@@ -40,9 +47,25 @@ func NewInput() *Input {
 
 func (i *Input) calcSize() {
 	i.Height = 3 // minimum height
+	if i.Error != "" {
+		i.Height++
+	}
 	i.Width = i.MaxLen + (i.padding[0] * 2)
 }
 
+// Set maximum input length, resizing input to fit
+func (i *Input) SetMaxLen(n int) {
+	i.MaxLen = n
+	i.calcSize()
+}
+
+// Display an error message below the input, or clear
+// the current error if s is empty
+func (i *Input) SetError(s string) {
+	i.Error = s
+	i.calcSize()
+}
+
 func (i *Input) Buffer() ui.Buffer {
 	var cell ui.Cell
 	buf := i.Block.Buffer()
@@ -55,7 +78,18 @@ func (i *Input) Buffer() ui.Buffer {
 		x++
 	}
 
-	return buf
+	if i.Error != "" {
+		x = i.Block.X + 2
+		for _, ch := range i.Error {
+			if x >= i.Block.X+i.Width-2 {
+				break
+			}
+			buf.Set(x, y+1, ui.Cell{Ch: ch, Fg: ui.ColorRed, Bg: i.TextBgColor})
+			x++
+		}
+	}
+
+	return cwidgets.ASCIIBuffer(buf)
 }
 
 func (i *Input) Stream() chan string {
@@ -63,13 +97,23 @@ func (i *Input) Stream() chan string {
 	return i.stream
 }
 
+// Send current text to stream, if one is open
+func (i *Input) send() {
+	if i.stream != nil {
+		i.stream <- i.Data
+	}
+}
+
 func (i *Input) KeyPress(e ui.Event) {
 	ch := strings.Replace(e.Path, "/sys/kbd/", "", -1)
+	if ch == "<space>" {
+		ch = " "
+	}
 	if ch == "C-8" {
 		idx := len(i.Data) - 1
 		if idx > -1 {
 			i.Data = i.Data[0:idx]
-			i.stream <- i.Data
+			i.send()
 		}
 		ui.Render(i)
 		return
@@ -77,9 +121,9 @@ func (i *Input) KeyPress(e ui.Event) {
 	if len(i.Data) >= i.MaxLen {
 		return
 	}
-	if strings.Index(input_chars, ch) > -1 {
+	if len(ch) == 1 && strings.Index(i.Chars, ch) > -1 {
 		i.Data += ch
-		i.stream <- i.Data
+		i.send()
 		ui.Render(i)
 	}
 }
@@ -0,0 +1,250 @@
+package inspector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bcicen/ctop/cwidgets"
+	ui "github.com/gizak/termui"
+)
+
+// Scrollable, collapsible tree view of a JSON document
+type Viewer struct {
+	ui.Block
+	root      *node
+	lines     []*node // currently visible nodes
+	cursorPos int
+	offset    int // first visible line
+}
+
+func NewViewer() *Viewer {
+	v := &Viewer{
+		Block: *ui.NewBlock(),
+		root:  &node{object: true, expanded: true},
+	}
+	v.BorderFg = ui.ThemeAttr("menu.border.fg")
+	v.BorderLabelFg = ui.ThemeAttr("menu.label.fg")
+	return v
+}
+
+// Load a new document, preserving the expanded state and
+// cursor position of any paths also present in the new document
+func (v *Viewer) Load(doc interface{}) error {
+	data, err := decode(doc)
+	if err != nil {
+		return err
+	}
+
+	expanded := make(map[string]bool)
+	for _, n := range v.root.walk(nil) {
+		if n.expanded {
+			expanded[n.path()] = true
+		}
+	}
+	selected := v.selectedPath()
+
+	v.root = newNode("", data, nil)
+	v.root.expanded = true
+	for _, n := range v.root.walk(nil) {
+		if expanded[n.path()] {
+			n.expanded = true
+		}
+	}
+
+	v.refresh()
+	v.setCursor(selected)
+	return nil
+}
+
+func (v *Viewer) selectedPath() string {
+	if n := v.selected(); n != nil {
+		return n.path()
+	}
+	return ""
+}
+
+func (v *Viewer) selected() *node {
+	if v.cursorPos < len(v.lines) {
+		return v.lines[v.cursorPos]
+	}
+	return nil
+}
+
+// Move cursor to the visible node with the given path
+func (v *Viewer) setCursor(path string) {
+	for i, n := range v.lines {
+		if n.path() == path {
+			v.cursorPos = i
+			v.scroll()
+			return
+		}
+	}
+}
+
+// Rebuild list of visible nodes
+func (v *Viewer) refresh() {
+	v.lines = v.lines[:0]
+	for _, c := range v.root.children {
+		v.lines = c.flatten(v.lines)
+	}
+	if v.cursorPos >= len(v.lines) {
+		v.cursorPos = len(v.lines) - 1
+	}
+	if v.cursorPos < 0 {
+		v.cursorPos = 0
+	}
+	v.scroll()
+}
+
+// Number of lines displayed within the border
+func (v *Viewer) rows() int { return v.Height - 2 }
+
+// Adjust offset to keep cursor visible
+func (v *Viewer) scroll() {
+	if v.cursorPos < v.offset {
+		v.offset = v.cursorPos
+	}
+	if v.cursorPos >= v.offset+v.rows() {
+		v.offset = v.cursorPos - v.rows() + 1
+	}
+}
+
+func (v *Viewer) move(n int) {
+	v.cursorPos += n
+	if v.cursorPos >= len(v.lines) {
+		v.cursorPos = len(v.lines) - 1
+	}
+	if v.cursorPos < 0 {
+		v.cursorPos = 0
+	}
+	v.scroll()
+	ui.Render(v)
+}
+
+func (v *Viewer) Up()     { v.move(-1) }
+func (v *Viewer) Down()   { v.move(1) }
+func (v *Viewer) PgUp()   { v.move(-v.rows()) }
+func (v *Viewer) PgDown() { v.move(v.rows()) }
+
+// Expand or collapse the node under the cursor
+func (v *Viewer) Toggle() {
+	n := v.selected()
+	if n == nil || !n.container() {
+		return
+	}
+	n.expanded = !n.expanded
+	v.refresh()
+	ui.Render(v)
+}
+
+// Move the cursor to the next node, in document order, whose key
+// contains s, expanding its parents as needed. Returns false if no
+// matching key is found
+func (v *Viewer) Search(s string) bool {
+	if s == "" {
+		return false
+	}
+	s = strings.ToLower(s)
+
+	all := v.root.walk(nil)[1:]
+	start := 0
+	if cur := v.selected(); cur != nil {
+		for i, n := range all {
+			if n == cur {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	for i := range all {
+		n := all[(start+i)%len(all)]
+		if strings.Contains(strings.ToLower(n.key), s) {
+			n.reveal()
+			v.refresh()
+			v.setCursor(n.path())
+			ui.Render(v)
+			return true
+		}
+	}
+	return false
+}
+
+// Return the path and value of the node under the cursor
+func (v *Viewer) Selected() (path, val string) {
+	n := v.selected()
+	if n == nil {
+		return "", ""
+	}
+	return n.path(), n.text()
+}
+
+// Return display text and color for a node value
+func (v *Viewer) valueText(n *node) (string, ui.Attribute) {
+	switch {
+	case n.object && !n.expanded:
+		return fmt.Sprintf("{%c} %d keys", cwidgets.Glyphs.Ellipsis, len(n.children)), ui.ThemeAttr("par.text.fg")
+	case n.array && !n.expanded:
+		return fmt.Sprintf("[%c] %d items", cwidgets.Glyphs.Ellipsis, len(n.children)), ui.ThemeAttr("par.text.fg")
+	case n.container():
+		return "", ui.ThemeAttr("par.text.fg")
+	}
+
+	switch val := n.val.(type) {
+	case string:
+		b, _ := json.Marshal(val)
+		return string(b), ui.ThemeAttr("inspect.string.fg")
+	case json.Number:
+		return val.String(), ui.ThemeAttr("inspect.number.fg")
+	case bool:
+		return fmt.Sprintf("%t", val), ui.ThemeAttr("inspect.bool.fg")
+	case nil:
+		return "null", ui.ThemeAttr("inspect.null.fg")
+	}
+	return fmt.Sprintf("%v", n.val), ui.ThemeAttr("par.text.fg")
+}
+
+func (v *Viewer) Buffer() ui.Buffer {
+	buf := v.Block.Buffer()
+	maxX := v.X + v.Width - 1
+
+	write := func(x, y int, s string, fg, bg ui.Attribute) int {
+		for _, ch := range s {
+			if x >= maxX {
+				break
+			}
+			buf.Set(x, y, ui.Cell{Ch: ch, Fg: fg, Bg: bg})
+			x++
+		}
+		return x
+	}
+
+	for i := 0; i < v.rows() && v.offset+i < len(v.lines); i++ {
+		n := v.lines[v.offset+i]
+		y := v.Y + 1 + i
+		x := v.X + n.depth*2
+
+		bg := ui.ThemeAttr("par.text.bg")
+		keyFg := ui.ThemeAttr("inspect.key.fg")
+		if v.offset+i == v.cursorPos {
+			bg = ui.ThemeAttr("inspect.cursor.bg")
+		}
+
+		marker := " "
+		if n.container() {
+			marker = "+"
+			if n.expanded {
+				marker = "-"
+			}
+		}
+		x = write(x, y, marker+" ", ui.ThemeAttr("par.text.fg"), bg)
+		x = write(x, y, n.key, keyFg, bg)
+		x = write(x, y, ": ", ui.ThemeAttr("par.text.fg"), bg)
+
+		val, fg := v.valueText(n)
+		write(x, y, val, fg, bg)
+	}
+
+	return cwidgets.ASCIIBuffer(buf)
+}
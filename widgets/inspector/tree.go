@@ -0,0 +1,135 @@
+package inspector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Single key or array element of a JSON document
+type node struct {
+	key      string
+	val      interface{} // leaf value; nil for objects and arrays
+	children []*node
+	parent   *node
+	depth    int
+	array    bool // node is an array, children are its elements
+	object   bool // node is an object, children are its keys
+	expanded bool
+}
+
+func (n *node) container() bool { return n.object || n.array }
+
+// Build a node tree from a decoded JSON value
+func newNode(key string, v interface{}, parent *node) *node {
+	n := &node{key: key, parent: parent}
+	if parent != nil {
+		n.depth = parent.depth + 1
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n.object = true
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			n.children = append(n.children, newNode(k, v[k], n))
+		}
+	case []interface{}:
+		n.array = true
+		for i, e := range v {
+			n.children = append(n.children, newNode(fmt.Sprintf("[%d]", i), e, n))
+		}
+	default:
+		n.val = v
+	}
+	return n
+}
+
+// Return the decoded JSON value represented by this node
+func (n *node) value() interface{} {
+	switch {
+	case n.object:
+		m := make(map[string]interface{})
+		for _, c := range n.children {
+			m[c.key] = c.value()
+		}
+		return m
+	case n.array:
+		var a []interface{}
+		for _, c := range n.children {
+			a = append(a, c.value())
+		}
+		return a
+	}
+	return n.val
+}
+
+// Return the node value as text; leaf strings are returned
+// unquoted and objects or arrays as indented JSON
+func (n *node) text() string {
+	if s, ok := n.val.(string); ok {
+		return s
+	}
+	b, err := json.MarshalIndent(n.value(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", n.val)
+	}
+	return string(b)
+}
+
+// Dotted path from the document root to this node
+func (n *node) path() string {
+	var parts []string
+	for p := n; p != nil && p.parent != nil; p = p.parent {
+		parts = append([]string{p.key}, parts...)
+	}
+	return strings.Replace(strings.Join(parts, "."), ".[", "[", -1)
+}
+
+// Append this node and all visible descendants to list
+func (n *node) flatten(list []*node) []*node {
+	list = append(list, n)
+	if n.expanded {
+		for _, c := range n.children {
+			list = c.flatten(list)
+		}
+	}
+	return list
+}
+
+// Return all nodes in document order, regardless of expansion
+func (n *node) walk(list []*node) []*node {
+	list = append(list, n)
+	for _, c := range n.children {
+		list = c.walk(list)
+	}
+	return list
+}
+
+// Expand all ancestors of this node, making it visible
+func (n *node) reveal() {
+	for p := n.parent; p != nil; p = p.parent {
+		p.expanded = true
+	}
+}
+
+// Decode a document into a generic JSON value, preserving numbers
+func decode(doc interface{}) (interface{}, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -3,6 +3,7 @@ package menu
 import (
 	"sort"
 
+	"github.com/bcicen/ctop/cwidgets"
 	ui "github.com/gizak/termui"
 )
 
@@ -82,12 +83,22 @@ func (m *Menu) Buffer() ui.Buffer {
 	var cell ui.Cell
 	buf := m.Block.Buffer()
 
-	for n, item := range m.items {
+	// scroll items to keep cursor visible
+	rows := m.Height - (m.padding[1] * 2)
+	start := 0
+	if m.cursorPos >= rows {
+		start = m.cursorPos - rows + 1
+	}
+
+	for n, item := range m.items[start:] {
+		if n >= rows {
+			break
+		}
 		x := m.X + m.padding[0]
 		y := m.Y + m.padding[1]
 		for _, ch := range item.Text() {
 			// invert bg/fg colors on currently selected row
-			if m.Selectable && n == m.cursorPos {
+			if m.Selectable && n+start == m.cursorPos {
 				cell = ui.Cell{Ch: ch, Fg: ui.ColorBlack, Bg: m.TextFgColor}
 			} else {
 				cell = ui.Cell{Ch: ch, Fg: m.TextFgColor, Bg: m.TextBgColor}
@@ -97,7 +108,7 @@ func (m *Menu) Buffer() ui.Buffer {
 		}
 	}
 
-	return buf
+	return cwidgets.ASCIIBuffer(buf)
 }
 
 func (m *Menu) Up() {
@@ -127,5 +138,14 @@ func (m *Menu) calcSize() {
 	}
 
 	m.Width += (m.padding[0] * 2)
+	// fit border label
+	if len(m.BorderLabel)+4 > m.Width {
+		m.Width = len(m.BorderLabel) + 4
+	}
 	m.Height = len(items) + (m.padding[1] * 2)
+
+	// limit height to terminal size
+	if max := ui.TermHeight() - m.Y; m.Height > max {
+		m.Height = max
+	}
 }
@@ -0,0 +1,194 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bcicen/ctop/cwidgets"
+	ui "github.com/gizak/termui"
+)
+
+// Scrollable, searchable pane of command output
+type View struct {
+	ui.Block
+	Title   string
+	Status  string // shown in the border label, e.g. exit status
+	lines   []string
+	colors  []ui.Attribute // foreground color of each line, if set
+	search  string
+	matches []int // indexes of lines containing search
+	match   int   // index of current match in matches
+	offset  int
+}
+
+func NewView() *View {
+	v := &View{Block: *ui.NewBlock()}
+	v.BorderFg = ui.ThemeAttr("menu.border.fg")
+	v.BorderLabelFg = ui.ThemeAttr("menu.label.fg")
+	return v
+}
+
+// Set output text to display, replacing any existing text
+func (v *View) SetText(s string) {
+	s = strings.Replace(strings.TrimRight(s, "\n"), "\t", "    ", -1)
+	v.lines, v.colors = nil, nil
+	if s != "" {
+		v.lines = strings.Split(s, "\n")
+	}
+	v.offset = 0
+	v.SetSearch(v.search)
+}
+
+// Set lines to display, each with the given foreground color,
+// keeping the scroll position unless scrolled to the bottom, in
+// which case the view follows new lines
+func (v *View) SetLines(lines []string, colors []ui.Attribute) {
+	follow := v.AtBottom()
+	v.lines, v.colors = lines, colors
+	if follow {
+		v.offset = len(v.lines)
+	}
+	v.scroll(0)
+	v.findMatches()
+}
+
+// Return whether the last line is in view
+func (v *View) AtBottom() bool { return v.offset+v.rows() >= len(v.lines) }
+
+// Highlight lines containing s, scrolling to the first match
+func (v *View) SetSearch(s string) {
+	v.search = s
+	v.match = 0
+	v.findMatches()
+	v.show()
+}
+
+func (v *View) findMatches() {
+	v.matches = v.matches[:0]
+	if v.search == "" {
+		return
+	}
+	for n, line := range v.lines {
+		if strings.Contains(line, v.search) {
+			v.matches = append(v.matches, n)
+		}
+	}
+	if v.match >= len(v.matches) {
+		v.match = 0
+	}
+}
+
+func (v *View) Search() string { return v.search }
+
+// Scroll to the next or previous match, wrapping around
+func (v *View) Next() { v.step(1) }
+func (v *View) Prev() { v.step(-1) }
+
+func (v *View) step(n int) {
+	if len(v.matches) == 0 {
+		return
+	}
+	v.match = (v.match + n + len(v.matches)) % len(v.matches)
+	v.show()
+	ui.Render(v)
+}
+
+// Scroll the current match into view
+func (v *View) show() {
+	if len(v.matches) == 0 {
+		return
+	}
+	line := v.matches[v.match]
+	if line < v.offset || line >= v.offset+v.rows() {
+		v.scroll(line - v.rows()/2 - v.offset)
+	}
+}
+
+func (v *View) rows() int { return v.Height - 2 }
+
+func (v *View) scroll(n int) {
+	v.offset += n
+	if max := len(v.lines) - v.rows(); v.offset > max {
+		v.offset = max
+	}
+	if v.offset < 0 {
+		v.offset = 0
+	}
+}
+
+func (v *View) Up()     { v.scroll(-1); ui.Render(v) }
+func (v *View) Down()   { v.scroll(1); ui.Render(v) }
+func (v *View) PgUp()   { v.scroll(-v.rows()); ui.Render(v) }
+func (v *View) PgDown() { v.scroll(v.rows()); ui.Render(v) }
+func (v *View) Top()    { v.offset = 0; ui.Render(v) }
+func (v *View) Bottom() { v.scroll(len(v.lines)); ui.Render(v) }
+
+func (v *View) Buffer() ui.Buffer {
+	v.BorderLabel = fmt.Sprintf(" %s ", v.Title)
+	if v.Status != "" {
+		v.BorderLabel += fmt.Sprintf("- %s ", v.Status)
+	}
+	if v.search != "" {
+		pos := 0
+		if len(v.matches) > 0 {
+			pos = v.match + 1
+		}
+		v.BorderLabel += fmt.Sprintf("(search: %s, %d/%d) ", v.search, pos, len(v.matches))
+	}
+
+	buf := v.Block.Buffer()
+	maxX := v.X + v.Width - 1
+	fg, bg := ui.ThemeAttr("par.text.fg"), ui.ThemeAttr("par.text.bg")
+
+	if len(v.lines) == 0 {
+		for x, ch := range []rune("no output") {
+			buf.Set(v.X+2+x, v.Y+1, ui.Cell{Ch: ch, Fg: fg, Bg: bg})
+		}
+		return cwidgets.ASCIIBuffer(buf)
+	}
+
+	var current = -1
+	if len(v.matches) > 0 {
+		current = v.matches[v.match]
+	}
+
+	for i := 0; i < v.rows() && v.offset+i < len(v.lines); i++ {
+		n := v.offset + i
+		line := []rune(v.lines[n])
+
+		// mark matched spans, by rune index
+		marked := make([]bool, len(line))
+		if v.search != "" {
+			q := []rune(v.search)
+			for j := 0; j+len(q) <= len(line); j++ {
+				if string(line[j:j+len(q)]) == v.search {
+					for k := range q {
+						marked[j+k] = true
+					}
+				}
+			}
+		}
+
+		lineFg, lineBg := fg, bg
+		if n < len(v.colors) {
+			lineFg = v.colors[n]
+		}
+		if n == current {
+			lineBg = ui.ThemeAttr("inspect.cursor.bg")
+		}
+		x := v.X + 2
+		for j, ch := range line {
+			if x >= maxX {
+				break
+			}
+			cell := ui.Cell{Ch: ch, Fg: lineFg, Bg: lineBg}
+			if marked[j] {
+				cell.Fg, cell.Bg = ui.ThemeAttr("output.match.fg"), ui.ThemeAttr("output.match.bg")
+			}
+			buf.Set(x, v.Y+1+i, cell)
+			x++
+		}
+	}
+
+	return cwidgets.ASCIIBuffer(buf)
+}